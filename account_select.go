@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// How the account number is matched against the dropdown item text
+const (
+	// AccountMatchContains matches items whose text contains the number (default)
+	AccountMatchContains = "contains"
+	// AccountMatchWord matches the number only as a whole word, so 123 doesn't hit 91234
+	AccountMatchWord = "word"
+	// AccountMatchExact matches items whose trimmed text is exactly the number
+	AccountMatchExact = "exact"
+)
+
+var accountMatchMode = AccountMatchContains
+
+// SetAccountMatchMode selects how the account number is matched in the dropdown
+func SetAccountMatchMode(mode string) error {
+	switch mode {
+	case "":
+		accountMatchMode = AccountMatchContains
+	case AccountMatchContains, AccountMatchWord, AccountMatchExact:
+		accountMatchMode = mode
+	default:
+		return fmt.Errorf("account match mode must be %q, %q or %q, got %q",
+			AccountMatchContains, AccountMatchWord, AccountMatchExact, mode)
+	}
+	return nil
+}
+
+// Account selection errors
+var (
+	// ErrAccountNotFound means no dropdown item matched the account number
+	ErrAccountNotFound = errors.New("account_not_found")
+	// ErrAmbiguousAccount means several dropdown items matched the account number
+	ErrAmbiguousAccount = errors.New("ambiguous_account")
+)
+
+// selectAccountJS counts the dropdown items matching the account number and
+// clicks the item only when exactly one matches. Returns the matching texts.
+const selectAccountJS = `
+	(function(account, mode, items) {
+		const escaped = account.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+		const word = new RegExp('(^|[^0-9A-Za-z])' + escaped + '([^0-9A-Za-z]|$)');
+		const matches = (text) => {
+			switch (mode) {
+			case 'exact': return text.trim() === account;
+			case 'word': return word.test(text);
+			default: return text.includes(account);
+			}
+		};
+		const links = Array.from(document.querySelectorAll(items))
+			.filter(link => matches(link.textContent));
+		if (links.length === 1) links[0].click();
+		return links.map(link => link.textContent.trim());
+	})(%s, %s, %s)
+`
+
+// selectAccount picks the dropdown item for the account number. It refuses to
+// guess: zero or several matches are configuration problems, not clicks.
+func selectAccount(ctx context.Context, sel Selectors, accountNumber string, logger Logger) error {
+	account, _ := json.Marshal(accountNumber)
+	mode, _ := json.Marshal(accountMatchMode)
+	items, _ := json.Marshal(sel.AccountItem)
+
+	var matched []string
+	err := chromedp.Run(ctx,
+		settleAfter(chromedp.Evaluate(fmt.Sprintf(selectAccountJS, account, mode, items), &matched), 2*time.Second),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to select account %s: %w", accountNumber, err)
+	}
+	if err := accountMatchError(accountNumber, matched); err != nil {
+		return err
+	}
+	logVerbose(logger, fmt.Sprintf("Account dropdown item matched: %s", matched[0]))
+	return nil
+}
+
+// accountMatchError returns the error for anything but exactly one matching item
+func accountMatchError(accountNumber string, matched []string) error {
+	switch len(matched) {
+	case 0:
+		return fmt.Errorf("%w: no dropdown item matches %s (match mode %s)",
+			ErrAccountNotFound, accountNumber, accountMatchMode)
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("%w: %d dropdown items match %s (match mode %s): %q",
+			ErrAmbiguousAccount, len(matched), accountNumber, accountMatchMode, matched)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetAccountMatchMode(t *testing.T) {
+	prev := accountMatchMode
+	t.Cleanup(func() { accountMatchMode = prev })
+
+	tests := []struct {
+		mode     string
+		wantMode string
+		wantErr  bool
+	}{
+		{"", AccountMatchContains, false},
+		{"contains", AccountMatchContains, false},
+		{"word", AccountMatchWord, false},
+		{"exact", AccountMatchExact, false},
+		{"prefix", "", true},
+		{"Exact", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			err := SetAccountMatchMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetAccountMatchMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if err == nil && accountMatchMode != tt.wantMode {
+				t.Errorf("accountMatchMode = %q, want %q", accountMatchMode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestAccountMatchError(t *testing.T) {
+	tests := []struct {
+		name    string
+		matched []string
+		wantErr error
+	}{
+		{"one match", []string{"Особовий рахунок 123456"}, nil},
+		{"no match", nil, ErrAccountNotFound},
+		{"several matches", []string{"123456", "9123456"}, ErrAmbiguousAccount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := accountMatchError("123456", tt.matched)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("accountMatchError() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// WarmupResponse reports the result of pre-launching the browser pool
+type WarmupResponse struct {
+	Warmed    int `json:"warmed"`
+	PoolSize  int `json:"pool_size"`
+	TabsInUse int `json:"tabs_in_use"`
+	MaxTabs   int `json:"max_tabs"` // 0 = no cap
+}
+
+// handleAdminWarmup pre-launches browser pool allocators so the next job skips the Chrome cold start
+func handleAdminWarmup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if browserPool == nil {
+		jsonError(w, "Browser pool not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	warmed, err := browserPool.Warmup()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Browser pool warmup failed", "warmed", warmed, "error", err)
+		jsonError(w, "Failed to warm up browser pool", http.StatusInternalServerError)
+		return
+	}
+
+	tabs, maxTabs := browserPool.InUse()
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(WarmupResponse{
+		Warmed:    warmed,
+		PoolSize:  browserPool.Size(),
+		TabsInUse: tabs,
+		MaxTabs:   maxTabs,
+	})
+}
+
+// handleAdminFlags returns the effective feature flags and their sources
+func handleAdminFlags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]interface{}{"flags": featureFlags.All()})
+}
+
+// MaintenanceRequest toggles maintenance mode
+type MaintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// handleAdminMaintenance reports (GET) or toggles (PUT) maintenance mode
+func handleAdminMaintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req MaintenanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			jsonError(w, "Request body must contain 'enabled'", http.StatusBadRequest)
+			return
+		}
+		jobManager.SetMaintenance(*req.Enabled)
+	default:
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]bool{"maintenance": jobManager.InMaintenance()})
+}
+
+// handleAdminScrapingHealth lists users whose page structure checks keep failing
+func handleAdminScrapingHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	broken, err := ListBrokenScraping()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get scraping health", "error", err)
+		jsonError(w, "Failed to get scraping health", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]interface{}{
+		"scraping_broken": len(broken) > 0,
+		"threshold":       scrapingBrokenThreshold,
+		"broken_users":    broken,
+	})
+}
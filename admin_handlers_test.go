@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleAdminWarmup(t *testing.T) {
+	warmPool := func() *BrowserPool {
+		p := NewBrowserPool(2, 1, 4)
+		p.allocators = []*pooledAllocator{fakeAllocator(1)}
+		return p
+	}
+	closedPool := func() *BrowserPool {
+		p := NewBrowserPool(2, 1, 0)
+		p.Close()
+		return p
+	}
+	tests := []struct {
+		name       string
+		method     string
+		pool       *BrowserPool
+		wantStatus int
+		want       WarmupResponse
+	}{
+		{"wrong method", http.MethodGet, warmPool(), http.StatusMethodNotAllowed, WarmupResponse{}},
+		{"no pool", http.MethodPost, nil, http.StatusServiceUnavailable, WarmupResponse{}},
+		{"already warm", http.MethodPost, warmPool(), http.StatusOK, WarmupResponse{Warmed: 0, PoolSize: 1, TabsInUse: 1, MaxTabs: 4}},
+		{"closed pool", http.MethodPost, closedPool(), http.StatusInternalServerError, WarmupResponse{}},
+	}
+	prev := browserPool
+	t.Cleanup(func() { browserPool = prev })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			browserPool = tt.pool
+			rec := httptest.NewRecorder()
+			handleAdminWarmup(rec, httptest.NewRequest(tt.method, "/api/admin/warmup", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var got WarmupResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("response = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAdminMaintenance(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		paused     bool
+		wantStatus int
+		wantPaused bool
+	}{
+		{"get while running", http.MethodGet, "", false, http.StatusOK, false},
+		{"get while paused", http.MethodGet, "", true, http.StatusOK, true},
+		{"pause", http.MethodPut, `{"enabled":true}`, false, http.StatusOK, true},
+		{"resume", http.MethodPut, `{"enabled":false}`, true, http.StatusOK, false},
+		{"missing enabled", http.MethodPut, `{}`, true, http.StatusBadRequest, true},
+		{"wrong method", http.MethodPost, `{"enabled":true}`, false, http.StatusMethodNotAllowed, false},
+	}
+	prev := jobManager
+	t.Cleanup(func() { jobManager = prev })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobManager = NewJobManagerWithExecutor(&fakeExecutor{})
+			jobManager.SetMaintenance(tt.paused)
+			rec := httptest.NewRecorder()
+			handleAdminMaintenance(rec, httptest.NewRequest(tt.method, "/api/admin/maintenance", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := jobManager.InMaintenance(); got != tt.wantPaused {
+				t.Errorf("InMaintenance() = %v, want %v", got, tt.wantPaused)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var got map[string]bool
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got["maintenance"] != tt.wantPaused {
+				t.Errorf("response maintenance = %v, want %v", got["maintenance"], tt.wantPaused)
+			}
+		})
+	}
+}
+
+func TestQueueJobRejectedDuringMaintenance(t *testing.T) {
+	prev := jobManager
+	t.Cleanup(func() { jobManager = prev })
+	jobManager = NewJobManagerWithExecutor(&fakeExecutor{})
+	jobManager.SetMaintenance(true)
+
+	rec := httptest.NewRecorder()
+	handleCreateDryRunJob(rec, newAuthedRequest(http.MethodPost, "/api/jobs/dry-run", "", 1))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503 (%s)", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got == "" {
+		t.Error("Retry-After header not set")
+	}
+}
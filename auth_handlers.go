@@ -5,8 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +21,14 @@ var (
 	jwtSecret       []byte
 	accessTokenTTL  = 15 * time.Minute
 	refreshTokenTTL = 7 * 24 * time.Hour
+	jwtLeeway       = defaultJWTLeeway
+)
+
+const (
+	// defaultJWTLeeway absorbs small client/server clock skew on exp, nbf and iat
+	defaultJWTLeeway = 30 * time.Second
+	// maxJWTLeeway caps the leeway so it can't quietly extend token lifetimes
+	maxJWTLeeway = 5 * time.Minute
 )
 
 // SetJWTConfig configures JWT settings
@@ -30,6 +42,27 @@ func SetJWTConfig(secret string, accessTTL, refreshTTL time.Duration) {
 	}
 }
 
+// SetJWTLeeway sets the clock-skew leeway applied when validating access tokens
+func SetJWTLeeway(d time.Duration) {
+	jwtLeeway = d
+}
+
+// ParseJWTLeeway parses JWT_LEEWAY; empty means the default. The leeway must
+// be between 0 and maxJWTLeeway.
+func ParseJWTLeeway(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultJWTLeeway, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 || d > maxJWTLeeway {
+		return 0, fmt.Errorf("must be between 0 and %v, got %s", maxJWTLeeway, s)
+	}
+	return d, nil
+}
+
 // Claims for JWT tokens
 type Claims struct {
 	UserID int64 `json:"user_id"`
@@ -75,8 +108,7 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
@@ -92,7 +124,7 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user exists
-	existing, err := GetUserByEmail(req.Email)
+	existing, err := GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
 		jsonError(w, "Database error", http.StatusInternalServerError)
 		return
@@ -104,6 +136,10 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 
 	// Create user
 	user, err := CreateUser(req.Email, req.Password)
+	if errors.Is(err, ErrEmailTaken) {
+		jsonError(w, "Email already registered", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		jsonError(w, "Failed to create user", http.StatusInternalServerError)
 		return
@@ -111,7 +147,7 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(UserResponse{
+	newJSONEncoder(w).Encode(UserResponse{
 		ID:        user.ID,
 		Email:     user.Email,
 		CreatedAt: user.CreatedAt,
@@ -126,30 +162,62 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 
+	// Throttle password guessing per client and per account
+	ipKey, emailKey := "ip:"+clientIP(r), "email:"+req.Email
+	if retryAfter, ok := loginLimiter.Allow(ipKey, emailKey); !ok {
+		slog.WarnContext(r.Context(), "Login rate limit hit", "email", req.Email, "ip", clientIP(r))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		jsonError(w, "Too many failed login attempts. Please try again later.", http.StatusTooManyRequests)
+		return
+	}
+
 	// Find user
-	user, err := GetUserByEmail(req.Email)
+	user, err := GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
 		jsonError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 	if user == nil {
+		slog.WarnContext(r.Context(), "Failed login for unknown email", "email", req.Email, "ip", clientIP(r))
+		loginLimiter.RecordFailure(ipKey, emailKey)
 		jsonError(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
-	// Verify password
+	// Verify password. A wrong one gets the same 401 as an unknown email even
+	// on a locked account, so the lock doesn't reveal that the email exists.
 	if !VerifyPassword(user.PasswordHash, req.Password) {
+		slog.WarnContext(r.Context(), "Failed login", "user_id", user.ID, "ip", clientIP(r))
+		loginLimiter.RecordFailure(ipKey, emailKey)
+		if accountLockout.Threshold > 0 {
+			lockedUntil, err := RecordFailedLogin(user.ID, accountLockout.Threshold, accountLockout.Window, accountLockout.Duration)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Failed to record failed login", "user_id", user.ID, "error", err)
+			} else if lockedUntil != nil {
+				slog.WarnContext(r.Context(), "User locked after repeated failed logins", "user_id", user.ID, "locked_until", lockedUntil.Format(time.RFC3339))
+			}
+		}
 		jsonError(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
+	if wait := lockedFor(user, time.Now()); wait > 0 {
+		slog.WarnContext(r.Context(), "Login for locked user", "user_id", user.ID, "ip", clientIP(r))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		jsonError(w, "Account is temporarily locked after too many failed logins", http.StatusLocked)
+		return
+	}
+	loginLimiter.Reset(emailKey)
+	if err := ClearFailedLogins(user.ID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to clear failed logins", "user_id", user.ID, "error", err)
+	}
+
 	// Generate tokens
 	accessToken, err := generateAccessToken(user.ID)
 	if err != nil {
@@ -157,14 +225,14 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshToken, err := generateRefreshToken(user.ID)
+	refreshToken, err := generateRefreshToken(r, user.ID)
 	if err != nil {
 		jsonError(w, "Failed to generate refresh token", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(TokenResponse{
+	newJSONEncoder(w).Encode(TokenResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresIn:    int(accessTokenTTL.Seconds()),
@@ -179,8 +247,7 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req RefreshRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
@@ -195,6 +262,9 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 	// Find token
 	userID, expiresAt, err := GetRefreshToken(tokenHash)
 	if err != nil {
+		if ownerID, rotated, rerr := GetRotatedRefreshToken(tokenHash); rerr == nil && rotated {
+			revokeOnRefreshTokenReuse(ownerID, r)
+		}
 		jsonError(w, "Invalid refresh token", http.StatusUnauthorized)
 		return
 	}
@@ -206,6 +276,21 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Rotate: the presented token is spent whatever happens next
+	if err := DeleteRefreshToken(tokenHash); err != nil {
+		if errors.Is(err, errRefreshTokenNotFound) {
+			// Another request spent it between the lookup and now
+			revokeOnRefreshTokenReuse(userID, r)
+			jsonError(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		jsonError(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+	if err := RecordRotatedRefreshToken(userID, tokenHash, expiresAt); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record rotated refresh token", "user_id", userID, "error", err)
+	}
+
 	// Generate new access token
 	accessToken, err := generateAccessToken(userID)
 	if err != nil {
@@ -213,13 +298,30 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	refreshToken, err := generateRefreshToken(r, userID)
+	if err != nil {
+		jsonError(w, "Failed to generate refresh token", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(TokenResponse{
-		AccessToken: accessToken,
-		ExpiresIn:   int(accessTokenTTL.Seconds()),
+	newJSONEncoder(w).Encode(TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
 	})
 }
 
+// revokeOnRefreshTokenReuse signs the user out everywhere when a rotated
+// refresh token is presented again: either the client or an attacker holds a
+// stolen copy, and there's no telling which.
+func revokeOnRefreshTokenReuse(userID int64, r *http.Request) {
+	slog.WarnContext(r.Context(), "Refresh token reuse; revoking all refresh tokens", "user_id", userID, "ip", clientIP(r))
+	if err := DeleteUserRefreshTokens(userID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to revoke refresh tokens", "user_id", userID, "error", err)
+	}
+}
+
 // handleLogout handles user logout
 func handleLogout(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -228,8 +330,7 @@ func handleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req RefreshRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
@@ -239,7 +340,7 @@ func handleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+	newJSONEncoder(w).Encode(map[string]string{"message": "Logged out"})
 }
 
 // generateAccessToken creates a new JWT access token
@@ -256,8 +357,9 @@ func generateAccessToken(userID int64) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// generateRefreshToken creates a new refresh token and stores its hash
-func generateRefreshToken(userID int64) (string, error) {
+// generateRefreshToken creates a new refresh token and stores its hash along
+// with the client that requested it
+func generateRefreshToken(r *http.Request, userID int64) (string, error) {
 	// Generate random token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -269,7 +371,7 @@ func generateRefreshToken(userID int64) (string, error) {
 	tokenHash := hashToken(token)
 	expiresAt := time.Now().Add(refreshTokenTTL)
 
-	if err := SaveRefreshToken(userID, tokenHash, expiresAt); err != nil {
+	if err := SaveRefreshToken(userID, tokenHash, expiresAt, sessionUserAgent(r), clientIP(r)); err != nil {
 		return "", err
 	}
 
@@ -286,7 +388,7 @@ func hashToken(token string) string {
 func parseAccessToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, jwt.WithLeeway(jwtLeeway))
 
 	if err != nil {
 		return nil, err
@@ -299,9 +401,13 @@ func parseAccessToken(tokenString string) (*Claims, error) {
 	return nil, jwt.ErrSignatureInvalid
 }
 
-// jsonError sends a JSON error response
+// jsonError sends a JSON error response, with the request ID when there is one
 func jsonError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
+	body := map[string]string{"error": message}
+	if id := w.Header().Get(requestIDHeader); id != "" {
+		body["request_id"] = id
+	}
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	newJSONEncoder(w).Encode(body)
 }
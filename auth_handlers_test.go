@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestParseJWTLeeway(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", defaultJWTLeeway, false},
+		{"0s", 0, false},
+		{"45s", 45 * time.Second, false},
+		{"5m", maxJWTLeeway, false},
+		{"5m1s", 0, true},
+		{"-1s", 0, true},
+		{"30", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseJWTLeeway(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseJWTLeeway(%q) = %v, %v, want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// signTestToken signs an access token for user 1 with the given expiry and not-before times
+func signTestToken(t *testing.T, expiresAt, notBefore time.Time) string {
+	t.Helper()
+	claims := Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		NotBefore: jwt.NewNumericDate(notBefore),
+	}}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseAccessTokenLeeway(t *testing.T) {
+	prevSecret, prevLeeway := jwtSecret, jwtLeeway
+	t.Cleanup(func() { jwtSecret, jwtLeeway = prevSecret, prevLeeway })
+	jwtSecret = []byte("test-secret-for-leeway")
+
+	now := time.Now()
+	// Token times are whole seconds, so cases stay a few seconds clear of the edge
+	tests := []struct {
+		name      string
+		leeway    time.Duration
+		expiresAt time.Time
+		notBefore time.Time
+		wantValid bool
+	}{
+		{"valid token", 30 * time.Second, now.Add(time.Minute), now.Add(-time.Minute), true},
+		{"expired within the leeway", 30 * time.Second, now.Add(-20 * time.Second), now.Add(-time.Hour), true},
+		{"expired past the leeway", 30 * time.Second, now.Add(-40 * time.Second), now.Add(-time.Hour), false},
+		{"expired without leeway", 0, now.Add(-3 * time.Second), now.Add(-time.Hour), false},
+		{"not yet valid within the leeway", 30 * time.Second, now.Add(time.Hour), now.Add(20 * time.Second), true},
+		{"not yet valid past the leeway", 30 * time.Second, now.Add(time.Hour), now.Add(40 * time.Second), false},
+		{"maximum leeway", maxJWTLeeway, now.Add(-maxJWTLeeway + 5*time.Second), now.Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetJWTLeeway(tt.leeway)
+			claims, err := parseAccessToken(signTestToken(t, tt.expiresAt, tt.notBefore))
+			if (err == nil) != tt.wantValid {
+				t.Fatalf("parseAccessToken() error = %v, want valid %v", err, tt.wantValid)
+			}
+			if err == nil && claims.UserID != 1 {
+				t.Errorf("UserID = %d, want 1", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestParseAccessTokenRejectsOtherSecret(t *testing.T) {
+	prevSecret := jwtSecret
+	t.Cleanup(func() { jwtSecret = prevSecret })
+
+	jwtSecret = []byte("one-secret")
+	token := signTestToken(t, time.Now().Add(time.Minute), time.Now().Add(-time.Minute))
+	jwtSecret = []byte("another-secret")
+	if _, err := parseAccessToken(token); err == nil {
+		t.Error("parseAccessToken() accepted a token signed with another secret")
+	}
+}
+
+// refresh posts a refresh token to handleRefresh and returns the recorder
+func refresh(token string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handleRefresh(rec, newAuthedRequest(http.MethodPost, "/api/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, token), 0))
+	return rec
+}
+
+func TestHandleRefreshRequiresToken(t *testing.T) {
+	rec := refresh("")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleRefreshRotation(t *testing.T) {
+	user := createTestUser(t)
+	first, err := generateRefreshToken(httptest.NewRequest(http.MethodPost, "/", nil), user.ID)
+	if err != nil {
+		t.Fatalf("generateRefreshToken: %v", err)
+	}
+
+	rec := refresh(first)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first refresh status = %d: %s", rec.Code, errorMessage(t, rec))
+	}
+	var resp TokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.RefreshToken == "" || resp.RefreshToken == first {
+		t.Fatalf("refresh token not rotated: %q", resp.RefreshToken)
+	}
+
+	// Replaying the spent token revokes the one issued in its place too
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"replayed token", first},
+		{"token issued by the replayed one", resp.RefreshToken},
+		{"unknown token", "not-a-token"},
+	}
+	for _, tt := range tests {
+		if rec := refresh(tt.token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestHandleRegisterConcurrentSameEmail(t *testing.T) {
+	requireTestDB(t)
+	email := fmt.Sprintf("race-%s@example.com", uuid.NewString())
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE email = $1", email) })
+
+	// Both requests pass the existence check before either inserts; the
+	// unique index must turn the loser into a 409 rather than a 500
+	body := fmt.Sprintf(`{"email":%q,"password":"password123"}`, email)
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handleRegister(rec, newAuthedRequest(http.MethodPost, "/api/auth/register", body, 0))
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	count := map[int]int{}
+	for _, code := range codes {
+		count[code]++
+	}
+	if count[http.StatusCreated] != 1 || count[http.StatusConflict] != 1 {
+		t.Errorf("status codes = %v, want one %d and one %d", codes, http.StatusCreated, http.StatusConflict)
+	}
+}
+
+func TestCreateUserEmailTaken(t *testing.T) {
+	user := createTestUser(t)
+	if _, err := CreateUser(user.Email, "password123"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("CreateUser(existing email) error = %v, want %v", err, ErrEmailTaken)
+	}
+}
@@ -41,6 +41,40 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// adminEmails holds the lowercased emails of admin users
+var adminEmails map[string]bool
+
+// SetAdminEmails configures which users may call admin endpoints
+func SetAdminEmails(emails []string) {
+	adminEmails = make(map[string]bool, len(emails))
+	for _, e := range emails {
+		adminEmails[strings.ToLower(e)] = true
+	}
+}
+
+// AdminMiddleware rejects users that are not admins. Must run after AuthMiddleware.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserIDFromContext(r.Context())
+		if !ok {
+			jsonError(w, "User not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := GetUserByID(r.Context(), userID)
+		if err != nil {
+			jsonError(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if user == nil || !adminEmails[strings.ToLower(user.Email)] {
+			jsonError(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // GetUserIDFromContext retrieves the user ID from context
 func GetUserIDFromContext(ctx context.Context) (int64, bool) {
 	userID, ok := ctx.Value(userIDKey).(int64)
@@ -65,8 +99,9 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
 				w.Header().Set("Access-Control-Max-Age", "86400")
+				w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, Link, X-Request-ID")
 			}
 
 			// Handle preflight
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// defaultUserAgent is the user agent used for all browser sessions
+const defaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// browserAllocatorOptions returns the Chrome flags shared by all browser sessions
+func browserAllocatorOptions() []chromedp.ExecAllocatorOption {
+	return append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-dev-shm-usage", true),
+		chromedp.UserAgent(defaultUserAgent),
+	)
+}
+
+var browserPool *BrowserPool
+
+// BrowserPool reuses Chrome processes (allocators) across jobs. Each allocator
+// serves up to maxTabs concurrent tabs; further tabs spill onto a new allocator.
+// Every tab gets its own browser context, so cookies are not shared between users.
+// With a total tab cap, NewTab waits for a free tab once the pool is saturated.
+type BrowserPool struct {
+	mu         sync.Mutex
+	maxTabs    int
+	warmSize   int
+	allocators []*pooledAllocator
+	closed     bool
+	launching  int // allocators being started outside the lock by Warmup
+
+	// slots holds one token per tab in use; nil means no total cap
+	slots chan struct{}
+}
+
+// pooledAllocator is a single long-lived Chrome process
+type pooledAllocator struct {
+	browserCtx  context.Context
+	cancel      context.CancelFunc
+	allocCancel context.CancelFunc
+	tabs        int
+}
+
+// NewBrowserPool creates a browser pool with the given number of tabs per allocator.
+// warmSize is the number of allocators Warmup pre-launches. maxTotalTabs caps
+// concurrent tabs across all allocators (0 = no cap).
+func NewBrowserPool(maxTabsPerAllocator, warmSize, maxTotalTabs int) *BrowserPool {
+	if maxTabsPerAllocator < 1 {
+		maxTabsPerAllocator = 1
+	}
+	if warmSize < 1 {
+		warmSize = 1
+	}
+	p := &BrowserPool{maxTabs: maxTabsPerAllocator, warmSize: warmSize}
+	if maxTotalTabs > 0 {
+		p.slots = make(chan struct{}, maxTotalTabs)
+	}
+	return p
+}
+
+// NewTab returns an isolated tab context, waiting until ctx is done if the pool
+// is saturated. The returned cancel func closes the tab and releases its slot.
+func (p *BrowserPool) NewTab(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if err := p.acquireSlot(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.releaseSlot()
+		return nil, nil, errors.New("browser pool is closed")
+	}
+	alloc := p.pickAllocator()
+	if alloc == nil {
+		// Launching Chrome is slow; don't hold up other tabs meanwhile
+		p.mu.Unlock()
+		launched, err := startAllocator()
+		if err != nil {
+			p.releaseSlot()
+			return nil, nil, err
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			launched.close()
+			p.releaseSlot()
+			return nil, nil, errors.New("browser pool is closed")
+		}
+		alloc = p.addAllocator(launched)
+	}
+
+	tabCtx, tabCancel := chromedp.NewContext(alloc.browserCtx, chromedp.WithNewBrowserContext())
+	alloc.tabs++
+	p.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			tabCancel()
+			p.mu.Lock()
+			alloc.tabs--
+			p.mu.Unlock()
+			p.releaseSlot()
+		})
+	}
+
+	return tabCtx, cancel, nil
+}
+
+// acquireSlot takes a tab slot, waiting while all are in use
+func (p *BrowserPool) acquireSlot(ctx context.Context) error {
+	if p.slots == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	slog.Info("Browser pool: all tabs in use, waiting", "tabs", cap(p.slots))
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for a browser tab: %w", ctx.Err())
+	}
+}
+
+// releaseSlot returns a tab slot taken by acquireSlot
+func (p *BrowserPool) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// InUse returns the number of open tabs and the total cap (0 = no cap)
+func (p *BrowserPool) InUse() (tabs, limit int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range p.allocators {
+		tabs += a.tabs
+	}
+	return tabs, cap(p.slots)
+}
+
+// Size returns the number of running allocators
+func (p *BrowserPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.allocators)
+}
+
+// Warmup launches allocators until the pool holds warmSize of them and
+// returns how many were started
+func (p *BrowserPool) Warmup() (int, error) {
+	warmed := 0
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return warmed, errors.New("browser pool is closed")
+		}
+		p.evictDead()
+		if len(p.allocators)+p.launching >= p.warmSize {
+			p.mu.Unlock()
+			return warmed, nil
+		}
+		p.launching++
+		p.mu.Unlock()
+
+		a, err := startAllocator()
+
+		p.mu.Lock()
+		p.launching--
+		if err == nil && p.closed {
+			a.close()
+			err = errors.New("browser pool is closed")
+		}
+		if err != nil {
+			p.mu.Unlock()
+			return warmed, err
+		}
+		p.addAllocator(a)
+		p.mu.Unlock()
+		warmed++
+	}
+}
+
+// Close shuts down all Chrome processes
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, a := range p.allocators {
+		a.close()
+	}
+	p.allocators = nil
+}
+
+// close shuts down the allocator's Chrome process
+func (a *pooledAllocator) close() {
+	a.cancel()
+	a.allocCancel()
+}
+
+// alive reports whether Chrome is still running; chromedp cancels the browser
+// context when it loses the connection, e.g. after a crash
+func (a *pooledAllocator) alive() bool {
+	return a.browserCtx.Err() == nil
+}
+
+// evictDead drops allocators whose Chrome has exited, so no new tab is routed
+// to them. Tabs already open on one fail on their own. Callers hold p.mu.
+func (p *BrowserPool) evictDead() {
+	live := p.allocators[:0]
+	for _, a := range p.allocators {
+		if a.alive() {
+			live = append(live, a)
+			continue
+		}
+		slog.Warn("Browser pool: evicting crashed allocator", "open_tabs", a.tabs)
+		a.close()
+	}
+	clear(p.allocators[len(live):])
+	p.allocators = live
+}
+
+// pickAllocator returns the least busy live allocator with a free tab slot, if
+// any. Callers hold p.mu.
+func (p *BrowserPool) pickAllocator() *pooledAllocator {
+	p.evictDead()
+	var best *pooledAllocator
+	for _, a := range p.allocators {
+		if a.tabs >= p.maxTabs {
+			continue
+		}
+		if best == nil || a.tabs < best.tabs {
+			best = a
+		}
+	}
+	return best
+}
+
+// startAllocator starts a new Chrome process. It doesn't touch the pool, so
+// callers run it without holding p.mu.
+func startAllocator() (*pooledAllocator, error) {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), browserAllocatorOptions()...)
+	browserCtx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+
+	// Run with no actions to start the browser
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancel()
+		allocCancel()
+		return nil, fmt.Errorf("failed to start browser: %w", err)
+	}
+
+	return &pooledAllocator{
+		browserCtx:  browserCtx,
+		cancel:      cancel,
+		allocCancel: allocCancel,
+	}, nil
+}
+
+// addAllocator adds a started allocator to the pool. Callers hold p.mu.
+func (p *BrowserPool) addAllocator(a *pooledAllocator) *pooledAllocator {
+	p.allocators = append(p.allocators, a)
+	slog.Info("Browser pool: launched allocator", "allocators", len(p.allocators), "max_tabs", p.maxTabs)
+	return a
+}
+
+// BrowserFingerprint holds per-user browser overrides. Zero values keep the global defaults.
+type BrowserFingerprint struct {
+	UserAgent      string `json:"user_agent,omitempty"`
+	ViewportWidth  int    `json:"viewport_width,omitempty"`
+	ViewportHeight int    `json:"viewport_height,omitempty"`
+	Timezone       string `json:"timezone,omitempty"`
+	Locale         string `json:"locale,omitempty"`
+}
+
+// Validate checks that the fingerprint values are usable
+func (f BrowserFingerprint) Validate() error {
+	if (f.ViewportWidth == 0) != (f.ViewportHeight == 0) {
+		return errors.New("viewport width and height must be set together")
+	}
+	if f.ViewportWidth != 0 && (f.ViewportWidth < 320 || f.ViewportWidth > 3840) {
+		return errors.New("viewport width must be between 320 and 3840")
+	}
+	if f.ViewportHeight != 0 && (f.ViewportHeight < 240 || f.ViewportHeight > 2160) {
+		return errors.New("viewport height must be between 240 and 2160")
+	}
+	if f.Timezone != "" {
+		if _, err := time.LoadLocation(f.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", f.Timezone)
+		}
+	}
+	if f.Locale != "" && !localePattern.MatchString(f.Locale) {
+		return fmt.Errorf("invalid locale %q", f.Locale)
+	}
+	return nil
+}
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}([_-][A-Za-z]{2,4})?$`)
+
+// actions returns the emulation actions that apply the fingerprint to a tab
+func (f BrowserFingerprint) actions() []chromedp.Action {
+	var actions []chromedp.Action
+	if f.UserAgent != "" || f.Locale != "" {
+		ua := f.UserAgent
+		if ua == "" {
+			ua = defaultUserAgent
+		}
+		override := emulation.SetUserAgentOverride(ua)
+		if f.Locale != "" {
+			override = override.WithAcceptLanguage(strings.ReplaceAll(f.Locale, "_", "-"))
+		}
+		actions = append(actions, override)
+	}
+	if f.Locale != "" {
+		actions = append(actions, emulation.SetLocaleOverride().WithLocale(strings.ReplaceAll(f.Locale, "-", "_")))
+	}
+	if f.Timezone != "" {
+		actions = append(actions, emulation.SetTimezoneOverride(f.Timezone))
+	}
+	if f.ViewportWidth > 0 && f.ViewportHeight > 0 {
+		actions = append(actions, chromedp.EmulateViewport(int64(f.ViewportWidth), int64(f.ViewportHeight)))
+	}
+	return actions
+}
+
+// newJobBrowserContext returns a tab from the browser pool, or a standalone
+// browser when no pool is configured (e.g. CLI mode), with the fingerprint
+// applied. waitCtx bounds the wait for a free tab.
+func newJobBrowserContext(waitCtx context.Context, fingerprint BrowserFingerprint) (context.Context, context.CancelFunc, error) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if browserPool == nil {
+		ctx, cancel = createJobBrowserContext()
+	} else {
+		var err error
+		ctx, cancel, err = browserPool.NewTab(waitCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if actions := fingerprint.actions(); len(actions) > 0 {
+		if err := chromedp.Run(ctx, actions...); err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("failed to apply browser fingerprint: %w", err)
+		}
+	}
+	return ctx, cancel, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// fakeAllocator returns an allocator with the given open tabs and no Chrome
+// behind it; cancelling its context makes it look crashed
+func fakeAllocator(tabs int) *pooledAllocator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pooledAllocator{browserCtx: ctx, cancel: cancel, allocCancel: func() {}, tabs: tabs}
+}
+
+func TestNewBrowserPoolClampsSizes(t *testing.T) {
+	p := NewBrowserPool(0, 0, 0)
+	if p.maxTabs != 1 || p.warmSize != 1 || p.slots != nil {
+		t.Errorf("NewBrowserPool(0, 0, 0) = maxTabs %d, warmSize %d, slots %v", p.maxTabs, p.warmSize, p.slots)
+	}
+	if p := NewBrowserPool(3, 2, 5); cap(p.slots) != 5 {
+		t.Errorf("total tab cap = %d, want 5", cap(p.slots))
+	}
+}
+
+func TestPickAllocator(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxTabs int
+		tabs    []int
+		want    int // index of the picked allocator, -1 for none
+	}{
+		{"empty pool", 2, nil, -1},
+		{"one tab each, busy", 1, []int{1}, -1},
+		{"one tab each, free", 1, []int{1, 0}, 1},
+		{"spare capacity", 3, []int{2}, 0},
+		{"all full", 2, []int{2, 2}, -1},
+		{"least busy wins", 4, []int{3, 1, 2}, 1},
+		{"first of equals", 4, []int{2, 2}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBrowserPool(tt.maxTabs, 1, 0)
+			for _, n := range tt.tabs {
+				p.allocators = append(p.allocators, fakeAllocator(n))
+			}
+			got := p.pickAllocator()
+			if tt.want < 0 {
+				if got != nil {
+					t.Errorf("picked allocator with %d tabs, want none", got.tabs)
+				}
+				return
+			}
+			if got != p.allocators[tt.want] {
+				t.Errorf("picked %v, want allocator %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrowserPoolSlotCap(t *testing.T) {
+	p := NewBrowserPool(1, 1, 1)
+	if err := p.acquireSlot(context.Background()); err != nil {
+		t.Fatalf("first acquireSlot: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.acquireSlot(ctx); err == nil {
+		t.Fatal("acquireSlot on a saturated pool succeeded, want the context error")
+	}
+
+	p.releaseSlot()
+	if err := p.acquireSlot(ctx); err != nil {
+		t.Errorf("acquireSlot after release: %v", err)
+	}
+}
+
+func TestEvictDead(t *testing.T) {
+	tests := []struct {
+		name    string
+		crashed []bool
+		want    []int // tabs of the allocators left, in order
+	}{
+		{"all alive", []bool{false, false}, []int{0, 1}},
+		{"one crashed", []bool{false, true, false}, []int{0, 2}},
+		{"all crashed", []bool{true, true}, []int{}},
+		{"empty pool", nil, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBrowserPool(4, 1, 0)
+			for i, crashed := range tt.crashed {
+				a := fakeAllocator(i)
+				if crashed {
+					a.cancel()
+				}
+				p.allocators = append(p.allocators, a)
+			}
+			p.evictDead()
+			got := []int{}
+			for _, a := range p.allocators {
+				got = append(got, a.tabs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allocators left = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickAllocatorSkipsCrashed(t *testing.T) {
+	p := NewBrowserPool(4, 1, 0)
+	crashed, busy := fakeAllocator(0), fakeAllocator(2)
+	crashed.cancel()
+	p.allocators = []*pooledAllocator{crashed, busy}
+
+	if got := p.pickAllocator(); got != busy {
+		t.Errorf("picked %v, want the live allocator", got)
+	}
+	if p.Size() != 1 {
+		t.Errorf("pool size = %d, want 1 after evicting the crashed allocator", p.Size())
+	}
+}
+
+func TestBrowserPoolInUse(t *testing.T) {
+	p := NewBrowserPool(4, 1, 6)
+	p.allocators = []*pooledAllocator{fakeAllocator(2), fakeAllocator(3)}
+	if tabs, limit := p.InUse(); tabs != 5 || limit != 6 {
+		t.Errorf("InUse() = %d, %d, want 5, 6", tabs, limit)
+	}
+}
+
+func TestNewTabOnClosedPool(t *testing.T) {
+	p := NewBrowserPool(1, 1, 1)
+	p.Close()
+	if _, _, err := p.NewTab(context.Background()); err == nil {
+		t.Fatal("NewTab on a closed pool succeeded")
+	}
+	// The slot taken for the failed tab was given back
+	if len(p.slots) != 0 {
+		t.Errorf("%d slots in use, want 0", len(p.slots))
+	}
+	if _, err := p.Warmup(); err == nil {
+		t.Error("Warmup on a closed pool succeeded")
+	}
+}
+
+func TestBrowserFingerprintValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		f       BrowserFingerprint
+		wantErr bool
+	}{
+		{"empty keeps defaults", BrowserFingerprint{}, false},
+		{"full", BrowserFingerprint{UserAgent: "UA", ViewportWidth: 1366, ViewportHeight: 768, Timezone: "Europe/Kyiv", Locale: "uk-UA"}, false},
+		{"underscore locale", BrowserFingerprint{Locale: "uk_UA"}, false},
+		{"width without height", BrowserFingerprint{ViewportWidth: 1024}, true},
+		{"height without width", BrowserFingerprint{ViewportHeight: 768}, true},
+		{"too narrow", BrowserFingerprint{ViewportWidth: 100, ViewportHeight: 768}, true},
+		{"too tall", BrowserFingerprint{ViewportWidth: 1024, ViewportHeight: 5000}, true},
+		{"unknown timezone", BrowserFingerprint{Timezone: "Mars/Olympus"}, true},
+		{"bad locale", BrowserFingerprint{Locale: "ukrainian"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.f.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBrowserFingerprintActions(t *testing.T) {
+	tests := []struct {
+		name string
+		f    BrowserFingerprint
+		want int
+	}{
+		{"defaults add nothing", BrowserFingerprint{}, 0},
+		{"user agent", BrowserFingerprint{UserAgent: "UA"}, 1},
+		{"locale sets UA language and locale", BrowserFingerprint{Locale: "uk-UA"}, 2},
+		{"timezone", BrowserFingerprint{Timezone: "Europe/Kyiv"}, 1},
+		{"viewport", BrowserFingerprint{ViewportWidth: 1024, ViewportHeight: 768}, 1},
+	}
+	for _, tt := range tests {
+		if got := len(tt.f.actions()); got != tt.want {
+			t.Errorf("%s: %d actions, want %d", tt.name, got, tt.want)
+		}
+	}
+}
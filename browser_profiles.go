@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// userDataBaseDir holds a persistent Chrome profile per user; empty keeps
+// every job on an ephemeral profile
+var userDataBaseDir string
+
+// SetUserDataBaseDir enables persistent per-user browser profiles under dir
+func SetUserDataBaseDir(dir string) {
+	userDataBaseDir = dir
+}
+
+// userDataDir returns the user's profile directory, or "" when profiles are ephemeral
+func userDataDir(userID int64) string {
+	if userDataBaseDir == "" || userID <= 0 {
+		return ""
+	}
+	return filepath.Join(userDataBaseDir, strconv.FormatInt(userID, 10))
+}
+
+// newUserBrowserContext returns a browser for a user's job. With persistent
+// profiles the job gets its own Chrome process on the user's profile, so
+// cookies and sessions carry over to the next job; otherwise it is a regular
+// pooled tab. A user's jobs run one at a time, so a profile is never opened
+// by two processes at once.
+func newUserBrowserContext(waitCtx context.Context, userID int64, fingerprint BrowserFingerprint) (context.Context, context.CancelFunc, error) {
+	dir := userDataDir(userID)
+	if dir == "" {
+		return newJobBrowserContext(waitCtx, fingerprint)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create browser profile dir: %w", err)
+	}
+
+	// The profile's Chrome still counts against the pool's tab cap
+	if browserPool != nil {
+		if err := browserPool.acquireSlot(waitCtx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(),
+		append(browserAllocatorOptions(), chromedp.UserDataDir(dir))...)
+	ctx, tabCancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			tabCancel()
+			allocCancel()
+			if browserPool != nil {
+				browserPool.releaseSlot()
+			}
+		})
+	}
+
+	if actions := fingerprint.actions(); len(actions) > 0 {
+		if err := chromedp.Run(ctx, actions...); err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("failed to apply browser fingerprint: %w", err)
+		}
+	}
+	return ctx, cancel, nil
+}
+
+// RemoveUserDataDir deletes a user's persistent browser profile
+func RemoveUserDataDir(userID int64) error {
+	dir := userDataDir(userID)
+	if dir == "" {
+		return nil
+	}
+	return os.RemoveAll(dir)
+}
+
+// PruneUserDataDirs deletes the profiles of users that no longer exist and
+// returns how many were removed
+func PruneUserDataDirs() (int, error) {
+	if userDataBaseDir == "" {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(userDataBaseDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		userID, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		user, err := GetUserByID(context.Background(), userID)
+		if err != nil {
+			return removed, err
+		}
+		if user != nil {
+			continue
+		}
+		if err := RemoveUserDataDir(userID); err != nil {
+			slog.Error("Failed to remove browser profile of deleted user", "user_id", userID, "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// setUserDataBaseDirForTest enables persistent profiles under dir for the test
+func setUserDataBaseDirForTest(t *testing.T, dir string) {
+	t.Helper()
+	prev := userDataBaseDir
+	t.Cleanup(func() { SetUserDataBaseDir(prev) })
+	SetUserDataBaseDir(dir)
+}
+
+func TestUserDataDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		userID int64
+		want   string
+	}{
+		{"ephemeral", "", 7, ""},
+		{"persistent", "/var/lib/profiles", 7, filepath.Join("/var/lib/profiles", "7")},
+		{"no user", "/var/lib/profiles", 0, ""},
+		{"negative user", "/var/lib/profiles", -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUserDataBaseDirForTest(t, tt.base)
+			if got := userDataDir(tt.userID); got != tt.want {
+				t.Errorf("userDataDir(%d) = %q, want %q", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveUserDataDir(t *testing.T) {
+	base := t.TempDir()
+	setUserDataBaseDirForTest(t, base)
+	for _, name := range []string{"7", "8"} {
+		if err := os.MkdirAll(filepath.Join(base, name, "Default"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RemoveUserDataDir(7); err != nil {
+		t.Fatalf("RemoveUserDataDir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "7")); !os.IsNotExist(err) {
+		t.Errorf("profile 7 still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "8")); err != nil {
+		t.Errorf("profile 8 removed: %v", err)
+	}
+	if err := RemoveUserDataDir(7); err != nil {
+		t.Errorf("RemoveUserDataDir of a missing profile: %v", err)
+	}
+}
+
+func TestPruneUserDataDirsWithoutProfiles(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+	}{
+		{"ephemeral", ""},
+		{"missing base dir", filepath.Join(t.TempDir(), "profiles")},
+	}
+	for _, tt := range tests {
+		setUserDataBaseDirForTest(t, tt.base)
+		if n, err := PruneUserDataDirs(); n != 0 || err != nil {
+			t.Errorf("%s: PruneUserDataDirs() = %d, %v, want 0, nil", tt.name, n, err)
+		}
+	}
+}
+
+func TestPruneUserDataDirs(t *testing.T) {
+	user := createTestUser(t)
+	base := t.TempDir()
+	setUserDataBaseDirForTest(t, base)
+
+	live := strconv.FormatInt(user.ID, 10)
+	deleted := strconv.FormatInt(user.ID+1_000_000, 10)
+	for _, name := range []string{live, deleted, "not-a-user"} {
+		if err := os.Mkdir(filepath.Join(base, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := PruneUserDataDirs()
+	if err != nil || n != 1 {
+		t.Fatalf("PruneUserDataDirs() = %d, %v, want 1, nil", n, err)
+	}
+	for name, wantExists := range map[string]bool{live: true, deleted: false, "not-a-user": true} {
+		_, err := os.Stat(filepath.Join(base, name))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("profile %s exists = %v, want %v", name, exists, wantExists)
+		}
+	}
+}
+
+func TestNewUserBrowserContextProfileDirError(t *testing.T) {
+	// A file where the base directory should be makes the profile uncreatable
+	base := filepath.Join(t.TempDir(), "profiles")
+	if err := os.WriteFile(base, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	setUserDataBaseDirForTest(t, base)
+
+	if _, _, err := newUserBrowserContext(context.Background(), 7, BrowserFingerprint{}); err == nil {
+		t.Error("newUserBrowserContext() error = nil, want a profile dir error")
+	}
+}
@@ -2,281 +2,224 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/chromedp/chromedp"
 )
 
-// CheckAndUpdateIfNeeded navigates to the target page, checks values, and updates if needed
-//
-// DRY-RUN mode is controlled by GASOLINA_DRY_RUN env var (default: true/enabled)
-// Set GASOLINA_DRY_RUN=false to enable real submissions.
-//
-// It will:
-//   - Read current value from #last_value field
-//   - Calculate new value (current + previous month's increment)
-//   - Find the input field and "Ввести" button
-//   - In dry-run mode: log what it would do and save a screenshot
-//   - In live mode: actually submit the form
-func CheckAndUpdateIfNeeded(ctx context.Context, config *Config) error {
-	now := time.Now()
-	currentDay := now.Day()
-	currentMonth := int(now.Month())
-
-	// Check if we're within the allowed submission window (1st-5th of month)
-	if currentDay < 1 || currentDay > 5 {
-		log.Printf("Today is day %d of the month - submission only allowed on days 1-5", currentDay)
-		return fmt.Errorf("outside submission window (days 1-5)")
-	}
-
-	log.Printf("Day %d is within submission window (1-5) - proceeding", currentDay)
-
-	// Get the increment for previous month (we submit consumption from last month)
-	increment, prevMonth, err := config.GetIncrementForPreviousMonth(currentMonth)
-	if err != nil {
-		return fmt.Errorf("failed to get increment for previous month %d: %w", prevMonth, err)
-	}
-
-	log.Printf("Using increment from previous month %d: %d", prevMonth, increment)
-
-	// First, navigate to main page to read current value from #last_value field
-	log.Println("Navigating to main page to read current value from #last_value...")
-	var currentValueStr string
-
-	err = chromedp.Run(ctx,
-		chromedp.Navigate("https://gasolina-online.com/"),
-		chromedp.Sleep(2*time.Second),
-		chromedp.WaitReady("body"),
-		chromedp.WaitVisible(`#last_value`, chromedp.ByID),
-		chromedp.Value(`#last_value`, &currentValueStr, chromedp.ByID),
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to read #last_value from main page: %w", err)
-	}
-
-	if currentValueStr == "" {
-		return fmt.Errorf("#last_value field is empty on main page")
-	}
-
-	log.Printf("Current value from #last_value field: %s", currentValueStr)
-
-	// Parse current value
-	var currentValue int
-	_, err = fmt.Sscanf(currentValueStr, "%d", &currentValue)
-	if err != nil {
-		return fmt.Errorf("failed to parse current value '%s': %w", currentValueStr, err)
-	}
-
-	// Calculate new value
-	newValue := currentValue + increment
-	log.Printf("=== CALCULATED VALUE: %d + %d = %d ===", currentValue, increment, newValue)
+// Success criteria for a live submission
+const (
+	// SuccessModeText looks for a success message on the page after submitting
+	SuccessModeText = "text"
+	// SuccessModeRowCount requires the indicator table row count for the current year to increase
+	SuccessModeRowCount = "row_count"
+)
 
-	// Now navigate to indicator page to check for existing records
-	log.Printf("Navigating to: %s", config.CheckURL)
+// ErrSubmitFailed is returned when a submission could not be confirmed as successful
+var ErrSubmitFailed = errors.New("submit_failed")
 
-	err = chromedp.Run(ctx,
-		chromedp.Navigate(config.CheckURL),
-		chromedp.Sleep(2*time.Second),
-		chromedp.WaitReady("body"),
-	)
+// ErrSubmitUncertain marks failures after the submit button was clicked: the
+// reading may already be recorded, so the check must not simply be re-run
+var ErrSubmitUncertain = errors.New("submit_uncertain")
 
-	if err != nil {
-		return fmt.Errorf("failed to navigate to indicator page: %w", err)
-	}
+// Where the current reading is taken from on the element matched by the value selector
+const (
+	ValueSourceValue     = "value"     // form field value (default)
+	ValueSourceInnerText = "innerText" // rendered text content
+	// any "data-*" attribute name is also accepted
+)
 
-	// Check if a record for the current month/year already exists
-	recordExists, err := checkForCurrentMonthRecordInTable(ctx, now, &defaultLogger{})
-	if err != nil {
-		log.Printf("Warning: error checking for existing record: %v", err)
-	}
+// defaultValueSelector is the element holding the last submitted reading
+const defaultValueSelector = "#last_value"
 
-	if recordExists {
-		log.Println("===========================================")
-		log.Println("RECORD ALREADY EXISTS - STOPPING JOB")
-		log.Println("===========================================")
-		log.Printf("Record for %s %d already exists in the system",
-			getUkrainianMonthName(now.Month()), now.Year())
-		log.Println("No submission needed - job complete")
-		log.Println("===========================================")
-		return nil
-	}
+// dataAttributePattern matches valid data- attribute names
+var dataAttributePattern = regexp.MustCompile(`^data-[a-z0-9][a-z0-9_.-]*$`)
 
-	log.Printf("No record found for current month (%s %d)",
-		getUkrainianMonthName(now.Month()), now.Year())
-	log.Printf("Proceeding to submit new value: %d", newValue)
+// isValidValueSource reports whether source is a supported value source
+func isValidValueSource(source string) bool {
+	return source == ValueSourceValue || source == ValueSourceInnerText || dataAttributePattern.MatchString(source)
+}
 
-	// Navigate back to main page where the "Ввести" button is located
-	log.Println("Navigating back to main page to find 'Ввести' button...")
-	err = chromedp.Run(ctx,
-		chromedp.Navigate("https://gasolina-online.com/"),
-		chromedp.Sleep(2*time.Second),
-		chromedp.WaitReady("body"),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to navigate back to main page: %w", err)
+// valueSelector returns the configured value selector and source with defaults applied
+func (c *Config) valueSelector() (string, string) {
+	selector, source := c.ValueSelector, c.ValueSource
+	if selector == "" {
+		selector = defaultValueSelector
 	}
-
-	// Find the modal trigger button (the "Ввести" button that opens the modal)
-	// This button has data-toggle="modal" attribute
-	var modalButtonFound bool
-	err = chromedp.Run(ctx,
-		chromedp.Evaluate(`document.querySelector('button[data-toggle="modal"][data-target="#counterModal"]') !== null`, &modalButtonFound),
-	)
-
-	if err != nil || !modalButtonFound {
-		log.Println("WARNING: Could not find modal trigger button with data-toggle='modal'")
-		_ = SaveScreenshot(ctx, fmt.Sprintf("no_modal_button_%d.png", time.Now().Unix()))
-		return fmt.Errorf("modal trigger button not found on indicator page")
-	}
-
-	log.Println("Found modal trigger button (data-toggle='modal')")
-
-	// Get button data attributes for logging
-	var buttonSerial, buttonValue string
-	_ = chromedp.Run(ctx,
-		chromedp.Evaluate(`document.querySelector('button[data-toggle="modal"][data-target="#counterModal"]').getAttribute('data-serial')`, &buttonSerial),
-		chromedp.Evaluate(`document.querySelector('button[data-toggle="modal"][data-target="#counterModal"]').getAttribute('data-value')`, &buttonValue),
-	)
-	log.Printf("Modal button data: serial=%s, current_value=%s", buttonSerial, buttonValue)
-
-	// Click the modal trigger button to open the modal
-	log.Println("Clicking modal trigger button to open form...")
-	err = chromedp.Run(ctx,
-		chromedp.Click(`button[data-toggle="modal"][data-target="#counterModal"]`, chromedp.ByQuery),
-		chromedp.Sleep(1*time.Second),
-	)
-	if err != nil {
-		_ = SaveScreenshot(ctx, fmt.Sprintf("error_open_modal_%d.png", time.Now().Unix()))
-		return fmt.Errorf("failed to click modal trigger button: %w", err)
+	if source == "" {
+		source = ValueSourceValue
 	}
+	return selector, source
+}
 
-	// Wait for the modal to be visible
-	log.Println("Waiting for modal to appear...")
-	err = chromedp.Run(ctx,
-		chromedp.WaitVisible(`#counterModal`, chromedp.ByID),
-		chromedp.Sleep(500*time.Millisecond),
-	)
-	if err != nil {
-		_ = SaveScreenshot(ctx, fmt.Sprintf("error_modal_not_visible_%d.png", time.Now().Unix()))
-		return fmt.Errorf("modal did not appear: %w", err)
+// readingUnchanged reports whether submitting newValue would leave the reading
+// as it is: it equals the current reading or the value the form already holds
+func readingUnchanged(currentValue, newValue float64, formValue string) bool {
+	if newValue == currentValue {
+		return true
 	}
+	prefilled, err := parseMeterReading(formValue, readingDecimalSeparator)
+	return err == nil && prefilled == newValue
+}
 
-	log.Println("Modal is now visible")
-
-	// Find the input field in the modal
-	var inputFound bool
-	err = chromedp.Run(ctx,
-		chromedp.Evaluate(`document.querySelector('#value') !== null`, &inputFound),
-	)
+// ErrAmbiguousSubmitButton is returned when several modal buttons could be the submit button
+var ErrAmbiguousSubmitButton = errors.New("ambiguous_submit_button")
+
+// ErrModalButtonMissing is returned when the "Ввести" button is absent and no
+// record for the current month exists, i.e. the site UI likely changed
+var ErrModalButtonMissing = errors.New("modal_button_missing")
+
+// SubmissionTracker records live submission attempts so a retried job can
+// re-verify an earlier attempt instead of submitting the reading twice
+type SubmissionTracker interface {
+	// Pending returns an attempted-but-unconfirmed submission for the counter and period
+	Pending(counterSerial string, year, month int) (*Submission, error)
+	// Attempt records a submission right before the submit click and returns its ID
+	Attempt(counterSerial string, year, month int, previousValue, newValue, increment float64) (string, error)
+	Confirm(id string) error
+	Abandon(id string) error
+	// ConfirmPeriod confirms any pending attempts once a record for the period is visible
+	ConfirmPeriod(year, month int) error
+}
 
-	if err != nil || !inputFound {
-		log.Println("WARNING: Could not find #value input field in modal")
-		_ = SaveScreenshot(ctx, fmt.Sprintf("no_input_in_modal_%d.png", time.Now().Unix()))
-		return fmt.Errorf("input field #value not found in modal")
-	}
+// CheckResult summarizes the value computed (and possibly submitted) by a check
+type CheckResult struct {
+	CounterSerial string `json:"counter_serial,omitempty"`
+	// Month is the consumption month whose increment was applied
+	Month         int     `json:"month"`
+	PreviousValue float64 `json:"previous_value"`
+	NewValue      float64 `json:"new_value"`
+	Increment     float64 `json:"increment"`
+	DryRun        bool    `json:"dry_run"`
+	Submitted     bool    `json:"submitted"`
+	// Anomaly is why a dry run's reading failed the sanity checks
+	Anomaly string `json:"anomaly,omitempty"`
+}
 
-	log.Println("Found input field #value in modal")
+// resultReporter is implemented by loggers that keep the check result
+type resultReporter interface {
+	Result(result *CheckResult)
+}
 
-	// Fill the input field with the new value
-	log.Printf("Filling input field with new value: %d", newValue)
-	err = chromedp.Run(ctx,
-		chromedp.Clear(`#value`, chromedp.ByID),
-		chromedp.SendKeys(`#value`, fmt.Sprintf("%d", newValue), chromedp.ByID),
-		chromedp.Sleep(500*time.Millisecond),
-	)
-	if err != nil {
-		_ = SaveScreenshot(ctx, fmt.Sprintf("error_fill_input_%d.png", time.Now().Unix()))
-		return fmt.Errorf("failed to fill input field: %w", err)
+// reportResult hands the check result to the logger if it supports it
+func reportResult(logger Logger, result *CheckResult) {
+	if rr, ok := logger.(resultReporter); ok {
+		rr.Result(result)
 	}
+}
 
-	// Verify the value was entered
-	var enteredValue string
-	_ = chromedp.Run(ctx,
-		chromedp.Value(`#value`, &enteredValue, chromedp.ByID),
-	)
-	log.Printf("Value entered in input field: %s", enteredValue)
-
-	// DRY-RUN MODE - controlled by GASOLINA_DRY_RUN env var (default: true)
-	// Set GASOLINA_DRY_RUN=false to enable real submissions
-
-	if config.DryRun {
-		log.Println("===========================================")
-		log.Println("DRY-RUN MODE (set GASOLINA_DRY_RUN=false to submit)")
-		log.Println("===========================================")
-		log.Printf("Form data ready for submission:")
-		log.Printf("  - Counter serial: %s", buttonSerial)
-		log.Printf("  - Previous value: %s", buttonValue)
-		log.Printf("  - New value: %d", newValue)
-		log.Printf("  - Input field: #value")
-		log.Printf("  - Value entered: %s", enteredValue)
-		log.Println("===========================================")
-		log.Println("SKIPPING submit button click (dry-run mode)")
-		log.Println("===========================================")
-
-		// Save screenshot of the filled form
-		_ = SaveScreenshot(ctx, fmt.Sprintf("dry_run_form_filled_%d.png", time.Now().Unix()))
-		return nil
+// CheckAndUpdateIfNeeded navigates to the target page, checks values, and updates if needed
+//
+// DRY-RUN mode is controlled by GASOLINA_DRY_RUN env var (default: true/enabled)
+// Set GASOLINA_DRY_RUN=false to enable real submissions.
+//
+// It will:
+//   - Read current value from #last_value field
+//   - Calculate new value (current + previous month's increment)
+//   - Find the input field and "Ввести" button
+//   - In dry-run mode: log what it would do and save a screenshot
+//   - In live mode: actually submit the form
+func CheckAndUpdateIfNeeded(ctx context.Context, config *Config) error {
+	// Use old-style timestamped screenshot files for CLI mode
+	saveScreenshot := func(name string) {
+		_ = SaveScreenshot(ctx, fmt.Sprintf("%s_%d.png", name, time.Now().Unix()))
 	}
+	return CheckAndUpdateIfNeededWithLogger(ctx, config, nil, saveScreenshot)
+}
 
-	// Find and click the submit button inside the modal
-	log.Println("Finding submit button in modal...")
-	var submitButtonFound bool
-	err = chromedp.Run(ctx,
-		chromedp.Evaluate(`
-			(function() {
-				const modal = document.querySelector('#counterModal');
-				if (!modal) return false;
-				const submitBtn = modal.querySelector('button[type="submit"]');
-				return submitBtn !== null;
-			})()
-		`, &submitButtonFound),
-	)
-
-	if err != nil || !submitButtonFound {
-		log.Println("WARNING: Could not find submit button in modal")
-		_ = SaveScreenshot(ctx, fmt.Sprintf("no_submit_button_%d.png", time.Now().Unix()))
-		return fmt.Errorf("submit button not found in modal")
+// readCurrentValue reads the current meter reading text from the element matching
+// selector, using its form value, innerText or a data- attribute
+func readCurrentValue(ctx context.Context, selector, source string) (string, error) {
+	var value string
+	switch {
+	case source == ValueSourceValue:
+		err := chromedp.Run(ctx,
+			chromedp.WaitVisible(selector, chromedp.ByQuery),
+			chromedp.Value(selector, &value, chromedp.ByQuery),
+		)
+		return value, err
+	case source == ValueSourceInnerText:
+		err := chromedp.Run(ctx,
+			chromedp.WaitVisible(selector, chromedp.ByQuery),
+			chromedp.Text(selector, &value, chromedp.ByQuery),
+		)
+		return strings.TrimSpace(value), err
+	case strings.HasPrefix(source, "data-"):
+		var ok bool
+		err := chromedp.Run(ctx,
+			chromedp.WaitReady(selector, chromedp.ByQuery),
+			chromedp.AttributeValue(selector, source, &value, &ok, chromedp.ByQuery),
+		)
+		if err == nil && !ok {
+			return "", fmt.Errorf("attribute %s not present", source)
+		}
+		return value, err
 	}
+	return "", fmt.Errorf("unsupported value source %q", source)
+}
 
-	log.Println("Found submit button, clicking...")
-	err = chromedp.Run(ctx,
-		chromedp.Click(`#counterModal button[type="submit"]`, chromedp.ByQuery),
-		chromedp.Sleep(3*time.Second),
-	)
+// submitButtonMarker is set on the resolved submit button so it can be clicked unambiguously
+const submitButtonMarker = "data-gasolina-submit"
+
+// resolveSubmitButton finds exactly one submit button in the modal, narrowed by
+// config.SubmitButtonSelector or config.SubmitButtonText, marks it and returns a
+// selector for it. Multiple candidates without a disambiguator are an error.
+func resolveSubmitButton(ctx context.Context, config *Config, logger Logger) (string, error) {
+	selector := config.SubmitButtonSelector
+	if selector == "" {
+		selector = `button[type="submit"]`
+	}
+	modal := config.selectors().Modal
+	modalJSON, _ := json.Marshal(modal)
+	selectorJSON, _ := json.Marshal(selector)
+	textJSON, _ := json.Marshal(strings.ToLower(config.SubmitButtonText))
+
+	var result struct {
+		Count int      `json:"count"`
+		Texts []string `json:"texts"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(`
+		(function() {
+			const modal = document.querySelector(%s);
+			if (!modal) return {count: 0, texts: []};
+			const text = %s;
+			let buttons = Array.from(modal.querySelectorAll(%s));
+			if (text) {
+				buttons = buttons.filter(b => (b.textContent || b.value || '').trim().toLowerCase().includes(text));
+			}
+			modal.querySelectorAll('[%s]').forEach(b => b.removeAttribute('%s'));
+			if (buttons.length === 1) buttons[0].setAttribute('%s', '1');
+			return {count: buttons.length, texts: buttons.map(b => (b.textContent || b.value || '').trim())};
+		})()
+	`, modalJSON, textJSON, selectorJSON, submitButtonMarker, submitButtonMarker, submitButtonMarker), &result))
 	if err != nil {
-		_ = SaveScreenshot(ctx, fmt.Sprintf("error_submit_%d.png", time.Now().Unix()))
-		return fmt.Errorf("failed to click submit button: %w", err)
+		return "", fmt.Errorf("failed to look up submit button: %w", err)
 	}
 
-	log.Println("Clicked submit button")
-
-	// Verify submission success
-	var successMessage string
-	_ = chromedp.Run(ctx,
-		chromedp.Sleep(2*time.Second),
-		chromedp.Evaluate(`document.body.innerText`, &successMessage),
-	)
-
-	if strings.Contains(strings.ToLower(successMessage), "успішно") ||
-		strings.Contains(strings.ToLower(successMessage), "success") {
-		log.Println("SUCCESS: Form submitted successfully!")
-		_ = SaveScreenshot(ctx, fmt.Sprintf("success_%d.png", time.Now().Unix()))
-	} else {
-		log.Println("WARNING: Could not confirm success message")
-		_ = SaveScreenshot(ctx, fmt.Sprintf("submit_complete_%d.png", time.Now().Unix()))
+	switch {
+	case result.Count == 0:
+		logger.Log(fmt.Sprintf("WARNING: No submit button in modal matches selector %q text %q", selector, config.SubmitButtonText))
+		return "", fmt.Errorf("submit button not found in modal")
+	case result.Count > 1:
+		logger.Log(fmt.Sprintf("WARNING: %d submit buttons in modal match: %q", result.Count, result.Texts))
+		return "", fmt.Errorf("%w: %d candidates %q - set submit_button_text or submit_button_selector",
+			ErrAmbiguousSubmitButton, result.Count, result.Texts)
 	}
 
-	return nil
+	logger.Log(fmt.Sprintf("Resolved submit button: %q", result.Texts[0]))
+	return fmt.Sprintf("%s [%s]", modal, submitButtonMarker), nil
 }
 
 // checkForCurrentMonthRecordInTable checks if a record for the current month/year exists in the indicator table
 // It also checks for records from the last 2 days of the previous month
 // It selects the current year in the dropdown and searches for matching dates
-func checkForCurrentMonthRecordInTable(ctx context.Context, now time.Time, logger Logger) (bool, error) {
+func checkForCurrentMonthRecordInTable(ctx context.Context, sel Selectors, now time.Time, logger Logger) (bool, error) {
 	currentMonth := now.Month()
 	currentYear := now.Year()
 
@@ -297,45 +240,11 @@ func checkForCurrentMonthRecordInTable(ctx context.Context, now time.Time, logge
 	}
 
 	for _, year := range yearsToCheck {
-		// Calculate the dropdown value for the year
-		// value="0" is 2026, value="1" is 2025, etc.
-		yearValue := 2026 - year
-		if yearValue < 0 {
-			yearValue = 0
-		}
-
-		logger.Log(fmt.Sprintf("Selecting year %d (dropdown value: %d)", year, yearValue))
-
-		// Select the year in the dropdown
-		err := chromedp.Run(ctx,
-			chromedp.WaitVisible(`#filter\[year\]`, chromedp.ByID),
-			chromedp.SetValue(`#filter\[year\]`, fmt.Sprintf("%d", yearValue), chromedp.ByID),
-			chromedp.Sleep(500*time.Millisecond),
-			// Trigger the onchange event to submit the form
-			chromedp.Evaluate(`document.getElementById('filter[year]').dispatchEvent(new Event('change'))`, nil),
-			chromedp.Sleep(2*time.Second),
-			chromedp.WaitReady("body"),
-		)
-
-		if err != nil {
-			return false, fmt.Errorf("failed to select year in dropdown: %w", err)
-		}
-
-		// Get all dates from the table
-		var dates []string
-		err = chromedp.Run(ctx,
-			chromedp.Evaluate(`
-				Array.from(document.querySelectorAll('table.table tbody tr td:nth-child(2)'))
-					.map(td => td.innerText.trim())
-			`, &dates),
-		)
-
+		dates, err := readIndicatorTableDates(ctx, sel, year, logger)
 		if err != nil {
-			return false, fmt.Errorf("failed to read table dates: %w", err)
+			return false, err
 		}
 
-		logger.Log(fmt.Sprintf("Found %d records in table for year %d", len(dates), year))
-
 		// Build list of patterns to match
 		// Current month pattern: .MM.YYYY (e.g., ".02.2026")
 		currentMonthPattern := fmt.Sprintf(".%02d.%d", currentMonth, currentYear)
@@ -348,13 +257,13 @@ func checkForCurrentMonthRecordInTable(ctx context.Context, now time.Time, logge
 
 			// Check current month
 			if strings.Contains(date, currentMonthPattern) {
-				logger.Log(fmt.Sprintf("Found matching record for current month: %s", date))
+				logVerbose(logger, fmt.Sprintf("Found matching record for current month: %s", date))
 				return true, nil
 			}
 
 			// Check last 2 days of previous month
 			if date == lastDayPattern || date == secondLastDayPattern {
-				logger.Log(fmt.Sprintf("Found matching record from end of previous month: %s", date))
+				logVerbose(logger, fmt.Sprintf("Found matching record from end of previous month: %s", date))
 				return true, nil
 			}
 		}
@@ -363,6 +272,223 @@ func checkForCurrentMonthRecordInTable(ctx context.Context, now time.Time, logge
 	return false, nil
 }
 
+// reverifySubmission checks the indicator table for the current month's record and
+// returns to the main page so the submission flow can continue if it is missing
+func reverifySubmission(ctx context.Context, config *Config, now time.Time, logger Logger) (bool, error) {
+	if err := chromedp.Run(ctx,
+		navigateAndSettle(config.CheckURL, 2*time.Second),
+		chromedp.WaitReady("body"),
+	); err != nil {
+		return false, fmt.Errorf("failed to navigate to indicator page: %w", err)
+	}
+
+	exists, err := checkForCurrentMonthRecordInTable(ctx, config.selectors(), now, logger)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return true, nil
+	}
+
+	if err := chromedp.Run(ctx,
+		navigateAndSettle(config.baseURL(), 2*time.Second),
+		chromedp.WaitReady("body"),
+	); err != nil {
+		return false, fmt.Errorf("failed to navigate back to main page: %w", err)
+	}
+	return false, nil
+}
+
+// readIndicatorTableDates selects a year in the indicator page filter and returns
+// the date column of every row in the table. The indicator page must already be open.
+func readIndicatorTableDates(ctx context.Context, sel Selectors, year int, logger Logger) ([]string, error) {
+	rows, err := readIndicatorTableRows(ctx, sel, year, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, 0, len(rows))
+	for _, row := range rows {
+		dates = append(dates, row.Date)
+	}
+	return dates, nil
+}
+
+// selectIndicatorYear switches the indicator table to the given year
+func selectIndicatorYear(ctx context.Context, sel Selectors, year int, logger Logger) error {
+	// The option values are opaque indexes, so find the one labelled with the year
+	options, err := readYearOptions(ctx, sel)
+	if err != nil {
+		return err
+	}
+	yearValue, found := yearOptionValue(options, year)
+	if !found {
+		logger.Log(fmt.Sprintf("Warning: year filter has no option for %d (options: %v), keeping the selected year", year, options))
+		return nil
+	}
+
+	logger.Log(fmt.Sprintf("Selecting year %d (dropdown value: %s)", year, yearValue))
+
+	// Select the year in the dropdown and trigger the onchange event to submit
+	// the form. The current table is marked so the wait below can tell the
+	// reloaded table from the stale one.
+	filterJSON, _ := json.Marshal(sel.YearFilter)
+	tableJSON, _ := json.Marshal(sel.IndicatorTable)
+	valueJSON, _ := json.Marshal(yearValue)
+	err = chromedp.Run(ctx,
+		chromedp.SetValue(sel.YearFilter, yearValue, chromedp.ByQuery),
+		chromedp.Evaluate(fmt.Sprintf(`(function() {
+			const table = document.querySelector(%s);
+			if (table) table.setAttribute('%s', '1');
+			document.querySelector(%s).dispatchEvent(new Event('change'));
+		})()`, tableJSON, staleTableMarker, filterJSON), nil),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to select year in dropdown: %w", err)
+	}
+
+	err = waitUntil(ctx, fmt.Sprintf("the indicator table for %d", year), fmt.Sprintf(`(function() {
+		const filter = document.querySelector(%s);
+		const table = document.querySelector(%s);
+		return document.readyState === 'complete' && filter && filter.value === %s &&
+			table && !table.hasAttribute('%s');
+	})()`, filterJSON, tableJSON, valueJSON, staleTableMarker))
+	if err != nil {
+		return fmt.Errorf("failed to select year in dropdown: %w", err)
+	}
+	return nil
+}
+
+// staleTableMarker is set on the indicator table before a year change
+const staleTableMarker = "data-gasolina-stale"
+
+// IndicatorRow is one row of the indicator table
+type IndicatorRow struct {
+	Date  string `json:"date"`
+	Value string `json:"value"`
+	// Columns holds every cell keyed by its column header (or "column_N" when unnamed)
+	Columns map[string]string `json:"columns"`
+}
+
+// indicatorRowsJS reads the indicator table as header names plus cell text per row
+const indicatorRowsJS = `
+	(function() {
+		const table = document.querySelector(%s);
+		if (!table) return {headers: [], rows: []};
+		const headers = Array.from(table.querySelectorAll('thead th')).map(th => th.innerText.trim());
+		const rows = Array.from(table.querySelectorAll('tbody tr'))
+			.map(tr => Array.from(tr.querySelectorAll('td')).map(td => td.innerText.trim()));
+		return {headers, rows};
+	})()
+`
+
+// readIndicatorTableRows selects a year in the indicator page filter and returns
+// every row of the table. The indicator page must already be open.
+func readIndicatorTableRows(ctx context.Context, sel Selectors, year int, logger Logger) ([]IndicatorRow, error) {
+	if err := selectIndicatorYear(ctx, sel, year, logger); err != nil {
+		return nil, err
+	}
+
+	var table struct {
+		Headers []string   `json:"headers"`
+		Rows    [][]string `json:"rows"`
+	}
+	tableJSON, _ := json.Marshal(sel.IndicatorTable)
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(indicatorRowsJS, tableJSON), &table)); err != nil {
+		return nil, fmt.Errorf("failed to read table rows: %w", err)
+	}
+
+	rows := make([]IndicatorRow, 0, len(table.Rows))
+	for _, cells := range table.Rows {
+		rows = append(rows, newIndicatorRow(table.Headers, cells))
+	}
+
+	logVerbose(logger, fmt.Sprintf("Found %d records in table for year %d", len(rows), year))
+	return rows, nil
+}
+
+// newIndicatorRow builds a row from its cells; the second column holds the date
+// and the third the reading
+func newIndicatorRow(headers, cells []string) IndicatorRow {
+	row := IndicatorRow{Columns: make(map[string]string, len(cells))}
+	for i, cell := range cells {
+		name := fmt.Sprintf("column_%d", i+1)
+		if i < len(headers) && headers[i] != "" {
+			name = headers[i]
+		}
+		row.Columns[name] = cell
+	}
+	if len(cells) > 1 {
+		row.Date = cells[1]
+	}
+	if len(cells) > 2 {
+		row.Value = cells[2]
+	}
+	return row
+}
+
+// readIndicatorYears returns the years offered by the indicator page year filter
+func readIndicatorYears(ctx context.Context, sel Selectors) ([]int, error) {
+	options, err := readYearOptions(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	var years []int
+	for _, opt := range options {
+		if year, err := strconv.Atoi(opt.Text); err == nil {
+			years = append(years, year)
+		}
+	}
+	return years, nil
+}
+
+// yearOption is an <option> of the indicator page's year filter
+type yearOption struct {
+	Value string `json:"value"`
+	Text  string `json:"text"`
+}
+
+// yearOptionValue returns the value of the option labelled with the year
+func yearOptionValue(options []yearOption, year int) (string, bool) {
+	for _, opt := range options {
+		if opt.Text == strconv.Itoa(year) {
+			return opt.Value, true
+		}
+	}
+	return "", false
+}
+
+// readYearOptions returns the value and visible text of each year filter option
+func readYearOptions(ctx context.Context, sel Selectors) ([]yearOption, error) {
+	filterJSON, _ := json.Marshal(sel.YearFilter)
+	var options []yearOption
+	err := chromedp.Run(ctx,
+		chromedp.WaitVisible(sel.YearFilter, chromedp.ByQuery),
+		chromedp.Evaluate(fmt.Sprintf(`Array.from(document.querySelector(%s).options).map(o => ({value: o.value, text: o.textContent.trim()}))`, filterJSON), &options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read year filter: %w", err)
+	}
+	return options, nil
+}
+
+// countIndicatorRows opens the indicator page and counts the table rows for a year
+func countIndicatorRows(ctx context.Context, config *Config, year int, logger Logger) (int, error) {
+	if err := chromedp.Run(ctx,
+		navigateAndSettle(config.CheckURL, 2*time.Second),
+		chromedp.WaitReady("body"),
+	); err != nil {
+		return 0, fmt.Errorf("failed to navigate to indicator page: %w", err)
+	}
+
+	dates, err := readIndicatorTableDates(ctx, config.selectors(), year, logger)
+	if err != nil {
+		return 0, err
+	}
+	return len(dates), nil
+}
+
 // getUkrainianMonthName returns the Ukrainian name for a given month
 func getUkrainianMonthName(month time.Month) string {
 	monthNames := map[time.Month]string{
@@ -392,65 +518,81 @@ func CheckAndUpdateIfNeededWithLogger(ctx context.Context, config *Config, logge
 		saveScreenshot = func(name string) {}
 	}
 
-	now := time.Now()
+	now := config.submissionNow()
+	sel := config.selectors()
 	currentDay := now.Day()
 	currentMonth := int(now.Month())
 
 	// Check if we're within the allowed submission window (1st-5th of month)
-	if currentDay < 1 || currentDay > 5 {
+	switch {
+	case config.IgnoreSubmissionWindow:
+		logger.Log(fmt.Sprintf("Day %d: submission window not enforced for this run", currentDay))
+	case currentDay < 1 || currentDay > 5:
 		logger.Log(fmt.Sprintf("Today is day %d of the month - submission only allowed on days 1-5", currentDay))
 		return fmt.Errorf("outside submission window (days 1-5)")
+	default:
+		logger.Log(fmt.Sprintf("Day %d is within submission window (1-5) - proceeding", currentDay))
 	}
 
-	logger.Log(fmt.Sprintf("Day %d is within submission window (1-5) - proceeding", currentDay))
+	// Optional time-of-day window on top of the day window
+	if err := config.checkSubmissionHours(now); err != nil {
+		logger.Log(fmt.Sprintf("Submission not allowed at this hour: %v", err))
+		return err
+	}
 
-	// Get the increment for previous month (we submit consumption from last month)
+	// Get the increment for previous month (we submit consumption from last month).
+	// Per-counter increments may replace it once the counter serial is known.
 	increment, prevMonth, err := config.GetIncrementForPreviousMonth(currentMonth)
-	if err != nil {
+	if err != nil && len(config.SerialIncrements) == 0 {
 		return fmt.Errorf("failed to get increment for previous month %d: %w", prevMonth, err)
 	}
 
-	logger.Log(fmt.Sprintf("Using increment from previous month %d: %d", prevMonth, increment))
+	if err == nil {
+		logger.Log(fmt.Sprintf("Using increment from previous month %d: %s", prevMonth, formatReading(increment)))
+	}
 
-	// First, navigate to main page to read current value from #last_value field
-	logger.Log("Navigating to main page to read current value from #last_value...")
-	var currentValueStr string
+	// First, navigate to main page to read the current value
+	valueSelector, valueSource := config.valueSelector()
+	logVerbose(logger, fmt.Sprintf("Navigating to main page to read current value from %s (%s)...", valueSelector, valueSource))
 
 	err = chromedp.Run(ctx,
-		chromedp.Navigate("https://gasolina-online.com/"),
-		chromedp.Sleep(2*time.Second),
+		navigateAndSettle(config.baseURL(), 2*time.Second),
 		chromedp.WaitReady("body"),
-		chromedp.WaitVisible(`#last_value`, chromedp.ByID),
-		chromedp.Value(`#last_value`, &currentValueStr, chromedp.ByID),
 	)
+	if err != nil {
+		return fmt.Errorf("failed to navigate to main page: %w", err)
+	}
 
+	currentValueStr, err := readCurrentValue(ctx, valueSelector, valueSource)
 	if err != nil {
-		return fmt.Errorf("failed to read #last_value from main page: %w", err)
+		return fmt.Errorf("failed to read %s from main page: %w", valueSelector, err)
 	}
 
 	if currentValueStr == "" {
-		return fmt.Errorf("#last_value field is empty on main page")
+		return fmt.Errorf("%s is empty on main page", valueSelector)
 	}
 
-	logger.Log(fmt.Sprintf("Current value from #last_value field: %s", currentValueStr))
+	logger.Log(fmt.Sprintf("Current value from %s: %s", valueSelector, currentValueStr))
 
 	// Parse current value
-	var currentValue int
-	_, err = fmt.Sscanf(currentValueStr, "%d", &currentValue)
+	currentValue, err := parseMeterReadingStrict(currentValueStr, readingDecimalSeparator)
 	if err != nil {
-		return fmt.Errorf("failed to parse current value '%s': %w", currentValueStr, err)
+		logger.Log(fmt.Sprintf("Current value %q can't be read reliably: %v", currentValueStr, err))
+		saveScreenshot("error_parse_current_value")
+		return fmt.Errorf("%w: failed to parse current value: %v", ErrImplausibleReading, err)
 	}
 
 	// Calculate new value
-	newValue := currentValue + increment
-	logger.Log(fmt.Sprintf("=== CALCULATED VALUE: %d + %d = %d ===", currentValue, increment, newValue))
+	newValue := roundReading(currentValue + increment)
+	logger.Log(fmt.Sprintf("=== CALCULATED VALUE: %s + %s = %s ===",
+		formatReading(currentValue), formatReading(increment), formatReading(newValue)))
+	reportPhase(logger, PhaseValueRead)
 
 	// Now navigate to indicator page to check for existing records
-	logger.Log(fmt.Sprintf("Navigating to: %s", config.CheckURL))
+	logVerbose(logger, fmt.Sprintf("Navigating to: %s", config.CheckURL))
 
 	err = chromedp.Run(ctx,
-		chromedp.Navigate(config.CheckURL),
-		chromedp.Sleep(2*time.Second),
+		navigateAndSettle(config.CheckURL, 2*time.Second),
 		chromedp.WaitReady("body"),
 	)
 
@@ -458,11 +600,23 @@ func CheckAndUpdateIfNeededWithLogger(ctx context.Context, config *Config, logge
 		return fmt.Errorf("failed to navigate to indicator page: %w", err)
 	}
 
+	// Canary: elements that should always be there, to spot layout changes early
+	if missing, err := verifyPageStructure(ctx, sel.indicatorPageSelectors()); err != nil {
+		logger.Log(fmt.Sprintf("Warning: %v", err))
+	} else {
+		if len(missing) > 0 {
+			logger.Log(fmt.Sprintf("Warning: indicator page is missing expected elements %v - the site layout may have changed", missing))
+			saveScreenshot("structure_changed")
+		}
+		reportStructure(logger, missing)
+	}
+
 	// Check if a record for the current month/year already exists
-	recordExists, err := checkForCurrentMonthRecordInTable(ctx, now, logger)
+	recordExists, err := checkForCurrentMonthRecordInTable(ctx, sel, now, logger)
 	if err != nil {
 		logger.Log(fmt.Sprintf("Warning: error checking for existing record: %v", err))
 	}
+	reportPhase(logger, PhaseRecordChecked)
 
 	if recordExists {
 		logger.Log("===========================================")
@@ -472,63 +626,171 @@ func CheckAndUpdateIfNeededWithLogger(ctx context.Context, config *Config, logge
 			getUkrainianMonthName(now.Month()), now.Year()))
 		logger.Log("No submission needed - job complete")
 		logger.Log("===========================================")
+		if config.Submissions != nil {
+			if err := config.Submissions.ConfirmPeriod(now.Year(), currentMonth); err != nil {
+				logger.Log(fmt.Sprintf("Warning: failed to confirm pending submissions: %v", err))
+			}
+		}
+		reportOutcome(logger, OutcomeAlreadyExists)
 		return nil
 	}
 
+	// Capture the row count for the current year while we are on the indicator page
+	rowsBefore := -1
+	if config.SuccessMode == SuccessModeRowCount && !config.DryRun {
+		dates, err := readIndicatorTableDates(ctx, sel, now.Year(), logger)
+		if err != nil {
+			return fmt.Errorf("failed to count indicator rows before submission: %w", err)
+		}
+		rowsBefore = len(dates)
+		logger.Log(fmt.Sprintf("Indicator rows for %d before submission: %d", now.Year(), rowsBefore))
+	}
+
 	logger.Log(fmt.Sprintf("No record found for current month (%s %d)",
 		getUkrainianMonthName(now.Month()), now.Year()))
-	logger.Log(fmt.Sprintf("Proceeding to submit new value: %d", newValue))
+	logger.Log(fmt.Sprintf("Proceeding to submit new value: %s", formatReading(newValue)))
 
 	// Navigate back to main page where the "Ввести" button is located
-	logger.Log("Navigating back to main page to find 'Ввести' button...")
+	logVerbose(logger, "Navigating back to main page to find 'Ввести' button...")
 	err = chromedp.Run(ctx,
-		chromedp.Navigate("https://gasolina-online.com/"),
-		chromedp.Sleep(2*time.Second),
+		navigateAndSettle(config.baseURL(), 2*time.Second),
 		chromedp.WaitReady("body"),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to navigate back to main page: %w", err)
 	}
 
+	// Some navigations end the session; make sure we're still logged in before the modal
+	if err := ensureSession(ctx, config, config.baseURL(), logger, saveScreenshot); err != nil {
+		return err
+	}
+
 	// Find the modal trigger button (the "Ввести" button that opens the modal)
+	modalButtonJSON, _ := json.Marshal(sel.ModalButton)
 	var modalButtonFound bool
 	err = chromedp.Run(ctx,
-		chromedp.Evaluate(`document.querySelector('button[data-toggle="modal"][data-target="#counterModal"]') !== null`, &modalButtonFound),
+		chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%s) !== null`, modalButtonJSON), &modalButtonFound),
 	)
 
 	if err != nil || !modalButtonFound {
-		logger.Log("WARNING: Could not find modal trigger button with data-toggle='modal'")
+		logger.Log(fmt.Sprintf("WARNING: Could not find modal trigger button %s", sel.ModalButton))
 		saveScreenshot("no_modal_button")
-		return fmt.Errorf("modal trigger button not found on indicator page")
+
+		// The site hides the button once a reading is in, so the record may have
+		// appeared since we checked; tell "already done" apart from a broken UI
+		if config.RecheckMissingButton {
+			logger.Log("Re-checking indicator table in case the reading was already submitted...")
+			exists, recheckErr := reverifySubmission(ctx, config, now, logger)
+			if recheckErr != nil {
+				logger.Log(fmt.Sprintf("Warning: record re-check failed: %v", recheckErr))
+			} else if exists {
+				logger.Log("Record appeared after re-check - the reading is already submitted, nothing to do")
+				if config.Submissions != nil {
+					if err := config.Submissions.ConfirmPeriod(now.Year(), currentMonth); err != nil {
+						logger.Log(fmt.Sprintf("Warning: failed to confirm pending submissions: %v", err))
+					}
+				}
+				reportOutcome(logger, OutcomeAlreadyExists)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: modal trigger button not found on indicator page", ErrModalButtonMissing)
 	}
 
-	logger.Log("Found modal trigger button (data-toggle='modal')")
+	logVerbose(logger, fmt.Sprintf("Found modal trigger button %s", sel.ModalButton))
 
 	// Get button data attributes for logging
 	var buttonSerial, buttonValue string
 	_ = chromedp.Run(ctx,
-		chromedp.Evaluate(`document.querySelector('button[data-toggle="modal"][data-target="#counterModal"]').getAttribute('data-serial')`, &buttonSerial),
-		chromedp.Evaluate(`document.querySelector('button[data-toggle="modal"][data-target="#counterModal"]').getAttribute('data-value')`, &buttonValue),
+		chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%s).getAttribute('data-serial')`, modalButtonJSON), &buttonSerial),
+		chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%s).getAttribute('data-value')`, modalButtonJSON), &buttonValue),
 	)
-	logger.Log(fmt.Sprintf("Modal button data: serial=%s, current_value=%s", buttonSerial, buttonValue))
+	logVerbose(logger, fmt.Sprintf("Modal button data: serial=%s, current_value=%s", buttonSerial, buttonValue))
+
+	// Multi-counter accounts may configure a separate increment per counter
+	if len(config.SerialIncrements) > 0 {
+		serialIncrement, err := config.GetIncrementForSerial(buttonSerial, prevMonth)
+		if err != nil {
+			return fmt.Errorf("failed to get increment for counter %s, month %d: %w", buttonSerial, prevMonth, err)
+		}
+		if serialIncrement != increment {
+			increment = serialIncrement
+			newValue = roundReading(currentValue + increment)
+			logger.Log(fmt.Sprintf("=== COUNTER %s INCREMENT: %s + %s = %s ===", buttonSerial,
+				formatReading(currentValue), formatReading(increment), formatReading(newValue)))
+		}
+	}
+
+	// A previous attempt for this counter may have been submitted before a failure;
+	// re-verify it via the indicator table instead of blindly submitting again
+	if config.Submissions != nil && !config.DryRun {
+		pending, err := config.Submissions.Pending(buttonSerial, now.Year(), currentMonth)
+		if err != nil {
+			return fmt.Errorf("failed to look up pending submission: %w", err)
+		}
+		if pending != nil {
+			logger.Log(fmt.Sprintf("Found unconfirmed submission %s (value %s) - re-verifying via indicator table",
+				pending.ID, formatReading(pending.SubmittedValue)))
+			landed, err := reverifySubmission(ctx, config, now, logger)
+			if err != nil {
+				return fmt.Errorf("failed to re-verify previous submission: %w", err)
+			}
+			if landed {
+				logger.Log("Previous submission is present in the indicator table - not submitting again")
+				if err := config.Submissions.Confirm(pending.ID); err != nil {
+					logger.Log(fmt.Sprintf("Warning: failed to confirm submission: %v", err))
+				}
+				return nil
+			}
+			logger.Log("Previous submission did not land - marking it abandoned and submitting again")
+			if err := config.Submissions.Abandon(pending.ID); err != nil {
+				logger.Log(fmt.Sprintf("Warning: failed to abandon submission: %v", err))
+			}
+		}
+	}
+
+	// Nothing to submit if the reading wouldn't change
+	if !config.ForceSubmit {
+		if readingUnchanged(currentValue, newValue, buttonValue) {
+			logger.Log("===========================================")
+			logger.Log(fmt.Sprintf("NO CHANGE - new value %s equals the current reading, not submitting", formatReading(newValue)))
+			logger.Log("Set force_submit to submit anyway")
+			logger.Log("===========================================")
+			reportOutcome(logger, OutcomeNoChange)
+			return nil
+		}
+	}
+
+	// Don't submit a reading that can't be right. A dry run may instead carry
+	// on as a pre-check and flag the anomaly, since nothing gets submitted.
+	var anomaly string
+	if err := validateReading(currentValue, increment, newValue, config.ForceSubmit); err != nil {
+		saveScreenshot("error_implausible_reading")
+		if !config.DryRun || !config.DryRunAnomalyWarning {
+			logger.Log(fmt.Sprintf("ABORTING: %v", err))
+			return err
+		}
+		anomaly = err.Error()
+		logAt(logger, LogLevelQuiet, fmt.Sprintf("ANOMALY WARNING: %v - a live run would abort", err))
+		reportOutcome(logger, OutcomeAnomalyWarning)
+	}
 
 	// Click the modal trigger button to open the modal
-	logger.Log("Clicking modal trigger button to open form...")
-	err = chromedp.Run(ctx,
-		chromedp.Click(`button[data-toggle="modal"][data-target="#counterModal"]`, chromedp.ByQuery),
-		chromedp.Sleep(1*time.Second),
-	)
+	dismissCookieBanner(ctx, logger)
+	logVerbose(logger, "Clicking modal trigger button to open form...")
+	err = chromedp.Run(ctx, chromedp.Click(sel.ModalButton, chromedp.ByQuery))
 	if err != nil {
 		saveScreenshot("error_open_modal")
 		return fmt.Errorf("failed to click modal trigger button: %w", err)
 	}
 
-	// Wait for the modal to be visible
-	logger.Log("Waiting for modal to appear...")
-	err = chromedp.Run(ctx,
-		chromedp.WaitVisible(`#counterModal`, chromedp.ByID),
-		chromedp.Sleep(500*time.Millisecond),
-	)
+	// Wait for the modal and its input; the input only shows once the fade-in is done
+	logVerbose(logger, "Waiting for modal to appear...")
+	err = waitVisible(ctx, sel.Modal)
+	if err == nil {
+		err = waitVisible(ctx, sel.ValueInput)
+	}
 	if err != nil {
 		saveScreenshot("error_modal_not_visible")
 		return fmt.Errorf("modal did not appear: %w", err)
@@ -537,37 +799,40 @@ func CheckAndUpdateIfNeededWithLogger(ctx context.Context, config *Config, logge
 	logger.Log("Modal is now visible")
 
 	// Find the input field in the modal
+	valueInputJSON, _ := json.Marshal(sel.ValueInput)
 	var inputFound bool
 	err = chromedp.Run(ctx,
-		chromedp.Evaluate(`document.querySelector('#value') !== null`, &inputFound),
+		chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%s) !== null`, valueInputJSON), &inputFound),
 	)
 
 	if err != nil || !inputFound {
-		logger.Log("WARNING: Could not find #value input field in modal")
+		logger.Log(fmt.Sprintf("WARNING: Could not find %s input field in modal", sel.ValueInput))
 		saveScreenshot("no_input_in_modal")
-		return fmt.Errorf("input field #value not found in modal")
+		return fmt.Errorf("input field %s not found in modal", sel.ValueInput)
 	}
 
-	logger.Log("Found input field #value in modal")
+	logVerbose(logger, fmt.Sprintf("Found input field %s in modal", sel.ValueInput))
 
 	// Fill the input field with the new value
-	logger.Log(fmt.Sprintf("Filling input field with new value: %d", newValue))
-	err = chromedp.Run(ctx,
-		chromedp.Clear(`#value`, chromedp.ByID),
-		chromedp.SendKeys(`#value`, fmt.Sprintf("%d", newValue), chromedp.ByID),
-		chromedp.Sleep(500*time.Millisecond),
-	)
-	if err != nil {
+	logger.Log(fmt.Sprintf("Filling input field with new value: %s", formatReadingForSite(newValue)))
+	if err := fillValueInput(ctx, sel.ValueInput, formatReadingForSite(newValue), logger); err != nil {
 		saveScreenshot("error_fill_input")
 		return fmt.Errorf("failed to fill input field: %w", err)
 	}
+	// Input masks may reformat the value asynchronously; the check below reports a mismatch
+	_ = waitUntil(ctx, "the entered value", fmt.Sprintf(`(document.querySelector(%s) || {}).value === %q`,
+		valueInputJSON, formatReadingForSite(newValue)))
 
-	// Verify the value was entered
+	// Verify the value was entered; residue from a pre-filled input would show up here
 	var enteredValue string
 	_ = chromedp.Run(ctx,
-		chromedp.Value(`#value`, &enteredValue, chromedp.ByID),
+		chromedp.Value(sel.ValueInput, &enteredValue, chromedp.ByQuery),
 	)
 	logger.Log(fmt.Sprintf("Value entered in input field: %s", enteredValue))
+	if entered, err := parseMeterReading(enteredValue, readingDecimalSeparator); err != nil || entered != newValue {
+		saveScreenshot("error_value_mismatch")
+		return fmt.Errorf("%w: input holds %q, expected %s", ErrValueMismatch, enteredValue, formatReadingForSite(newValue))
+	}
 
 	// DRY-RUN MODE
 	if config.DryRun {
@@ -577,59 +842,114 @@ func CheckAndUpdateIfNeededWithLogger(ctx context.Context, config *Config, logge
 		logger.Log("Form data ready for submission:")
 		logger.Log(fmt.Sprintf("  - Counter serial: %s", buttonSerial))
 		logger.Log(fmt.Sprintf("  - Previous value: %s", buttonValue))
-		logger.Log(fmt.Sprintf("  - New value: %d", newValue))
+		logger.Log(fmt.Sprintf("  - New value: %s", formatReading(newValue)))
 		logger.Log(fmt.Sprintf("  - Value entered: %s", enteredValue))
 		logger.Log("===========================================")
 		logger.Log("SKIPPING submit button click (dry-run mode)")
 		logger.Log("===========================================")
 
 		saveScreenshot("dry_run_form_filled")
+		reportResult(logger, &CheckResult{
+			CounterSerial: buttonSerial,
+			Month:         prevMonth,
+			PreviousValue: currentValue,
+			NewValue:      newValue,
+			Increment:     increment,
+			DryRun:        true,
+			Anomaly:       anomaly,
+		})
 		return nil
 	}
 
 	// Find and click the submit button inside the modal
 	logger.Log("Finding submit button in modal...")
-	var submitButtonFound bool
-	err = chromedp.Run(ctx,
-		chromedp.Evaluate(`
-			(function() {
-				const modal = document.querySelector('#counterModal');
-				if (!modal) return false;
-				const submitBtn = modal.querySelector('button[type="submit"]');
-				return submitBtn !== null;
-			})()
-		`, &submitButtonFound),
-	)
-
-	if err != nil || !submitButtonFound {
-		logger.Log("WARNING: Could not find submit button in modal")
+	submitSelector, err := resolveSubmitButton(ctx, config, logger)
+	if err != nil {
 		saveScreenshot("no_submit_button")
-		return fmt.Errorf("submit button not found in modal")
+		return err
+	}
+
+	var submissionID string
+	if config.Submissions != nil {
+		submissionID, err = config.Submissions.Attempt(buttonSerial, now.Year(), currentMonth, currentValue, newValue, increment)
+		if err != nil {
+			return fmt.Errorf("failed to record submission attempt: %w", err)
+		}
+		logger.Log(fmt.Sprintf("Recorded submission attempt %s", submissionID))
 	}
 
-	logger.Log("Found submit button, clicking...")
+	// The filled form, kept as evidence in case the provider disputes the reading
+	saveEvidence(saveScreenshot, EvidenceBeforeSubmit)
+
+	logVerbose(logger, "Found submit button, clicking...")
 	err = chromedp.Run(ctx,
-		chromedp.Click(`#counterModal button[type="submit"]`, chromedp.ByQuery),
-		chromedp.Sleep(3*time.Second),
+		settleAfter(chromedp.Click(submitSelector, chromedp.ByQuery), 3*time.Second),
 	)
 	if err != nil {
 		saveScreenshot("error_submit")
-		return fmt.Errorf("failed to click submit button: %w", err)
+		return fmt.Errorf("%w: failed to click submit button: %w", ErrSubmitUncertain, err)
 	}
 
 	logger.Log("Clicked submit button")
+	reportResult(logger, &CheckResult{
+		CounterSerial: buttonSerial,
+		Month:         prevMonth,
+		PreviousValue: currentValue,
+		NewValue:      newValue,
+		Increment:     increment,
+		Submitted:     true,
+	})
+
+	// Row count mode: a new row in the indicator table is the authoritative success signal
+	if rowsBefore >= 0 {
+		rowsAfter, err := countIndicatorRows(ctx, config, now.Year(), logger)
+		if err != nil {
+			return fmt.Errorf("%w: failed to count indicator rows after submission: %w", ErrSubmitUncertain, err)
+		}
+		logger.Log(fmt.Sprintf("Indicator rows for %d after submission: %d", now.Year(), rowsAfter))
+		saveEvidence(saveScreenshot, EvidenceAfterSubmit)
+
+		if rowsAfter <= rowsBefore {
+			saveScreenshot("submit_failed")
+			return fmt.Errorf("%w (%w): indicator row count did not increase (%d -> %d)", ErrSubmitFailed, ErrSubmitUncertain, rowsBefore, rowsAfter)
+		}
+
+		logger.Log("SUCCESS: New record appeared in indicator table")
+		reportPhase(logger, PhaseSubmitted)
+		saveScreenshot("success")
+		if submissionID != "" {
+			if err := config.Submissions.Confirm(submissionID); err != nil {
+				logger.Log(fmt.Sprintf("Warning: failed to confirm submission: %v", err))
+			}
+		}
+		return nil
+	}
 
 	// Verify submission success
+	// The site either shows a success message or closes the modal; give it up to
+	// the wait timeout, then judge whatever is on the page
+	modalJSON, _ := json.Marshal(sel.Modal)
+	_ = waitUntil(ctx, "the submission response", fmt.Sprintf(`(function() {
+		const text = document.body.innerText.toLowerCase();
+		const modal = document.querySelector(%s);
+		return text.includes('успішно') || text.includes('success') || !modal || modal.offsetParent === null;
+	})()`, modalJSON))
 	var successMessage string
 	_ = chromedp.Run(ctx,
-		chromedp.Sleep(2*time.Second),
 		chromedp.Evaluate(`document.body.innerText`, &successMessage),
 	)
+	saveEvidence(saveScreenshot, EvidenceAfterSubmit)
 
 	if strings.Contains(strings.ToLower(successMessage), "успішно") ||
 		strings.Contains(strings.ToLower(successMessage), "success") {
 		logger.Log("SUCCESS: Form submitted successfully!")
+		reportPhase(logger, PhaseSubmitted)
 		saveScreenshot("success")
+		if submissionID != "" {
+			if err := config.Submissions.Confirm(submissionID); err != nil {
+				logger.Log(fmt.Sprintf("Warning: failed to confirm submission: %v", err))
+			}
+		}
 	} else {
 		logger.Log("WARNING: Could not confirm success message")
 		saveScreenshot("submit_complete")
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestIsValidValueSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{ValueSourceValue, true},
+		{ValueSourceInnerText, true},
+		{"data-value", true},
+		{"data-last.reading_1", true},
+		{"data-", false},
+		{"data-Value", false},
+		{"innertext", false},
+		{"href", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isValidValueSource(tt.source); got != tt.want {
+			t.Errorf("isValidValueSource(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestConfigValueSelectorDefaults(t *testing.T) {
+	tests := []struct {
+		name                  string
+		config                Config
+		wantSelector, wantSrc string
+	}{
+		{"unset", Config{}, defaultValueSelector, ValueSourceValue},
+		{"selector only", Config{ValueSelector: ".reading"}, ".reading", ValueSourceValue},
+		{"both set", Config{ValueSelector: "#counter", ValueSource: "data-value"}, "#counter", "data-value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, source := tt.config.valueSelector()
+			if selector != tt.wantSelector || source != tt.wantSrc {
+				t.Errorf("valueSelector() = %q, %q, want %q, %q", selector, source, tt.wantSelector, tt.wantSrc)
+			}
+		})
+	}
+}
+
+func TestAmbiguousSubmitButtonErrorCode(t *testing.T) {
+	err := fmt.Errorf("%w: 2 candidates [\"Зберегти\" \"Скасувати\"] - set submit_button_text or submit_button_selector", ErrAmbiguousSubmitButton)
+	if got := errorCodeFor(fmt.Errorf("attempt 1: %w", err)); got != "ambiguous_submit_button" {
+		t.Errorf("errorCodeFor = %q, want ambiguous_submit_button", got)
+	}
+}
+
+func TestNewIndicatorRow(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		cells   []string
+		want    IndicatorRow
+	}{
+		{"named columns", []string{"№", "Дата", "Показник"}, []string{"1", "05.02.2026", "1234"},
+			IndicatorRow{Date: "05.02.2026", Value: "1234", Columns: map[string]string{"№": "1", "Дата": "05.02.2026", "Показник": "1234"}}},
+		{"unnamed and extra columns", []string{"№", ""}, []string{"1", "05.02.2026", "1234", "ok"},
+			IndicatorRow{Date: "05.02.2026", Value: "1234", Columns: map[string]string{"№": "1", "column_2": "05.02.2026", "column_3": "1234", "column_4": "ok"}}},
+		{"short row", nil, []string{"1"},
+			IndicatorRow{Columns: map[string]string{"column_1": "1"}}},
+		{"empty row", nil, nil, IndicatorRow{Columns: map[string]string{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newIndicatorRow(tt.headers, tt.cells); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newIndicatorRow() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadingUnchanged(t *testing.T) {
+	tests := []struct {
+		name          string
+		current, next float64
+		formValue     string
+		want          bool
+	}{
+		{"zero increment", 1234, 1234, "", true},
+		{"form already holds the new value", 1200, 1234, "1234", true},
+		{"new reading", 1200, 1234, "1200", false},
+		{"empty form", 1200, 1234, "", false},
+		{"unparseable form value", 1200, 1234, "n/a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readingUnchanged(tt.current, tt.next, tt.formValue); got != tt.want {
+				t.Errorf("readingUnchanged(%g, %g, %q) = %v, want %v", tt.current, tt.next, tt.formValue, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearOptionValue(t *testing.T) {
+	options := []yearOption{{Value: "0", Text: "2026"}, {Value: "1", Text: "2025"}, {Value: "7", Text: "2019"}, {Value: "", Text: "Усі роки"}}
+	tests := []struct {
+		name      string
+		options   []yearOption
+		year      int
+		wantValue string
+		wantFound bool
+	}{
+		{"newest year", options, 2026, "0", true},
+		{"older year", options, 2025, "1", true},
+		{"value is not an offset", options, 2019, "7", true},
+		{"missing year", options, 2027, "", false},
+		{"no options", nil, 2026, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, found := yearOptionValue(tt.options, tt.year)
+			if value != tt.wantValue || found != tt.wantFound {
+				t.Errorf("yearOptionValue(%d) = %q, %v, want %q, %v", tt.year, value, found, tt.wantValue, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   []string
+		want    int
+		wantErr bool
+	}{
+		{"none", nil, 0, false},
+		{"cidr and bare ips", []string{"10.0.0.0/8", " 192.168.1.1 ", "::1", ""}, 3, false},
+		{"not an address", []string{"proxy.local"}, 0, true},
+		{"bad mask", []string{"10.0.0.0/33"}, 0, true},
+	}
+	prev := trustedProxies
+	t.Cleanup(func() { trustedProxies = prev })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trustedProxies = nil
+			err := SetTrustedProxies(tt.cidrs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetTrustedProxies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(trustedProxies) != tt.want {
+				t.Errorf("parsed %d networks, want %d", len(trustedProxies), tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	prev := trustedProxies
+	t.Cleanup(func() { trustedProxies = prev })
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "::1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"spoofed XFF from untrusted peer", "203.0.113.7:5000", []string{"1.2.3.4"}, "", "203.0.113.7"},
+		{"spoofed X-Real-IP from untrusted peer", "203.0.113.7:5000", nil, "1.2.3.4", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", []string{"198.51.100.9"}, "", "198.51.100.9"},
+		{"client-prepended hop is ignored", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.9"}, "", "198.51.100.9"},
+		{"chain of trusted proxies", "10.0.0.2:5000", []string{"198.51.100.9, 10.0.0.5"}, "", "198.51.100.9"},
+		{"multiple XFF headers", "10.0.0.2:5000", []string{"1.2.3.4", "198.51.100.9"}, "", "198.51.100.9"},
+		{"all hops trusted", "10.0.0.2:5000", []string{"10.0.0.9, 10.0.0.5"}, "", "10.0.0.9"},
+		{"garbage hop stops at trusted peer", "10.0.0.2:5000", []string{"1.2.3.4, not-an-ip"}, "198.51.100.9", "10.0.0.2"},
+		{"X-Real-IP from trusted proxy", "10.0.0.2:5000", nil, " 198.51.100.9 ", "198.51.100.9"},
+		{"trusted proxy without headers", "10.0.0.2:5000", nil, "", "10.0.0.2"},
+		{"ipv6 trusted proxy", "[::1]:5000", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"remote without port", "203.0.113.7", nil, "", "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,80 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
+)
+
+// Site defaults for gasolina-online.com
+const (
+	defaultBaseURL  = "https://gasolina-online.com/"
+	defaultCheckURL = "https://gasolina-online.com/indicator"
+	allowedSiteHost = "gasolina-online.com"
 )
 
 // Config holds the application configuration (legacy, for CLI mode)
 type Config struct {
-	Email             string
-	Password          string
-	AccountNumber     string
-	CheckURL          string
-	CronSchedule      string
-	DryRun            bool
-	MonthlyIncrements map[int]int // month number -> increment value
+	Email           string
+	Password        string
+	AccountNumber   string
+	LoginURL        string
+	CheckURL        string
+	CronSchedule    string
+	CronWithSeconds bool // accept an optional leading seconds field in CronSchedule
+	DryRun          bool
+	SuccessMode     string // "text" (default) or "row_count"
+	// RecheckMissingButton re-verifies the indicator table when the "Ввести" button
+	// is missing, so an already-submitted reading is not reported as a failure
+	RecheckMissingButton bool
+	// ForceSubmit submits even when the new reading equals the current one
+	ForceSubmit bool
+	// DryRunAnomalyWarning lets a dry run with an implausible reading finish
+	// with an anomaly_warning outcome (and a notification) instead of failing
+	DryRunAnomalyWarning bool
+	// SubmitButtonText and SubmitButtonSelector pick the modal's submit button
+	// when the layout has more than one submit-type button
+	SubmitButtonText     string
+	SubmitButtonSelector string
+	// LandingURLPattern is a regexp the post-login URL must match; "{account}"
+	// stands for the account number. Empty only requires staying on the site.
+	LandingURLPattern string
+	// Optional [start, end) hour window for submissions, in SubmissionTimezone
+	// (server local time when empty)
+	SubmissionHourStart *int
+	SubmissionHourEnd   *int
+	SubmissionTimezone  string
+	// ValueSelector and ValueSource locate the current reading (default #last_value / value)
+	ValueSelector     string
+	ValueSource       string
+	MonthlyIncrements map[int]float64 // month number -> increment value
+	// SerialIncrements overrides MonthlyIncrements per counter serial (serial -> month -> increment)
+	SerialIncrements map[string]map[int]float64
+	// IncrementsURL, when set, supplies the increments at job time (JSON or CSV);
+	// the increments above are the fallback
+	IncrementsURL string
+	// IncrementFallback supplies the increment of months missing above
+	IncrementFallback IncrementFallback
+	// Selectors overrides the site's CSS selectors; empty fields use the defaults
+	Selectors Selectors
+	// BaseURL is the main page with the current reading (default: the Gasolina site)
+	BaseURL string
+	// IgnoreSubmissionWindow skips the day-of-month window, e.g. for sandbox runs
+	IgnoreSubmissionWindow bool
+
+	// Submissions tracks live submission attempts (nil disables tracking, e.g. in CLI mode)
+	Submissions SubmissionTracker
 }
 
 // AppConfig holds the HTTP server configuration
@@ -27,19 +83,164 @@ type AppConfig struct {
 	HTTPPort string
 
 	// JWT
-	JWTSecret        string
-	JWTAccessExpiry  time.Duration
-	JWTRefreshExpiry time.Duration
+	JWTSecret           string
+	JWTAccessExpiry     time.Duration
+	JWTRefreshExpiry    time.Duration
+	JWTLeeway           time.Duration // clock skew tolerated on token expiry
+	JWTSecretPolicy     string        // "length" (default, warns only), "basic" or "strict"
+	JWTSecretMinEntropy float64       // bits per character, enforced in strict mode
 
 	// Database
 	DatabaseURL string
 
+	// Retries of transient DB errors (attempts include the first try)
+	DBRetryAttempts int
+	DBRetryBackoff  time.Duration
+
 	// Screenshots
 	ScreenshotsPath string
 
+	// Maximum width/height of generated screenshot thumbnails
+	ThumbnailMaxDimension int
+
+	// Screenshot retention in days by job outcome (0 keeps forever)
+	ScreenshotRetentionSuccessDays int
+	ScreenshotRetentionFailureDays int
+
+	// Days to keep finished jobs with their screenshots (0 keeps forever); jobs
+	// with evidence screenshots are kept
+	JobRetentionDays int
+
+	// Browser pool
+	BrowserTabsPerAllocator int
+	BrowserPoolSize         int
+	BrowserMaxTabs          int // concurrent tabs across the pool (0 = no cap)
+
+	// Base directory for persistent per-user browser profiles (empty = ephemeral)
+	BrowserUserDataDir string
+
+	// Jobs executing at once across all users (0 = no cap)
+	MaxConcurrentJobs int
+
+	// Pending and running jobs a user may have before new API jobs get a 409 (0 = no limit)
+	MaxQueuedJobs int
+
+	// SMTP notifications (disabled when SMTPHost is empty)
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	SMTPTLSMode  string
+
+	// Telegram Bot API base URL, for self-hosted Bot API servers
+	TelegramAPIURL string
+
+	// Sandbox site for test-submit jobs (disabled when its URL is empty)
+	TestSubmitSandbox TestSubmitSandbox
+
+	// Live-eligible runs forced to dry-run for each user (0 disables the ramp)
+	DryRunRampRuns int
+
+	// Attempts per job phase, and whether a failed submit may be retried
+	// once the indicator table shows it didn't land
+	JobMaxAttempts   int
+	RetryAfterSubmit bool
+
+	// Decimal separator used by the site in meter readings ("." or ",")
+	ReadingDecimalSeparator string
+
+	// Accepted range for a monthly increment; readings outside it aren't submitted
+	MinIncrement int
+	MaxIncrement int
+
+	// Fractional digits kept in readings and increments (0 for whole numbers only)
+	ReadingPrecision int
+
+	// Log in again when the site drops the session mid-job
+	MidJobRelogin bool
+
+	// How long to wait for the login form after navigation
+	LoginPageTimeout time.Duration
+
+	// Max wait for an awaited element (modal, dropdown, table after a year change)
+	ElementWaitTimeout time.Duration
+
+	// How readings are entered into the form: "type" or "js"
+	ValueInputMode string
+
+	// Take before/after submit screenshots that retention never purges
+	EvidenceScreenshots bool
+
+	// How the account number is matched in the dropdown: contains, word or exact
+	AccountMatchMode string
+
+	// Site-wide CSS selector overrides from GASOLINA_SELECTORS_FILE
+	Selectors Selectors
+
+	// How pages settle after navigation: fixed sleeps or network idle
+	PageSettle PageSettle
+
+	// Accept-button selector of the site's cookie banner ("none" disables dismissal)
+	CookieBannerSelector string
+
+	// Allow serving failure HTML dumps as text/html (sandboxed) with ?render=html
+	RenderHTMLDumps bool
+
+	// Job log verbosity: quiet, normal or verbose
+	JobLogVerbosity LogLevel
+
+	// Server log format ("text" or "json") and minimum level
+	LogFormat string
+	LogLevel  slog.Level
+
+	// Failed logins allowed per client IP and per email within LoginFailureWindow (0 disables)
+	LoginMaxFailures   int
+	LoginFailureWindow time.Duration
+
+	// Account lockout after repeated failed logins
+	AccountLockout AccountLockout
+
+	// Password reset token lifetime and the link prefix sent in reset emails
+	PasswordResetTTL time.Duration
+	PasswordResetURL string
+
+	// How often expired refresh tokens are deleted
+	RefreshTokenCleanupInterval time.Duration
+
+	// Endpoint for per-phase job progress events (needs the webhooks flag)
+	ProgressWebhookURL string
+
+	// Start with job processing paused
+	MaintenanceMode bool
+
+	// Force every job to dry-run regardless of user config or job type
+	GlobalForceDryRun bool
+
+	// Consecutive failed page structure checks that flag a user's scraping as broken
+	ScrapingBrokenThreshold int
+
+	// Max handler time before a 503 (0 disables); streaming endpoints are exempt
+	RequestTimeout time.Duration
+
+	// Indent every JSON response (otherwise only with ?pretty=1)
+	PrettyJSON bool
+
+	// Feature flags from FEATURE_FLAGS ("name=true,other=false")
+	FeatureFlags map[FeatureFlag]bool
+
+	// Reverse proxies whose forwarding headers are trusted (CIDRs or IPs)
+	TrustedProxies []string
+
+	// Emails of users allowed to call admin endpoints
+	AdminEmails []string
+
 	// CORS
 	CORSAllowedOrigins []string
 
+	// Accept an optional seconds field in cron expressions
+	CronWithSeconds bool
+
 	// Legacy config (for CLI mode)
 	LegacyConfig *Config
 }
@@ -50,10 +251,116 @@ func LoadAppConfig() (*AppConfig, error) {
 	_ = godotenv.Load()
 
 	cfg := &AppConfig{
-		HTTPPort:        getEnvOrDefault("HTTP_PORT", "8080"),
-		JWTSecret:       os.Getenv("JWT_SECRET"),
-		DatabaseURL:     os.Getenv("DATABASE_URL"),
-		ScreenshotsPath: getEnvOrDefault("SCREENSHOTS_PATH", "./data/screenshots"),
+		HTTPPort:          getEnvOrDefault("HTTP_PORT", "8080"),
+		ScreenshotsPath:   getEnvOrDefault("SCREENSHOTS_PATH", "./data/screenshots"),
+		JWTSecretPolicy:   getEnvOrDefault("JWT_SECRET_POLICY", JWTSecretPolicyLength),
+		CronWithSeconds:   os.Getenv("CRON_WITH_SECONDS") == "true",
+		MaintenanceMode:   os.Getenv("MAINTENANCE_MODE") == "true",
+		GlobalForceDryRun: os.Getenv("GLOBAL_FORCE_DRY_RUN") == "true",
+		RenderHTMLDumps:   os.Getenv("RENDER_HTML_DUMPS") == "true",
+		PrettyJSON:        os.Getenv("PRETTY_JSON") == "true",
+
+		ThumbnailMaxDimension: getEnvIntOrDefault("THUMBNAIL_MAX_DIMENSION", 320),
+
+		ScreenshotRetentionSuccessDays: getEnvIntOrDefault("SCREENSHOT_RETENTION_SUCCESS_DAYS", 7),
+		ScreenshotRetentionFailureDays: getEnvIntOrDefault("SCREENSHOT_RETENTION_FAILURE_DAYS", 90),
+		JobRetentionDays:               getEnvIntOrDefault("JOB_RETENTION_DAYS", 0),
+
+		BrowserTabsPerAllocator: getEnvIntOrDefault("BROWSER_TABS_PER_ALLOCATOR", 1),
+		BrowserPoolSize:         getEnvIntOrDefault("BROWSER_POOL_SIZE", 1),
+		BrowserMaxTabs:          getEnvIntOrDefault("BROWSER_MAX_TABS", 4),
+		BrowserUserDataDir:      os.Getenv("BROWSER_USER_DATA_DIR"),
+		MaxConcurrentJobs:       getEnvIntOrDefault("MAX_CONCURRENT_JOBS", 4),
+		MaxQueuedJobs:           getEnvIntOrDefault("MAX_QUEUED_JOBS", 0),
+
+		SMTPHost:       os.Getenv("SMTP_HOST"),
+		TelegramAPIURL: getEnvOrDefault("TELEGRAM_API_URL", defaultTelegramAPIURL),
+		SMTPPort:       getEnvIntOrDefault("SMTP_PORT", 587),
+		SMTPUsername:   os.Getenv("SMTP_USERNAME"),
+		SMTPFrom:       os.Getenv("SMTP_FROM"),
+		SMTPTLSMode:    getEnvOrDefault("SMTP_TLS_MODE", SMTPTLSModeStartTLS),
+
+		ReadingDecimalSeparator: getEnvOrDefault("GASOLINA_DECIMAL_SEPARATOR", "."),
+		MinIncrement:            getEnvIntOrDefault("GASOLINA_MIN_INCREMENT", 0),
+		MaxIncrement:            getEnvIntOrDefault("GASOLINA_MAX_INCREMENT", 10000),
+		ReadingPrecision:        getEnvIntOrDefault("GASOLINA_READING_PRECISION", 3),
+		MidJobRelogin:           os.Getenv("GASOLINA_RELOGIN_MID_JOB") != "false",
+		CookieBannerSelector:    os.Getenv("GASOLINA_COOKIE_BANNER_SELECTOR"),
+		ValueInputMode:          getEnvOrDefault("GASOLINA_VALUE_INPUT_MODE", ValueInputModeType),
+		AccountMatchMode:        getEnvOrDefault("GASOLINA_ACCOUNT_MATCH", AccountMatchContains),
+		EvidenceScreenshots:     os.Getenv("GASOLINA_EVIDENCE_SCREENSHOTS") != "false",
+		DryRunRampRuns:          getEnvIntOrDefault("DRY_RUN_RAMP_RUNS", 1),
+		JobMaxAttempts:          getEnvIntOrDefault("JOB_MAX_ATTEMPTS", 3),
+		RetryAfterSubmit:        os.Getenv("JOB_RETRY_AFTER_SUBMIT") != "false",
+		LoginMaxFailures:        getEnvIntOrDefault("LOGIN_MAX_FAILURES", 5),
+		PasswordResetURL:        os.Getenv("PASSWORD_RESET_URL"),
+		ProgressWebhookURL:      os.Getenv("PROGRESS_WEBHOOK_URL"),
+		DBRetryAttempts:         getEnvIntOrDefault("DB_RETRY_ATTEMPTS", 3),
+		ScrapingBrokenThreshold: getEnvIntOrDefault("SCRAPING_BROKEN_THRESHOLD", 2),
+	}
+
+	if cfg.DBRetryAttempts < 1 || cfg.DBRetryAttempts > maxDBRetryAttempts {
+		return nil, fmt.Errorf("invalid DB_RETRY_ATTEMPTS: must be between 1 and %d", maxDBRetryAttempts)
+	}
+
+	if cfg.MaxConcurrentJobs < 0 {
+		return nil, fmt.Errorf("invalid MAX_CONCURRENT_JOBS: must be 0 (no cap) or more")
+	}
+	if cfg.MaxQueuedJobs < 0 {
+		return nil, fmt.Errorf("invalid MAX_QUEUED_JOBS: must be 0 (no limit) or more")
+	}
+
+	requestTimeout, err := time.ParseDuration(getEnvOrDefault("REQUEST_TIMEOUT", "10s"))
+	if err != nil || requestTimeout < 0 {
+		return nil, fmt.Errorf("invalid REQUEST_TIMEOUT: must be a duration, 0 to disable")
+	}
+	cfg.RequestTimeout = requestTimeout
+
+	loginFailureWindow, err := time.ParseDuration(getEnvOrDefault("LOGIN_FAILURE_WINDOW", "15m"))
+	if err != nil || loginFailureWindow <= 0 {
+		return nil, fmt.Errorf("invalid LOGIN_FAILURE_WINDOW: must be a positive duration")
+	}
+	cfg.LoginFailureWindow = loginFailureWindow
+
+	cfg.AccountLockout.Threshold = getEnvIntOrDefault("ACCOUNT_LOCKOUT_THRESHOLD", 10)
+	for _, d := range []struct {
+		env, def string
+		dst      *time.Duration
+	}{
+		{"ACCOUNT_LOCKOUT_WINDOW", "1h", &cfg.AccountLockout.Window},
+		{"ACCOUNT_LOCKOUT_DURATION", "30m", &cfg.AccountLockout.Duration},
+		{"PASSWORD_RESET_TTL", "1h", &cfg.PasswordResetTTL},
+		{"REFRESH_TOKEN_CLEANUP_INTERVAL", "1h", &cfg.RefreshTokenCleanupInterval},
+		{"DB_RETRY_BACKOFF", "100ms", &cfg.DBRetryBackoff},
+	} {
+		v, err := time.ParseDuration(getEnvOrDefault(d.env, d.def))
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid %s: must be a positive duration", d.env)
+		}
+		*d.dst = v
+	}
+
+	// Secrets may come from an external secret manager instead of the environment
+	secretProvider, err := NewSecretProvider(os.Getenv("SECRET_PROVIDER"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SECRET_PROVIDER: %w", err)
+	}
+	if err := loadSecrets(secretProvider, map[string]*string{
+		"JWT_SECRET":    &cfg.JWTSecret,
+		"DATABASE_URL":  &cfg.DatabaseURL,
+		"SMTP_PASSWORD": &cfg.SMTPPassword,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		cfg.TrustedProxies = strings.Split(proxies, ",")
+	}
+
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			cfg.AdminEmails = append(cfg.AdminEmails, strings.ToLower(email))
+		}
 	}
 
 	// Parse JWT expiry durations
@@ -71,6 +378,77 @@ func LoadAppConfig() (*AppConfig, error) {
 		cfg.JWTRefreshExpiry = 7 * 24 * time.Hour
 	}
 
+	cfg.TestSubmitSandbox = TestSubmitSandbox{
+		URL:           os.Getenv("TEST_SUBMIT_SANDBOX_URL"),
+		Email:         getEnvOrDefault("TEST_SUBMIT_EMAIL", "sandbox@example.com"),
+		Password:      getEnvOrDefault("TEST_SUBMIT_PASSWORD", "sandbox"),
+		AccountNumber: os.Getenv("TEST_SUBMIT_ACCOUNT_NUMBER"),
+		AllowRealSite: os.Getenv("TEST_SUBMIT_ALLOW_REAL_SITE") == "true",
+	}
+	if err := validateTestSubmitSandbox(cfg.TestSubmitSandbox); err != nil {
+		return nil, fmt.Errorf("invalid TEST_SUBMIT_SANDBOX_URL: %w", err)
+	}
+
+	jwtLeeway, err := ParseJWTLeeway(os.Getenv("JWT_LEEWAY"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_LEEWAY: %w", err)
+	}
+	cfg.JWTLeeway = jwtLeeway
+
+	cfg.JWTSecretMinEntropy = 3.5
+	if v := os.Getenv("JWT_SECRET_MIN_ENTROPY"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_SECRET_MIN_ENTROPY: %w", err)
+		}
+		cfg.JWTSecretMinEntropy = f
+	}
+
+	verbosity, err := ParseLogVerbosity(os.Getenv("JOB_LOG_VERBOSITY"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JOB_LOG_VERBOSITY: %w", err)
+	}
+	cfg.JobLogVerbosity = verbosity
+
+	if cfg.LogFormat, err = ParseLogFormat(os.Getenv("LOG_FORMAT")); err != nil {
+		return nil, fmt.Errorf("invalid LOG_FORMAT: %w", err)
+	}
+	if cfg.LogLevel, err = ParseLogLevel(os.Getenv("LOG_LEVEL")); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+
+	loginPageTimeout, err := ParseLoginPageTimeout(os.Getenv("LOGIN_PAGE_TIMEOUT"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_PAGE_TIMEOUT: %w", err)
+	}
+	cfg.LoginPageTimeout = loginPageTimeout
+
+	elementWaitTimeout, err := ParseElementWaitTimeout(os.Getenv("GASOLINA_ELEMENT_WAIT_TIMEOUT"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GASOLINA_ELEMENT_WAIT_TIMEOUT: %w", err)
+	}
+	cfg.ElementWaitTimeout = elementWaitTimeout
+
+	pageSettle, err := ParsePageSettle()
+	if err != nil {
+		return nil, fmt.Errorf("invalid page settle configuration: %w", err)
+	}
+	cfg.PageSettle = pageSettle
+
+	if path := os.Getenv("GASOLINA_SELECTORS_FILE"); path != "" {
+		selectors, err := LoadSelectorsFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GASOLINA_SELECTORS_FILE: %w", err)
+		}
+		cfg.Selectors = selectors
+	}
+
+	flags, err := ParseFeatureFlags(os.Getenv("FEATURE_FLAGS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEATURE_FLAGS: %w", err)
+	}
+	cfg.FeatureFlags = flags
+
 	// Parse CORS origins
 	corsOrigins := os.Getenv("CORS_ALLOWED_ORIGINS")
 	if corsOrigins != "" {
@@ -91,12 +469,42 @@ func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 
 	config := &Config{
-		Email:         os.Getenv("GASOLINA_EMAIL"),
-		Password:      os.Getenv("GASOLINA_PASSWORD"),
-		AccountNumber: os.Getenv("GASOLINA_ACCOUNT_NUMBER"),
-		CheckURL:      os.Getenv("GASOLINA_CHECK_URL"),
-		CronSchedule:  os.Getenv("CRON_SCHEDULE"),
-		DryRun:        os.Getenv("GASOLINA_DRY_RUN") != "false",
+		Email:           os.Getenv("GASOLINA_EMAIL"),
+		Password:        os.Getenv("GASOLINA_PASSWORD"),
+		AccountNumber:   os.Getenv("GASOLINA_ACCOUNT_NUMBER"),
+		LoginURL:        getEnvOrDefault("GASOLINA_LOGIN_URL", defaultBaseURL),
+		CheckURL:        os.Getenv("GASOLINA_CHECK_URL"),
+		CronSchedule:    os.Getenv("CRON_SCHEDULE"),
+		CronWithSeconds: os.Getenv("CRON_WITH_SECONDS") == "true",
+		DryRun:          os.Getenv("GASOLINA_DRY_RUN") != "false",
+		SuccessMode:     getEnvOrDefault("GASOLINA_SUCCESS_MODE", SuccessModeText),
+
+		RecheckMissingButton: os.Getenv("GASOLINA_RECHECK_MISSING_BUTTON") != "false",
+		ForceSubmit:          os.Getenv("GASOLINA_FORCE_SUBMIT") == "true",
+		DryRunAnomalyWarning: os.Getenv("GASOLINA_DRY_RUN_ANOMALY_WARNING") == "true",
+		SubmitButtonText:     os.Getenv("GASOLINA_SUBMIT_BUTTON_TEXT"),
+		SubmitButtonSelector: os.Getenv("GASOLINA_SUBMIT_BUTTON_SELECTOR"),
+		ValueSelector:        getEnvOrDefault("GASOLINA_VALUE_SELECTOR", defaultValueSelector),
+		ValueSource:          getEnvOrDefault("GASOLINA_VALUE_SOURCE", ValueSourceValue),
+		SubmissionTimezone:   os.Getenv("GASOLINA_SUBMISSION_TIMEZONE"),
+		LandingURLPattern:    os.Getenv("GASOLINA_LANDING_URL_PATTERN"),
+		IncrementsURL:        os.Getenv("GASOLINA_INCREMENTS_URL"),
+	}
+
+	for key, dst := range map[string]**int{
+		"GASOLINA_SUBMISSION_HOUR_START": &config.SubmissionHourStart,
+		"GASOLINA_SUBMISSION_HOUR_END":   &config.SubmissionHourEnd,
+	} {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be an hour: %w", key, err)
+			}
+			*dst = &n
+		}
+	}
+	if err := validateSubmissionHours(config.SubmissionHourStart, config.SubmissionHourEnd, config.SubmissionTimezone); err != nil {
+		return nil, err
 	}
 
 	// Set default cron schedule if not provided
@@ -105,14 +513,28 @@ func LoadConfig() (*Config, error) {
 	}
 
 	// Parse monthly increments JSON
+	fallback, err := ParseIncrementFallback(os.Getenv("GASOLINA_INCREMENT_FALLBACK"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GASOLINA_INCREMENT_FALLBACK: %w", err)
+	}
+	config.IncrementFallback = fallback
+	// A default increment covers every month, so the monthly ones become optional
+	hasDefaultIncrement := fallback.Mode == IncrementFallbackDefault
+
 	monthlyIncrementsJSON := os.Getenv("GASOLINA_MONTHLY_INCREMENTS")
 	if monthlyIncrementsJSON == "" {
-		return nil, fmt.Errorf("GASOLINA_MONTHLY_INCREMENTS is required")
+		if !hasDefaultIncrement {
+			return nil, fmt.Errorf("GASOLINA_MONTHLY_INCREMENTS is required")
+		}
+		monthlyIncrementsJSON = "{}"
 	}
 
-	if err := json.Unmarshal([]byte(monthlyIncrementsJSON), &config.MonthlyIncrements); err != nil {
+	increments, serialIncrements, err := parseMonthlyIncrements([]byte(monthlyIncrementsJSON))
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse GASOLINA_MONTHLY_INCREMENTS: %w", err)
 	}
+	config.MonthlyIncrements = increments
+	config.SerialIncrements = serialIncrements
 
 	// Validate required fields
 	if config.Email == "" {
@@ -127,25 +549,165 @@ func LoadConfig() (*Config, error) {
 	if config.CheckURL == "" {
 		return nil, fmt.Errorf("GASOLINA_CHECK_URL is required")
 	}
-	if len(config.MonthlyIncrements) == 0 {
+	if len(config.MonthlyIncrements) == 0 && len(config.SerialIncrements) == 0 && !hasDefaultIncrement {
 		return nil, fmt.Errorf("GASOLINA_MONTHLY_INCREMENTS must contain at least one month")
 	}
+	if err := validateSiteURL(config.LoginURL); err != nil {
+		return nil, fmt.Errorf("invalid GASOLINA_LOGIN_URL: %w", err)
+	}
+	if _, err := newCronParser(config.CronWithSeconds).Parse(config.CronSchedule); err != nil {
+		return nil, fmt.Errorf("invalid CRON_SCHEDULE: %w", err)
+	}
+	if _, err := compileLandingPattern(config.LandingURLPattern, config.AccountNumber); err != nil {
+		return nil, fmt.Errorf("invalid GASOLINA_LANDING_URL_PATTERN: %w", err)
+	}
+	if config.IncrementsURL != "" {
+		if err := validateIncrementsURL(config.IncrementsURL); err != nil {
+			return nil, fmt.Errorf("invalid GASOLINA_INCREMENTS_URL: %w", err)
+		}
+	}
+	if !isValidValueSource(config.ValueSource) {
+		return nil, fmt.Errorf("GASOLINA_VALUE_SOURCE must be %q, %q or a data- attribute", ValueSourceValue, ValueSourceInnerText)
+	}
+
+	if !isValidSuccessMode(config.SuccessMode) {
+		return nil, fmt.Errorf("GASOLINA_SUCCESS_MODE must be %q or %q", SuccessModeText, SuccessModeRowCount)
+	}
 
 	return config, nil
 }
 
-// GetIncrementForMonth returns the increment value for a given month (1-12)
-func (c *Config) GetIncrementForMonth(month int) (int, error) {
-	increment, ok := c.MonthlyIncrements[month]
-	if !ok {
-		return 0, fmt.Errorf("no increment configured for month %d", month)
+// parseMonthlyIncrements parses a JSON object of month ("1"-"12") to increment.
+// An entry whose value is itself an object is a per-counter override keyed by
+// the counter serial, e.g. {"1": 50, "A123": {"1": 30}}.
+// Unlike unmarshalling into map[int]float64, a bad entry doesn't discard the rest:
+// the valid entries are returned together with an error naming the first bad key.
+func parseMonthlyIncrements(data []byte) (map[int]float64, map[string]map[int]float64, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("monthly increments must be a JSON object: %w", err)
+	}
+
+	months := make(map[string]json.RawMessage, len(raw))
+	var serials map[string]map[int]float64
+	var firstErr error
+	for _, key := range sortedKeys(raw) {
+		value := bytes.TrimSpace(raw[key])
+		if len(value) == 0 || value[0] != '{' {
+			months[key] = raw[key]
+			continue
+		}
+		var serialRaw map[string]json.RawMessage
+		if err := json.Unmarshal(value, &serialRaw); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid increments for counter %q: %w", key, err)
+			}
+			continue
+		}
+		serialIncrements, err := parseMonthMap(serialRaw)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("counter %q: %w", key, err)
+		}
+		if serials == nil {
+			serials = make(map[string]map[int]float64)
+		}
+		serials[key] = serialIncrements
+	}
+
+	increments, err := parseMonthMap(months)
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return increments, serials, firstErr
+}
+
+// marshalIncrements serializes increments in the format read by parseMonthlyIncrements
+func marshalIncrements(monthly map[int]float64, bySerial map[string]map[int]float64) ([]byte, error) {
+	combined := make(map[string]interface{}, len(monthly)+len(bySerial))
+	for month, increment := range monthly {
+		combined[strconv.Itoa(month)] = increment
+	}
+	for serial, increments := range bySerial {
+		combined[serial] = increments
+	}
+	return json.Marshal(combined)
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxIncrement is the largest monthly increment accepted, far above any real
+// household's consumption
+const maxIncrement float64 = 100000
+
+// validIncrement reports whether v is a usable increment: finite, non-negative
+// and at most maxIncrement. NaN fails every comparison, so it is checked first.
+func validIncrement(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0) && v >= 0 && v <= maxIncrement
+}
+
+// parseMonthMap parses month-keyed increments, keeping the valid entries
+// and returning an error naming the first bad key
+func parseMonthMap(raw map[string]json.RawMessage) (map[int]float64, error) {
+	increments := make(map[int]float64, len(raw))
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, key := range sortedKeys(raw) {
+		month, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil || month < 1 || month > 12 {
+			fail(fmt.Errorf("invalid month key %q: must be 1-12", key))
+			continue
+		}
+		if _, dup := increments[month]; dup {
+			fail(fmt.Errorf("duplicate month key %q", key))
+			continue
+		}
+		var increment float64
+		if err := json.Unmarshal(raw[key], &increment); err != nil || !validIncrement(increment) {
+			fail(fmt.Errorf("invalid increment for month %q: must be a number from 0 to %g", key, maxIncrement))
+			continue
+		}
+		increments[month] = increment
+	}
+
+	return increments, firstErr
+}
+
+// GetIncrementForMonth returns the increment value for a given month (1-12).
+// A month without its own increment uses the IncrementFallback, if any.
+func (c *Config) GetIncrementForMonth(month int) (float64, error) {
+	if increment, ok := c.MonthlyIncrements[month]; ok {
+		return increment, nil
+	}
+	if increment, ok := c.IncrementFallback.incrementFor(c.MonthlyIncrements, month); ok {
+		return increment, nil
+	}
+	return 0, fmt.Errorf("no increment configured for month %d", month)
+}
+
+// GetIncrementForSerial returns the increment for a counter and month, using the
+// counter's own increments when configured and falling back to MonthlyIncrements
+func (c *Config) GetIncrementForSerial(serial string, month int) (float64, error) {
+	if increment, ok := c.SerialIncrements[serial][month]; ok {
+		return increment, nil
 	}
-	return increment, nil
+	return c.GetIncrementForMonth(month)
 }
 
 // GetIncrementForPreviousMonth returns the increment value for the previous month
 // If current month is January (1), returns December (12) increment
-func (c *Config) GetIncrementForPreviousMonth(currentMonth int) (int, int, error) {
+func (c *Config) GetIncrementForPreviousMonth(currentMonth int) (float64, int, error) {
 	prevMonth := currentMonth - 1
 	if prevMonth < 1 {
 		prevMonth = 12
@@ -154,9 +716,146 @@ func (c *Config) GetIncrementForPreviousMonth(currentMonth int) (int, int, error
 	return increment, prevMonth, err
 }
 
+// newCronParser returns the cron parser used by both the CLI scheduler and config
+// validation. With seconds enabled, a leading seconds field is optional.
+func newCronParser(withSeconds bool) cron.Parser {
+	fields := cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor
+	if withSeconds {
+		fields |= cron.SecondOptional
+	}
+	return cron.NewParser(fields)
+}
+
+var cronParser = newCronParser(false)
+
+// SetCronSecondsEnabled selects the cron parser used to validate user schedules
+func SetCronSecondsEnabled(enabled bool) {
+	cronParser = newCronParser(enabled)
+}
+
+// JWT secret validation policies
+const (
+	JWTSecretPolicyLength = "length"
+	JWTSecretPolicyBasic  = "basic"
+	JWTSecretPolicyStrict = "strict"
+)
+
+// weakSecretMarkers are placeholder values commonly left in example configs
+var weakSecretMarkers = []string{"changeme", "change-me", "change_me", "secret", "password", "example", "default", "your-", "your_", "qwerty", "123456"}
+
+// validateJWTSecret rejects a missing secret and, under the basic and strict
+// policies, a weak one. The length policy only requires a secret: the secret
+// also derives the credential encryption key, so forcing a rotation would make
+// every stored Gasolina password unreadable. runServer warns about it instead.
+func validateJWTSecret(secret, policy string, minEntropy float64) error {
+	if secret == "" {
+		return fmt.Errorf("JWT_SECRET is required")
+	}
+
+	switch policy {
+	case JWTSecretPolicyLength:
+		return nil
+	case JWTSecretPolicyBasic:
+		return jwtSecretWeakness(secret, 0)
+	case JWTSecretPolicyStrict:
+		return jwtSecretWeakness(secret, minEntropy)
+	}
+	return fmt.Errorf("unknown JWT_SECRET_POLICY %q", policy)
+}
+
+// jwtSecretWeakness reports why secret is weak: too short, a repeated
+// character or pattern, a placeholder value or, with minEntropy > 0, too low
+// entropy. Returns nil for a strong secret.
+func jwtSecretWeakness(secret string, minEntropy float64) error {
+	if len(secret) < 32 {
+		return fmt.Errorf("JWT_SECRET must be at least 32 characters")
+	}
+	if hasRepeatingPattern(secret) {
+		return fmt.Errorf("JWT_SECRET must not be a repeated character or pattern")
+	}
+	lower := strings.ToLower(secret)
+	for _, marker := range weakSecretMarkers {
+		if strings.Contains(lower, marker) {
+			return fmt.Errorf("JWT_SECRET looks like a placeholder (contains %q)", marker)
+		}
+	}
+
+	if minEntropy > 0 {
+		if entropy := shannonEntropy(secret); entropy < minEntropy {
+			return fmt.Errorf("JWT_SECRET entropy %.2f bits/char is below the required %.2f", entropy, minEntropy)
+		}
+	}
+	return nil
+}
+
+// hasRepeatingPattern reports whether s consists of a short unit (up to 4 chars) repeated
+func hasRepeatingPattern(s string) bool {
+	for n := 1; n <= 4 && n < len(s); n++ {
+		if strings.Repeat(s[:n], len(s)/n+1)[:len(s)] == s {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// isValidSuccessMode reports whether mode is a supported submission success criterion
+func isValidSuccessMode(mode string) bool {
+	return mode == SuccessModeText || mode == SuccessModeRowCount
+}
+
+// validateSiteURL ensures a URL points at the Gasolina site over HTTPS
+func validateSiteURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("URL must use https")
+	}
+	host := u.Hostname()
+	if host != allowedSiteHost && !strings.HasSuffix(host, "."+allowedSiteHost) {
+		return fmt.Errorf("URL host must be %s", allowedSiteHost)
+	}
+	return nil
+}
+
+// baseURL returns the main page URL, defaulting to the Gasolina site
+func (c *Config) baseURL() string {
+	if c.BaseURL == "" {
+		return defaultBaseURL
+	}
+	return c.BaseURL
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateSiteURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{defaultBaseURL, false},
+		{defaultCheckURL, false},
+		{"https://auth.gasolina-online.com/login", false},
+		{"http://gasolina-online.com/", true},
+		{"https://evil.com/", true},
+		{"https://gasolina-online.com.evil.com/", true},
+		{"https://evilgasolina-online.com/", true},
+		{"gasolina-online.com/login", true},
+		{"://bad", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := validateSiteURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSiteURL(%q) = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsValidSuccessMode(t *testing.T) {
+	tests := []struct {
+		mode string
+		want bool
+	}{
+		{SuccessModeText, true},
+		{SuccessModeRowCount, true},
+		{"", false},
+		{"Row_Count", false},
+		{"rows", false},
+	}
+	for _, tt := range tests {
+		if got := isValidSuccessMode(tt.mode); got != tt.want {
+			t.Errorf("isValidSuccessMode(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestValidateJWTSecret(t *testing.T) {
+	const strong = "k8#Vq2!mZ7rT$w4pL9xB1nC6yH3jF0dS"
+	tests := []struct {
+		name    string
+		secret  string
+		policy  string
+		wantErr bool
+	}{
+		{"missing", "", JWTSecretPolicyLength, true},
+		{"length allows weak", "short", JWTSecretPolicyLength, false},
+		{"basic strong", strong, JWTSecretPolicyBasic, false},
+		{"basic too short", "k8#Vq2!mZ7rT", JWTSecretPolicyBasic, true},
+		{"basic repeated char", strings.Repeat("a", 40), JWTSecretPolicyBasic, true},
+		{"basic repeated pattern", strings.Repeat("ab1!", 10), JWTSecretPolicyBasic, true},
+		{"basic placeholder", "please-ChangeMe-before-deploying-x9", JWTSecretPolicyBasic, true},
+		{"basic ignores entropy", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab", JWTSecretPolicyBasic, false},
+		{"strict strong", strong, JWTSecretPolicyStrict, false},
+		{"strict low entropy", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab", JWTSecretPolicyStrict, true},
+		{"unknown policy", strong, "paranoid", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJWTSecret(tt.secret, tt.policy, 3.5)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateJWTSecret(%q, %q) = %v, wantErr %v", tt.secret, tt.policy, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShannonEntropy(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"", 0},
+		{"aaaa", 0},
+		{"abab", 1},
+		{"abcd", 2},
+	}
+	for _, tt := range tests {
+		if got := shannonEntropy(tt.s); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("shannonEntropy(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNewCronParser(t *testing.T) {
+	tests := []struct {
+		spec        string
+		withSeconds bool
+		wantErr     bool
+		wantNext    string // next run after 2026-03-01T00:00:00Z
+	}{
+		{"0 9 1 * *", false, false, "2026-03-01T09:00:00Z"},
+		{"30 0 9 1 * *", false, true, ""},
+		{"30 0 9 1 * *", true, false, "2026-03-01T09:00:30Z"},
+		{"0 9 1 * *", true, false, "2026-03-01T09:00:00Z"},
+		{"*/15 * * * * *", true, false, "2026-03-01T00:00:15Z"},
+		{"@daily", true, false, "2026-03-02T00:00:00Z"},
+		{"0 0 9 1 * * *", true, true, ""},
+		{"61 * * * *", false, true, ""},
+	}
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/seconds=%v", tt.spec, tt.withSeconds), func(t *testing.T) {
+			sched, err := newCronParser(tt.withSeconds).Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := sched.Next(from).Format(time.RFC3339); got != tt.wantNext {
+				t.Errorf("next run = %s, want %s", got, tt.wantNext)
+			}
+		})
+	}
+}
+
+// setCLIEnv sets the environment LoadConfig needs, plus any overrides
+func setCLIEnv(t *testing.T, overrides map[string]string) {
+	t.Helper()
+	env := map[string]string{
+		"GASOLINA_EMAIL":              "user@example.com",
+		"GASOLINA_PASSWORD":           "secret",
+		"GASOLINA_ACCOUNT_NUMBER":     "12345",
+		"GASOLINA_CHECK_URL":          defaultCheckURL,
+		"GASOLINA_MONTHLY_INCREMENTS": `{"1": 10}`,
+	}
+	for k, v := range overrides {
+		env[k] = v
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func TestLoadConfigRecheckMissingButton(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setCLIEnv(t, map[string]string{"GASOLINA_RECHECK_MISSING_BUTTON": tt.value})
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.RecheckMissingButton != tt.want {
+				t.Errorf("RecheckMissingButton = %v, want %v", cfg.RecheckMissingButton, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMonthlyIncrements(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    map[int]float64
+		wantErr string
+	}{
+		{"valid", `{"1": 10, "12": 0}`, map[int]float64{1: 10, 12: 0}, ""},
+		{"padded key", `{" 3 ": 5}`, map[int]float64{3: 5}, ""},
+		{"not an object", `[10, 20]`, nil, "monthly increments must be a JSON object"},
+		{"month out of range", `{"13": 5, "2": 7}`, map[int]float64{2: 7}, `invalid month key "13": must be 1-12`},
+		{"named month", `{"jan": 5}`, map[int]float64{}, `invalid month key "jan": must be 1-12`},
+		{"duplicate month", `{"01": 5, "1": 6}`, map[int]float64{1: 5}, `duplicate month key "1"`},
+		{"negative increment", `{"4": -1}`, map[int]float64{}, `invalid increment for month "4"`},
+		{"fractional increment", `{"4": 1.5}`, map[int]float64{4: 1.5}, ""},
+		{"string increment", `{"4": "10"}`, map[int]float64{}, `invalid increment for month "4"`},
+		{"first bad key reported", `{"5": "x", "14": 1}`, map[int]float64{}, `invalid month key "14"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := parseMonthlyIncrements([]byte(tt.json))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("parseMonthlyIncrements() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Fatalf("parseMonthlyIncrements() error = %v, want prefix %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMonthlyIncrements() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMonthlyIncrementsSerials(t *testing.T) {
+	tests := []struct {
+		name        string
+		json        string
+		wantMonthly map[int]float64
+		wantSerials map[string]map[int]float64
+		wantErr     string
+	}{
+		{"months only", `{"1": 50}`, map[int]float64{1: 50}, nil, ""},
+		{"counter override", `{"1": 50, "A123": {"1": 30, "2": 20}}`,
+			map[int]float64{1: 50}, map[string]map[int]float64{"A123": {1: 30, 2: 20}}, ""},
+		{"counters only", `{"A123": {"1": 30}, "B456": {"1": 10}}`,
+			map[int]float64{}, map[string]map[int]float64{"A123": {1: 30}, "B456": {1: 10}}, ""},
+		{"bad counter month", `{"1": 50, "A123": {"13": 30, "2": 20}}`,
+			map[int]float64{1: 50}, map[string]map[int]float64{"A123": {2: 20}}, `counter "A123": invalid month key "13"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monthly, serials, err := parseMonthlyIncrements([]byte(tt.json))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("parseMonthlyIncrements() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Fatalf("parseMonthlyIncrements() error = %v, want prefix %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(monthly, tt.wantMonthly) {
+				t.Errorf("monthly = %v, want %v", monthly, tt.wantMonthly)
+			}
+			if !reflect.DeepEqual(serials, tt.wantSerials) {
+				t.Errorf("serials = %v, want %v", serials, tt.wantSerials)
+			}
+
+			// Whatever parsed must survive a round trip through storage
+			data, err := marshalIncrements(monthly, serials)
+			if err != nil {
+				t.Fatalf("marshalIncrements: %v", err)
+			}
+			monthly2, serials2, err := parseMonthlyIncrements(data)
+			if err != nil || !reflect.DeepEqual(monthly2, monthly) || !reflect.DeepEqual(serials2, serials) {
+				t.Errorf("round trip of %s = %v, %v, %v", data, monthly2, serials2, err)
+			}
+		})
+	}
+}
+
+func TestGetIncrementForSerial(t *testing.T) {
+	c := &Config{
+		MonthlyIncrements: map[int]float64{1: 50, 2: 40},
+		SerialIncrements:  map[string]map[int]float64{"A123": {1: 30}},
+	}
+	tests := []struct {
+		serial  string
+		month   int
+		want    float64
+		wantErr bool
+	}{
+		{"A123", 1, 30, false},
+		{"A123", 2, 40, false}, // falls back to the month map
+		{"B456", 1, 50, false},
+		{"A123", 3, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.serial, tt.month), func(t *testing.T) {
+			got, err := c.GetIncrementForSerial(tt.serial, tt.month)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetIncrementForSerial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetIncrementForSerial() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
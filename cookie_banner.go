@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// defaultCookieBannerSelectors are accept buttons of common cookie-consent banners
+var defaultCookieBannerSelectors = []string{
+	`#onetrust-accept-btn-handler`,
+	`.cc-allow`,
+	`.cc-dismiss`,
+	`#cookie-accept`,
+	`.cookie-accept`,
+	`[data-cookie-accept]`,
+	`.js-cookie-consent-agree`,
+}
+
+// cookieBannerSelectors are tried in order to dismiss a consent overlay
+var cookieBannerSelectors = defaultCookieBannerSelectors
+
+// SetCookieBannerSelector sets the accept-button selector of the site's cookie
+// banner. Empty keeps the defaults; "none" disables dismissal.
+func SetCookieBannerSelector(selector string) {
+	switch selector {
+	case "":
+		cookieBannerSelectors = defaultCookieBannerSelectors
+	case "none":
+		cookieBannerSelectors = nil
+	default:
+		cookieBannerSelectors = []string{selector}
+	}
+}
+
+// dismissCookieBannerJS clicks the first visible element matching one of the
+// selectors and returns the selector used, or "" if none was found
+const dismissCookieBannerJS = `
+	(function(selectors) {
+		for (const selector of selectors) {
+			let el;
+			try { el = document.querySelector(selector); } catch (e) { continue; }
+			if (el && el.offsetParent !== null) {
+				el.click();
+				return selector;
+			}
+		}
+		return '';
+	})(%s)
+`
+
+// dismissCookieBanner closes a cookie-consent overlay that would intercept clicks.
+// A missing banner is not an error.
+func dismissCookieBanner(ctx context.Context, logger Logger) {
+	if len(cookieBannerSelectors) == 0 {
+		return
+	}
+
+	selectors, err := json.Marshal(cookieBannerSelectors)
+	if err != nil {
+		return
+	}
+
+	var clicked string
+	if err := chromedp.Run(ctx,
+		chromedp.Evaluate(fmt.Sprintf(dismissCookieBannerJS, selectors), &clicked),
+	); err != nil {
+		logger.Log(fmt.Sprintf("Warning: cookie banner check failed: %v", err))
+		return
+	}
+	if clicked != "" {
+		logger.Log(fmt.Sprintf("Dismissed cookie banner (%s)", strings.TrimSpace(clicked)))
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetCookieBannerSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"", defaultCookieBannerSelectors},
+		{"none", nil},
+		{"#accept-all", []string{"#accept-all"}},
+	}
+	t.Cleanup(func() { SetCookieBannerSelector("") })
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			SetCookieBannerSelector(tt.selector)
+			if !reflect.DeepEqual(cookieBannerSelectors, tt.want) {
+				t.Errorf("cookieBannerSelectors = %q, want %q", cookieBannerSelectors, tt.want)
+			}
+		})
+	}
+}
+
+func TestDismissCookieBannerWithoutBrowser(t *testing.T) {
+	tests := []struct {
+		selector    string
+		wantWarning bool
+	}{
+		{"none", false}, // disabled: the page is never touched
+		{"#accept-all", true},
+	}
+	t.Cleanup(func() { SetCookieBannerSelector("") })
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			SetCookieBannerSelector(tt.selector)
+			logger := &testLogger{}
+			dismissCookieBanner(context.Background(), logger)
+			warned := len(logger.lines) == 1 && strings.HasPrefix(logger.lines[0], "Warning: cookie banner check failed")
+			if warned != tt.wantWarning {
+				t.Errorf("logged %q, want warning %v", logger.lines, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -11,14 +12,29 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var db *sql.DB
 
+// ErrEmailTaken is returned when creating a user whose email is already registered
+var ErrEmailTaken = errors.New("email already registered")
+
+// pgUniqueViolation is the Postgres error code for unique constraint violations
+const pgUniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation
+}
+
 // InitDB initializes the PostgreSQL database and runs migrations
 func InitDB(databaseURL string) error {
 	var err error
@@ -81,6 +97,19 @@ func runMigrations() error {
 			created_at TIMESTAMPTZ DEFAULT NOW()
 		)`,
 
+		// Where each refresh token (session) was issued and last used
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent TEXT`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS ip_address TEXT`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ`,
+
+		// Refresh tokens already exchanged for new ones; presenting one again means it leaked
+		`CREATE TABLE IF NOT EXISTS rotated_refresh_tokens (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at TIMESTAMPTZ NOT NULL,
+			rotated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+
 		// Jobs table
 		`CREATE TABLE IF NOT EXISTS jobs (
 			id TEXT PRIMARY KEY,
@@ -108,6 +137,169 @@ func runMigrations() error {
 		`CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_screenshots_job_id ON screenshots(job_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id)`,
+
+		// Separate login page URL
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS login_url TEXT`,
+
+		// Submission attempts (for retry-safe live submissions)
+		`CREATE TABLE IF NOT EXISTS submissions (
+			id TEXT PRIMARY KEY,
+			job_id TEXT REFERENCES jobs(id) ON DELETE SET NULL,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			counter_serial TEXT NOT NULL DEFAULT '',
+			year INTEGER NOT NULL,
+			month INTEGER NOT NULL,
+			previous_value INTEGER NOT NULL,
+			submitted_value INTEGER NOT NULL,
+			increment INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			confirmed_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_submissions_user_period ON submissions(user_id, year, month)`,
+		// Readings may be fractional
+		`DO $$ BEGIN
+			IF (SELECT data_type FROM information_schema.columns
+			    WHERE table_name = 'submissions' AND column_name = 'submitted_value') = 'integer' THEN
+				ALTER TABLE submissions
+					ALTER COLUMN previous_value TYPE NUMERIC,
+					ALTER COLUMN submitted_value TYPE NUMERIC,
+					ALTER COLUMN increment TYPE NUMERIC;
+			END IF;
+		END $$`,
+
+		// Submission success criteria
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS success_mode TEXT`,
+
+		// Per-user browser fingerprint
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS browser_user_agent TEXT`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS browser_viewport_width INTEGER`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS browser_viewport_height INTEGER`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS browser_timezone TEXT`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS browser_locale TEXT`,
+
+		// Coarse job progress (0-100)
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS progress INTEGER NOT NULL DEFAULT 0`,
+
+		// Re-verify the record when the submit button is missing
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS recheck_missing_button BOOLEAN DEFAULT TRUE`,
+
+		// Email address for job result notifications
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS notify_email TEXT`,
+
+		// Dry-run safety ramp for the first live runs
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS ramp_runs_done INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS skip_dry_run_ramp BOOLEAN DEFAULT FALSE`,
+
+		// Submit button disambiguation
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS submit_button_text TEXT`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS submit_button_selector TEXT`,
+
+		// Where the current reading is read from
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS value_selector TEXT`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS value_source TEXT`,
+
+		// Optional time-of-day submission window
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS submission_hour_start INTEGER`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS submission_hour_end INTEGER`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS submission_timezone TEXT`,
+
+		// Expected post-login URL pattern
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS landing_url_pattern TEXT`,
+
+		// Which job outcomes trigger a notification (comma-separated)
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS notify_on TEXT`,
+
+		// Submit even when the new reading equals the current one
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS force_submit BOOLEAN DEFAULT FALSE`,
+
+		// Final job outcome ("failure", "success" or a detailed success such as
+		// "already_exists"), used for retention and job results
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS outcome TEXT`,
+		`UPDATE jobs SET outcome = CASE status WHEN 'completed' THEN 'success' ELSE 'failure' END
+			WHERE outcome IS NULL AND status IN ('completed', 'failed')`,
+
+		// Structured check result (computed value, increment, whether it was submitted)
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS result TEXT`,
+
+		// User-provided note on a job
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS note TEXT`,
+
+		// Account lockout after repeated failed logins
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS first_failed_login_at TIMESTAMPTZ`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ`,
+
+		// Single-use password reset tokens (hashed, like refresh tokens)
+		`CREATE TABLE IF NOT EXISTS password_reset_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash TEXT UNIQUE NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id)`,
+
+		// Page structure canary results per user
+		`CREATE TABLE IF NOT EXISTS scraping_health (
+			user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			missing TEXT,
+			broken_since TIMESTAMPTZ,
+			last_checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+
+		// Dry runs flag implausible readings as anomaly_warning instead of failing
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS dry_run_anomaly_warning BOOLEAN DEFAULT FALSE`,
+
+		// External source of monthly increments (published CSV/JSON)
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS increments_url TEXT`,
+
+		// Per-user CSS selector overrides (JSON)
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS selectors TEXT`,
+
+		// Quiet hours hold back notifications until the window ends
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS quiet_hour_start INTEGER`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS quiet_hour_end INTEGER`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS quiet_timezone TEXT`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS quiet_bypass_critical BOOLEAN DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS deferred_notifications (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			job_id TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+			deliver_at TIMESTAMPTZ NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_deferred_notifications_deliver_at ON deferred_notifications(deliver_at)`,
+
+		// Telegram notifications through the user's own bot (token encrypted like the password)
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS telegram_bot_token TEXT`,
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS telegram_chat_id TEXT`,
+
+		// Increment for months missing from monthly_increments
+		`ALTER TABLE configs ADD COLUMN IF NOT EXISTS increment_fallback TEXT`,
+
+		// Reading values of a job as structured columns, so clients needn't parse logs
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS submitted_value DOUBLE PRECISION`,
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS previous_value DOUBLE PRECISION`,
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS increment_used DOUBLE PRECISION`,
+
+		// What created a job; jobs from before this column came from the API
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'api'`,
+
+		// Failure category of a failed job (e.g. invalid_credentials)
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS error_code TEXT`,
+
+		// Before/after submit screenshots kept as evidence through retention
+		`ALTER TABLE screenshots ADD COLUMN IF NOT EXISTS is_evidence BOOLEAN NOT NULL DEFAULT FALSE`,
+
+		// Per-deployment feature flag overrides
+		`CREATE TABLE IF NOT EXISTS feature_flags (
+			name TEXT PRIMARY KEY,
+			enabled BOOLEAN NOT NULL,
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
 	}
 
 	for _, migration := range migrations {
@@ -121,50 +313,145 @@ func runMigrations() error {
 
 // User represents a user in the system
 type User struct {
-	ID           int64     `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int64      `json:"id"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"`
+	LockedUntil  *time.Time `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 // UserConfig represents a user's Gasolina configuration
 type UserConfig struct {
-	ID                int64       `json:"id"`
-	UserID            int64       `json:"user_id"`
-	GasolinaEmail     string      `json:"gasolina_email,omitempty"`
-	GasolinaPassword  string      `json:"-"` // Never expose
-	AccountNumber     string      `json:"account_number,omitempty"`
-	CheckURL          string      `json:"check_url"`
-	CronSchedule      string      `json:"cron_schedule"`
-	DryRun            bool        `json:"dry_run"`
-	MonthlyIncrements map[int]int `json:"monthly_increments,omitempty"`
-	Configured        bool        `json:"configured"`
-	CreatedAt         time.Time   `json:"created_at"`
-	UpdatedAt         time.Time   `json:"updated_at"`
+	ID                   int64                      `json:"id"`
+	UserID               int64                      `json:"user_id"`
+	GasolinaEmail        string                     `json:"gasolina_email,omitempty"`
+	GasolinaPassword     string                     `json:"-"` // Never expose
+	AccountNumber        string                     `json:"account_number,omitempty"`
+	LoginURL             string                     `json:"login_url"`
+	CheckURL             string                     `json:"check_url"`
+	CronSchedule         string                     `json:"cron_schedule"`
+	DryRun               bool                       `json:"dry_run"`
+	SuccessMode          string                     `json:"success_mode"`
+	RecheckMissingButton bool                       `json:"recheck_missing_button"`
+	NotifyEmail          string                     `json:"notify_email,omitempty"`
+	NotifyOn             []string                   `json:"notify_on"`
+	SkipDryRunRamp       bool                       `json:"skip_dry_run_ramp"`
+	ForceSubmit          bool                       `json:"force_submit"`
+	DryRunAnomalyWarning bool                       `json:"dry_run_anomaly_warning"`
+	RampRunsDone         int                        `json:"ramp_runs_done"`
+	SubmitButtonText     string                     `json:"submit_button_text,omitempty"`
+	SubmitButtonSelector string                     `json:"submit_button_selector,omitempty"`
+	ValueSelector        string                     `json:"value_selector"`
+	ValueSource          string                     `json:"value_source"`
+	SubmissionHourStart  *int                       `json:"submission_hour_start,omitempty"`
+	SubmissionHourEnd    *int                       `json:"submission_hour_end,omitempty"`
+	SubmissionTimezone   string                     `json:"submission_timezone,omitempty"`
+	LandingURLPattern    string                     `json:"landing_url_pattern,omitempty"`
+	Fingerprint          BrowserFingerprint         `json:"browser_fingerprint"`
+	MonthlyIncrements    map[int]float64            `json:"monthly_increments,omitempty"`
+	IncrementsURL        string                     `json:"increments_url,omitempty"`
+	IncrementFallback    IncrementFallback          `json:"increment_fallback"`
+	Selectors            Selectors                  `json:"selectors"`
+	QuietHourStart       *int                       `json:"quiet_hour_start,omitempty"`
+	QuietHourEnd         *int                       `json:"quiet_hour_end,omitempty"`
+	QuietTimezone        string                     `json:"quiet_timezone,omitempty"`
+	QuietBypassCritical  bool                       `json:"quiet_bypass_critical"`
+	TelegramBotToken     string                     `json:"-"` // Never expose
+	TelegramBotTokenSet  bool                       `json:"telegram_bot_token_set"`
+	TelegramChatID       string                     `json:"telegram_chat_id,omitempty"`
+	SerialIncrements     map[string]map[int]float64 `json:"serial_increments,omitempty"`
+	IncrementsWarning    string                     `json:"monthly_increments_warning,omitempty"`
+	Configured           bool                       `json:"configured"`
+	CreatedAt            time.Time                  `json:"created_at"`
+	UpdatedAt            time.Time                  `json:"updated_at"`
+}
+
+// ToConfig converts the user's configuration to the Config used by login and the checker
+func (c *UserConfig) ToConfig() *Config {
+	return &Config{
+		Email:             c.GasolinaEmail,
+		Password:          c.GasolinaPassword,
+		AccountNumber:     c.AccountNumber,
+		LoginURL:          c.LoginURL,
+		CheckURL:          c.CheckURL,
+		CronSchedule:      c.CronSchedule,
+		DryRun:            c.DryRun,
+		SuccessMode:       c.SuccessMode,
+		MonthlyIncrements: c.MonthlyIncrements,
+		SerialIncrements:  c.SerialIncrements,
+		IncrementsURL:     c.IncrementsURL,
+		IncrementFallback: c.IncrementFallback,
+		Selectors:         c.Selectors,
+
+		RecheckMissingButton: c.RecheckMissingButton,
+		ForceSubmit:          c.ForceSubmit,
+		DryRunAnomalyWarning: c.DryRunAnomalyWarning,
+		SubmitButtonText:     c.SubmitButtonText,
+		SubmitButtonSelector: c.SubmitButtonSelector,
+		ValueSelector:        c.ValueSelector,
+		ValueSource:          c.ValueSource,
+		SubmissionHourStart:  c.SubmissionHourStart,
+		SubmissionHourEnd:    c.SubmissionHourEnd,
+		SubmissionTimezone:   c.SubmissionTimezone,
+		LandingURLPattern:    c.LandingURLPattern,
+	}
 }
 
 // Job represents a job execution record
 type Job struct {
-	ID          string     `json:"id"`
-	UserID      int64      `json:"user_id"`
-	Type        string     `json:"type"`
-	Status      string     `json:"status"`
-	Error       *string    `json:"error,omitempty"`
-	Logs        []string   `json:"logs,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	StartedAt   *time.Time `json:"started_at,omitempty"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ID             string       `json:"id"`
+	UserID         int64        `json:"user_id"`
+	Type           string       `json:"type"`
+	Status         string       `json:"status"`
+	Source         string       `json:"source"`
+	Progress       int          `json:"progress"`
+	Error          *string      `json:"error,omitempty"`
+	ErrorCode      string       `json:"error_code,omitempty"`
+	Logs           []string     `json:"logs,omitempty"`
+	Result         *CheckResult `json:"result,omitempty"`
+	Outcome        string       `json:"outcome,omitempty"`
+	Note           string       `json:"note,omitempty"`
+	SubmittedValue *float64     `json:"submitted_value,omitempty"` // only set when actually submitted
+	PreviousValue  *float64     `json:"previous_value,omitempty"`
+	IncrementUsed  *float64     `json:"increment_used,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	StartedAt      *time.Time   `json:"started_at,omitempty"`
+	CompletedAt    *time.Time   `json:"completed_at,omitempty"`
 }
 
 // Screenshot represents a screenshot record
 type Screenshot struct {
-	ID        int64     `json:"id"`
-	JobID     string    `json:"job_id"`
-	UserID    int64     `json:"user_id"`
-	Filename  string    `json:"filename"`
-	URL       string    `json:"url,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int64     `json:"id"`
+	JobID      string    `json:"job_id"`
+	UserID     int64     `json:"user_id"`
+	Filename   string    `json:"filename"`
+	IsEvidence bool      `json:"is_evidence"`
+	URL        string    `json:"url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Submission statuses
+const (
+	SubmissionAttempted = "attempted"
+	SubmissionConfirmed = "confirmed"
+	SubmissionAbandoned = "abandoned"
+)
+
+// Submission represents a live reading submission attempt
+type Submission struct {
+	ID             string     `json:"id"`
+	JobID          string     `json:"job_id,omitempty"`
+	UserID         int64      `json:"user_id"`
+	CounterSerial  string     `json:"counter_serial"`
+	Year           int        `json:"year"`
+	Month          int        `json:"month"`
+	PreviousValue  float64    `json:"previous_value"`
+	SubmittedValue float64    `json:"submitted_value"`
+	Increment      float64    `json:"increment"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
 }
 
 // CreateUser creates a new user with hashed password
@@ -179,20 +466,27 @@ func CreateUser(email, password string) (*User, error) {
 		"INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id",
 		email, string(hash),
 	).Scan(&id)
+	if isUniqueViolation(err) {
+		// Lost a race with a concurrent registration of the same email
+		return nil, ErrEmailTaken
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return GetUserByID(id)
+	return GetUserByID(context.Background(), id)
 }
 
 // GetUserByID retrieves a user by ID
-func GetUserByID(id int64) (*User, error) {
+func GetUserByID(ctx context.Context, id int64) (*User, error) {
 	user := &User{}
-	err := db.QueryRow(
-		"SELECT id, email, password_hash, created_at, updated_at FROM users WHERE id = $1",
-		id,
-	).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	var lockedUntil sql.NullTime
+	err := withDBRetry(ctx, "GetUserByID", func() error {
+		return db.QueryRowContext(ctx,
+			"SELECT id, email, password_hash, locked_until, created_at, updated_at FROM users WHERE id = $1",
+			id,
+		).Scan(&user.ID, &user.Email, &user.PasswordHash, &lockedUntil, asUTC(&user.CreatedAt), asUTC(&user.UpdatedAt))
+	})
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -200,17 +494,21 @@ func GetUserByID(id int64) (*User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	user.LockedUntil = utcPtr(lockedUntil)
 
 	return user, nil
 }
 
 // GetUserByEmail retrieves a user by email
-func GetUserByEmail(email string) (*User, error) {
+func GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	user := &User{}
-	err := db.QueryRow(
-		"SELECT id, email, password_hash, created_at, updated_at FROM users WHERE email = $1",
-		email,
-	).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	var lockedUntil sql.NullTime
+	err := withDBRetry(ctx, "GetUserByEmail", func() error {
+		return db.QueryRowContext(ctx,
+			"SELECT id, email, password_hash, locked_until, created_at, updated_at FROM users WHERE email = $1",
+			email,
+		).Scan(&user.ID, &user.Email, &user.PasswordHash, &lockedUntil, asUTC(&user.CreatedAt), asUTC(&user.UpdatedAt))
+	})
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -218,6 +516,7 @@ func GetUserByEmail(email string) (*User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	user.LockedUntil = utcPtr(lockedUntil)
 
 	return user, nil
 }
@@ -242,28 +541,108 @@ func UpdateUserPassword(userID int64, newPassword string) error {
 	return err
 }
 
+// RecordFailedLogin counts a failed login for the user. Failures older than
+// window start a new count; reaching threshold locks the account for lockFor.
+// Returns the lock expiry when the account is (now) locked.
+func RecordFailedLogin(userID int64, threshold int, window, lockFor time.Duration) (*time.Time, error) {
+	var count int
+	err := db.QueryRow(`
+		UPDATE users SET
+			failed_login_count = CASE
+				WHEN first_failed_login_at IS NULL OR first_failed_login_at < NOW() - make_interval(secs => $2)
+				THEN 1 ELSE failed_login_count + 1 END,
+			first_failed_login_at = CASE
+				WHEN first_failed_login_at IS NULL OR first_failed_login_at < NOW() - make_interval(secs => $2)
+				THEN NOW() ELSE first_failed_login_at END
+		WHERE id = $1
+		RETURNING failed_login_count`,
+		userID, window.Seconds(),
+	).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	if threshold <= 0 || count < threshold {
+		return nil, nil
+	}
+
+	var lockedUntil time.Time
+	err = db.QueryRow(`
+		UPDATE users SET locked_until = NOW() + make_interval(secs => $2),
+			failed_login_count = 0, first_failed_login_at = NULL
+		WHERE id = $1
+		RETURNING locked_until`,
+		userID, lockFor.Seconds(),
+	).Scan(asUTC(&lockedUntil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock account: %w", err)
+	}
+	return &lockedUntil, nil
+}
+
+// ClearFailedLogins resets the user's failed login count and any lockout
+func ClearFailedLogins(userID int64) error {
+	_, err := db.Exec(`
+		UPDATE users SET failed_login_count = 0, first_failed_login_at = NULL, locked_until = NULL
+		WHERE id = $1 AND (failed_login_count > 0 OR locked_until IS NOT NULL)`,
+		userID,
+	)
+	return err
+}
+
 // GetUserConfig retrieves a user's configuration
-func GetUserConfig(userID int64) (*UserConfig, error) {
+func GetUserConfig(ctx context.Context, userID int64) (*UserConfig, error) {
 	cfg := &UserConfig{UserID: userID}
 	var incrementsJSON sql.NullString
-	var gasolinaEmail, gasolinaPassword, accountNumber, checkURL, cronSchedule sql.NullString
-
-	err := db.QueryRow(`
-		SELECT id, gasolina_email, gasolina_password, account_number, check_url,
-		       cron_schedule, dry_run, monthly_increments, created_at, updated_at
-		FROM configs WHERE user_id = $1`, userID,
-	).Scan(&cfg.ID, &gasolinaEmail, &gasolinaPassword, &accountNumber,
-		&checkURL, &cronSchedule, &cfg.DryRun, &incrementsJSON,
-		&cfg.CreatedAt, &cfg.UpdatedAt)
+	var gasolinaEmail, gasolinaPassword, accountNumber, loginURL, checkURL, cronSchedule, successMode sql.NullString
+	var notifyOn, selectorsJSON sql.NullString
+	var notifyEmail, submitButtonText, submitButtonSelector sql.NullString
+	var valueSelector, valueSource, submissionTimezone, landingURLPattern, incrementsURL sql.NullString
+	var submissionHourStart, submissionHourEnd, quietHourStart, quietHourEnd sql.NullInt64
+	var quietTimezone, telegramBotToken, telegramChatID, incrementFallback sql.NullString
+	var skipDryRunRamp, forceSubmit, dryRunAnomalyWarning, quietBypassCritical sql.NullBool
+	var userAgent, timezone, locale sql.NullString
+	var viewportWidth, viewportHeight sql.NullInt64
+	var recheckMissingButton sql.NullBool
+
+	err := withDBRetry(ctx, "GetUserConfig", func() error {
+		return db.QueryRowContext(ctx, `
+			SELECT id, gasolina_email, gasolina_password, account_number, login_url, check_url,
+			       cron_schedule, dry_run, success_mode, monthly_increments,
+			       browser_user_agent, browser_viewport_width, browser_viewport_height,
+			       browser_timezone, browser_locale, recheck_missing_button, notify_email,
+			       skip_dry_run_ramp, ramp_runs_done, submit_button_text, submit_button_selector,
+			       value_selector, value_source, submission_hour_start, submission_hour_end,
+			       submission_timezone, landing_url_pattern, notify_on, force_submit, increments_url,
+			       dry_run_anomaly_warning, selectors, quiet_hour_start, quiet_hour_end, quiet_timezone,
+			       quiet_bypass_critical, telegram_bot_token, telegram_chat_id, increment_fallback,
+			       created_at, updated_at
+			FROM configs WHERE user_id = $1`, userID,
+		).Scan(&cfg.ID, &gasolinaEmail, &gasolinaPassword, &accountNumber,
+			&loginURL, &checkURL, &cronSchedule, &cfg.DryRun, &successMode, &incrementsJSON,
+			&userAgent, &viewportWidth, &viewportHeight, &timezone, &locale, &recheckMissingButton, &notifyEmail,
+			&skipDryRunRamp, &cfg.RampRunsDone, &submitButtonText, &submitButtonSelector,
+			&valueSelector, &valueSource, &submissionHourStart, &submissionHourEnd,
+			&submissionTimezone, &landingURLPattern, &notifyOn, &forceSubmit, &incrementsURL,
+			&dryRunAnomalyWarning, &selectorsJSON, &quietHourStart, &quietHourEnd, &quietTimezone,
+			&quietBypassCritical, &telegramBotToken, &telegramChatID, &incrementFallback,
+			asUTC(&cfg.CreatedAt), asUTC(&cfg.UpdatedAt))
+	})
 
 	if err == sql.ErrNoRows {
 		// Return default config
 		return &UserConfig{
 			UserID:       userID,
-			CheckURL:     "https://gasolina-online.com/indicator",
+			LoginURL:     defaultBaseURL,
+			CheckURL:     defaultCheckURL,
 			CronSchedule: "0 0 1 * *",
 			DryRun:       true,
+			SuccessMode:  SuccessModeText,
 			Configured:   false,
+
+			RecheckMissingButton: true,
+			NotifyOn:             defaultNotifyOn,
+			ValueSelector:        defaultValueSelector,
+			ValueSource:          ValueSourceValue,
 		}, nil
 	}
 	if err != nil {
@@ -274,18 +653,91 @@ func GetUserConfig(userID int64) (*UserConfig, error) {
 	cfg.AccountNumber = accountNumber.String
 
 	// Apply defaults for empty values
+	cfg.LoginURL = loginURL.String
+	if cfg.LoginURL == "" {
+		cfg.LoginURL = defaultBaseURL
+	}
 	cfg.CheckURL = checkURL.String
 	if cfg.CheckURL == "" {
-		cfg.CheckURL = "https://gasolina-online.com/indicator"
+		cfg.CheckURL = defaultCheckURL
 	}
 	cfg.CronSchedule = cronSchedule.String
 	if cfg.CronSchedule == "" {
 		cfg.CronSchedule = "0 0 1 * *"
 	}
+	cfg.SuccessMode = successMode.String
+	if cfg.SuccessMode == "" {
+		cfg.SuccessMode = SuccessModeText
+	}
+	cfg.RecheckMissingButton = !recheckMissingButton.Valid || recheckMissingButton.Bool
+	cfg.NotifyEmail = notifyEmail.String
+	cfg.NotifyOn = defaultNotifyOn
+	if notifyOn.String != "" {
+		cfg.NotifyOn = strings.Split(notifyOn.String, ",")
+	}
+	cfg.SkipDryRunRamp = skipDryRunRamp.Bool
+	cfg.ForceSubmit = forceSubmit.Bool
+	cfg.DryRunAnomalyWarning = dryRunAnomalyWarning.Bool
+	cfg.SubmitButtonText = submitButtonText.String
+	cfg.SubmitButtonSelector = submitButtonSelector.String
+	cfg.ValueSelector = valueSelector.String
+	if cfg.ValueSelector == "" {
+		cfg.ValueSelector = defaultValueSelector
+	}
+	cfg.ValueSource = valueSource.String
+	if cfg.ValueSource == "" {
+		cfg.ValueSource = ValueSourceValue
+	}
+	if submissionHourStart.Valid && submissionHourEnd.Valid {
+		start, end := int(submissionHourStart.Int64), int(submissionHourEnd.Int64)
+		cfg.SubmissionHourStart, cfg.SubmissionHourEnd = &start, &end
+	}
+	cfg.SubmissionTimezone = submissionTimezone.String
+	if quietHourStart.Valid && quietHourEnd.Valid {
+		start, end := int(quietHourStart.Int64), int(quietHourEnd.Int64)
+		cfg.QuietHourStart, cfg.QuietHourEnd = &start, &end
+	}
+	cfg.QuietTimezone = quietTimezone.String
+	cfg.QuietBypassCritical = quietBypassCritical.Bool
+	cfg.TelegramChatID = telegramChatID.String
+	if telegramBotToken.String != "" {
+		decrypted, err := decrypt(telegramBotToken.String)
+		if errors.Is(err, errEncryptionNotInitialized) {
+			return nil, err
+		}
+		if err == nil {
+			cfg.TelegramBotToken = decrypted
+			cfg.TelegramBotTokenSet = true
+		}
+	}
+	cfg.LandingURLPattern = landingURLPattern.String
+	cfg.IncrementsURL = incrementsURL.String
+	if fallback, err := ParseIncrementFallback(incrementFallback.String); err == nil {
+		cfg.IncrementFallback = fallback
+	} else {
+		slog.Warn("Invalid increment fallback, ignoring it", "user_id", userID, "error", err)
+	}
+	if selectorsJSON.String != "" {
+		if err := json.Unmarshal([]byte(selectorsJSON.String), &cfg.Selectors); err != nil {
+			slog.Warn("Malformed selectors, using defaults", "user_id", userID, "error", err)
+			cfg.Selectors = Selectors{}
+		}
+	}
+	cfg.Fingerprint = BrowserFingerprint{
+		UserAgent:      userAgent.String,
+		ViewportWidth:  int(viewportWidth.Int64),
+		ViewportHeight: int(viewportHeight.Int64),
+		Timezone:       timezone.String,
+		Locale:         locale.String,
+	}
 
-	// Decrypt password if present
+	// Decrypt password if present. Without the key every password would look
+	// unset, so that is an error rather than an unconfigured user.
 	if gasolinaPassword.Valid && gasolinaPassword.String != "" {
 		decrypted, err := decrypt(gasolinaPassword.String)
+		if errors.Is(err, errEncryptionNotInitialized) {
+			return nil, err
+		}
 		if err == nil {
 			cfg.GasolinaPassword = decrypted
 		}
@@ -293,22 +745,33 @@ func GetUserConfig(userID int64) (*UserConfig, error) {
 
 	// Parse increments JSON
 	if incrementsJSON.Valid && incrementsJSON.String != "" {
-		if err := json.Unmarshal([]byte(incrementsJSON.String), &cfg.MonthlyIncrements); err != nil {
-			cfg.MonthlyIncrements = make(map[int]int)
+		increments, serialIncrements, err := parseMonthlyIncrements([]byte(incrementsJSON.String))
+		if err != nil {
+			// Keep the valid months and surface the problem instead of dropping everything
+			slog.Warn("Malformed monthly increments", "user_id", userID, "error", err)
+			cfg.IncrementsWarning = err.Error()
 		}
+		if increments == nil {
+			increments = make(map[int]float64)
+		}
+		cfg.MonthlyIncrements = increments
+		cfg.SerialIncrements = serialIncrements
 	}
 
 	cfg.Configured = cfg.GasolinaEmail != "" && cfg.GasolinaPassword != ""
 	return cfg, nil
 }
 
-// SaveUserConfig saves or updates a user's configuration
-func SaveUserConfig(userID int64, email, password, accountNumber, checkURL, cronSchedule string, dryRun bool, increments map[int]int) error {
+// SaveUserConfig saves or updates a user's configuration.
+// Empty string fields keep their existing values, except notify_email and the
+// Telegram settings, which are always replaced so they can be cleared.
+// GasolinaPassword and TelegramBotToken are plaintext and get encrypted.
+func SaveUserConfig(cfg *UserConfig) error {
 	// Encrypt password if provided
 	var encryptedPassword string
-	if password != "" {
+	if cfg.GasolinaPassword != "" {
 		var err error
-		encryptedPassword, err = encrypt(password)
+		encryptedPassword, err = encrypt(cfg.GasolinaPassword)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt password: %w", err)
 		}
@@ -316,58 +779,211 @@ func SaveUserConfig(userID int64, email, password, accountNumber, checkURL, cron
 
 	// Serialize increments
 	var incrementsJSON []byte
-	if increments != nil {
+	if cfg.MonthlyIncrements != nil || cfg.SerialIncrements != nil {
 		var err error
-		incrementsJSON, err = json.Marshal(increments)
+		incrementsJSON, err = marshalIncrements(cfg.MonthlyIncrements, cfg.SerialIncrements)
 		if err != nil {
 			return fmt.Errorf("failed to serialize increments: %w", err)
 		}
 	}
 
+	var encryptedTelegramToken string
+	if cfg.TelegramBotToken != "" {
+		var err error
+		encryptedTelegramToken, err = encrypt(cfg.TelegramBotToken)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt telegram bot token: %w", err)
+		}
+	}
+
+	// Serialize selector overrides; none keeps the stored ones
+	var selectorsJSON []byte
+	if !cfg.Selectors.IsZero() {
+		var err error
+		if selectorsJSON, err = json.Marshal(cfg.Selectors); err != nil {
+			return fmt.Errorf("failed to serialize selectors: %w", err)
+		}
+	}
+
 	// Upsert config
 	_, err := db.Exec(`
 		INSERT INTO configs (user_id, gasolina_email, gasolina_password, account_number,
-		                     check_url, cron_schedule, dry_run, monthly_increments)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		                     login_url, check_url, cron_schedule, dry_run, success_mode, monthly_increments,
+		                     browser_user_agent, browser_viewport_width, browser_viewport_height,
+		                     browser_timezone, browser_locale, recheck_missing_button, notify_email,
+		                     skip_dry_run_ramp, submit_button_text, submit_button_selector,
+		                     value_selector, value_source, submission_hour_start, submission_hour_end,
+		                     submission_timezone, landing_url_pattern, notify_on, force_submit, increments_url,
+		                     dry_run_anomaly_warning, selectors, quiet_hour_start, quiet_hour_end, quiet_timezone,
+		                     quiet_bypass_critical, telegram_bot_token, telegram_chat_id, increment_fallback)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+		        $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38)
 		ON CONFLICT(user_id) DO UPDATE SET
 			gasolina_email = COALESCE(NULLIF(excluded.gasolina_email, ''), configs.gasolina_email),
 			gasolina_password = COALESCE(NULLIF(excluded.gasolina_password, ''), configs.gasolina_password),
 			account_number = COALESCE(NULLIF(excluded.account_number, ''), configs.account_number),
+			login_url = COALESCE(NULLIF(excluded.login_url, ''), configs.login_url),
 			check_url = COALESCE(NULLIF(excluded.check_url, ''), configs.check_url),
 			cron_schedule = COALESCE(NULLIF(excluded.cron_schedule, ''), configs.cron_schedule),
 			dry_run = excluded.dry_run,
+			success_mode = COALESCE(NULLIF(excluded.success_mode, ''), configs.success_mode),
 			monthly_increments = COALESCE(NULLIF(excluded.monthly_increments, ''), configs.monthly_increments),
+			browser_user_agent = excluded.browser_user_agent,
+			browser_viewport_width = excluded.browser_viewport_width,
+			browser_viewport_height = excluded.browser_viewport_height,
+			browser_timezone = excluded.browser_timezone,
+			browser_locale = excluded.browser_locale,
+			recheck_missing_button = excluded.recheck_missing_button,
+			notify_email = excluded.notify_email,
+			skip_dry_run_ramp = excluded.skip_dry_run_ramp,
+			submit_button_text = COALESCE(NULLIF(excluded.submit_button_text, ''), configs.submit_button_text),
+			submit_button_selector = COALESCE(NULLIF(excluded.submit_button_selector, ''), configs.submit_button_selector),
+			value_selector = COALESCE(NULLIF(excluded.value_selector, ''), configs.value_selector),
+			value_source = COALESCE(NULLIF(excluded.value_source, ''), configs.value_source),
+			submission_hour_start = COALESCE(excluded.submission_hour_start, configs.submission_hour_start),
+			submission_hour_end = COALESCE(excluded.submission_hour_end, configs.submission_hour_end),
+			submission_timezone = COALESCE(NULLIF(excluded.submission_timezone, ''), configs.submission_timezone),
+			landing_url_pattern = COALESCE(NULLIF(excluded.landing_url_pattern, ''), configs.landing_url_pattern),
+			notify_on = COALESCE(NULLIF(excluded.notify_on, ''), configs.notify_on),
+			force_submit = excluded.force_submit,
+			increments_url = COALESCE(NULLIF(excluded.increments_url, ''), configs.increments_url),
+			dry_run_anomaly_warning = excluded.dry_run_anomaly_warning,
+			selectors = COALESCE(NULLIF(excluded.selectors, ''), configs.selectors),
+			quiet_hour_start = COALESCE(excluded.quiet_hour_start, configs.quiet_hour_start),
+			quiet_hour_end = COALESCE(excluded.quiet_hour_end, configs.quiet_hour_end),
+			quiet_timezone = COALESCE(NULLIF(excluded.quiet_timezone, ''), configs.quiet_timezone),
+			quiet_bypass_critical = excluded.quiet_bypass_critical,
+			telegram_bot_token = excluded.telegram_bot_token,
+			telegram_chat_id = excluded.telegram_chat_id,
+			increment_fallback = excluded.increment_fallback,
 			updated_at = NOW()`,
-		userID, email, encryptedPassword, accountNumber, checkURL, cronSchedule, dryRun, string(incrementsJSON),
+		cfg.UserID, cfg.GasolinaEmail, encryptedPassword, cfg.AccountNumber, cfg.LoginURL, cfg.CheckURL,
+		cfg.CronSchedule, cfg.DryRun, cfg.SuccessMode, string(incrementsJSON),
+		cfg.Fingerprint.UserAgent, cfg.Fingerprint.ViewportWidth, cfg.Fingerprint.ViewportHeight,
+		cfg.Fingerprint.Timezone, cfg.Fingerprint.Locale, cfg.RecheckMissingButton, cfg.NotifyEmail,
+		cfg.SkipDryRunRamp, cfg.SubmitButtonText, cfg.SubmitButtonSelector,
+		cfg.ValueSelector, cfg.ValueSource, cfg.SubmissionHourStart, cfg.SubmissionHourEnd,
+		cfg.SubmissionTimezone, cfg.LandingURLPattern, strings.Join(cfg.NotifyOn, ","), cfg.ForceSubmit,
+		cfg.IncrementsURL, cfg.DryRunAnomalyWarning, string(selectorsJSON),
+		cfg.QuietHourStart, cfg.QuietHourEnd, cfg.QuietTimezone, cfg.QuietBypassCritical,
+		encryptedTelegramToken, cfg.TelegramChatID, cfg.IncrementFallback.String(),
+	)
+
+	return err
+}
+
+// IncrementRampRuns records one more completed dry-run ramp run for a user
+func IncrementRampRuns(userID int64) error {
+	_, err := db.Exec(
+		"UPDATE configs SET ramp_runs_done = ramp_runs_done + 1 WHERE user_id = $1",
+		userID,
 	)
+	return err
+}
 
+// UpdateGasolinaPassword encrypts and stores a new Gasolina password for a user
+func UpdateGasolinaPassword(userID int64, password string) error {
+	encryptedPassword, err := encrypt(password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO configs (user_id, gasolina_password)
+		VALUES ($1, $2)
+		ON CONFLICT(user_id) DO UPDATE SET
+			gasolina_password = excluded.gasolina_password,
+			updated_at = NOW()`,
+		userID, encryptedPassword,
+	)
 	return err
 }
 
+// Job sources: what created a job
+const (
+	JobSourceAPI      = "api"
+	JobSourceSchedule = "schedule"
+	JobSourceAdmin    = "admin"
+	JobSourceRetry    = "retry"
+	JobSourceCLI      = "cli"
+)
+
+// isValidJobSource reports whether source is one of the job sources
+func isValidJobSource(source string) bool {
+	switch source {
+	case JobSourceAPI, JobSourceSchedule, JobSourceAdmin, JobSourceRetry, JobSourceCLI:
+		return true
+	}
+	return false
+}
+
 // CreateJob creates a new job record
-func CreateJob(id string, userID int64, jobType string) (*Job, error) {
+func CreateJob(id string, userID int64, jobType, source string) (*Job, error) {
 	_, err := db.Exec(
-		"INSERT INTO jobs (id, user_id, type, status) VALUES ($1, $2, $3, $4)",
-		id, userID, jobType, "pending",
+		"INSERT INTO jobs (id, user_id, type, status, source) VALUES ($1, $2, $3, $4, $5)",
+		id, userID, jobType, "pending", source,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	return GetJob(context.Background(), id)
+}
+
+// errJobQueueFull is returned when a user already has the maximum number of
+// pending and running jobs
+var errJobQueueFull = errors.New("job queue full")
+
+// CreateJobWithinLimit creates a pending job unless the user already has
+// maxActive pending or running jobs, in which case it returns errJobQueueFull.
+// A per-user advisory lock serializes concurrent creations, across server
+// instances too, so they can't all pass the count.
+func CreateJobWithinLimit(id string, userID int64, jobType, source string, maxActive int) (*Job, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended('job_queue:' || $1::text, 0))", userID); err != nil {
+		return nil, fmt.Errorf("failed to lock job queue: %w", err)
+	}
+	res, err := tx.Exec(`
+		INSERT INTO jobs (id, user_id, type, status, source)
+		SELECT $1, $2, $3, 'pending', $4
+		WHERE (SELECT COUNT(*) FROM jobs WHERE user_id = $2 AND status IN ('pending', 'running')) < $5`,
+		id, userID, jobType, source, maxActive,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create job: %w", err)
 	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, errJobQueueFull
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
 
-	return GetJob(id)
+	return GetJob(context.Background(), id)
 }
 
 // GetJob retrieves a job by ID
-func GetJob(id string) (*Job, error) {
+func GetJob(ctx context.Context, id string) (*Job, error) {
 	job := &Job{}
-	var errorStr, logsJSON sql.NullString
+	var errorStr, errorCode, logsJSON, resultJSON, outcome, note sql.NullString
 	var startedAt, completedAt sql.NullTime
+	var submittedValue, previousValue, incrementUsed sql.NullFloat64
 
-	err := db.QueryRow(`
-		SELECT id, user_id, type, status, error, logs, created_at, started_at, completed_at
-		FROM jobs WHERE id = $1`, id,
-	).Scan(&job.ID, &job.UserID, &job.Type, &job.Status, &errorStr, &logsJSON,
-		&job.CreatedAt, &startedAt, &completedAt)
+	err := withDBRetry(ctx, "GetJob", func() error {
+		return db.QueryRowContext(ctx, `
+			SELECT id, user_id, type, status, progress, error, error_code, logs, result, outcome, note, source,
+			       submitted_value, previous_value, increment_used, created_at, started_at, completed_at
+			FROM jobs WHERE id = $1`, id,
+		).Scan(&job.ID, &job.UserID, &job.Type, &job.Status, &job.Progress, &errorStr, &errorCode, &logsJSON, &resultJSON, &outcome, &note, &job.Source,
+			&submittedValue, &previousValue, &incrementUsed, asUTC(&job.CreatedAt), &startedAt, &completedAt)
+	})
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -380,46 +996,82 @@ func GetJob(id string) (*Job, error) {
 		job.Error = &errorStr.String
 	}
 	if logsJSON.Valid {
-		json.Unmarshal([]byte(logsJSON.String), &job.Logs)
+		job.Logs = decodeJobLogs(job.ID, logsJSON.String)
+	}
+	job.Outcome = outcome.String
+	job.ErrorCode = errorCode.String
+	job.Note = note.String
+	job.setReadingValues(submittedValue, previousValue, incrementUsed)
+	if resultJSON.Valid && resultJSON.String != "" {
+		var result CheckResult
+		if err := json.Unmarshal([]byte(resultJSON.String), &result); err != nil {
+			slog.Warn("Unreadable job result", "job_id", job.ID, "error", err)
+		} else {
+			job.Result = &result
+		}
 	}
 	if startedAt.Valid {
-		job.StartedAt = &startedAt.Time
+		job.StartedAt = utcPtr(startedAt)
 	}
 	if completedAt.Valid {
-		job.CompletedAt = &completedAt.Time
+		job.CompletedAt = utcPtr(completedAt)
 	}
 
 	return job, nil
 }
 
-// GetUserJobs retrieves jobs for a user
-func GetUserJobs(userID int64, limit int, status string) ([]*Job, int, error) {
-	// Count total
-	var total int
-	var countQuery string
-	var args []interface{}
+// JobFilter narrows a job listing; empty fields match everything
+type JobFilter struct {
+	Status string
+	Source string
+	Type   string
+	From   *time.Time // created at or after
+	To     *time.Time // created before
+}
 
-	if status != "" {
-		countQuery = "SELECT COUNT(*) FROM jobs WHERE user_id = $1 AND status = $2"
-		args = []interface{}{userID, status}
-	} else {
-		countQuery = "SELECT COUNT(*) FROM jobs WHERE user_id = $1"
-		args = []interface{}{userID}
+// where returns the SQL condition for the filter on userID's jobs and its arguments
+func (f JobFilter) where(userID int64) (string, []interface{}) {
+	conds := []string{"user_id = $1"}
+	args := []interface{}{userID}
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf("%s = $%d", column, len(args)))
 	}
+	if f.Status != "" {
+		add("status", f.Status)
+	}
+	if f.Source != "" {
+		add("source", f.Source)
+	}
+	if f.Type != "" {
+		add("type", f.Type)
+	}
+	if f.From != nil {
+		args = append(args, *f.From)
+		conds = append(conds, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if f.To != nil {
+		args = append(args, *f.To)
+		conds = append(conds, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// GetUserJobs retrieves jobs for a user
+func GetUserJobs(userID int64, limit, offset int, filter JobFilter) ([]*Job, int, error) {
+	where, args := filter.where(userID)
 
-	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+	// Count total
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM jobs WHERE "+where, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	// Query jobs
-	var query string
-	if status != "" {
-		query = "SELECT id, user_id, type, status, error, logs, created_at, started_at, completed_at FROM jobs WHERE user_id = $1 AND status = $2 ORDER BY created_at DESC LIMIT $3"
-		args = []interface{}{userID, status, limit}
-	} else {
-		query = "SELECT id, user_id, type, status, error, logs, created_at, started_at, completed_at FROM jobs WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2"
-		args = []interface{}{userID, limit}
-	}
+	// Query jobs; the id tiebreak keeps pages stable when jobs share a timestamp
+	query := fmt.Sprintf(`SELECT id, user_id, type, status, progress, error, error_code, outcome, logs, source,
+		       submitted_value, previous_value, increment_used, created_at, started_at, completed_at
+		FROM jobs WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
@@ -427,25 +1079,29 @@ func GetUserJobs(userID int64, limit int, status string) ([]*Job, int, error) {
 	}
 	defer rows.Close()
 
-	var jobs []*Job
+	jobs := []*Job{}
 	for rows.Next() {
 		job := &Job{}
-		var errorStr, logsJSON sql.NullString
+		var errorStr, errorCode, outcome, logsJSON sql.NullString
 		var startedAt, completedAt sql.NullTime
+		var submittedValue, previousValue, incrementUsed sql.NullFloat64
 
-		if err := rows.Scan(&job.ID, &job.UserID, &job.Type, &job.Status, &errorStr, &logsJSON,
-			&job.CreatedAt, &startedAt, &completedAt); err != nil {
+		if err := rows.Scan(&job.ID, &job.UserID, &job.Type, &job.Status, &job.Progress, &errorStr, &errorCode, &outcome, &logsJSON, &job.Source,
+			&submittedValue, &previousValue, &incrementUsed, asUTC(&job.CreatedAt), &startedAt, &completedAt); err != nil {
 			return nil, 0, err
 		}
+		job.setReadingValues(submittedValue, previousValue, incrementUsed)
+		job.ErrorCode = errorCode.String
+		job.Outcome = outcome.String
 
 		if errorStr.Valid {
 			job.Error = &errorStr.String
 		}
 		if startedAt.Valid {
-			job.StartedAt = &startedAt.Time
+			job.StartedAt = utcPtr(startedAt)
 		}
 		if completedAt.Valid {
-			job.CompletedAt = &completedAt.Time
+			job.CompletedAt = utcPtr(completedAt)
 		}
 
 		jobs = append(jobs, job)
@@ -454,120 +1110,1024 @@ func GetUserJobs(userID int64, limit int, status string) ([]*Job, int, error) {
 	return jobs, total, nil
 }
 
+// utcTime scans a timestamp and normalizes it to UTC, so API responses don't
+// depend on the database session or server timezone
+type utcTime struct {
+	t *time.Time
+}
+
+func (u utcTime) Scan(src interface{}) error {
+	var nt sql.NullTime
+	if err := nt.Scan(src); err != nil {
+		return err
+	}
+	*u.t = nt.Time.UTC()
+	return nil
+}
+
+// asUTC wraps a scan destination so the timestamp is stored in UTC
+func asUTC(t *time.Time) sql.Scanner {
+	return utcTime{t}
+}
+
+// utcPtr converts a nullable timestamp to a UTC pointer
+func utcPtr(nt sql.NullTime) *time.Time {
+	if !nt.Valid {
+		return nil
+	}
+	t := nt.Time.UTC()
+	return &t
+}
+
+// decodeJobLogs decodes a stored log blob. The current format is a JSON array of
+// strings; older rows hold a JSON array of {time, message} objects or a single
+// JSON string of newline-separated lines. Anything else is returned verbatim as
+// one entry so the diagnostic information is not lost.
+func decodeJobLogs(jobID, raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	var logs []string
+	if err := json.Unmarshal([]byte(raw), &logs); err == nil {
+		return logs
+	}
+
+	var entries []struct {
+		Time    string `json:"time"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(raw), &entries); err == nil {
+		logs = make([]string, 0, len(entries))
+		for _, e := range entries {
+			logs = append(logs, strings.TrimSpace(e.Time+" "+e.Message))
+		}
+		return logs
+	}
+
+	var text string
+	if err := json.Unmarshal([]byte(raw), &text); err == nil {
+		return strings.Split(strings.TrimRight(text, "\n"), "\n")
+	}
+
+	slog.Warn("Job logs in an unknown format; returning them raw", "job_id", jobID)
+	return []string{"[warning: logs could not be parsed, raw content follows] " + raw}
+}
+
 // UpdateJobStatus updates a job's status
 func UpdateJobStatus(id, status string, errorMsg *string) error {
+	return withDBRetry(context.Background(), "UpdateJobStatus", func() error {
+		return updateJobStatus(id, status, errorMsg)
+	})
+}
+
+func updateJobStatus(id, status string, errorMsg *string) error {
 	var err error
 	if status == "running" {
 		_, err = db.Exec(
 			"UPDATE jobs SET status = $1, started_at = NOW() WHERE id = $2",
 			status, id,
 		)
-	} else if status == "completed" || status == "failed" {
+	} else if status == "completed" {
+		_, err = db.Exec(
+			"UPDATE jobs SET status = $1, error = $2, progress = 100, outcome = 'success', completed_at = NOW() WHERE id = $3",
+			status, errorMsg, id,
+		)
+	} else if status == "failed" {
 		_, err = db.Exec(
-			"UPDATE jobs SET status = $1, error = $2, completed_at = NOW() WHERE id = $3",
+			"UPDATE jobs SET status = $1, error = $2, outcome = 'failure', completed_at = NOW() WHERE id = $3",
 			status, errorMsg, id,
 		)
+	} else if status == "cancelled" {
+		_, err = db.Exec(
+			"UPDATE jobs SET status = $1, completed_at = NOW() WHERE id = $2",
+			status, id,
+		)
 	} else {
 		_, err = db.Exec("UPDATE jobs SET status = $1 WHERE id = $2", status, id)
 	}
 	return err
 }
 
-// AppendJobLogs appends logs to a job
-func AppendJobLogs(id string, logs []string) error {
-	logsJSON, _ := json.Marshal(logs)
-	_, err := db.Exec("UPDATE jobs SET logs = $1 WHERE id = $2", string(logsJSON), id)
+// UpdateJobResult stores a job's check result
+func UpdateJobResult(id string, result *CheckResult) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+	var submitted *float64
+	if result.Submitted {
+		submitted = &result.NewValue
+	}
+	return withDBRetry(context.Background(), "UpdateJobResult", func() error {
+		_, err := db.Exec(`
+			UPDATE jobs SET result = $1, submitted_value = $2, previous_value = $3, increment_used = $4
+			WHERE id = $5`,
+			string(resultJSON), submitted, result.PreviousValue, result.Increment, id)
+		return err
+	})
+}
+
+// applyResult attaches a check result to the job along with the reading
+// values UpdateJobResult stores for it
+func (j *Job) applyResult(result *CheckResult) {
+	j.Result = result
+	j.SubmittedValue = nil
+	if result.Submitted {
+		v := result.NewValue
+		j.SubmittedValue = &v
+	}
+	prev, inc := result.PreviousValue, result.Increment
+	j.PreviousValue, j.IncrementUsed = &prev, &inc
+}
+
+// setReadingValues fills the job's reading value fields from nullable columns
+func (j *Job) setReadingValues(submitted, previous, increment sql.NullFloat64) {
+	for _, v := range []struct {
+		src sql.NullFloat64
+		dst **float64
+	}{
+		{submitted, &j.SubmittedValue},
+		{previous, &j.PreviousValue},
+		{increment, &j.IncrementUsed},
+	} {
+		if v.src.Valid {
+			f := v.src.Float64
+			*v.dst = &f
+		}
+	}
+}
+
+// ErrJobRunning is returned when deleting a job that is still running
+var ErrJobRunning = errors.New("job is running")
+
+// DeleteJob deletes a job unless it is running. Screenshot records go with it
+// via the foreign key; submissions keep their history with the job unset.
+func DeleteJob(id string) error {
+	res, err := db.Exec("DELETE FROM jobs WHERE id = $1 AND status <> 'running'", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrJobRunning
+	}
+	return nil
+}
+
+// SetJobOutcome stores a finished job's detailed outcome
+func SetJobOutcome(id, outcome string) error {
+	return withDBRetry(context.Background(), "SetJobOutcome", func() error {
+		_, err := db.Exec("UPDATE jobs SET outcome = $1 WHERE id = $2", outcome, id)
+		return err
+	})
+}
+
+// SetJobErrorCode stores the failure category of a failed job
+func SetJobErrorCode(id, code string) error {
+	return withDBRetry(context.Background(), "SetJobErrorCode", func() error {
+		_, err := db.Exec("UPDATE jobs SET error_code = $1 WHERE id = $2", code, id)
+		return err
+	})
+}
+
+// SetJobNote stores the user's note on a job; an empty note clears it
+func SetJobNote(id, note string) error {
+	_, err := db.Exec("UPDATE jobs SET note = NULLIF($1, '') WHERE id = $2", note, id)
 	return err
 }
 
-// CreateScreenshot creates a screenshot record
-func CreateScreenshot(jobID string, userID int64, filename string) error {
-	_, err := db.Exec(
-		"INSERT INTO screenshots (job_id, user_id, filename) VALUES ($1, $2, $3)",
-		jobID, userID, filename,
+// ClaimJob moves a pending job to running. Returns false if the job is no
+// longer pending (e.g. it was cancelled while queued).
+func ClaimJob(id string) (bool, error) {
+	res, err := db.Exec(
+		"UPDATE jobs SET status = 'running', started_at = NOW() WHERE id = $1 AND status = 'pending'",
+		id,
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
 }
 
-// GetJobScreenshots retrieves screenshots for a job
-func GetJobScreenshots(jobID string) ([]*Screenshot, error) {
+// FailInterruptedJobs marks jobs left running by a previous process as failed
+// and returns their IDs. Rerunning them could repeat a submit that went through.
+func FailInterruptedJobs(errorMsg string) ([]string, error) {
 	rows, err := db.Query(
-		"SELECT id, job_id, user_id, filename, created_at FROM screenshots WHERE job_id = $1 ORDER BY created_at",
-		jobID,
+		`UPDATE jobs SET status = 'failed', error = $1, error_code = $2, outcome = 'failure', completed_at = NOW()
+		WHERE status = 'running' RETURNING id`,
+		errorMsg, ErrorCodeInterrupted,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var screenshots []*Screenshot
+	var ids []string
 	for rows.Next() {
-		s := &Screenshot{}
-		if err := rows.Scan(&s.ID, &s.JobID, &s.UserID, &s.Filename, &s.CreatedAt); err != nil {
+		var id string
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		screenshots = append(screenshots, s)
+		ids = append(ids, id)
 	}
-
-	return screenshots, nil
+	return ids, rows.Err()
 }
 
-// SaveRefreshToken saves a hashed refresh token
-func SaveRefreshToken(userID int64, tokenHash string, expiresAt time.Time) error {
-	_, err := db.Exec(
-		"INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
-		userID, tokenHash, expiresAt,
+// ListPendingJobs returns all pending jobs, oldest first
+func ListPendingJobs() ([]*Job, error) {
+	rows, err := db.Query(
+		"SELECT id, user_id, type, status, created_at FROM jobs WHERE status = 'pending' ORDER BY created_at",
 	)
-	return err
-}
-
-// GetRefreshToken retrieves a refresh token by hash
-func GetRefreshToken(tokenHash string) (int64, time.Time, error) {
-	var userID int64
-	var expiresAt time.Time
-
-	err := db.QueryRow(
-		"SELECT user_id, expires_at FROM refresh_tokens WHERE token_hash = $1",
-		tokenHash,
-	).Scan(&userID, &expiresAt)
-
-	if err == sql.ErrNoRows {
-		return 0, time.Time{}, errors.New("token not found")
-	}
 	if err != nil {
-		return 0, time.Time{}, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return userID, expiresAt, nil
-}
-
-// DeleteRefreshToken deletes a refresh token
-func DeleteRefreshToken(tokenHash string) error {
-	_, err := db.Exec("DELETE FROM refresh_tokens WHERE token_hash = $1", tokenHash)
+	var jobs []*Job
+	for rows.Next() {
+		job := &Job{}
+		if err := rows.Scan(&job.ID, &job.UserID, &job.Type, &job.Status, asUTC(&job.CreatedAt)); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetUserJobQueue returns the user's running job and pending jobs in queue order
+func GetUserJobQueue(userID int64) (*JobQueueStatus, error) {
+	rows, err := db.Query(
+		"SELECT id, status FROM jobs WHERE user_id = $1 AND status IN ('pending', 'running') ORDER BY created_at, id",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := &JobQueueStatus{PendingJobIDs: []string{}, MaxQueued: maxQueuedJobs}
+	for rows.Next() {
+		var id, status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		if status == "running" {
+			queue.RunningJobID = &id
+		} else {
+			queue.PendingJobIDs = append(queue.PendingJobIDs, id)
+		}
+	}
+	queue.PendingCount = len(queue.PendingJobIDs)
+	return queue, rows.Err()
+}
+
+// CancelPendingJob marks a single job cancelled if it is still pending
+func CancelPendingJob(id string) (bool, error) {
+	res, err := db.Exec(
+		"UPDATE jobs SET status = 'cancelled', completed_at = NOW() WHERE id = $1 AND status = 'pending'",
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// CancelPendingJobs marks all of a user's pending jobs as cancelled and returns their IDs
+func CancelPendingJobs(userID int64) ([]string, error) {
+	rows, err := db.Query(
+		"UPDATE jobs SET status = 'cancelled', completed_at = NOW() WHERE user_id = $1 AND status = 'pending' RETURNING id",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpdateJobProgress raises a job's progress; it never moves backwards
+func UpdateJobProgress(id string, progress int) error {
+	return withDBRetry(context.Background(), "UpdateJobProgress", func() error {
+		_, err := db.Exec("UPDATE jobs SET progress = GREATEST(progress, $1) WHERE id = $2", progress, id)
+		return err
+	})
+}
+
+// GetFeatureFlagOverrides returns the feature flag values stored in the database
+func GetFeatureFlagOverrides() (map[FeatureFlag]bool, error) {
+	rows, err := db.Query("SELECT name, enabled FROM feature_flags")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[FeatureFlag]bool)
+	for rows.Next() {
+		var name string
+		var enabled bool
+		if err := rows.Scan(&name, &enabled); err != nil {
+			return nil, err
+		}
+		overrides[FeatureFlag(name)] = enabled
+	}
+	return overrides, rows.Err()
+}
+
+// AppendJobLogs appends logs to a job
+func AppendJobLogs(id string, logs []string) error {
+	logsJSON, _ := json.Marshal(logs)
+	return withDBRetry(context.Background(), "AppendJobLogs", func() error {
+		_, err := db.Exec("UPDATE jobs SET logs = $1 WHERE id = $2", string(logsJSON), id)
+		return err
+	})
+}
+
+// CreateScreenshot creates a screenshot record; evidence screenshots are flagged by name
+func CreateScreenshot(jobID string, userID int64, filename string) error {
+	_, err := db.Exec(
+		"INSERT INTO screenshots (job_id, user_id, filename, is_evidence) VALUES ($1, $2, $3, $4)",
+		jobID, userID, filename, isEvidenceScreenshot(filename),
+	)
+	return err
+}
+
+// DeleteExpiredScreenshots deletes screenshot records older than the retention
+// period for their job's outcome and returns them so the files can be removed.
+// A retention of 0 days keeps that outcome's screenshots forever; evidence
+// screenshots are always kept.
+func DeleteExpiredScreenshots(successDays, failureDays int) ([]*Screenshot, error) {
+	rows, err := db.Query(`
+		DELETE FROM screenshots s
+		USING jobs j
+		WHERE s.job_id = j.id AND NOT s.is_evidence AND (
+			($1 > 0 AND j.outcome <> 'failure' AND s.created_at < NOW() - make_interval(days => $1)) OR
+			($2 > 0 AND j.outcome = 'failure' AND s.created_at < NOW() - make_interval(days => $2))
+		)
+		RETURNING s.id, s.job_id, s.user_id, s.filename, s.created_at`,
+		successDays, failureDays,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var screenshots []*Screenshot
+	for rows.Next() {
+		s := &Screenshot{}
+		if err := rows.Scan(&s.ID, &s.JobID, &s.UserID, &s.Filename, asUTC(&s.CreatedAt)); err != nil {
+			return nil, err
+		}
+		screenshots = append(screenshots, s)
+	}
+	return screenshots, rows.Err()
+}
+
+// DeleteJobsOlderThan deletes finished jobs created more than age ago, with
+// their screenshot records, and returns the deleted jobs' IDs and owners.
+// Pending and running jobs are kept whatever their age, and so are jobs with
+// evidence screenshots, which outlive every retention period.
+func DeleteJobsOlderThan(age time.Duration) ([]*Job, error) {
+	rows, err := db.Query(`
+		DELETE FROM jobs
+		WHERE status NOT IN ('pending', 'running') AND created_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM screenshots s WHERE s.job_id = jobs.id AND s.is_evidence)
+		RETURNING id, user_id`,
+		timeNow().Add(-age),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		job := &Job{}
+		if err := rows.Scan(&job.ID, &job.UserID); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetJobScreenshots retrieves screenshots for a job
+func GetJobScreenshots(jobID string) ([]*Screenshot, error) {
+	rows, err := db.Query(
+		"SELECT id, job_id, user_id, filename, is_evidence, created_at FROM screenshots WHERE job_id = $1 ORDER BY created_at",
+		jobID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var screenshots []*Screenshot
+	for rows.Next() {
+		s := &Screenshot{}
+		if err := rows.Scan(&s.ID, &s.JobID, &s.UserID, &s.Filename, &s.IsEvidence, asUTC(&s.CreatedAt)); err != nil {
+			return nil, err
+		}
+		screenshots = append(screenshots, s)
+	}
+
+	return screenshots, nil
+}
+
+// RecordSubmissionAttempt stores a submission as attempted before the submit click
+func RecordSubmissionAttempt(s *Submission) error {
+	_, err := db.Exec(`
+		INSERT INTO submissions (id, job_id, user_id, counter_serial, year, month,
+		                         previous_value, submitted_value, increment, status)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT(id) DO UPDATE SET
+			previous_value = excluded.previous_value,
+			submitted_value = excluded.submitted_value,
+			increment = excluded.increment,
+			status = excluded.status`,
+		s.ID, s.JobID, s.UserID, s.CounterSerial, s.Year, s.Month,
+		s.PreviousValue, s.SubmittedValue, s.Increment, SubmissionAttempted,
+	)
 	return err
 }
 
+// GetPendingSubmission returns the latest attempted-but-unconfirmed submission for a period and counter
+func GetPendingSubmission(userID int64, counterSerial string, year, month int) (*Submission, error) {
+	s := &Submission{}
+	var jobID sql.NullString
+	err := db.QueryRow(`
+		SELECT id, job_id, user_id, counter_serial, year, month, previous_value,
+		       submitted_value, increment, status, created_at
+		FROM submissions
+		WHERE user_id = $1 AND counter_serial = $2 AND year = $3 AND month = $4 AND status = $5
+		ORDER BY created_at DESC LIMIT 1`,
+		userID, counterSerial, year, month, SubmissionAttempted,
+	).Scan(&s.ID, &jobID, &s.UserID, &s.CounterSerial, &s.Year, &s.Month, &s.PreviousValue,
+		&s.SubmittedValue, &s.Increment, &s.Status, asUTC(&s.CreatedAt))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending submission: %w", err)
+	}
+
+	s.JobID = jobID.String
+	return s, nil
+}
+
+// SetSubmissionStatus marks a submission as confirmed or abandoned
+func SetSubmissionStatus(id, status string) error {
+	var err error
+	if status == SubmissionConfirmed {
+		_, err = db.Exec("UPDATE submissions SET status = $1, confirmed_at = NOW() WHERE id = $2", status, id)
+	} else {
+		_, err = db.Exec("UPDATE submissions SET status = $1 WHERE id = $2", status, id)
+	}
+	return err
+}
+
+// ConfirmPendingSubmissions confirms all attempted submissions for a period
+func ConfirmPendingSubmissions(userID int64, year, month int) error {
+	_, err := db.Exec(
+		"UPDATE submissions SET status = $1, confirmed_at = NOW() WHERE user_id = $2 AND year = $3 AND month = $4 AND status = $5",
+		SubmissionConfirmed, userID, year, month, SubmissionAttempted,
+	)
+	return err
+}
+
+// SubmissionFilter narrows submission queries; zero values match everything
+type SubmissionFilter struct {
+	Year          int
+	Month         int
+	CounterSerial string
+	Status        string
+}
+
+// where builds the WHERE clause and arguments for a user's filtered submissions
+func (f SubmissionFilter) where(userID int64) (string, []interface{}) {
+	clauses := []string{"user_id = $1"}
+	args := []interface{}{userID}
+
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		clauses = append(clauses, fmt.Sprintf(clause, len(args)))
+	}
+	if f.Year != 0 {
+		add("year = $%d", f.Year)
+	}
+	if f.Month != 0 {
+		add("month = $%d", f.Month)
+	}
+	if f.CounterSerial != "" {
+		add("counter_serial = $%d", f.CounterSerial)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+
+	return "WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// ListSubmissions returns a page of a user's submissions, newest period first, and the total count
+func ListSubmissions(userID int64, filter SubmissionFilter, limit, offset int) ([]*Submission, int, error) {
+	where, args := filter.where(userID)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM submissions "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, job_id, user_id, counter_serial, year, month, previous_value,
+		       submitted_value, increment, status, created_at, confirmed_at
+		FROM submissions %s
+		ORDER BY year DESC, month DESC, created_at DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var submissions []*Submission
+	for rows.Next() {
+		s := &Submission{}
+		var jobID sql.NullString
+		var confirmedAt sql.NullTime
+		if err := rows.Scan(&s.ID, &jobID, &s.UserID, &s.CounterSerial, &s.Year, &s.Month, &s.PreviousValue,
+			&s.SubmittedValue, &s.Increment, &s.Status, asUTC(&s.CreatedAt), &confirmedAt); err != nil {
+			return nil, 0, err
+		}
+		s.JobID = jobID.String
+		if confirmedAt.Valid {
+			s.ConfirmedAt = utcPtr(confirmedAt)
+		}
+		submissions = append(submissions, s)
+	}
+
+	return submissions, total, rows.Err()
+}
+
+// SubmissionSummary aggregates a user's confirmed submissions
+type SubmissionSummary struct {
+	TotalSubmitted   int      `json:"total_submitted"`
+	TotalIncrement   float64  `json:"total_increment"`
+	AverageIncrement float64  `json:"average_increment"`
+	LatestSubmitted  *float64 `json:"latest_submitted_value,omitempty"`
+}
+
+// GetSubmissionSummary aggregates confirmed submissions matching the filter
+func GetSubmissionSummary(userID int64, filter SubmissionFilter) (*SubmissionSummary, error) {
+	filter.Status = SubmissionConfirmed
+	where, args := filter.where(userID)
+
+	summary := &SubmissionSummary{}
+	var latest sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(increment), 0), COALESCE(AVG(increment), 0),
+		       (SELECT submitted_value FROM submissions `+where+` ORDER BY year DESC, month DESC, created_at DESC LIMIT 1)
+		FROM submissions `+where, args...,
+	).Scan(&summary.TotalSubmitted, &summary.TotalIncrement, &summary.AverageIncrement, &latest)
+	if err != nil {
+		return nil, err
+	}
+
+	if latest.Valid {
+		v := latest.Float64
+		summary.LatestSubmitted = &v
+	}
+	return summary, nil
+}
+
+// ConsumptionPoint is one confirmed monthly reading for a counter
+type ConsumptionPoint struct {
+	CounterSerial  string
+	Year           int
+	Month          int
+	SubmittedValue float64
+	Increment      float64
+}
+
+// GetConsumptionPoints returns the latest confirmed submission per counter and month,
+// oldest first. A zero year returns all years.
+func GetConsumptionPoints(userID int64, year int) ([]ConsumptionPoint, error) {
+	where, args := SubmissionFilter{Year: year, Status: SubmissionConfirmed}.where(userID)
+	rows, err := db.Query(`
+		SELECT DISTINCT ON (counter_serial, year, month)
+		       counter_serial, year, month, submitted_value, increment
+		FROM submissions `+where+`
+		ORDER BY counter_serial, year, month, created_at DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []ConsumptionPoint
+	for rows.Next() {
+		var p ConsumptionPoint
+		if err := rows.Scan(&p.CounterSerial, &p.Year, &p.Month, &p.SubmittedValue, &p.Increment); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// ScrapingHealth is the page structure canary state for a user
+type ScrapingHealth struct {
+	UserID              int64      `json:"user_id,omitempty"`
+	Broken              bool       `json:"broken"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Missing             []string   `json:"missing,omitempty"`
+	BrokenSince         *time.Time `json:"broken_since,omitempty"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+}
+
+// RecordStructureCheck stores a structure check result. Failures accumulate
+// until threshold flags the user as broken; a passing check clears the state.
+// Also returns whether the user was flagged before this check.
+func RecordStructureCheck(userID int64, missing []string, threshold int) (*ScrapingHealth, bool, error) {
+	var wasBroken bool
+	err := db.QueryRow("SELECT broken_since IS NOT NULL FROM scraping_health WHERE user_id = $1", userID).Scan(&wasBroken)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	if len(missing) == 0 {
+		_, err := db.Exec(`
+			INSERT INTO scraping_health (user_id, consecutive_failures, last_checked_at) VALUES ($1, 0, NOW())
+			ON CONFLICT (user_id) DO UPDATE SET
+				consecutive_failures = 0, missing = NULL, broken_since = NULL, last_checked_at = NOW()`,
+			userID,
+		)
+		if err != nil {
+			return nil, wasBroken, err
+		}
+		return &ScrapingHealth{UserID: userID}, wasBroken, nil
+	}
+
+	health := &ScrapingHealth{UserID: userID, Missing: missing}
+	var brokenSince sql.NullTime
+	err = db.QueryRow(`
+		INSERT INTO scraping_health (user_id, consecutive_failures, missing, broken_since, last_checked_at)
+		VALUES ($1, 1, $2, CASE WHEN $3 <= 1 THEN NOW() END, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			consecutive_failures = scraping_health.consecutive_failures + 1,
+			missing = EXCLUDED.missing,
+			broken_since = COALESCE(scraping_health.broken_since,
+				CASE WHEN scraping_health.consecutive_failures + 1 >= $3 THEN NOW() END),
+			last_checked_at = NOW()
+		RETURNING consecutive_failures, broken_since`,
+		userID, strings.Join(missing, ","), threshold,
+	).Scan(&health.ConsecutiveFailures, &brokenSince)
+	if err != nil {
+		return nil, wasBroken, err
+	}
+	health.BrokenSince = utcPtr(brokenSince)
+	health.Broken = health.BrokenSince != nil
+	return health, wasBroken, nil
+}
+
+// scanScrapingHealth scans a scraping_health row
+func scanScrapingHealth(row interface{ Scan(...interface{}) error }) (*ScrapingHealth, error) {
+	health := &ScrapingHealth{}
+	var missing sql.NullString
+	var brokenSince, lastCheckedAt sql.NullTime
+	if err := row.Scan(&health.UserID, &health.ConsecutiveFailures, &missing, &brokenSince, &lastCheckedAt); err != nil {
+		return nil, err
+	}
+	if missing.String != "" {
+		health.Missing = strings.Split(missing.String, ",")
+	}
+	health.BrokenSince = utcPtr(brokenSince)
+	health.LastCheckedAt = utcPtr(lastCheckedAt)
+	health.Broken = health.BrokenSince != nil
+	return health, nil
+}
+
+// GetScrapingHealth returns a user's structure canary state (zero value if never checked)
+func GetScrapingHealth(userID int64) (*ScrapingHealth, error) {
+	health, err := scanScrapingHealth(db.QueryRow(
+		"SELECT user_id, consecutive_failures, missing, broken_since, last_checked_at FROM scraping_health WHERE user_id = $1",
+		userID,
+	))
+	if err == sql.ErrNoRows {
+		return &ScrapingHealth{UserID: userID}, nil
+	}
+	return health, err
+}
+
+// ListBrokenScraping returns the users whose scraping is flagged as broken
+func ListBrokenScraping() ([]*ScrapingHealth, error) {
+	rows, err := db.Query(`
+		SELECT user_id, consecutive_failures, missing, broken_since, last_checked_at
+		FROM scraping_health WHERE broken_since IS NOT NULL ORDER BY broken_since`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*ScrapingHealth, 0)
+	for rows.Next() {
+		health, err := scanScrapingHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, health)
+	}
+	return result, rows.Err()
+}
+
+// DeferredNotification is a job notification held back by quiet hours
+type DeferredNotification struct {
+	ID        int64
+	UserID    int64
+	JobID     string
+	DeliverAt time.Time
+}
+
+// DeferNotification queues a job notification for delivery at deliverAt
+func DeferNotification(userID int64, jobID string, deliverAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO deferred_notifications (user_id, job_id, deliver_at) VALUES ($1, $2, $3)",
+		userID, jobID, deliverAt,
+	)
+	return err
+}
+
+// ListDueNotifications returns the deferred notifications due at now, oldest first
+func ListDueNotifications(now time.Time) ([]*DeferredNotification, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, job_id, deliver_at FROM deferred_notifications
+		WHERE deliver_at <= $1 ORDER BY deliver_at, id`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []*DeferredNotification
+	for rows.Next() {
+		n := &DeferredNotification{}
+		if err := rows.Scan(&n.ID, &n.UserID, &n.JobID, asUTC(&n.DeliverAt)); err != nil {
+			return nil, err
+		}
+		due = append(due, n)
+	}
+	return due, rows.Err()
+}
+
+// DeleteDeferredNotification removes a delivered notification
+func DeleteDeferredNotification(id int64) error {
+	_, err := db.Exec("DELETE FROM deferred_notifications WHERE id = $1", id)
+	return err
+}
+
+// RecordNotificationFailure counts a failed delivery and drops the
+// notification once it has failed maxAttempts times
+func RecordNotificationFailure(id int64, maxAttempts int) error {
+	_, err := db.Exec(`
+		WITH failed AS (
+			UPDATE deferred_notifications SET attempts = attempts + 1 WHERE id = $1 RETURNING id, attempts
+		)
+		DELETE FROM deferred_notifications WHERE id IN (SELECT id FROM failed WHERE attempts >= $2)`,
+		id, maxAttempts,
+	)
+	return err
+}
+
+// SaveRefreshToken saves a hashed refresh token with the client it was issued to
+func SaveRefreshToken(userID int64, tokenHash string, expiresAt time.Time, userAgent, ipAddress string) error {
+	_, err := db.Exec(
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at, user_agent, ip_address, last_used_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())`,
+		userID, tokenHash, expiresAt, userAgent, ipAddress,
+	)
+	return err
+}
+
+// Session is an active refresh token as shown to its user; the hash is never exposed
+type Session struct {
+	ID         int64      `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+// ListUserSessions returns a user's unexpired refresh tokens, most recently used first
+func ListUserSessions(userID int64) ([]*Session, error) {
+	rows, err := db.Query(`
+		SELECT id, user_agent, ip_address, created_at, last_used_at, expires_at
+		FROM refresh_tokens
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY COALESCE(last_used_at, created_at) DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		s := &Session{}
+		var userAgent, ipAddress sql.NullString
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&s.ID, &userAgent, &ipAddress, asUTC(&s.CreatedAt), &lastUsedAt, asUTC(&s.ExpiresAt)); err != nil {
+			return nil, err
+		}
+		s.UserAgent = userAgent.String
+		s.IPAddress = ipAddress.String
+		if lastUsedAt.Valid {
+			s.LastUsedAt = utcPtr(lastUsedAt)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteUserSession revokes one of a user's refresh tokens by ID, reporting
+// whether it existed
+func DeleteUserSession(userID, sessionID int64) (bool, error) {
+	res, err := db.Exec("DELETE FROM refresh_tokens WHERE id = $1 AND user_id = $2", sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetRefreshToken retrieves a refresh token by hash
+func GetRefreshToken(tokenHash string) (int64, time.Time, error) {
+	var userID int64
+	var expiresAt time.Time
+
+	err := db.QueryRow(
+		"SELECT user_id, expires_at FROM refresh_tokens WHERE token_hash = $1",
+		tokenHash,
+	).Scan(&userID, &expiresAt)
+
+	if err == sql.ErrNoRows {
+		return 0, time.Time{}, errors.New("token not found")
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return userID, expiresAt, nil
+}
+
+// errRefreshTokenNotFound is returned when deleting a refresh token that no longer exists
+var errRefreshTokenNotFound = errors.New("refresh token not found")
+
+// DeleteRefreshToken deletes a refresh token. It returns errRefreshTokenNotFound
+// if the token was already gone, e.g. used by a concurrent refresh.
+func DeleteRefreshToken(tokenHash string) error {
+	res, err := db.Exec("DELETE FROM refresh_tokens WHERE token_hash = $1", tokenHash)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errRefreshTokenNotFound
+	}
+	return nil
+}
+
+// CleanExpiredRefreshTokens deletes expired refresh tokens, and records of
+// rotated ones past their expiry, returning how many were deleted
+func CleanExpiredRefreshTokens() (int64, error) {
+	var total int64
+	for _, table := range []string{"refresh_tokens", "rotated_refresh_tokens"} {
+		res, err := db.Exec("DELETE FROM " + table + " WHERE expires_at < NOW()")
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// RecordRotatedRefreshToken remembers a refresh token exchanged for a new one
+// until it would have expired, so a replay can be detected
+func RecordRotatedRefreshToken(userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO rotated_refresh_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (token_hash) DO NOTHING",
+		tokenHash, userID, expiresAt,
+	)
+	return err
+}
+
+// GetRotatedRefreshToken returns the owner of an already rotated refresh token
+func GetRotatedRefreshToken(tokenHash string) (int64, bool, error) {
+	var userID int64
+	err := db.QueryRow(
+		"SELECT user_id FROM rotated_refresh_tokens WHERE token_hash = $1",
+		tokenHash,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
+
 // DeleteUserRefreshTokens deletes all refresh tokens for a user
 func DeleteUserRefreshTokens(userID int64) error {
 	_, err := db.Exec("DELETE FROM refresh_tokens WHERE user_id = $1", userID)
 	return err
 }
 
-// Encryption helpers using AES-256-GCM
-var encryptionKey []byte
+// SavePasswordResetToken stores a password reset token hash
+func SavePasswordResetToken(userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		userID, tokenHash, expiresAt,
+	)
+	return err
+}
+
+// ConsumePasswordResetToken deletes a reset token and returns its user and expiry
+func ConsumePasswordResetToken(tokenHash string) (int64, time.Time, error) {
+	var userID int64
+	var expiresAt time.Time
+
+	err := db.QueryRow(
+		"DELETE FROM password_reset_tokens WHERE token_hash = $1 RETURNING user_id, expires_at",
+		tokenHash,
+	).Scan(&userID, &expiresAt)
+
+	if err == sql.ErrNoRows {
+		return 0, time.Time{}, errors.New("token not found")
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return userID, expiresAt, nil
+}
+
+// DeleteUserPasswordResetTokens deletes all password reset tokens for a user
+func DeleteUserPasswordResetTokens(userID int64) error {
+	_, err := db.Exec("DELETE FROM password_reset_tokens WHERE user_id = $1", userID)
+	return err
+}
+
+// Encryption helpers using AES-256-GCM. The key is set once at startup but
+// read from job goroutines, so it is stored atomically.
+var encryptionKey atomic.Pointer[[]byte]
+
+// errEncryptionNotInitialized is returned by encrypt/decrypt before SetEncryptionKey
+var errEncryptionNotInitialized = errors.New("encryption not initialized")
 
 // SetEncryptionKey derives a 32-byte key from the JWT secret
 func SetEncryptionKey(secret string) {
 	hash := sha256.Sum256([]byte(secret))
-	encryptionKey = hash[:]
+	key := hash[:]
+	encryptionKey.Store(&key)
+}
+
+// encryptionReady reports whether SetEncryptionKey has been called
+func encryptionReady() bool {
+	return encryptionKey.Load() != nil
+}
+
+// currentEncryptionKey returns the key, or errEncryptionNotInitialized
+func currentEncryptionKey() ([]byte, error) {
+	key := encryptionKey.Load()
+	if key == nil {
+		return nil, errEncryptionNotInitialized
+	}
+	return *key, nil
 }
 
 func encrypt(plaintext string) (string, error) {
-	if len(encryptionKey) == 0 {
-		return "", errors.New("encryption key not set")
+	key, err := currentEncryptionKey()
+	if err != nil {
+		return "", err
 	}
 
-	block, err := aes.NewCipher(encryptionKey)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
@@ -587,8 +2147,9 @@ func encrypt(plaintext string) (string, error) {
 }
 
 func decrypt(ciphertext string) (string, error) {
-	if len(encryptionKey) == 0 {
-		return "", errors.New("encryption key not set")
+	key, err := currentEncryptionKey()
+	if err != nil {
+		return "", err
 	}
 
 	data, err := base64.StdEncoding.DecodeString(ciphertext)
@@ -596,7 +2157,7 @@ func decrypt(ciphertext string) (string, error) {
 		return "", err
 	}
 
-	block, err := aes.NewCipher(encryptionKey)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxDBRetryAttempts caps DB retries so an outage can't pile up slow requests
+const maxDBRetryAttempts = 5
+
+var (
+	dbRetryAttempts = 3
+	dbRetryBackoff  = 100 * time.Millisecond
+)
+
+// SetDBRetry configures retries of transient DB errors. attempts counts the
+// first try; 1 disables retrying.
+func SetDBRetry(attempts int, backoff time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > maxDBRetryAttempts {
+		attempts = maxDBRetryAttempts
+	}
+	dbRetryAttempts = attempts
+	if backoff > 0 {
+		dbRetryBackoff = backoff
+	}
+}
+
+// isTransientDBError reports whether err is a connectivity or capacity problem
+// worth retrying, as opposed to a logical error (constraint, syntax, no rows)
+func isTransientDBError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Class() == "08": // connection_exception
+			return true
+		case pqErr.Code == "53300": // too_many_connections
+			return true
+		case pqErr.Code == "57P01", pqErr.Code == "57P03": // admin_shutdown, cannot_connect_now
+			return true
+		case pqErr.Code == "40001", pqErr.Code == "40P01": // serialization_failure, deadlock_detected
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// withDBRetry runs fn, retrying with a short doubling backoff while it fails
+// with a transient error. It stops waiting once ctx is done, e.g. when the
+// client of an HTTP request has gone, returning the last error joined with
+// the context's. Only wrap statements that are safe to repeat.
+func withDBRetry(ctx context.Context, op string, fn func() error) error {
+	backoff := dbRetryBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !isTransientDBError(err) || attempt >= dbRetryAttempts {
+			return err
+		}
+		slog.WarnContext(ctx, "Transient DB error, retrying", "op", op, "attempt", attempt,
+			"max_attempts", dbRetryAttempts, "backoff", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// setDBRetryForTest configures fast retries and restores the defaults after the test
+func setDBRetryForTest(t *testing.T, attempts int) {
+	t.Helper()
+	prevAttempts, prevBackoff := dbRetryAttempts, dbRetryBackoff
+	SetDBRetry(attempts, time.Millisecond)
+	t.Cleanup(func() { dbRetryAttempts, dbRetryBackoff = prevAttempts, prevBackoff })
+}
+
+// flakyDB fails with err for the first failures calls, then succeeds
+type flakyDB struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyDB) query() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func TestIsTransientDBError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"no rows", sql.ErrNoRows, false},
+		{"bad conn", driver.ErrBadConn, true},
+		{"wrapped bad conn", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"connection reset", syscall.ECONNRESET, true},
+		{"connection refused", syscall.ECONNREFUSED, true},
+		{"connection exception", &pq.Error{Code: "08006"}, true},
+		{"too many connections", &pq.Error{Code: "53300"}, true},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, true},
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"syntax error", &pq.Error{Code: "42601"}, false},
+		{"plain error", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientDBError(tt.err); got != tt.want {
+				t.Errorf("isTransientDBError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDBRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"succeeds first try", 3, 0, driver.ErrBadConn, 1, false},
+		{"recovers from transient errors", 3, 2, driver.ErrBadConn, 3, false},
+		{"gives up after attempts", 3, 5, driver.ErrBadConn, 3, true},
+		{"retrying disabled", 1, 1, driver.ErrBadConn, 1, true},
+		{"logical error not retried", 3, 1, sql.ErrNoRows, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDBRetryForTest(t, tt.attempts)
+			f := &flakyDB{failures: tt.failures, err: tt.err}
+
+			err := withDBRetry(context.Background(), "test", f.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withDBRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if f.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", f.calls, tt.wantCalls)
+			}
+			if tt.wantErr && !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestWithDBRetryStopsWhenContextDone(t *testing.T) {
+	prevAttempts, prevBackoff := dbRetryAttempts, dbRetryBackoff
+	SetDBRetry(maxDBRetryAttempts, time.Hour)
+	t.Cleanup(func() { dbRetryAttempts, dbRetryBackoff = prevAttempts, prevBackoff })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &flakyDB{failures: 10, err: driver.ErrBadConn}
+	done := make(chan error, 1)
+	go func() { done <- withDBRetry(ctx, "test", f.query) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, driver.ErrBadConn) {
+			t.Errorf("error = %v, want the DB error joined with context.Canceled", err)
+		}
+		if f.calls != 1 {
+			t.Errorf("calls = %d, want 1", f.calls)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("withDBRetry kept sleeping after the context was cancelled")
+	}
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testDBOnce sync.Once
+var testDBErr error
+
+// requireTestDB connects to TEST_DATABASE_URL once per run and skips the
+// test when it isn't set; the database must be disposable
+func requireTestDB(t *testing.T) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	testDBOnce.Do(func() { testDBErr = InitDB(url) })
+	if testDBErr != nil {
+		t.Fatalf("InitDB: %v", testDBErr)
+	}
+}
+
+// createTestUser registers a throwaway user that is deleted, with everything
+// it owns, when the test ends
+func createTestUser(t *testing.T) *User {
+	t.Helper()
+	requireTestDB(t)
+	user, err := CreateUser(fmt.Sprintf("test-%s@example.com", uuid.NewString()), "password123")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", user.ID) })
+	return user
+}
+
+func TestSubmissionAttemptLifecycle(t *testing.T) {
+	user := createTestUser(t)
+	tracker := &jobSubmissionTracker{userID: user.ID, submissionID: uuid.NewString()}
+
+	if s, err := tracker.Pending("C1", 2026, 3); err != nil || s != nil {
+		t.Fatalf("Pending before any attempt = %v, %v; want nil, nil", s, err)
+	}
+
+	id, err := tracker.Attempt("C1", 2026, 3, 100, 112.5, 12.5)
+	if err != nil {
+		t.Fatalf("Attempt: %v", err)
+	}
+	// A retried check re-records the same attempt instead of adding a second one
+	if again, err := tracker.Attempt("C1", 2026, 3, 100, 112.5, 12.5); err != nil || again != id {
+		t.Fatalf("second Attempt = %q, %v; want %q", again, err, id)
+	}
+
+	pending, err := tracker.Pending("C1", 2026, 3)
+	if err != nil || pending == nil {
+		t.Fatalf("Pending after attempt = %v, %v", pending, err)
+	}
+	if pending.ID != id || pending.SubmittedValue != 112.5 || pending.Status != SubmissionAttempted {
+		t.Errorf("pending = %+v", pending)
+	}
+	if s, _ := tracker.Pending("C2", 2026, 3); s != nil {
+		t.Errorf("Pending for another counter = %+v, want nil", s)
+	}
+
+	if err := tracker.ConfirmPeriod(2026, 3); err != nil {
+		t.Fatalf("ConfirmPeriod: %v", err)
+	}
+	if s, _ := tracker.Pending("C1", 2026, 3); s != nil {
+		t.Errorf("Pending after ConfirmPeriod = %+v, want nil", s)
+	}
+}
+
+// createTestJob inserts a pending job for userID
+func createTestJob(t *testing.T, userID int64, jobType string) *Job {
+	t.Helper()
+	job, err := CreateJob(uuid.NewString(), userID, jobType, JobSourceAPI)
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	return job
+}
+
+// recordTestSubmission stores a submission with the given status
+func recordTestSubmission(t *testing.T, userID int64, year, month int, value, increment float64, status string) {
+	t.Helper()
+	s := &Submission{ID: uuid.NewString(), UserID: userID, CounterSerial: "C1", Year: year, Month: month,
+		PreviousValue: value - increment, SubmittedValue: value, Increment: increment}
+	if err := RecordSubmissionAttempt(s); err != nil {
+		t.Fatalf("RecordSubmissionAttempt: %v", err)
+	}
+	if status != SubmissionAttempted {
+		if err := SetSubmissionStatus(s.ID, status); err != nil {
+			t.Fatalf("SetSubmissionStatus: %v", err)
+		}
+	}
+}
+
+func TestListSubmissionsFilters(t *testing.T) {
+	user := createTestUser(t)
+	recordTestSubmission(t, user.ID, 2025, 12, 100, 10, SubmissionConfirmed)
+	recordTestSubmission(t, user.ID, 2026, 1, 112, 12, SubmissionConfirmed)
+	recordTestSubmission(t, user.ID, 2026, 2, 126, 14, SubmissionAbandoned)
+
+	tests := []struct {
+		name      string
+		filter    SubmissionFilter
+		wantTotal int
+		wantFirst float64
+	}{
+		{"all, newest first", SubmissionFilter{}, 3, 126},
+		{"year", SubmissionFilter{Year: 2026}, 2, 126},
+		{"month", SubmissionFilter{Year: 2025, Month: 12}, 1, 100},
+		{"status", SubmissionFilter{Status: SubmissionConfirmed}, 2, 112},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subs, total, err := ListSubmissions(user.ID, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("ListSubmissions: %v", err)
+			}
+			if total != tt.wantTotal || len(subs) != tt.wantTotal {
+				t.Fatalf("total %d, %d rows; want %d", total, len(subs), tt.wantTotal)
+			}
+			if subs[0].SubmittedValue != tt.wantFirst {
+				t.Errorf("first submitted value = %v, want %v", subs[0].SubmittedValue, tt.wantFirst)
+			}
+		})
+	}
+
+	summary, err := GetSubmissionSummary(user.ID, SubmissionFilter{})
+	if err != nil {
+		t.Fatalf("GetSubmissionSummary: %v", err)
+	}
+	if summary.TotalSubmitted != 2 || summary.TotalIncrement != 22 || summary.AverageIncrement != 11 {
+		t.Errorf("summary = %+v, want 2 confirmed totalling 22", summary)
+	}
+	if summary.LatestSubmitted == nil || *summary.LatestSubmitted != 112 {
+		t.Errorf("latest submitted = %v, want 112", summary.LatestSubmitted)
+	}
+}
+
+func TestSubmitButtonSettingsRoundTrip(t *testing.T) {
+	user := createTestUser(t)
+	if err := SaveUserConfig(&UserConfig{
+		UserID:               user.ID,
+		SubmitButtonText:     "Зберегти",
+		SubmitButtonSelector: `button.btn-primary[type="submit"]`,
+	}); err != nil {
+		t.Fatalf("SaveUserConfig: %v", err)
+	}
+	// An update that leaves them out keeps the stored values
+	if err := SaveUserConfig(&UserConfig{UserID: user.ID, AccountNumber: "12345"}); err != nil {
+		t.Fatalf("SaveUserConfig: %v", err)
+	}
+
+	cfg, err := GetUserConfig(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserConfig: %v", err)
+	}
+	legacy := cfg.ToConfig()
+	if legacy.SubmitButtonText != "Зберегти" || legacy.SubmitButtonSelector != `button.btn-primary[type="submit"]` {
+		t.Errorf("submit button = %q / %q", legacy.SubmitButtonText, legacy.SubmitButtonSelector)
+	}
+}
+
+func TestDryRunAnomalyWarningRoundTrip(t *testing.T) {
+	user := createTestUser(t)
+	if err := SaveUserConfig(&UserConfig{UserID: user.ID, DryRun: true, DryRunAnomalyWarning: true}); err != nil {
+		t.Fatalf("SaveUserConfig: %v", err)
+	}
+	cfg, err := GetUserConfig(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserConfig: %v", err)
+	}
+	if !cfg.DryRunAnomalyWarning || !cfg.ToConfig().DryRunAnomalyWarning {
+		t.Errorf("DryRunAnomalyWarning = %v (legacy %v), want true", cfg.DryRunAnomalyWarning, cfg.ToConfig().DryRunAnomalyWarning)
+	}
+}
+
+func TestDecodeJobLogs(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"blank", "  \n", nil},
+		{"current format", `["2026-03-01T09:00:00Z Login accepted","2026-03-01T09:00:05Z Done"]`,
+			[]string{"2026-03-01T09:00:00Z Login accepted", "2026-03-01T09:00:05Z Done"}},
+		{"empty array", `[]`, []string{}},
+		{"legacy objects", `[{"time":"09:00","message":"Login accepted"},{"message":"Done"}]`,
+			[]string{"09:00 Login accepted", "Done"}},
+		{"legacy text", `"Login accepted\nDone\n"`, []string{"Login accepted", "Done"}},
+		{"corrupt", `["Login accepted", `, []string{`[warning: logs could not be parsed, raw content follows] ["Login accepted", `}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeJobLogs("job", tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeJobLogs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsUTC(t *testing.T) {
+	kyiv := time.FixedZone("EET", 2*60*60)
+	local := time.Date(2026, 3, 1, 9, 30, 0, 0, kyiv)
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    time.Time
+		wantErr bool
+	}{
+		{"offset timestamp", local, time.Date(2026, 3, 1, 7, 30, 0, 0, time.UTC), false},
+		{"already UTC", local.UTC(), time.Date(2026, 3, 1, 7, 30, 0, 0, time.UTC), false},
+		{"null", nil, time.Time{}, false},
+		{"not a time", "yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got time.Time
+			err := asUTC(&got).Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("Scan() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUTCPtr(t *testing.T) {
+	if got := utcPtr(sql.NullTime{}); got != nil {
+		t.Errorf("utcPtr(null) = %v, want nil", got)
+	}
+	local := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("EET", 2*60*60))
+	got := utcPtr(sql.NullTime{Time: local, Valid: true})
+	if got == nil || got.Location() != time.UTC || !got.Equal(local) {
+		t.Errorf("utcPtr() = %v, want %s", got, local.UTC())
+	}
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestJobApplyResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		result        CheckResult
+		wantSubmitted *float64
+	}{
+		{"submitted", CheckResult{PreviousValue: 1000, Increment: 25, NewValue: 1025, Submitted: true}, floatPtr(1025)},
+		{"dry run", CheckResult{PreviousValue: 1000, Increment: 25, NewValue: 1025, DryRun: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A stale submitted value from an earlier result must not survive
+			job := &Job{SubmittedValue: floatPtr(1)}
+			job.applyResult(&tt.result)
+			if !reflect.DeepEqual(job.SubmittedValue, tt.wantSubmitted) {
+				t.Errorf("SubmittedValue = %v, want %v", job.SubmittedValue, tt.wantSubmitted)
+			}
+			if *job.PreviousValue != 1000 || *job.IncrementUsed != 25 {
+				t.Errorf("previous %v increment %v, want 1000 and 25", *job.PreviousValue, *job.IncrementUsed)
+			}
+		})
+	}
+}
+
+func TestJobSetReadingValues(t *testing.T) {
+	job := &Job{}
+	job.setReadingValues(sql.NullFloat64{}, sql.NullFloat64{Float64: 1000, Valid: true}, sql.NullFloat64{Float64: 12.5, Valid: true})
+	if job.SubmittedValue != nil {
+		t.Errorf("SubmittedValue = %v, want nil for a NULL column", *job.SubmittedValue)
+	}
+	if job.PreviousValue == nil || *job.PreviousValue != 1000 || job.IncrementUsed == nil || *job.IncrementUsed != 12.5 {
+		t.Errorf("previous %v increment %v, want 1000 and 12.5", job.PreviousValue, job.IncrementUsed)
+	}
+}
+
+func TestUpdateJobResultStoresReadingColumns(t *testing.T) {
+	user := createTestUser(t)
+	tests := []struct {
+		name          string
+		result        CheckResult
+		wantSubmitted *float64
+	}{
+		{"submitted", CheckResult{PreviousValue: 1000, Increment: 12.5, NewValue: 1012.5, Submitted: true}, floatPtr(1012.5)},
+		{"dry run", CheckResult{PreviousValue: 1000, Increment: 12.5, NewValue: 1012.5, DryRun: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := createTestJob(t, user.ID, "full")
+			if err := UpdateJobResult(job.ID, &tt.result); err != nil {
+				t.Fatalf("UpdateJobResult: %v", err)
+			}
+			got, err := GetJob(context.Background(), job.ID)
+			if err != nil {
+				t.Fatalf("GetJob: %v", err)
+			}
+			if !reflect.DeepEqual(got.SubmittedValue, tt.wantSubmitted) ||
+				got.PreviousValue == nil || *got.PreviousValue != 1000 || got.IncrementUsed == nil || *got.IncrementUsed != 12.5 {
+				t.Errorf("stored submitted %v previous %v increment %v", got.SubmittedValue, got.PreviousValue, got.IncrementUsed)
+			}
+		})
+	}
+}
+
+func TestIsValidJobSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{JobSourceAPI, true},
+		{JobSourceSchedule, true},
+		{JobSourceAdmin, true},
+		{JobSourceRetry, true},
+		{JobSourceCLI, true},
+		{"", false},
+		{"API", false},
+		{"webhook", false},
+	}
+	for _, tt := range tests {
+		if got := isValidJobSource(tt.source); got != tt.want {
+			t.Errorf("isValidJobSource(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestJobFilterWhere(t *testing.T) {
+	rangeFrom := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	rangeTo := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		filter    JobFilter
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{"no filter", JobFilter{}, "user_id = $1", []interface{}{int64(7)}},
+		{"status", JobFilter{Status: "failed"}, "user_id = $1 AND status = $2", []interface{}{int64(7), "failed"}},
+		{"source", JobFilter{Source: JobSourceSchedule}, "user_id = $1 AND source = $2", []interface{}{int64(7), JobSourceSchedule}},
+		{"status and source", JobFilter{Status: "completed", Source: JobSourceRetry},
+			"user_id = $1 AND status = $2 AND source = $3", []interface{}{int64(7), "completed", JobSourceRetry}},
+		{"type", JobFilter{Type: "dry-run"}, "user_id = $1 AND type = $2", []interface{}{int64(7), "dry-run"}},
+		{"date range", JobFilter{From: &rangeFrom, To: &rangeTo},
+			"user_id = $1 AND created_at >= $2 AND created_at < $3", []interface{}{int64(7), rangeFrom, rangeTo}},
+		{"everything", JobFilter{Status: "failed", Source: JobSourceAPI, Type: "full", From: &rangeFrom, To: &rangeTo},
+			"user_id = $1 AND status = $2 AND source = $3 AND type = $4 AND created_at >= $5 AND created_at < $6",
+			[]interface{}{int64(7), "failed", JobSourceAPI, "full", rangeFrom, rangeTo}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := tt.filter.where(7)
+			if where != tt.wantWhere || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("where() = %q, %v, want %q, %v", where, args, tt.wantWhere, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestGetUserJobsBySource(t *testing.T) {
+	user := createTestUser(t)
+	createTestJob(t, user.ID, "full")
+	scheduled, err := CreateJob(uuid.NewString(), user.ID, "full", JobSourceSchedule)
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+
+	tests := []struct {
+		source    string
+		wantTotal int
+	}{
+		{"", 2},
+		{JobSourceAPI, 1},
+		{JobSourceSchedule, 1},
+		{JobSourceAdmin, 0},
+	}
+	for _, tt := range tests {
+		jobs, total, err := GetUserJobs(user.ID, 20, 0, JobFilter{Source: tt.source})
+		if err != nil {
+			t.Fatalf("GetUserJobs(source %q): %v", tt.source, err)
+		}
+		if total != tt.wantTotal || len(jobs) != tt.wantTotal {
+			t.Errorf("GetUserJobs(source %q) = %d jobs, total %d, want %d", tt.source, len(jobs), total, tt.wantTotal)
+		}
+		for _, job := range jobs {
+			if tt.source != "" && job.Source != tt.source {
+				t.Errorf("GetUserJobs(source %q) returned job with source %q", tt.source, job.Source)
+			}
+		}
+	}
+
+	if job, err := GetJob(context.Background(), scheduled.ID); err != nil || job.Source != JobSourceSchedule {
+		t.Errorf("GetJob source = %v, %v, want %q", job, err, JobSourceSchedule)
+	}
+}
+
+// setEncryptionKeyForTest sets the encryption key from secret, or clears it
+// if secret is empty, restoring the previous key when the test ends
+func setEncryptionKeyForTest(t *testing.T, secret string) {
+	t.Helper()
+	prev := encryptionKey.Load()
+	t.Cleanup(func() { encryptionKey.Store(prev) })
+	if secret == "" {
+		encryptionKey.Store(nil)
+		return
+	}
+	SetEncryptionKey(secret)
+}
+
+func TestEncryptionRequiresKey(t *testing.T) {
+	setEncryptionKeyForTest(t, "")
+
+	if encryptionReady() {
+		t.Error("encryptionReady() = true without a key")
+	}
+	if _, err := encrypt("secret"); !errors.Is(err, errEncryptionNotInitialized) {
+		t.Errorf("encrypt() error = %v, want %v", err, errEncryptionNotInitialized)
+	}
+	if _, err := decrypt("c2VjcmV0"); !errors.Is(err, errEncryptionNotInitialized) {
+		t.Errorf("decrypt() error = %v, want %v", err, errEncryptionNotInitialized)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	setEncryptionKeyForTest(t, "test-secret")
+	ciphertext, err := encrypt("hunter2")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		secret     string
+		ciphertext string
+		want       string
+		wantErr    bool
+	}{
+		{"same key", "test-secret", ciphertext, "hunter2", false},
+		{"other key", "other-secret", ciphertext, "", true},
+		{"tampered", "test-secret", ciphertext[:len(ciphertext)-4] + "AAAA", "", true},
+		{"too short", "test-secret", "AAAA", "", true},
+		{"not base64", "test-secret", "!!!", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetEncryptionKey(tt.secret)
+			got, err := decrypt(tt.ciphertext)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("decrypt() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetUserJobsIncludesErrorCodeAndOutcome(t *testing.T) {
+	user := createTestUser(t)
+	failed := createTestJob(t, user.ID, "full")
+	errMsg := "wrong password"
+	if err := UpdateJobStatus(failed.ID, "failed", &errMsg); err != nil {
+		t.Fatalf("UpdateJobStatus: %v", err)
+	}
+	if err := SetJobOutcome(failed.ID, OutcomeFailure); err != nil {
+		t.Fatalf("SetJobOutcome: %v", err)
+	}
+	if err := SetJobErrorCode(failed.ID, ErrInvalidCredentials.Error()); err != nil {
+		t.Fatalf("SetJobErrorCode: %v", err)
+	}
+	pending := createTestJob(t, user.ID, "full")
+
+	jobs, _, err := GetUserJobs(user.ID, 20, 0, JobFilter{})
+	if err != nil {
+		t.Fatalf("GetUserJobs: %v", err)
+	}
+	want := map[string][2]string{
+		failed.ID:  {ErrInvalidCredentials.Error(), OutcomeFailure},
+		pending.ID: {"", ""},
+	}
+	for _, job := range jobs {
+		if got := [2]string{job.ErrorCode, job.Outcome}; got != want[job.ID] {
+			t.Errorf("job %s error code and outcome = %v, want %v", job.ID, got, want[job.ID])
+		}
+	}
+	if len(jobs) != len(want) {
+		t.Errorf("jobs = %d, want %d", len(jobs), len(want))
+	}
+}
+
+func TestGetUserJobsByTypeAndDate(t *testing.T) {
+	user := createTestUser(t)
+	now := time.Now().UTC()
+	jobs := map[string]*Job{}
+	for name, j := range map[string]struct {
+		jobType string
+		daysOld int
+	}{
+		"old full":       {"full", 40},
+		"old dry-run":    {"dry-run", 40},
+		"recent full":    {"full", 5},
+		"recent dry-run": {"dry-run", 5},
+	} {
+		job := createTestJob(t, user.ID, j.jobType)
+		if _, err := db.Exec("UPDATE jobs SET created_at = NOW() - make_interval(days => $1) WHERE id = $2", j.daysOld, job.ID); err != nil {
+			t.Fatalf("backdate job: %v", err)
+		}
+		jobs[name] = job
+	}
+
+	monthAgo, weekAgo := now.AddDate(0, 0, -30), now.AddDate(0, 0, -7)
+	tests := []struct {
+		name   string
+		filter JobFilter
+		want   []string
+	}{
+		{"type", JobFilter{Type: "dry-run"}, []string{"recent dry-run", "old dry-run"}},
+		{"from", JobFilter{From: &monthAgo}, []string{"recent full", "recent dry-run"}},
+		{"to", JobFilter{To: &monthAgo}, []string{"old full", "old dry-run"}},
+		{"type in range", JobFilter{Type: "full", From: &monthAgo, To: &now}, []string{"recent full"}},
+		{"empty range", JobFilter{From: &weekAgo, To: &weekAgo}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := GetUserJobs(user.ID, 20, 0, tt.filter)
+			if err != nil {
+				t.Fatalf("GetUserJobs: %v", err)
+			}
+			want := map[string]bool{}
+			for _, name := range tt.want {
+				want[jobs[name].ID] = true
+			}
+			if total != len(tt.want) || len(got) != len(tt.want) {
+				t.Errorf("got %d jobs, total %d, want %d", len(got), total, len(tt.want))
+			}
+			for _, job := range got {
+				if !want[job.ID] {
+					t.Errorf("unexpected job %s (%s, created %v)", job.ID, job.Type, job.CreatedAt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/chromedp/chromedp"
+)
+
+// HTML dumps are captured page sources and may contain arbitrary scripts
+const (
+	// htmlDumpCSP blocks every resource and script, for dumps served as text
+	htmlDumpCSP = "default-src 'none'; sandbox"
+	// htmlDumpRenderCSP allows inline styles and data images only, in an opaque-origin sandbox
+	htmlDumpRenderCSP = "default-src 'none'; style-src 'unsafe-inline'; img-src data:; sandbox"
+)
+
+var renderHTMLDumps = false
+
+// SetHTMLDumpRendering allows serving HTML dumps as text/html when requested with ?render=html
+func SetHTMLDumpRendering(enabled bool) {
+	renderHTMLDumps = enabled
+}
+
+// SaveHTMLDumpToPath saves the current page's HTML to a file for debugging
+func SaveHTMLDumpToPath(ctx context.Context, path string) error {
+	var html string
+	if err := chromedp.Run(ctx, chromedp.OuterHTML("html", &html, chromedp.ByQuery)); err != nil {
+		return fmt.Errorf("failed to capture page HTML: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(html), 0644); err != nil {
+		return fmt.Errorf("failed to save page HTML: %w", err)
+	}
+
+	return nil
+}
+
+// setHTMLDumpHeaders sets headers that keep a captured HTML dump from executing.
+// Dumps are plain text unless rendering is enabled and the client asks for it.
+func setHTMLDumpHeaders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if renderHTMLDumps && r.URL.Query().Get("render") == "html" {
+		w.Header().Set("Content-Security-Policy", htmlDumpRenderCSP)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		return
+	}
+	w.Header().Set("Content-Security-Policy", htmlDumpCSP)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+}
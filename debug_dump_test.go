@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetHTMLDumpHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		renderEnabled   bool
+		target          string
+		wantContentType string
+		wantCSP         string
+	}{
+		{"text by default", false, "/dump.html", "text/plain; charset=utf-8", htmlDumpCSP},
+		{"render asked but disabled", false, "/dump.html?render=html", "text/plain; charset=utf-8", htmlDumpCSP},
+		{"render enabled but not asked", true, "/dump.html", "text/plain; charset=utf-8", htmlDumpCSP},
+		{"render enabled and asked", true, "/dump.html?render=html", "text/html; charset=utf-8", htmlDumpRenderCSP},
+		{"unknown render value", true, "/dump.html?render=raw", "text/plain; charset=utf-8", htmlDumpCSP},
+	}
+	prev := renderHTMLDumps
+	t.Cleanup(func() { SetHTMLDumpRendering(prev) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetHTMLDumpRendering(tt.renderEnabled)
+			rec := httptest.NewRecorder()
+			setHTMLDumpHeaders(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := rec.Header().Get("Content-Security-Policy"); got != tt.wantCSP {
+				t.Errorf("Content-Security-Policy = %q, want %q", got, tt.wantCSP)
+			}
+			if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// EffectiveConfig is a user's configuration as a full job would resolve it
+// right now, with every default and fallback applied. Credentials are omitted.
+type EffectiveConfig struct {
+	Now          time.Time `json:"now"` // in the submission timezone
+	Timezone     string    `json:"timezone"`
+	LoginURL     string    `json:"login_url"`
+	CheckURL     string    `json:"check_url"`
+	CronSchedule string    `json:"cron_schedule"`
+
+	// DryRun is whether a full job would only fill the form; DryRunReason says why
+	DryRun       bool   `json:"dry_run"`
+	DryRunReason string `json:"dry_run_reason,omitempty"`
+
+	SuccessMode          string    `json:"success_mode"`
+	ValueSelector        string    `json:"value_selector"`
+	ValueSource          string    `json:"value_source"`
+	SubmitButtonText     string    `json:"submit_button_text,omitempty"`
+	SubmitButtonSelector string    `json:"submit_button_selector,omitempty"`
+	LandingURLPattern    string    `json:"landing_url_pattern,omitempty"`
+	Selectors            Selectors `json:"selectors"`
+
+	// The day-of-month window and the optional hour window
+	InSubmissionWindow  bool `json:"in_submission_window"`
+	SubmissionHourStart *int `json:"submission_hour_start,omitempty"`
+	SubmissionHourEnd   *int `json:"submission_hour_end,omitempty"`
+	InSubmissionHours   bool `json:"in_submission_hours"`
+
+	// The increment a job would add now, for the month before the current one.
+	// With an increments URL the fetched values take precedence at job time.
+	PreviousMonth     int               `json:"previous_month"`
+	Increment         *float64          `json:"increment,omitempty"`
+	IncrementSource   string            `json:"increment_source"` // "configured", "fallback" or "none"
+	IncrementError    string            `json:"increment_error,omitempty"`
+	IncrementFallback IncrementFallback `json:"increment_fallback"`
+	IncrementsURL     string            `json:"increments_url,omitempty"`
+}
+
+// resolveEffectiveConfig applies the defaults and fallbacks a full job would
+// apply to cfg at now
+func resolveEffectiveConfig(cfg *UserConfig, now time.Time) *EffectiveConfig {
+	config := cfg.ToConfig()
+	if config.SubmissionTimezone != "" {
+		if loc, err := time.LoadLocation(config.SubmissionTimezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	valueSelector, valueSource := config.valueSelector()
+
+	eff := &EffectiveConfig{
+		Now:          now,
+		Timezone:     now.Location().String(),
+		LoginURL:     config.LoginURL,
+		CheckURL:     config.CheckURL,
+		CronSchedule: config.CronSchedule,
+		DryRun:       config.DryRun,
+
+		SuccessMode:          config.SuccessMode,
+		ValueSelector:        valueSelector,
+		ValueSource:          valueSource,
+		SubmitButtonText:     config.SubmitButtonText,
+		SubmitButtonSelector: config.SubmitButtonSelector,
+		LandingURLPattern:    config.LandingURLPattern,
+		Selectors:            config.selectors(),
+
+		InSubmissionWindow:  now.Day() >= 1 && now.Day() <= 5,
+		SubmissionHourStart: config.SubmissionHourStart,
+		SubmissionHourEnd:   config.SubmissionHourEnd,
+		InSubmissionHours:   config.checkSubmissionHours(now) == nil,
+
+		IncrementFallback: config.IncrementFallback,
+		IncrementsURL:     config.IncrementsURL,
+	}
+
+	// Same order as applyDryRunPolicy for a full job
+	switch {
+	case globalForceDryRun:
+		eff.DryRun, eff.DryRunReason = true, "global_force_dry_run"
+	case config.DryRun:
+		eff.DryRunReason = "config"
+	case dryRunRampApplies(cfg):
+		eff.DryRun, eff.DryRunReason = true, "dry_run_ramp"
+	}
+
+	increment, prevMonth, err := config.GetIncrementForPreviousMonth(int(now.Month()))
+	eff.PreviousMonth = prevMonth
+	switch _, configured := config.MonthlyIncrements[prevMonth]; {
+	case err != nil:
+		eff.IncrementSource = "none"
+		eff.IncrementError = err.Error()
+	case configured:
+		eff.IncrementSource = "configured"
+		eff.Increment = &increment
+	default:
+		eff.IncrementSource = "fallback"
+		eff.Increment = &increment
+	}
+	return eff
+}
+
+// handleGetEffectiveConfig returns the user's config as a full job would see it now
+func handleGetEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	cfg, err := GetUserConfig(r.Context(), userID)
+	if err != nil {
+		jsonError(w, "Failed to get config", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(resolveEffectiveConfig(cfg, timeNow()))
+}
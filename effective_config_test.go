@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResolveEffectiveConfigTime(t *testing.T) {
+	// 23:30 UTC on March 31st is already April 1st in Kyiv
+	now := time.Date(2026, time.March, 31, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		timezone      string
+		wantTimezone  string
+		wantInWindow  bool
+		wantPrevMonth int
+	}{
+		{"UTC", "", "UTC", false, 2},
+		{"submission timezone", "Europe/Kyiv", "Europe/Kyiv", true, 3},
+		{"unknown timezone", "Mars/Olympus", "UTC", false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eff := resolveEffectiveConfig(&UserConfig{SubmissionTimezone: tt.timezone}, now)
+			if eff.Timezone != tt.wantTimezone || !eff.Now.Equal(now) {
+				t.Errorf("Now = %v in %s, want %v in %s", eff.Now, eff.Timezone, now, tt.wantTimezone)
+			}
+			if eff.InSubmissionWindow != tt.wantInWindow {
+				t.Errorf("InSubmissionWindow = %v, want %v", eff.InSubmissionWindow, tt.wantInWindow)
+			}
+			if eff.PreviousMonth != tt.wantPrevMonth {
+				t.Errorf("PreviousMonth = %d, want %d", eff.PreviousMonth, tt.wantPrevMonth)
+			}
+		})
+	}
+}
+
+func TestResolveEffectiveConfigSubmissionHours(t *testing.T) {
+	now := time.Date(2026, time.April, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		start, end *int
+		want       bool
+	}{
+		{"no hour window", nil, nil, true},
+		{"inside", intPtr(8), intPtr(18), true},
+		{"outside", intPtr(12), intPtr(18), false},
+		{"overnight", intPtr(22), intPtr(6), false},
+	}
+	for _, tt := range tests {
+		eff := resolveEffectiveConfig(&UserConfig{SubmissionHourStart: tt.start, SubmissionHourEnd: tt.end}, now)
+		if eff.InSubmissionHours != tt.want {
+			t.Errorf("%s: InSubmissionHours = %v, want %v", tt.name, eff.InSubmissionHours, tt.want)
+		}
+	}
+}
+
+func TestResolveEffectiveConfigIncrement(t *testing.T) {
+	// Previous month is March
+	now := time.Date(2026, time.April, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		cfg           UserConfig
+		wantSource    string
+		wantIncrement *float64
+	}{
+		{"configured", UserConfig{MonthlyIncrements: map[int]float64{3: 120}}, "configured", floatPtr(120)},
+		{"fallback", UserConfig{
+			MonthlyIncrements: map[int]float64{1: 100, 2: 80},
+			IncrementFallback: IncrementFallback{Mode: IncrementFallbackAverage},
+		}, "fallback", floatPtr(90)},
+		{"none", UserConfig{MonthlyIncrements: map[int]float64{1: 100}}, "none", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eff := resolveEffectiveConfig(&tt.cfg, now)
+			if eff.IncrementSource != tt.wantSource {
+				t.Errorf("IncrementSource = %q, want %q", eff.IncrementSource, tt.wantSource)
+			}
+			if (eff.Increment == nil) != (tt.wantIncrement == nil) ||
+				(eff.Increment != nil && *eff.Increment != *tt.wantIncrement) {
+				t.Errorf("Increment = %v, want %v", eff.Increment, tt.wantIncrement)
+			}
+			if (eff.IncrementError != "") != (tt.wantSource == "none") {
+				t.Errorf("IncrementError = %q", eff.IncrementError)
+			}
+		})
+	}
+}
+
+func TestResolveEffectiveConfigDryRun(t *testing.T) {
+	prevForce, prevRuns := globalForceDryRun, dryRunRampRuns
+	t.Cleanup(func() {
+		SetGlobalForceDryRun(prevForce)
+		dryRunRampRuns = prevRuns
+	})
+	SetDryRunRampRuns(1)
+	setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagDryRunRamp: true})
+
+	tests := []struct {
+		name       string
+		force      bool
+		cfg        UserConfig
+		wantDryRun bool
+		wantReason string
+	}{
+		{"live", false, UserConfig{RampRunsDone: 1}, false, ""},
+		{"forced globally", true, UserConfig{RampRunsDone: 1}, true, "global_force_dry_run"},
+		{"configured", false, UserConfig{DryRun: true}, true, "config"},
+		{"ramp", false, UserConfig{}, true, "dry_run_ramp"},
+		{"ramp skipped", false, UserConfig{SkipDryRunRamp: true}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetGlobalForceDryRun(tt.force)
+			eff := resolveEffectiveConfig(&tt.cfg, time.Now())
+			if eff.DryRun != tt.wantDryRun || eff.DryRunReason != tt.wantReason {
+				t.Errorf("DryRun = %v (%q), want %v (%q)", eff.DryRun, eff.DryRunReason, tt.wantDryRun, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestHandleGetEffectiveConfigRejectsRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		userID int64
+		want   int
+	}{
+		{"wrong method", http.MethodPost, 1, http.StatusMethodNotAllowed},
+		{"no user", http.MethodGet, 0, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleGetEffectiveConfig(rec, newAuthedRequest(tt.method, "/api/config/effective", "", tt.userID))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// Error codes for failures that match no sentinel error
+const (
+	ErrorCodeTimeout     = "timeout"
+	ErrorCodeInterrupted = "interrupted" // the server stopped mid-job
+	ErrorCodeUnknown     = "unknown"
+)
+
+// jobErrorCodes are the sentinel errors whose text doubles as a job's error
+// code, most specific first: a failure may wrap several of them
+var jobErrorCodes = []error{
+	ErrInvalidCredentials,
+	ErrLoginPageNotLoaded,
+	ErrLoginFieldsNotFound,
+	ErrUnexpectedLanding,
+	ErrAccountNotFound,
+	ErrAmbiguousAccount,
+	ErrSessionLost,
+	ErrOutsideSubmissionHours,
+	ErrModalButtonMissing,
+	ErrAmbiguousSubmitButton,
+	ErrImplausibleReading,
+	ErrInputNotCleared,
+	ErrValueMismatch,
+	ErrSubmitUncertain,
+	ErrSubmitFailed,
+}
+
+// errorCodeFor classifies a job failure into a stable code for dashboards
+func errorCodeFor(err error) string {
+	for _, sentinel := range jobErrorCodes {
+		if errors.Is(err, sentinel) {
+			return sentinel.Error()
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorCodeTimeout
+	}
+	return ErrorCodeUnknown
+}
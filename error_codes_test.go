@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCodeFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ErrorCodeUnknown},
+		{"plain error", errors.New("chrome crashed"), ErrorCodeUnknown},
+		{"sentinel", ErrInvalidCredentials, "invalid_credentials"},
+		{"wrapped sentinel", fmt.Errorf("login: %w", ErrAccountNotFound), ErrAccountNotFound.Error()},
+		{"most specific of several", errors.Join(ErrSubmitFailed, ErrValueMismatch), ErrValueMismatch.Error()},
+		{"deadline", context.DeadlineExceeded, ErrorCodeTimeout},
+		{"wrapped deadline", fmt.Errorf("waiting for form: %w", context.DeadlineExceeded), ErrorCodeTimeout},
+		{"sentinel over deadline", errors.Join(context.DeadlineExceeded, ErrSessionLost), ErrSessionLost.Error()},
+		{"cancelled", context.Canceled, ErrorCodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCodeFor(tt.err); got != tt.want {
+				t.Errorf("errorCodeFor(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobErrorCodesAreDistinct(t *testing.T) {
+	seen := map[string]bool{ErrorCodeTimeout: true, ErrorCodeInterrupted: true, ErrorCodeUnknown: true}
+	for _, sentinel := range jobErrorCodes {
+		code := sentinel.Error()
+		if seen[code] {
+			t.Errorf("error code %q is used twice", code)
+		}
+		seen[code] = true
+	}
+}
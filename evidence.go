@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// evidenceScreenshotPrefix marks screenshots kept as proof of a submission.
+// Retention never purges them; deleting the job does.
+const evidenceScreenshotPrefix = "evidence_"
+
+// Stable evidence screenshot names, taken around the submit click
+const (
+	EvidenceBeforeSubmit = "before_submit"
+	EvidenceAfterSubmit  = "after_submit"
+)
+
+// evidenceScreenshots enables the before/after submit screenshots
+var evidenceScreenshots = true
+
+// SetEvidenceScreenshots enables or disables evidence screenshots around submits
+func SetEvidenceScreenshots(enabled bool) {
+	evidenceScreenshots = enabled
+}
+
+// saveEvidence takes an evidence screenshot under its stable name
+func saveEvidence(saveScreenshot func(string), name string) {
+	if evidenceScreenshots {
+		saveScreenshot(evidenceScreenshotPrefix + name)
+	}
+}
+
+// isEvidenceScreenshot reports whether a screenshot file is submission evidence
+func isEvidenceScreenshot(filename string) bool {
+	return strings.HasPrefix(filepath.Base(filename), evidenceScreenshotPrefix)
+}
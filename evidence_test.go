@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsEvidenceScreenshot(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     bool
+	}{
+		{"evidence_before_submit.png", true},
+		{"03_evidence_after_submit.png", false},
+		{"1/job-id/evidence_after_submit.png", true},
+		{"02_error_final.png", false},
+		{"before_submit.png", false},
+	}
+	for _, tt := range tests {
+		if got := isEvidenceScreenshot(tt.filename); got != tt.want {
+			t.Errorf("isEvidenceScreenshot(%q) = %v, want %v", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestSaveEvidence(t *testing.T) {
+	prev := evidenceScreenshots
+	t.Cleanup(func() { SetEvidenceScreenshots(prev) })
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    []string
+	}{
+		{"enabled", true, []string{"evidence_before_submit"}},
+		{"disabled", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetEvidenceScreenshots(tt.enabled)
+			var saved []string
+			saveEvidence(func(name string) { saved = append(saved, name) }, EvidenceBeforeSubmit)
+			if !reflect.DeepEqual(saved, tt.want) {
+				t.Errorf("saved %q, want %q", saved, tt.want)
+			}
+		})
+	}
+}
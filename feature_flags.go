@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FeatureFlag names a server-side toggle
+type FeatureFlag string
+
+// Known feature flags
+const (
+	FlagEmailNotifications    FeatureFlag = "email_notifications"
+	FlagTelegramNotifications FeatureFlag = "telegram_notifications"
+	FlagDryRunRamp            FeatureFlag = "dry_run_ramp"
+	FlagWebhooks              FeatureFlag = "webhooks"
+	FlagTwoFactor             FeatureFlag = "two_factor"
+	FlagScheduler             FeatureFlag = "scheduler"
+)
+
+// defaultFlags holds the built-in state of every known flag
+var defaultFlags = map[FeatureFlag]bool{
+	FlagEmailNotifications:    true,
+	FlagTelegramNotifications: true,
+	FlagDryRunRamp:            true,
+	FlagWebhooks:              false,
+	FlagTwoFactor:             false,
+	FlagScheduler:             false,
+}
+
+// Flag value sources, in increasing precedence
+const (
+	FlagSourceDefault = "default"
+	FlagSourceEnv     = "env"
+	FlagSourceDB      = "db"
+)
+
+// featureFlagOverrideTTL is how long DB overrides are cached
+const featureFlagOverrideTTL = 30 * time.Second
+
+// FeatureFlags resolves flags from defaults, the FEATURE_FLAGS env var and DB overrides
+type FeatureFlags struct {
+	mu            sync.Mutex
+	env           map[FeatureFlag]bool
+	overrides     map[FeatureFlag]bool
+	loadedAt      time.Time
+	loadOverrides func() (map[FeatureFlag]bool, error)
+}
+
+var featureFlags = NewFeatureFlags(nil, nil)
+
+// SetFeatureFlags replaces the global feature flag set
+func SetFeatureFlags(f *FeatureFlags) {
+	featureFlags = f
+}
+
+// NewFeatureFlags creates a flag set from env values and an optional DB override loader
+func NewFeatureFlags(env map[FeatureFlag]bool, loadOverrides func() (map[FeatureFlag]bool, error)) *FeatureFlags {
+	if env == nil {
+		env = make(map[FeatureFlag]bool)
+	}
+	return &FeatureFlags{env: env, loadOverrides: loadOverrides}
+}
+
+// ParseFeatureFlags parses "name=true,other=false" into flag values
+func ParseFeatureFlags(s string) (map[FeatureFlag]bool, error) {
+	flags := make(map[FeatureFlag]bool)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid feature flag %q: expected name=bool", pair)
+		}
+		flag := FeatureFlag(strings.TrimSpace(name))
+		if _, known := defaultFlags[flag]; !known {
+			return nil, fmt.Errorf("unknown feature flag %q", flag)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for feature flag %q: %w", flag, err)
+		}
+		flags[flag] = enabled
+	}
+	return flags, nil
+}
+
+// IsEnabled reports whether a flag is on in the global flag set
+func IsEnabled(flag FeatureFlag) bool {
+	return featureFlags.IsEnabled(flag)
+}
+
+// IsEnabled reports whether a flag is on; DB overrides win over env, env over defaults
+func (f *FeatureFlags) IsEnabled(flag FeatureFlag) bool {
+	enabled, _ := f.resolve(flag)
+	return enabled
+}
+
+// FeatureFlagState is a flag's effective value and where it came from
+type FeatureFlagState struct {
+	Name    FeatureFlag `json:"name"`
+	Enabled bool        `json:"enabled"`
+	Source  string      `json:"source"`
+}
+
+// All returns the effective state of every known flag, sorted by name
+func (f *FeatureFlags) All() []FeatureFlagState {
+	states := make([]FeatureFlagState, 0, len(defaultFlags))
+	for flag := range defaultFlags {
+		enabled, source := f.resolve(flag)
+		states = append(states, FeatureFlagState{Name: flag, Enabled: enabled, Source: source})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// resolve returns a flag's value and source
+func (f *FeatureFlags) resolve(flag FeatureFlag) (bool, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.refreshOverrides()
+	if v, ok := f.overrides[flag]; ok {
+		return v, FlagSourceDB
+	}
+	if v, ok := f.env[flag]; ok {
+		return v, FlagSourceEnv
+	}
+	return defaultFlags[flag], FlagSourceDefault
+}
+
+// refreshOverrides reloads DB overrides once the cache expires. Must hold f.mu.
+func (f *FeatureFlags) refreshOverrides() {
+	if f.loadOverrides == nil || time.Since(f.loadedAt) < featureFlagOverrideTTL {
+		return
+	}
+	f.loadedAt = time.Now()
+
+	overrides, err := f.loadOverrides()
+	if err != nil {
+		// Keep the last known overrides rather than flapping to env values
+		slog.Error("Failed to load feature flag overrides", "error", err)
+		return
+	}
+	f.overrides = overrides
+}
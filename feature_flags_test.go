@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFeatureFlags(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[FeatureFlag]bool
+		wantErr bool
+	}{
+		{"", map[FeatureFlag]bool{}, false},
+		{"webhooks=true", map[FeatureFlag]bool{FlagWebhooks: true}, false},
+		{" webhooks = 1 , email_notifications=false ,", map[FeatureFlag]bool{FlagWebhooks: true, FlagEmailNotifications: false}, false},
+		{"webhooks", nil, true},
+		{"teleport=true", nil, true},
+		{"webhooks=maybe", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFeatureFlags(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFeatureFlags(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFeatureFlags(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagsPrecedence(t *testing.T) {
+	env := map[FeatureFlag]bool{FlagWebhooks: true, FlagScheduler: true}
+	overrides := map[FeatureFlag]bool{FlagScheduler: false}
+	f := NewFeatureFlags(env, func() (map[FeatureFlag]bool, error) { return overrides, nil })
+
+	tests := []struct {
+		flag        FeatureFlag
+		wantEnabled bool
+		wantSource  string
+	}{
+		{FlagEmailNotifications, true, FlagSourceDefault},
+		{FlagTwoFactor, false, FlagSourceDefault},
+		{FlagWebhooks, true, FlagSourceEnv},
+		{FlagScheduler, false, FlagSourceDB},
+	}
+	for _, tt := range tests {
+		enabled, source := f.resolve(tt.flag)
+		if enabled != tt.wantEnabled || source != tt.wantSource {
+			t.Errorf("%s = %v from %s, want %v from %s", tt.flag, enabled, source, tt.wantEnabled, tt.wantSource)
+		}
+	}
+
+	all := f.All()
+	if len(all) != len(defaultFlags) {
+		t.Fatalf("All() returned %d flags, want %d", len(all), len(defaultFlags))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Name >= all[i].Name {
+			t.Errorf("All() not sorted: %s before %s", all[i-1].Name, all[i].Name)
+		}
+	}
+}
+
+func TestFeatureFlagsCachesOverrides(t *testing.T) {
+	loads := 0
+	fail := false
+	f := NewFeatureFlags(nil, func() (map[FeatureFlag]bool, error) {
+		loads++
+		if fail {
+			return nil, errors.New("db down")
+		}
+		return map[FeatureFlag]bool{FlagWebhooks: true}, nil
+	})
+
+	f.IsEnabled(FlagWebhooks)
+	f.IsEnabled(FlagScheduler)
+	if loads != 1 {
+		t.Fatalf("overrides loaded %d times within the TTL, want 1", loads)
+	}
+
+	// A failed reload keeps the last known overrides
+	fail = true
+	f.loadedAt = f.loadedAt.Add(-2 * featureFlagOverrideTTL)
+	if !f.IsEnabled(FlagWebhooks) {
+		t.Error("override lost after a failed reload")
+	}
+	if loads != 2 {
+		t.Errorf("overrides loaded %d times after the TTL, want 2", loads)
+	}
+}
@@ -2,15 +2,21 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/mail"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/chromedp/chromedp"
 )
@@ -35,14 +41,14 @@ func handleGetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := GetUserByID(userID)
+	user, err := GetUserByID(r.Context(), userID)
 	if err != nil || user == nil {
 		jsonError(w, "User not found", http.StatusNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(UserResponse{
+	newJSONEncoder(w).Encode(UserResponse{
 		ID:        user.ID,
 		Email:     user.Email,
 		CreatedAt: user.CreatedAt,
@@ -69,8 +75,7 @@ func handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req ChangePasswordRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
 		return
 	}
 
@@ -79,7 +84,7 @@ func handleChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := GetUserByID(userID)
+	user, err := GetUserByID(r.Context(), userID)
 	if err != nil || user == nil {
 		jsonError(w, "User not found", http.StatusNotFound)
 		return
@@ -91,23 +96,50 @@ func handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := UpdateUserPassword(userID, req.NewPassword); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update password", "user_id", userID, "error", err)
 		jsonError(w, "Failed to update password", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Password updated"})
+	newJSONEncoder(w).Encode(map[string]string{"message": "Password updated"})
 }
 
 // ConfigUpdateRequest is the request body for config update
 type ConfigUpdateRequest struct {
-	GasolinaEmail     string      `json:"gasolina_email"`
-	GasolinaPassword  string      `json:"gasolina_password"`
-	AccountNumber     string      `json:"account_number"`
-	CheckURL          string      `json:"check_url"`
-	CronSchedule      string      `json:"cron_schedule"`
-	DryRun            *bool       `json:"dry_run"`
-	MonthlyIncrements map[int]int `json:"monthly_increments"`
+	GasolinaEmail        string                         `json:"gasolina_email"`
+	GasolinaPassword     string                         `json:"gasolina_password"`
+	AccountNumber        string                         `json:"account_number"`
+	LoginURL             string                         `json:"login_url"`
+	CheckURL             string                         `json:"check_url"`
+	CronSchedule         string                         `json:"cron_schedule"`
+	DryRun               *bool                          `json:"dry_run"`
+	SuccessMode          string                         `json:"success_mode"`
+	RecheckMissingButton *bool                          `json:"recheck_missing_button"`
+	NotifyEmail          Resettable[string]             `json:"notify_email"`
+	NotifyOn             []string                       `json:"notify_on"`
+	SkipDryRunRamp       *bool                          `json:"skip_dry_run_ramp"`
+	ForceSubmit          *bool                          `json:"force_submit"`
+	DryRunAnomalyWarning *bool                          `json:"dry_run_anomaly_warning"`
+	SubmitButtonText     string                         `json:"submit_button_text"`
+	SubmitButtonSelector string                         `json:"submit_button_selector"`
+	ValueSelector        string                         `json:"value_selector"`
+	ValueSource          string                         `json:"value_source"`
+	SubmissionHourStart  *int                           `json:"submission_hour_start"`
+	SubmissionHourEnd    *int                           `json:"submission_hour_end"`
+	SubmissionTimezone   string                         `json:"submission_timezone"`
+	LandingURLPattern    string                         `json:"landing_url_pattern"`
+	Fingerprint          Resettable[BrowserFingerprint] `json:"browser_fingerprint"`
+	MonthlyIncrements    json.RawMessage                `json:"monthly_increments"`
+	IncrementsURL        string                         `json:"increments_url"`
+	IncrementFallback    *IncrementFallback             `json:"increment_fallback"`
+	Selectors            *Selectors                     `json:"selectors"`
+	QuietHourStart       *int                           `json:"quiet_hour_start"`
+	QuietHourEnd         *int                           `json:"quiet_hour_end"`
+	QuietTimezone        string                         `json:"quiet_timezone"`
+	QuietBypassCritical  *bool                          `json:"quiet_bypass_critical"`
+	TelegramBotToken     Resettable[string]             `json:"telegram_bot_token"`
+	TelegramChatID       Resettable[string]             `json:"telegram_chat_id"`
 }
 
 // handleGetConfig returns user's Gasolina config
@@ -123,14 +155,15 @@ func handleGetConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg, err := GetUserConfig(userID)
+	cfg, err := GetUserConfig(r.Context(), userID)
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get config", "user_id", userID, "error", err)
 		jsonError(w, "Failed to get config", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(cfg)
+	newJSONEncoder(w).Encode(cfg)
 }
 
 // handleUpdateConfig updates user's Gasolina config
@@ -147,35 +180,437 @@ func handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req ConfigUpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if req.LoginURL != "" {
+		if err := validateSiteURL(req.LoginURL); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid login_url: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.CronSchedule != "" {
+		if _, err := cronParser.Parse(req.CronSchedule); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid cron_schedule: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.SuccessMode != "" && !isValidSuccessMode(req.SuccessMode) {
+		jsonError(w, fmt.Sprintf("Invalid success_mode. Must be '%s' or '%s'", SuccessModeText, SuccessModeRowCount), http.StatusBadRequest)
+		return
+	}
+
+	if req.ValueSource != "" && !isValidValueSource(req.ValueSource) {
+		jsonError(w, fmt.Sprintf("Invalid value_source. Must be '%s', '%s' or a data- attribute", ValueSourceValue, ValueSourceInnerText), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := compileLandingPattern(req.LandingURLPattern, req.AccountNumber); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid landing_url_pattern: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateSubmissionHours(req.SubmissionHourStart, req.SubmissionHourEnd, req.SubmissionTimezone); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid submission hours: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateQuietHours(req.QuietHourStart, req.QuietHourEnd, req.QuietTimezone); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid quiet hours: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var increments map[int]float64
+	var serialIncrements map[string]map[int]float64
+	if len(req.MonthlyIncrements) > 0 && string(req.MonthlyIncrements) != "null" {
+		var err error
+		if increments, serialIncrements, err = parseMonthlyIncrements(req.MonthlyIncrements); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid monthly_increments: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.IncrementsURL != "" {
+		if err := validateIncrementsURL(req.IncrementsURL); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid increments_url: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := validateNotifyOn(req.NotifyOn); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid notify_on: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.TelegramBotToken.Value != "" && !telegramBotTokenPattern.MatchString(req.TelegramBotToken.Value) {
+		jsonError(w, "Invalid telegram_bot_token", http.StatusBadRequest)
+		return
+	}
+	if req.TelegramChatID.Value != "" && !telegramChatIDPattern.MatchString(req.TelegramChatID.Value) {
+		jsonError(w, "Invalid telegram_chat_id: must be a numeric ID or @channel", http.StatusBadRequest)
+		return
+	}
+
+	var selectors Selectors
+	if req.Selectors != nil {
+		selectors = *req.Selectors
+		if err := selectors.Validate(); err != nil {
+			jsonError(w, fmt.Sprintf("Invalid selectors: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.NotifyEmail.Value != "" {
+		if _, err := mail.ParseAddress(req.NotifyEmail.Value); err != nil {
+			jsonError(w, "Invalid notify_email", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := req.Fingerprint.Value.Validate(); err != nil {
+		jsonError(w, fmt.Sprintf("Invalid browser_fingerprint: %v", err), http.StatusBadRequest)
 		return
 	}
 
 	// Get existing config for defaults
-	existing, _ := GetUserConfig(userID)
+	existing, _ := GetUserConfig(r.Context(), userID)
+
+	// A sent fingerprint replaces the stored one whole: null, {} or an empty
+	// field resets to the server defaults. Same for an empty or null notify_email
+	// and Telegram bot token or chat ID, which turns that channel off.
+	fingerprint := req.Fingerprint.Or(existing.Fingerprint)
+	notifyEmail := req.NotifyEmail.Or(existing.NotifyEmail)
+	telegramBotToken := req.TelegramBotToken.Or(existing.TelegramBotToken)
+	telegramChatID := req.TelegramChatID.Or(existing.TelegramChatID)
 
 	dryRun := existing.DryRun
 	if req.DryRun != nil {
 		dryRun = *req.DryRun
 	}
 
-	if err := SaveUserConfig(
-		userID,
-		req.GasolinaEmail,
-		req.GasolinaPassword,
-		req.AccountNumber,
-		req.CheckURL,
-		req.CronSchedule,
-		dryRun,
-		req.MonthlyIncrements,
-	); err != nil {
+	skipDryRunRamp := existing.SkipDryRunRamp
+	if req.SkipDryRunRamp != nil {
+		skipDryRunRamp = *req.SkipDryRunRamp
+	}
+
+	forceSubmit := existing.ForceSubmit
+	if req.ForceSubmit != nil {
+		forceSubmit = *req.ForceSubmit
+	}
+
+	dryRunAnomalyWarning := existing.DryRunAnomalyWarning
+	if req.DryRunAnomalyWarning != nil {
+		dryRunAnomalyWarning = *req.DryRunAnomalyWarning
+	}
+
+	recheckMissingButton := existing.RecheckMissingButton
+	if req.RecheckMissingButton != nil {
+		recheckMissingButton = *req.RecheckMissingButton
+	}
+
+	incrementFallback := existing.IncrementFallback
+	if req.IncrementFallback != nil {
+		incrementFallback = *req.IncrementFallback
+	}
+
+	quietBypassCritical := existing.QuietBypassCritical
+	if req.QuietBypassCritical != nil {
+		quietBypassCritical = *req.QuietBypassCritical
+	}
+
+	if err := SaveUserConfig(&UserConfig{
+		UserID:            userID,
+		GasolinaEmail:     req.GasolinaEmail,
+		GasolinaPassword:  req.GasolinaPassword,
+		AccountNumber:     req.AccountNumber,
+		LoginURL:          req.LoginURL,
+		CheckURL:          req.CheckURL,
+		CronSchedule:      req.CronSchedule,
+		DryRun:            dryRun,
+		SuccessMode:       req.SuccessMode,
+		Fingerprint:       fingerprint,
+		MonthlyIncrements: increments,
+		SerialIncrements:  serialIncrements,
+		IncrementsURL:     req.IncrementsURL,
+		IncrementFallback: incrementFallback,
+		Selectors:         selectors,
+
+		RecheckMissingButton: recheckMissingButton,
+		NotifyEmail:          notifyEmail,
+		NotifyOn:             req.NotifyOn,
+		SkipDryRunRamp:       skipDryRunRamp,
+		ForceSubmit:          forceSubmit,
+		DryRunAnomalyWarning: dryRunAnomalyWarning,
+		SubmitButtonText:     req.SubmitButtonText,
+		SubmitButtonSelector: req.SubmitButtonSelector,
+		ValueSelector:        req.ValueSelector,
+		ValueSource:          req.ValueSource,
+		SubmissionHourStart:  req.SubmissionHourStart,
+		SubmissionHourEnd:    req.SubmissionHourEnd,
+		SubmissionTimezone:   req.SubmissionTimezone,
+		LandingURLPattern:    req.LandingURLPattern,
+		QuietHourStart:       req.QuietHourStart,
+		QuietHourEnd:         req.QuietHourEnd,
+		QuietTimezone:        req.QuietTimezone,
+		QuietBypassCritical:  quietBypassCritical,
+		TelegramBotToken:     telegramBotToken,
+		TelegramChatID:       telegramChatID,
+	}); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update config", "user_id", userID, "error", err)
 		jsonError(w, "Failed to update config", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Configuration updated"})
+	newJSONEncoder(w).Encode(map[string]string{"message": "Configuration updated"})
+}
+
+// RotateCredentialsRequest is the request body for rotating the Gasolina password
+type RotateCredentialsRequest struct {
+	GasolinaPassword string `json:"gasolina_password"`
+	Verify           bool   `json:"verify"`
+}
+
+// handleRotateCredentials replaces the stored Gasolina password, optionally
+// verifying that it can log in first. The password is never echoed back.
+func handleRotateCredentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req RotateCredentialsRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if req.GasolinaPassword == "" {
+		jsonError(w, "gasolina_password is required", http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := GetUserConfig(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get config", "user_id", userID, "error", err)
+		jsonError(w, "Failed to get config", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Verify {
+		if cfg.GasolinaEmail == "" {
+			jsonError(w, "Gasolina email not configured", http.StatusBadRequest)
+			return
+		}
+		if !allowCredentialCheck(w, userID) {
+			return
+		}
+		loginCfg := cfg.ToConfig()
+		loginCfg.Password = req.GasolinaPassword
+		// The browser login outlasts the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(credentialVerifyTimeout + 5*time.Second))
+		if err := verifyGasolinaCredentials(r.Context(), loginCfg); err != nil {
+			jsonError(w, "Login with the new password failed", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	if err := UpdateGasolinaPassword(userID, req.GasolinaPassword); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update credentials", "user_id", userID, "error", err)
+		jsonError(w, "Failed to update credentials", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]string{"message": "Credentials rotated"})
+}
+
+// credentialVerifyTimeout bounds the one-off login that verifies credentials
+const credentialVerifyTimeout = 60 * time.Second
+
+// credentialCheckLimiter caps the browser logins a user can start through
+// /api/config/validate and /api/config/rotate-credentials; every attempt counts
+var credentialCheckLimiter = NewLoginLimiter(5, 15*time.Minute)
+
+// allowCredentialCheck writes an error and returns false when a one-off
+// browser login must not start: during maintenance or past the user's limit
+func allowCredentialCheck(w http.ResponseWriter, userID int64) bool {
+	if jobManager.InMaintenance() {
+		w.Header().Set("Retry-After", "300")
+		jsonError(w, "Job processing is paused for maintenance. Please try again later.", http.StatusServiceUnavailable)
+		return false
+	}
+	key := fmt.Sprintf("user:%d", userID)
+	if retryAfter, ok := credentialCheckLimiter.Allow(key); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		jsonError(w, "Too many credential checks. Please try again later.", http.StatusTooManyRequests)
+		return false
+	}
+	credentialCheckLimiter.RecordFailure(key)
+	return true
+}
+
+// newCredentialCheckContext opens a browser tab for a one-off login. The login
+// takes one of the MAX_CONCURRENT_JOBS slots like a job, and the tab closes
+// when the client goes away.
+func newCredentialCheckContext(reqCtx context.Context) (context.Context, context.CancelFunc, error) {
+	if !jobManager.acquireSlot(reqCtx) {
+		if err := reqCtx.Err(); err != nil {
+			return nil, nil, fmt.Errorf("waiting for a free job slot: %w", err)
+		}
+		return nil, nil, errors.New("job manager is shutting down")
+	}
+	ctx, cancel, err := newJobBrowserContext(reqCtx, BrowserFingerprint{})
+	if err != nil {
+		jobManager.releaseSlot()
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(reqCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		jobManager.releaseSlot()
+	}, nil
+}
+
+// verifyGasolinaCredentials performs a one-off login to confirm the credentials work
+func verifyGasolinaCredentials(reqCtx context.Context, config *Config) error {
+	ctx, cancel, err := newCredentialCheckContext(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	ctx, timeoutCancel := context.WithTimeout(ctx, credentialVerifyTimeout)
+	defer timeoutCancel()
+
+	return GasolinaLogin(ctx, config, nil, nil)
+}
+
+// ValidateCredentialsRequest is the request body for validating Gasolina
+// credentials; empty fields fall back to the stored config
+type ValidateCredentialsRequest struct {
+	GasolinaEmail    string `json:"gasolina_email"`
+	GasolinaPassword string `json:"gasolina_password"`
+	AccountNumber    string `json:"account_number"`
+}
+
+// ValidateCredentialsResponse is the result of a credential validation
+type ValidateCredentialsResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+	// Screenshot is the page the login ended on, as a data URL
+	Screenshot string `json:"screenshot,omitempty"`
+}
+
+// credentialValidateTimeout bounds the login behind POST /api/config/validate
+const credentialValidateTimeout = 45 * time.Second
+
+// handleValidateCredentials logs in to Gasolina synchronously to check the
+// credentials, without creating a job or touching the queue. The login counts
+// against MAX_CONCURRENT_JOBS and a per-user limit.
+func handleValidateCredentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ValidateCredentialsRequest
+	if r.ContentLength != 0 && !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	cfg, err := GetUserConfig(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get config", "user_id", userID, "error", err)
+		jsonError(w, "Failed to get config", http.StatusInternalServerError)
+		return
+	}
+	loginCfg := cfg.ToConfig()
+	if req.GasolinaEmail != "" {
+		loginCfg.Email = req.GasolinaEmail
+	}
+	if req.GasolinaPassword != "" {
+		loginCfg.Password = req.GasolinaPassword
+	}
+	if req.AccountNumber != "" {
+		loginCfg.AccountNumber = req.AccountNumber
+	}
+	if loginCfg.Email == "" || loginCfg.Password == "" {
+		jsonError(w, "Gasolina email and password are required", http.StatusBadRequest)
+		return
+	}
+	if !allowCredentialCheck(w, userID) {
+		return
+	}
+
+	// The browser login outlasts the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(credentialValidateTimeout + 5*time.Second))
+	screenshot, err := loginWithScreenshot(r.Context(), loginCfg, credentialValidateTimeout)
+
+	resp := ValidateCredentialsResponse{Valid: err == nil}
+	if len(screenshot) > 0 {
+		resp.Screenshot = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(screenshot)
+	}
+	status := http.StatusOK
+	if err != nil {
+		resp.Error = describeLoginError(err)
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	newJSONEncoder(w).Encode(resp)
+}
+
+// loginWithScreenshot performs a one-off login and captures the page it ended
+// on, whether or not the login succeeded
+func loginWithScreenshot(reqCtx context.Context, config *Config, timeout time.Duration) ([]byte, error) {
+	ctx, cancel, err := newCredentialCheckContext(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	ctx, timeoutCancel := context.WithTimeout(ctx, timeout)
+	defer timeoutCancel()
+
+	loginErr := GasolinaLogin(ctx, config, nil, nil)
+
+	var buf []byte
+	if err := chromedp.Run(ctx, chromedp.FullScreenshot(&buf, 90)); err != nil {
+		slog.WarnContext(reqCtx, "Failed to capture credential validation screenshot", "error", err)
+	}
+	return buf, loginErr
+}
+
+// describeLoginError turns a login failure into a message for the user
+func describeLoginError(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return "The site rejected the email or password"
+	case errors.Is(err, ErrAccountNotFound):
+		return "No account in the dropdown matches the account number"
+	case errors.Is(err, ErrAmbiguousAccount):
+		return "Several accounts in the dropdown match the account number"
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("Login did not finish within %v", credentialValidateTimeout)
+	}
+	return fmt.Sprintf("Login failed: %v", err)
 }
 
 // CreateJobRequest is the request body for creating a job
@@ -185,10 +620,15 @@ type CreateJobRequest struct {
 
 // JobListResponse is the response for listing jobs
 type JobListResponse struct {
-	Jobs  []*Job `json:"jobs"`
-	Total int    `json:"total"`
+	Jobs   []*Job `json:"jobs"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
 }
 
+// validJobTypes are the job types a user can create
+var validJobTypes = map[string]bool{"full": true, "dry-run": true, "test-login": true, "test-check": true, "test-submit": true}
+
 // handleCreateJob creates a new job
 func handleCreateJob(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -209,15 +649,47 @@ func handleCreateJob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate job type
-	validTypes := map[string]bool{"full": true, "test-login": true, "test-check": true}
-	if !validTypes[req.Type] {
-		jsonError(w, "Invalid job type. Must be 'full', 'test-login', or 'test-check'", http.StatusBadRequest)
+	if !validJobTypes[req.Type] {
+		jsonError(w, "Invalid job type. Must be 'full', 'dry-run', 'test-login', 'test-check' or 'test-submit'", http.StatusBadRequest)
+		return
+	}
+	if req.Type == "test-submit" && !testSubmitEnabled() {
+		jsonError(w, "test-submit jobs need a sandbox: TEST_SUBMIT_SANDBOX_URL is not set on this server", http.StatusBadRequest)
+		return
+	}
+
+	queueJob(w, r, userID, req.Type)
+}
+
+// handleCreateDryRunJob queues a full job that never submits, regardless of the
+// user's dry_run setting. The job's result holds the value it would submit.
+func handleCreateDryRunJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	queueJob(w, r, userID, "dry-run")
+}
+
+// queueJob checks that the user can run jobs and queues one of jobType
+func queueJob(w http.ResponseWriter, r *http.Request, userID int64, jobType string) {
+	if jobManager.InMaintenance() {
+		w.Header().Set("Retry-After", "300")
+		jsonError(w, "Job processing is paused for maintenance. Please try again later.", http.StatusServiceUnavailable)
 		return
 	}
 
 	// Check user config
-	cfg, err := GetUserConfig(userID)
+	cfg, err := GetUserConfig(r.Context(), userID)
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get user config", "user_id", userID, "error", err)
 		jsonError(w, "Failed to get user config", http.StatusInternalServerError)
 		return
 	}
@@ -227,15 +699,84 @@ func handleCreateJob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create and queue job
-	job, err := jobManager.CreateJob(userID, req.Type)
+	job, err := jobManager.CreateJobWithinLimit(userID, jobType, JobSourceAPI, maxQueuedJobs)
+	if errors.Is(err, errJobQueueFull) {
+		jsonError(w, fmt.Sprintf("Too many jobs queued: at most %d may be pending or running. Wait for them to finish or cancel pending ones.", maxQueuedJobs), http.StatusConflict)
+		return
+	}
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create job", "user_id", userID, "error", err)
 		jsonError(w, "Failed to create job", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(job)
+	newJSONEncoder(w).Encode(job)
+}
+
+// handleCancelPendingJobs cancels all of the user's queued jobs
+func handleCancelPendingJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := jobManager.CancelPending(userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to cancel pending jobs", "user_id", userID, "error", err)
+		jsonError(w, "Failed to cancel pending jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]int{"cancelled": count})
+}
+
+// handleCancelJob cancels a queued or running job
+func handleCancelJob(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := requireOwnedJob(r.Context(), userID, jobID)
+	if err != nil {
+		writeJobLookupError(w, r, err)
+		return
+	}
+
+	wasRunning, err := jobManager.CancelJob(job)
+	if err != nil {
+		if errors.Is(err, ErrJobNotCancellable) {
+			jsonError(w, "Job has already finished", http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to cancel job", "user_id", userID, "job_id", jobID, "error", err)
+		jsonError(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if wasRunning {
+		// The job stops at its next browser step and is marked cancelled then
+		w.WriteHeader(http.StatusAccepted)
+		newJSONEncoder(w).Encode(map[string]string{"message": "Cancellation requested"})
+		return
+	}
+	newJSONEncoder(w).Encode(map[string]string{"message": "Job cancelled"})
 }
 
 // handleListJobs lists user's jobs
@@ -251,24 +792,115 @@ func handleListJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse query params
-	limit := 20
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	status := r.URL.Query().Get("status")
+	filter, err := parseJobFilter(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	jobs, total, err := GetUserJobs(userID, limit, status)
+	jobs, total, err := GetUserJobs(userID, limit, offset, filter)
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get jobs", "user_id", userID, "error", err)
 		jsonError(w, "Failed to get jobs", http.StatusInternalServerError)
 		return
 	}
 
+	setPaginationHeaders(w, r, limit, offset, total)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(JobListResponse{Jobs: jobs, Total: total})
+	newJSONEncoder(w).Encode(JobListResponse{Jobs: jobs, Total: total, Limit: limit, Offset: offset})
+}
+
+// parseJobFilter reads the status, source, type and from/to (RFC3339) job
+// list filters; to is exclusive
+func parseJobFilter(r *http.Request) (JobFilter, error) {
+	q := r.URL.Query()
+	filter := JobFilter{
+		Status: q.Get("status"),
+		Source: q.Get("source"),
+		Type:   q.Get("type"),
+	}
+
+	if filter.Source != "" && !isValidJobSource(filter.Source) {
+		return filter, fmt.Errorf("Invalid source. Must be 'api', 'schedule', 'admin', 'retry' or 'cli'")
+	}
+	if filter.Type != "" && !validJobTypes[filter.Type] {
+		return filter, fmt.Errorf("Invalid type. Must be 'full', 'dry-run', 'test-login', 'test-check' or 'test-submit'")
+	}
+
+	bounds := []struct {
+		param string
+		dst   **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}}
+	for _, b := range bounds {
+		if v := q.Get(b.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid %s. Must be an RFC3339 timestamp", b.param)
+			}
+			*b.dst = &t
+		}
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("Invalid range. from must be before to")
+	}
+
+	return filter, nil
+}
+
+// parsePagination reads the limit/offset query params (default limit 20, max 100)
+func parsePagination(r *http.Request) (int, int, error) {
+	limit, offset := 20, 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > 100 {
+			return 0, 0, fmt.Errorf("Invalid limit. Must be 1-100")
+		}
+		limit = parsed
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		parsed, err := strconv.Atoi(o)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("Invalid offset. Must be 0 or greater")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+// setPaginationHeaders sets X-Total-Count and an RFC 8288 Link header with
+// first/prev/next/last pages computed from limit, offset and total
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, limit, offset, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	pageURL := func(offset int) string {
+		u := *r.URL
+		q := u.Query()
+		q.Set("limit", strconv.Itoa(limit))
+		q.Set("offset", strconv.Itoa(offset))
+		u.RawQuery = q.Encode()
+		return u.RequestURI()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(0))}
+	if offset > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(max(offset-limit, 0))))
+	}
+	if offset+limit < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(offset+limit)))
+	}
+	if total > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL((total-1)/limit*limit)))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
 }
 
 // JobDetailResponse is the detailed job response including screenshots
@@ -277,6 +909,51 @@ type JobDetailResponse struct {
 	Screenshots []*Screenshot `json:"screenshots,omitempty"`
 }
 
+// JobResultResponse is the outcome of a job without its logs
+type JobResultResponse struct {
+	JobID       string       `json:"job_id"`
+	Type        string       `json:"type"`
+	Status      string       `json:"status"`
+	Outcome     string       `json:"outcome,omitempty"`
+	Decision    string       `json:"decision"`
+	Result      *CheckResult `json:"result,omitempty"`
+	Error       *string      `json:"error,omitempty"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
+
+// Job lookup results from requireOwnedJob
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrJobForbidden = errors.New("job belongs to another user")
+)
+
+// requireOwnedJob loads a job and checks that it belongs to userID
+func requireOwnedJob(ctx context.Context, userID int64, jobID string) (*Job, error) {
+	job, err := GetJob(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	if job.UserID != userID {
+		return nil, ErrJobForbidden
+	}
+	return job, nil
+}
+
+// writeJobLookupError responds to a requireOwnedJob failure. Other users' jobs
+// are reported as not found so job IDs can't be probed.
+func writeJobLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrJobNotFound), errors.Is(err, ErrJobForbidden):
+		jsonError(w, "Job not found", http.StatusNotFound)
+	default:
+		slog.ErrorContext(r.Context(), "Failed to get job", "error", err)
+		jsonError(w, "Failed to get job", http.StatusInternalServerError)
+	}
+}
+
 // handleGetJob returns job details
 func handleGetJob(w http.ResponseWriter, r *http.Request, jobID string) {
 	if r.Method != http.MethodGet {
@@ -290,30 +967,172 @@ func handleGetJob(w http.ResponseWriter, r *http.Request, jobID string) {
 		return
 	}
 
-	job, err := GetJob(jobID)
+	job, err := requireOwnedJob(r.Context(), userID, jobID)
 	if err != nil {
-		jsonError(w, "Failed to get job", http.StatusInternalServerError)
+		writeJobLookupError(w, r, err)
 		return
 	}
-	if job == nil {
-		jsonError(w, "Job not found", http.StatusNotFound)
+
+	// Get screenshots
+	screenshots, _ := GetJobScreenshots(jobID)
+	for _, s := range screenshots {
+		s.URL = fmt.Sprintf("/api/screenshots/%s/%s", jobID, s.Filename)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(JobDetailResponse{Job: job, Screenshots: screenshots})
+}
+
+// maxJobNoteLength is the longest note accepted on a job, in characters
+const maxJobNoteLength = 1000
+
+// JobNoteRequest is the request body for setting a job's note
+type JobNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// handleSetJobNote sets or clears the user's note on a job
+func handleSetJobNote(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPut {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Verify ownership
-	if job.UserID != userID {
-		jsonError(w, "Job not found", http.StatusNotFound)
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	// Get screenshots
-	screenshots, _ := GetJobScreenshots(jobID)
-	for _, s := range screenshots {
-		s.URL = fmt.Sprintf("/api/screenshots/%s/%s", jobID, s.Filename)
+	var req JobNoteRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+	if n := utf8.RuneCountInString(req.Note); n > maxJobNoteLength {
+		jsonError(w, fmt.Sprintf("Note is too long (%d characters, max %d)", n, maxJobNoteLength), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := requireOwnedJob(r.Context(), userID, jobID); err != nil {
+		writeJobLookupError(w, r, err)
+		return
+	}
+
+	if err := SetJobNote(jobID, req.Note); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save note", "user_id", userID, "job_id", jobID, "error", err)
+		jsonError(w, "Failed to save note", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]string{"note": req.Note})
+}
+
+// handleGetJobResult returns a job's structured result and decision summary
+func handleGetJobResult(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := requireOwnedJob(r.Context(), userID, jobID)
+	if err != nil {
+		writeJobLookupError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(JobDetailResponse{Job: job, Screenshots: screenshots})
+	newJSONEncoder(w).Encode(JobResultResponse{
+		JobID:       job.ID,
+		Type:        job.Type,
+		Status:      job.Status,
+		Outcome:     job.Outcome,
+		Decision:    describeJobDecision(job),
+		Result:      job.Result,
+		Error:       job.Error,
+		CompletedAt: job.CompletedAt,
+	})
+}
+
+// describeJobDecision summarizes in one sentence what a job decided to do
+func describeJobDecision(job *Job) string {
+	switch {
+	case job.Status == "pending" || job.Status == "running":
+		return "The job has not finished yet"
+	case job.Status == "cancelled":
+		return "The job was cancelled"
+	case job.Status == "failed":
+		return "The job failed; nothing is known to have been submitted"
+	case job.Outcome == OutcomeAlreadyExists:
+		return "A reading for this month was already recorded; nothing was submitted"
+	case job.Outcome == OutcomeNoChange:
+		return "The reading would not change; nothing was submitted"
+	case job.Result == nil:
+		return "The job completed without computing a reading"
+	}
+
+	r := job.Result
+	calc := fmt.Sprintf("%s (previous %s + increment %s)",
+		formatReading(r.NewValue), formatReading(r.PreviousValue), formatReading(r.Increment))
+	if r.Anomaly != "" {
+		return "Dry run: " + calc + " fails the sanity checks and a live run would abort: " + r.Anomaly
+	}
+	if r.Submitted {
+		return "Submitted " + calc
+	}
+	return "Dry run: would submit " + calc
+}
+
+// handleDeleteJob deletes a finished or queued job together with its screenshots
+func handleDeleteJob(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodDelete {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := requireOwnedJob(r.Context(), userID, jobID)
+	if err != nil {
+		writeJobLookupError(w, r, err)
+		return
+	}
+	if job.Status == "running" {
+		jsonError(w, "Job is running and can't be deleted", http.StatusConflict)
+		return
+	}
+
+	// The status check in the query catches a job that started since we looked
+	if err := DeleteJob(jobID); err != nil {
+		if errors.Is(err, ErrJobRunning) {
+			jsonError(w, "Job is running and can't be deleted", http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to delete job", "user_id", userID, "job_id", jobID, "error", err)
+		jsonError(w, "Failed to delete job", http.StatusInternalServerError)
+		return
+	}
+
+	dir := filepath.Join(screenshotsPath, fmt.Sprintf("%d", userID), jobID)
+	if err := os.RemoveAll(dir); err != nil {
+		// The job is gone; leftover files are only wasted space
+		slog.WarnContext(r.Context(), "Failed to remove job screenshots", "job_id", jobID, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]string{"message": "Job deleted"})
 }
 
 // handleListScreenshots lists screenshots for a job
@@ -329,15 +1148,14 @@ func handleListScreenshots(w http.ResponseWriter, r *http.Request, jobID string)
 		return
 	}
 
-	// Verify job ownership
-	job, err := GetJob(jobID)
-	if err != nil || job == nil || job.UserID != userID {
-		jsonError(w, "Job not found", http.StatusNotFound)
+	if _, err := requireOwnedJob(r.Context(), userID, jobID); err != nil {
+		writeJobLookupError(w, r, err)
 		return
 	}
 
 	screenshots, err := GetJobScreenshots(jobID)
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get screenshots", "user_id", userID, "job_id", jobID, "error", err)
 		jsonError(w, "Failed to get screenshots", http.StatusInternalServerError)
 		return
 	}
@@ -347,7 +1165,7 @@ func handleListScreenshots(w http.ResponseWriter, r *http.Request, jobID string)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(screenshots)
+	newJSONEncoder(w).Encode(screenshots)
 }
 
 // handleGetScreenshot serves a screenshot file
@@ -363,16 +1181,14 @@ func handleGetScreenshot(w http.ResponseWriter, r *http.Request, jobID, filename
 		return
 	}
 
-	// Verify job ownership
-	job, err := GetJob(jobID)
-	if err != nil || job == nil || job.UserID != userID {
-		jsonError(w, "Job not found", http.StatusNotFound)
+	if _, err := requireOwnedJob(r.Context(), userID, jobID); err != nil {
+		writeJobLookupError(w, r, err)
 		return
 	}
 
 	// Sanitize filename to prevent path traversal
-	filename = filepath.Base(filename)
-	if filename == "." || filename == ".." {
+	filename, ok = sanitizeFilename(filename)
+	if !ok {
 		jsonError(w, "Invalid filename", http.StatusBadRequest)
 		return
 	}
@@ -390,6 +1206,7 @@ func handleGetScreenshot(w http.ResponseWriter, r *http.Request, jobID, filename
 	// Open and serve file
 	file, err := os.Open(filePath)
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to open screenshot", "user_id", userID, "job_id", jobID, "error", err)
 		jsonError(w, "Failed to open screenshot", http.StatusInternalServerError)
 		return
 	}
@@ -403,15 +1220,46 @@ func handleGetScreenshot(w http.ResponseWriter, r *http.Request, jobID, filename
 		contentType = "image/jpeg"
 	}
 
-	w.Header().Set("Content-Type", contentType)
+	if strings.HasSuffix(filename, ".html") {
+		setHTMLDumpHeaders(w, r)
+	} else {
+		w.Header().Set("Content-Type", contentType)
+	}
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
 	io.Copy(w, file)
 }
 
-// handleHealth returns service health status
+// sanitizeFilename strips any directory components to prevent path traversal
+func sanitizeFilename(filename string) (string, bool) {
+	filename = filepath.Base(filename)
+	if filename == "." || filename == ".." || filename == string(filepath.Separator) {
+		return "", false
+	}
+	return filename, true
+}
+
+// healthCheckTimeout bounds the database ping of a readiness check
+const healthCheckTimeout = 2 * time.Second
+
+// handleLivez reports that the process is up; it checks no dependencies
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleHealth reports readiness: 503 when the database is unreachable
 func handleHealth(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	if err := db.PingContext(ctx); err != nil {
+		slog.WarnContext(r.Context(), "Health check failed: database unreachable", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		newJSONEncoder(w).Encode(map[string]string{"status": "unavailable", "db": "down"})
+		return
+	}
+	newJSONEncoder(w).Encode(map[string]string{"status": "ok", "db": "up"})
 }
 
 // handleStatus returns service status (protected)
@@ -427,29 +1275,35 @@ func handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg, _ := GetUserConfig(userID)
-	jobs, _, _ := GetUserJobs(userID, 5, "")
+	cfg, _ := GetUserConfig(r.Context(), userID)
+	jobs, _, _ := GetUserJobs(userID, 5, 0, JobFilter{})
+	scraping, _ := GetScrapingHealth(userID)
+	broken, _ := ListBrokenScraping()
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	newJSONEncoder(w).Encode(map[string]interface{}{
 		"configured":  cfg.Configured,
 		"recent_jobs": jobs,
+		"maintenance": jobManager.InMaintenance(),
+		"scraping":    scraping,
+		// Any user flagged means the site probably changed for everyone
+		"scraping_broken": len(broken) > 0,
 	})
 }
 
 // GasolinaUserInfo contains data scraped from gasolina-online.com
 type GasolinaUserInfo struct {
-	UserName           string `json:"user_name"`
-	UserAddress        string `json:"user_address"`
+	UserName             string `json:"user_name"`
+	UserAddress          string `json:"user_address"`
 	GasDistributionPrice string `json:"gas_distribution_price"`
 	GasDistributionDebt  string `json:"gas_distribution_debt"`
 	GasDistributionDate  string `json:"gas_distribution_date"`
-	CounterNumber      string `json:"counter_number"`
-	CounterType        string `json:"counter_type"`
-	PreviousReading    string `json:"previous_reading"`
-	TechServiceDebt    string `json:"tech_service_debt"`
-	TechServiceDate    string `json:"tech_service_date"`
-	FetchedAt          string `json:"fetched_at"`
+	CounterNumber        string `json:"counter_number"`
+	CounterType          string `json:"counter_type"`
+	PreviousReading      string `json:"previous_reading"`
+	TechServiceDebt      string `json:"tech_service_debt"`
+	TechServiceDate      string `json:"tech_service_date"`
+	FetchedAt            string `json:"fetched_at"`
 }
 
 // handleGetGasolinaInfo fetches user info from gasolina-online.com
@@ -466,8 +1320,9 @@ func handleGetGasolinaInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user config for Gasolina credentials
-	cfg, err := GetUserConfig(userID)
+	cfg, err := GetUserConfig(r.Context(), userID)
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get user config", "user_id", userID, "error", err)
 		jsonError(w, "Failed to get user config", http.StatusInternalServerError)
 		return
 	}
@@ -478,18 +1333,19 @@ func handleGetGasolinaInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch data from gasolina-online.com
-	info, err := fetchGasolinaUserInfo(cfg.GasolinaEmail, cfg.GasolinaPassword, cfg.AccountNumber)
+	info, err := fetchGasolinaUserInfo(cfg.ToConfig())
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch data", "user_id", userID, "error", err)
 		jsonError(w, fmt.Sprintf("Failed to fetch data: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(info)
+	newJSONEncoder(w).Encode(info)
 }
 
 // fetchGasolinaUserInfo logs into gasolina-online.com and scrapes user info
-func fetchGasolinaUserInfo(email, password, accountNumber string) (*GasolinaUserInfo, error) {
+func fetchGasolinaUserInfo(config *Config) (*GasolinaUserInfo, error) {
 	// Create browser context
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", true),
@@ -509,7 +1365,7 @@ func fetchGasolinaUserInfo(email, password, accountNumber string) (*GasolinaUser
 	defer cancel()
 
 	// Login first
-	err := Login(ctx, email, password, accountNumber)
+	err := Login(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("login failed: %w", err)
 	}
@@ -521,7 +1377,7 @@ func fetchGasolinaUserInfo(email, password, accountNumber string) (*GasolinaUser
 	var techDebt, techDate string
 
 	err = chromedp.Run(ctx,
-		chromedp.Navigate("https://gasolina-online.com/"),
+		chromedp.Navigate(defaultBaseURL),
 		chromedp.Sleep(2*time.Second),
 		chromedp.WaitReady("body"),
 
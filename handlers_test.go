@@ -0,0 +1,607 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newAuthedRequest builds a request as AuthMiddleware would hand it to a
+// handler; userID 0 leaves the user out of the context
+func newAuthedRequest(method, target, body string, userID int64) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if userID != 0 {
+		r = r.WithContext(context.WithValue(r.Context(), userIDKey, userID))
+	}
+	return r
+}
+
+// errorMessage returns the "error" field of a JSON error response
+func errorMessage(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not JSON: %v (%q)", err, rec.Body.String())
+	}
+	return body.Error
+}
+
+func TestHandleRotateCredentialsRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		userID     int64
+		wantStatus int
+		wantError  string
+	}{
+		{"wrong method", http.MethodGet, "", 1, http.StatusMethodNotAllowed, "Method not allowed"},
+		{"no user", http.MethodPost, `{"gasolina_password":"x"}`, 0, http.StatusUnauthorized, "User not found in context"},
+		{"empty body", http.MethodPost, "", 1, http.StatusBadRequest, "Request body is empty"},
+		{"unknown field", http.MethodPost, `{"password":"x"}`, 1, http.StatusBadRequest, `Unknown field "password"`},
+		{"missing password", http.MethodPost, `{"verify":true}`, 1, http.StatusBadRequest, "gasolina_password is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleRotateCredentials(rec, newAuthedRequest(tt.method, "/api/config/rotate-credentials", tt.body, tt.userID))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := errorMessage(t, rec); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateConfigRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantError string
+	}{
+		{"unknown success mode", `{"success_mode":"screenshot"}`, "Invalid success_mode. Must be 'text' or 'row_count'"},
+		{"seconds field while disabled", `{"cron_schedule":"0 0 9 1 * *"}`, "Invalid cron_schedule"},
+		{"bad notify email", `{"notify_email":"not an address"}`, "Invalid notify_email"},
+		{"unknown value source", `{"value_source":"href"}`, "Invalid value_source"},
+		{"submission hour start only", `{"submission_hour_start":8}`, "Invalid submission hours"},
+		{"unknown submission timezone", `{"submission_timezone":"Mars/Olympus"}`, "Invalid submission hours"},
+		{"bad increment month", `{"monthly_increments":{"13":5}}`, `Invalid monthly_increments: invalid month key "13"`},
+		{"bad landing pattern", `{"landing_url_pattern":"/account/("}`, "Invalid landing_url_pattern"},
+		{"unknown notify outcome", `{"notify_on":["timeout"]}`, "Invalid notify_on"},
+		{"half a viewport", `{"browser_fingerprint":{"viewport_width":1024}}`, "Invalid browser_fingerprint: viewport width and height must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleUpdateConfig(rec, newAuthedRequest(http.MethodPut, "/api/config", tt.body, 1))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (%s)", rec.Code, rec.Body.String())
+			}
+			if got := errorMessage(t, rec); !strings.HasPrefix(got, tt.wantError) {
+				t.Errorf("error = %q, want prefix %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query                 string
+		wantLimit, wantOffset int
+		wantErr               bool
+	}{
+		{"", 20, 0, false},
+		{"limit=50&offset=100", 50, 100, false},
+		{"limit=1", 1, 0, false},
+		{"limit=100", 100, 0, false},
+		{"limit=0", 0, 0, true},
+		{"limit=101", 0, 0, true},
+		{"limit=ten", 0, 0, true},
+		{"offset=-1", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			limit, offset, err := parsePagination(httptest.NewRequest(http.MethodGet, "/api/jobs?"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePagination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if limit != tt.wantLimit || offset != tt.wantOffset {
+				t.Errorf("parsePagination() = %d, %d, want %d, %d", limit, offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestParseJobFilter(t *testing.T) {
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query   string
+		want    JobFilter
+		wantErr string
+	}{
+		{"", JobFilter{}, ""},
+		{"status=failed&source=schedule&type=dry-run", JobFilter{Status: "failed", Source: JobSourceSchedule, Type: "dry-run"}, ""},
+		{"from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z", JobFilter{From: &from, To: &to}, ""},
+		{"from=2026-03-01T00:00:00Z", JobFilter{From: &from}, ""},
+		{"type=screenshot", JobFilter{}, "Invalid type"},
+		{"source=webhook", JobFilter{}, "Invalid source"},
+		{"from=yesterday", JobFilter{}, "Invalid from"},
+		{"to=2026-04-01", JobFilter{}, "Invalid to"},
+		{"from=bad&to=bad", JobFilter{}, "Invalid from"},
+		{"from=2026-04-01T00:00:00Z&to=2026-03-01T00:00:00Z", JobFilter{}, "Invalid range"},
+		{"from=2026-03-01T00:00:00Z&to=2026-03-01T00:00:00Z", JobFilter{}, "Invalid range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseJobFilter(httptest.NewRequest(http.MethodGet, "/api/jobs?"+tt.query, nil))
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("parseJobFilter() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJobFilter() error = %v", err)
+			}
+			if got.Status != tt.want.Status || got.Source != tt.want.Source || got.Type != tt.want.Type ||
+				!equalTimePtr(got.From, tt.want.From) || !equalTimePtr(got.To, tt.want.To) {
+				t.Errorf("parseJobFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// equalTimePtr reports whether a and b are both nil or the same instant
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestHandleListJobsRejectsInvalidFilters(t *testing.T) {
+	for _, query := range []string{"type=screenshot", "from=yesterday", "from=2026-04-01T00:00:00Z&to=2026-03-01T00:00:00Z"} {
+		rec := httptest.NewRecorder()
+		handleListJobs(rec, newAuthedRequest(http.MethodGet, "/api/jobs?"+query, "", 1))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestHandleListJobsRejectsInvalidSource(t *testing.T) {
+	tests := []string{"webhook", "API", "schedule,api"}
+	for _, source := range tests {
+		rec := httptest.NewRecorder()
+		handleListJobs(rec, newAuthedRequest(http.MethodGet, "/api/jobs?source="+source, "", 1))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("source=%s: status = %d, want %d", source, rec.Code, http.StatusBadRequest)
+		}
+		if msg := errorMessage(t, rec); !strings.HasPrefix(msg, "Invalid source") {
+			t.Errorf("source=%s: error = %q", source, msg)
+		}
+	}
+}
+
+func TestSetPaginationHeaders(t *testing.T) {
+	tests := []struct {
+		name                 string
+		limit, offset, total int
+		wantLink             string
+	}{
+		{"empty", 20, 0, 0, `</api/jobs?limit=20&offset=0&status=failed>; rel="first"`},
+		{"single page", 20, 0, 5, `</api/jobs?limit=20&offset=0&status=failed>; rel="first", </api/jobs?limit=20&offset=0&status=failed>; rel="last"`},
+		{"first of three", 10, 0, 25, `</api/jobs?limit=10&offset=0&status=failed>; rel="first", </api/jobs?limit=10&offset=10&status=failed>; rel="next", </api/jobs?limit=10&offset=20&status=failed>; rel="last"`},
+		{"middle page", 10, 10, 25, `</api/jobs?limit=10&offset=0&status=failed>; rel="first", </api/jobs?limit=10&offset=0&status=failed>; rel="prev", </api/jobs?limit=10&offset=20&status=failed>; rel="next", </api/jobs?limit=10&offset=20&status=failed>; rel="last"`},
+		{"unaligned offset", 10, 5, 25, `</api/jobs?limit=10&offset=0&status=failed>; rel="first", </api/jobs?limit=10&offset=0&status=failed>; rel="prev", </api/jobs?limit=10&offset=15&status=failed>; rel="next", </api/jobs?limit=10&offset=20&status=failed>; rel="last"`},
+		{"exact last page", 10, 20, 30, `</api/jobs?limit=10&offset=0&status=failed>; rel="first", </api/jobs?limit=10&offset=10&status=failed>; rel="prev", </api/jobs?limit=10&offset=20&status=failed>; rel="last"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/jobs?status=failed&offset=3", nil)
+			setPaginationHeaders(rec, r, tt.limit, tt.offset, tt.total)
+			if got, want := rec.Header().Get("X-Total-Count"), strconv.Itoa(tt.total); got != want {
+				t.Errorf("X-Total-Count = %q, want %q", got, want)
+			}
+			if got := rec.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link =\n  %s\nwant\n  %s", got, tt.wantLink)
+			}
+		})
+	}
+}
+
+func TestWriteJobLookupError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{"not found", ErrJobNotFound, http.StatusNotFound, "Job not found"},
+		{"other user's job looks missing", ErrJobForbidden, http.StatusNotFound, "Job not found"},
+		{"database error", fmt.Errorf("failed to get job: %w", errors.New("connection reset")), http.StatusInternalServerError, "Failed to get job"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeJobLookupError(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/x", nil), tt.err)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := errorMessage(t, rec); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRequireOwnedJob(t *testing.T) {
+	owner := createTestUser(t)
+	other := createTestUser(t)
+	job := createTestJob(t, owner.ID, "full")
+
+	tests := []struct {
+		name    string
+		userID  int64
+		jobID   string
+		wantErr error
+	}{
+		{"owner", owner.ID, job.ID, nil},
+		{"other user", other.ID, job.ID, ErrJobForbidden},
+		{"missing job", owner.ID, uuid.NewString(), ErrJobNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := requireOwnedJob(context.Background(), tt.userID, tt.jobID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("requireOwnedJob() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.ID != job.ID {
+				t.Errorf("requireOwnedJob() = job %s, want %s", got.ID, job.ID)
+			}
+		})
+	}
+}
+
+func TestHandleCreateJobRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		userID     int64
+		wantStatus int
+		wantError  string
+	}{
+		{"wrong method", http.MethodGet, "", 1, http.StatusMethodNotAllowed, "Method not allowed"},
+		{"no user", http.MethodPost, `{"type":"dry-run"}`, 0, http.StatusUnauthorized, "User not found in context"},
+		{"malformed body", http.MethodPost, `{"type":`, 1, http.StatusBadRequest, "Invalid request body"},
+		{"unknown type", http.MethodPost, `{"type":"preview"}`, 1, http.StatusBadRequest, "Invalid job type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleCreateJob(rec, newAuthedRequest(tt.method, "/api/jobs", tt.body, tt.userID))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := errorMessage(t, rec); !strings.HasPrefix(got, tt.wantError) {
+				t.Errorf("error = %q, want prefix %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleSetJobNoteRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		userID     int64
+		wantStatus int
+		wantError  string
+	}{
+		{"wrong method", http.MethodPost, `{"note":"x"}`, 1, http.StatusMethodNotAllowed, "Method not allowed"},
+		{"no user", http.MethodPut, `{"note":"x"}`, 0, http.StatusUnauthorized, "User not found in context"},
+		{"unknown field", http.MethodPut, `{"text":"x"}`, 1, http.StatusBadRequest, `Unknown field "text"`},
+		{"too long", http.MethodPut, `{"note":"` + strings.Repeat("ї", maxJobNoteLength+1) + `"}`, 1, http.StatusBadRequest,
+			fmt.Sprintf("Note is too long (%d characters, max %d)", maxJobNoteLength+1, maxJobNoteLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleSetJobNote(rec, newAuthedRequest(tt.method, "/api/jobs/x/note", tt.body, tt.userID), "x")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := errorMessage(t, rec); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleSetJobNote(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "full")
+
+	tests := []struct {
+		name     string
+		body     string
+		wantNote string
+	}{
+		{"set", `{"note":"  meter was replaced  "}`, "meter was replaced"},
+		{"max length", `{"note":"` + strings.Repeat("ї", maxJobNoteLength) + `"}`, strings.Repeat("ї", maxJobNoteLength)},
+		{"clear", `{"note":""}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleSetJobNote(rec, newAuthedRequest(http.MethodPut, "/api/jobs/"+job.ID+"/note", tt.body, user.ID), job.ID)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (%s)", rec.Code, rec.Body.String())
+			}
+			got, err := GetJob(context.Background(), job.ID)
+			if err != nil {
+				t.Fatalf("GetJob: %v", err)
+			}
+			if got.Note != tt.wantNote {
+				t.Errorf("note = %q, want %q", got.Note, tt.wantNote)
+			}
+		})
+	}
+
+	other := createTestUser(t)
+	rec := httptest.NewRecorder()
+	handleSetJobNote(rec, newAuthedRequest(http.MethodPut, "/api/jobs/"+job.ID+"/note", `{"note":"x"}`, other.ID), job.ID)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("other user's note: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDescribeJobDecision(t *testing.T) {
+	result := &CheckResult{PreviousValue: 1000, Increment: 25.5, NewValue: 1025.5}
+	tests := []struct {
+		name string
+		job  Job
+		want string
+	}{
+		{"running", Job{Status: "running"}, "The job has not finished yet"},
+		{"cancelled", Job{Status: "cancelled"}, "The job was cancelled"},
+		{"failed", Job{Status: "failed", Result: result}, "The job failed; nothing is known to have been submitted"},
+		{"already recorded", Job{Status: "completed", Outcome: OutcomeAlreadyExists},
+			"A reading for this month was already recorded; nothing was submitted"},
+		{"no change", Job{Status: "completed", Outcome: OutcomeNoChange}, "The reading would not change; nothing was submitted"},
+		{"no result", Job{Status: "completed", Outcome: OutcomeSuccess}, "The job completed without computing a reading"},
+		{"submitted", Job{Status: "completed", Outcome: OutcomeSuccess,
+			Result: &CheckResult{PreviousValue: 1000, Increment: 25.5, NewValue: 1025.5, Submitted: true}},
+			"Submitted 1025.5 (previous 1000 + increment 25.5)"},
+		{"dry run", Job{Status: "completed", Outcome: OutcomeSuccess, Result: result},
+			"Dry run: would submit 1025.5 (previous 1000 + increment 25.5)"},
+		{"dry run anomaly", Job{Status: "completed", Outcome: OutcomeSuccess,
+			Result: &CheckResult{PreviousValue: 1000, Increment: 25.5, NewValue: 1025.5, DryRun: true, Anomaly: "increment too large"}},
+			"Dry run: 1025.5 (previous 1000 + increment 25.5) fails the sanity checks and a live run would abort: increment too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeJobDecision(&tt.job); got != tt.want {
+				t.Errorf("describeJobDecision() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleGetJobResult(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "dry-run")
+	result := &CheckResult{PreviousValue: 1000, Increment: 25, NewValue: 1025, DryRun: true}
+	if err := UpdateJobResult(job.ID, result); err != nil {
+		t.Fatalf("UpdateJobResult: %v", err)
+	}
+	if err := UpdateJobStatus(job.ID, "completed", nil); err != nil {
+		t.Fatalf("UpdateJobStatus: %v", err)
+	}
+	if err := SetJobOutcome(job.ID, OutcomeSuccess); err != nil {
+		t.Fatalf("SetJobOutcome: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handleGetJobResult(rec, newAuthedRequest(http.MethodGet, "/api/jobs/"+job.ID+"/result", "", user.ID), job.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var resp JobResultResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "completed" || resp.Outcome != OutcomeSuccess || resp.Result == nil || resp.Result.NewValue != 1025 {
+		t.Errorf("response = %+v, want the completed dry-run result", resp)
+	}
+	if want := "Dry run: would submit 1025 (previous 1000 + increment 25)"; resp.Decision != want {
+		t.Errorf("decision = %q, want %q", resp.Decision, want)
+	}
+}
+
+func TestHandleCancelJob(t *testing.T) {
+	prev := jobManager
+	t.Cleanup(func() { jobManager = prev })
+	jobManager = NewJobManagerWithExecutor(&fakeExecutor{})
+
+	user := createTestUser(t)
+	other := createTestUser(t)
+	tests := []struct {
+		name       string
+		method     string
+		status     string
+		userID     int64
+		wantStatus int
+	}{
+		{"queued job", http.MethodPost, "pending", user.ID, http.StatusOK},
+		{"finished job", http.MethodPost, "completed", user.ID, http.StatusConflict},
+		{"someone else's job", http.MethodPost, "pending", other.ID, http.StatusNotFound},
+		{"wrong method", http.MethodGet, "pending", user.ID, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := createTestJob(t, user.ID, "full")
+			if tt.status != "pending" {
+				if err := UpdateJobStatus(job.ID, tt.status, nil); err != nil {
+					t.Fatalf("UpdateJobStatus: %v", err)
+				}
+			}
+			rec := httptest.NewRecorder()
+			handleCancelJob(rec, newAuthedRequest(tt.method, "/api/jobs/"+job.ID+"/cancel", "", tt.userID), job.ID)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandleDeleteJob(t *testing.T) {
+	prevPath := screenshotsPath
+	SetScreenshotsPath(t.TempDir())
+	t.Cleanup(func() { SetScreenshotsPath(prevPath) })
+
+	user := createTestUser(t)
+	other := createTestUser(t)
+	tests := []struct {
+		name        string
+		status      string
+		userID      int64
+		wantStatus  int
+		wantDeleted bool
+	}{
+		{"finished job", "completed", user.ID, http.StatusOK, true},
+		{"queued job", "pending", user.ID, http.StatusOK, true},
+		{"running job", "running", user.ID, http.StatusConflict, false},
+		{"someone else's job", "completed", other.ID, http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := createTestJob(t, user.ID, "full")
+			if tt.status != "pending" {
+				if err := UpdateJobStatus(job.ID, tt.status, nil); err != nil {
+					t.Fatalf("UpdateJobStatus: %v", err)
+				}
+			}
+			dir := filepath.Join(screenshotsPath, fmt.Sprintf("%d", user.ID), job.ID)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "01_login.png"), []byte("png"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			handleDeleteJob(rec, newAuthedRequest(http.MethodDelete, "/api/jobs/"+job.ID, "", tt.userID), job.ID)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			got, err := GetJob(context.Background(), job.ID)
+			if err != nil {
+				t.Fatalf("GetJob: %v", err)
+			}
+			if deleted := got == nil; deleted != tt.wantDeleted {
+				t.Errorf("job row deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			_, statErr := os.Stat(dir)
+			if removed := os.IsNotExist(statErr); removed != tt.wantDeleted {
+				t.Errorf("screenshot dir removed = %v, want %v", removed, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateConfigClearsTelegram(t *testing.T) {
+	user := createTestUser(t)
+	setEncryptionKeyForTest(t, "test-secret")
+
+	tests := []struct {
+		name      string
+		body      string
+		wantToken string
+		wantChat  string
+	}{
+		{"set", `{"telegram_bot_token":"` + testTelegramToken + `","telegram_chat_id":"42"}`, testTelegramToken, "42"},
+		{"omitted keeps", `{"account_number":"12345"}`, testTelegramToken, "42"},
+		{"null clears the chat", `{"telegram_chat_id":null}`, testTelegramToken, ""},
+		{"empty clears the token", `{"telegram_bot_token":""}`, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleUpdateConfig(rec, newAuthedRequest(http.MethodPut, "/api/config", tt.body, user.ID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.name, rec.Code, rec.Body)
+		}
+		cfg, err := GetUserConfig(context.Background(), user.ID)
+		if err != nil {
+			t.Fatalf("GetUserConfig: %v", err)
+		}
+		if cfg.TelegramBotToken != tt.wantToken || cfg.TelegramChatID != tt.wantChat {
+			t.Errorf("%s: telegram = %q, %q, want %q, %q", tt.name, cfg.TelegramBotToken, cfg.TelegramChatID, tt.wantToken, tt.wantChat)
+		}
+		if cfg.TelegramBotTokenSet != (tt.wantToken != "") {
+			t.Errorf("%s: TelegramBotTokenSet = %v", tt.name, cfg.TelegramBotTokenSet)
+		}
+	}
+}
+
+func TestHandleLivez(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleLivez(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"status":"ok"}` {
+		t.Errorf("livez = %d %s, want 200 {\"status\":\"ok\"}", rec.Code, rec.Body)
+	}
+}
+
+func TestHandleHealthDatabaseDown(t *testing.T) {
+	closed, err := sql.Open("postgres", "postgres://localhost/none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	prev := db
+	t.Cleanup(func() { db = prev })
+	db = closed
+
+	rec := httptest.NewRecorder()
+	handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body["status"] != "unavailable" || body["db"] != "down" {
+		t.Errorf("body = %v, want status unavailable, db down", body)
+	}
+}
+
+func TestHandleHealthDatabaseUp(t *testing.T) {
+	requireTestDB(t)
+	rec := httptest.NewRecorder()
+	handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"db":"up","status":"ok"}` {
+		t.Errorf("health = %d %s, want 200 with db up", rec.Code, rec.Body)
+	}
+}
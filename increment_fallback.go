@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Increment fallback modes for months without a configured increment
+const (
+	IncrementFallbackNone        = ""
+	IncrementFallbackDefault     = "default"
+	IncrementFallbackAverage     = "average"
+	IncrementFallbackInterpolate = "interpolate"
+)
+
+// IncrementFallback decides the increment of a month missing from
+// MonthlyIncrements. Written as text it is a number (a default increment),
+// "average" or "interpolate"; empty means a missing month is an error.
+type IncrementFallback struct {
+	Mode  string
+	Value float64 // the increment in default mode
+}
+
+// ParseIncrementFallback parses the text form of an increment fallback
+func ParseIncrementFallback(s string) (IncrementFallback, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case IncrementFallbackNone:
+		return IncrementFallback{}, nil
+	case IncrementFallbackAverage:
+		return IncrementFallback{Mode: IncrementFallbackAverage}, nil
+	case IncrementFallbackInterpolate:
+		return IncrementFallback{Mode: IncrementFallbackInterpolate}, nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || !validIncrement(value) {
+		return IncrementFallback{}, fmt.Errorf("must be a number from 0 to %g, %q or %q, got %q",
+			maxIncrement, IncrementFallbackAverage, IncrementFallbackInterpolate, s)
+	}
+	return IncrementFallback{Mode: IncrementFallbackDefault, Value: value}, nil
+}
+
+// String returns the text form read by ParseIncrementFallback
+func (f IncrementFallback) String() string {
+	if f.Mode == IncrementFallbackDefault {
+		return strconv.FormatFloat(f.Value, 'f', -1, 64)
+	}
+	return f.Mode
+}
+
+// MarshalText encodes the fallback as its text form in JSON
+func (f IncrementFallback) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText decodes the text form of a fallback
+func (f *IncrementFallback) UnmarshalText(text []byte) error {
+	parsed, err := ParseIncrementFallback(string(text))
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
+
+// incrementFor derives the increment of a month missing from increments
+func (f IncrementFallback) incrementFor(increments map[int]float64, month int) (float64, bool) {
+	switch f.Mode {
+	case IncrementFallbackDefault:
+		return f.Value, true
+	case IncrementFallbackAverage:
+		if len(increments) == 0 {
+			return 0, false
+		}
+		var sum float64
+		for _, increment := range increments {
+			sum += increment
+		}
+		return sum / float64(len(increments)), true
+	case IncrementFallbackInterpolate:
+		return interpolateIncrement(increments, month)
+	}
+	return 0, false
+}
+
+// interpolateIncrement linearly interpolates a month's increment between the
+// nearest configured months before and after it, wrapping around the year
+func interpolateIncrement(increments map[int]float64, month int) (float64, bool) {
+	if len(increments) == 0 {
+		return 0, false
+	}
+	var before, after float64
+	var distBefore, distAfter int
+	for d := 1; d < 12; d++ {
+		if v, ok := increments[(month-d+11)%12+1]; ok && distBefore == 0 {
+			before, distBefore = v, d
+		}
+		if v, ok := increments[(month+d-1)%12+1]; ok && distAfter == 0 {
+			after, distAfter = v, d
+		}
+	}
+	return before + (after-before)*float64(distBefore)/float64(distBefore+distAfter), true
+}
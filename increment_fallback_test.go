@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseIncrementFallback(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    IncrementFallback
+		wantErr bool
+	}{
+		{"", IncrementFallback{}, false},
+		{"  ", IncrementFallback{}, false},
+		{"average", IncrementFallback{Mode: IncrementFallbackAverage}, false},
+		{"Interpolate", IncrementFallback{Mode: IncrementFallbackInterpolate}, false},
+		{"42.5", IncrementFallback{Mode: IncrementFallbackDefault, Value: 42.5}, false},
+		{"0", IncrementFallback{Mode: IncrementFallbackDefault}, false},
+		{"-1", IncrementFallback{}, true},
+		{"NaN", IncrementFallback{}, true},
+		{"1e9", IncrementFallback{}, true},
+		{"median", IncrementFallback{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIncrementFallback(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseIncrementFallback(%q) = %+v, %v, want %+v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIncrementFallbackJSON(t *testing.T) {
+	tests := []struct {
+		fallback IncrementFallback
+		want     string
+	}{
+		{IncrementFallback{}, `""`},
+		{IncrementFallback{Mode: IncrementFallbackAverage}, `"average"`},
+		{IncrementFallback{Mode: IncrementFallbackDefault, Value: 12.5}, `"12.5"`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.fallback)
+		if err != nil || string(data) != tt.want {
+			t.Errorf("Marshal(%+v) = %s, %v, want %s", tt.fallback, data, err, tt.want)
+			continue
+		}
+		var back IncrementFallback
+		if err := json.Unmarshal(data, &back); err != nil || back != tt.fallback {
+			t.Errorf("Unmarshal(%s) = %+v, %v, want %+v", data, back, err, tt.fallback)
+		}
+	}
+
+	var f IncrementFallback
+	if err := json.Unmarshal([]byte(`"median"`), &f); err == nil {
+		t.Error(`Unmarshal("median") succeeded`)
+	}
+}
+
+func TestIncrementFallbackIncrementFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		fallback   IncrementFallback
+		increments map[int]float64
+		month      int
+		want       float64
+		wantOK     bool
+	}{
+		{"none", IncrementFallback{}, map[int]float64{1: 100}, 2, 0, false},
+		{"default", IncrementFallback{Mode: IncrementFallbackDefault, Value: 50}, nil, 2, 50, true},
+		{"average", IncrementFallback{Mode: IncrementFallbackAverage}, map[int]float64{1: 100, 2: 80, 3: 90}, 6, 90, true},
+		{"average of nothing", IncrementFallback{Mode: IncrementFallbackAverage}, nil, 6, 0, false},
+		{"interpolate between months", IncrementFallback{Mode: IncrementFallbackInterpolate}, map[int]float64{1: 100, 4: 130}, 3, 120, true},
+		{"interpolate across the new year", IncrementFallback{Mode: IncrementFallbackInterpolate}, map[int]float64{11: 90, 2: 120}, 1, 110, true},
+		{"interpolate from one month", IncrementFallback{Mode: IncrementFallbackInterpolate}, map[int]float64{5: 80}, 1, 80, true},
+		{"interpolate from nothing", IncrementFallback{Mode: IncrementFallbackInterpolate}, nil, 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.fallback.incrementFor(tt.increments, tt.month)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("incrementFor(%d) = %v, %v, want %v, %v", tt.month, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// External increments (e.g. a Google Sheet published as CSV) are fetched at
+// job time and cached; on a failed fetch the last good copy is used, then the
+// increments stored in the config.
+const (
+	incrementsFetchTimeout = 10 * time.Second
+	incrementsCacheTTL     = 15 * time.Minute
+	incrementsMaxBytes     = 64 << 10
+)
+
+// cachedIncrements is the last good fetch from an increments URL
+type cachedIncrements struct {
+	monthly   map[int]float64
+	bySerial  map[string]map[int]float64
+	fetchedAt time.Time
+}
+
+var (
+	incrementsCacheMu sync.Mutex
+	incrementsCache   = make(map[string]*cachedIncrements)
+)
+
+// incrementsClient refuses to connect to private, loopback and other reserved
+// addresses, so a user-supplied URL can't reach internal services
+var incrementsClient = &http.Client{
+	Timeout: incrementsFetchTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: incrementsFetchTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				if isReservedAddr(addrPort.Addr()) {
+					return fmt.Errorf("address %s is not allowed", addrPort.Addr())
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+}
+
+// reservedPrefixes are the special-purpose ranges (RFC 6890 and successors)
+// an increments URL may not resolve to: private, shared (CGNAT), loopback,
+// link-local, documentation, benchmarking, multicast and reserved space
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// isReservedAddr reports whether ip is in a reserved range. IPv4-mapped IPv6
+// addresses are checked as IPv4.
+func isReservedAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateIncrementsURL checks an increments URL before it is stored
+func validateIncrementsURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.New("must be an https URL")
+	}
+	return nil
+}
+
+// applyIncrementsSource replaces the config's increments with those fetched
+// from its IncrementsURL. Fetch errors are logged and leave the cached or
+// stored increments in place, so a sheet outage doesn't stop submissions.
+func applyIncrementsSource(ctx context.Context, config *Config, logger Logger) {
+	if config.IncrementsURL == "" {
+		return
+	}
+	if logger == nil {
+		logger = &defaultLogger{}
+	}
+
+	incrementsCacheMu.Lock()
+	cached := incrementsCache[config.IncrementsURL]
+	incrementsCacheMu.Unlock()
+
+	if cached == nil || time.Since(cached.fetchedAt) > incrementsCacheTTL {
+		monthly, bySerial, err := fetchIncrements(ctx, config.IncrementsURL)
+		if err != nil {
+			if cached != nil {
+				logger.Log(fmt.Sprintf("Warning: failed to fetch increments, using copy from %s: %v",
+					cached.fetchedAt.UTC().Format(time.RFC3339), err))
+			} else {
+				logger.Log(fmt.Sprintf("Warning: failed to fetch increments, using stored values: %v", err))
+				return
+			}
+		} else {
+			cached = &cachedIncrements{monthly: monthly, bySerial: bySerial, fetchedAt: time.Now()}
+			incrementsCacheMu.Lock()
+			incrementsCache[config.IncrementsURL] = cached
+			incrementsCacheMu.Unlock()
+			logger.Log(fmt.Sprintf("Fetched increments for %d months from increments URL", len(monthly)))
+		}
+	}
+
+	config.MonthlyIncrements = cached.monthly
+	config.SerialIncrements = cached.bySerial
+}
+
+// fetchIncrements downloads and strictly parses increments. JSON bodies use
+// the monthly_increments format; anything else is read as CSV.
+func fetchIncrements(ctx context.Context, rawURL string) (map[int]float64, map[string]map[int]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := incrementsClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, incrementsMaxBytes+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) > incrementsMaxBytes {
+		return nil, nil, fmt.Errorf("response larger than %d bytes", incrementsMaxBytes)
+	}
+
+	var monthly map[int]float64
+	var bySerial map[string]map[int]float64
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "{") {
+		monthly, bySerial, err = parseMonthlyIncrements([]byte(trimmed))
+	} else {
+		monthly, bySerial, err = parseIncrementsCSV(trimmed)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(monthly) == 0 && len(bySerial) == 0 {
+		return nil, nil, errors.New("no increments found")
+	}
+	return monthly, bySerial, nil
+}
+
+// parseIncrementsCSV parses "month,increment" rows, or "serial,month,increment"
+// rows for per-counter overrides. A header row is allowed; any other bad row
+// rejects the whole file.
+func parseIncrementsCSV(data string) (map[int]float64, map[string]map[int]float64, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	monthly := make(map[int]float64)
+	var bySerial map[string]map[int]float64
+	for i, row := range rows {
+		var serial string
+		switch len(row) {
+		case 2:
+		case 3:
+			serial, row = strings.TrimSpace(row[0]), row[1:]
+		default:
+			return nil, nil, fmt.Errorf("row %d: expected 2 or 3 columns, got %d", i+1, len(row))
+		}
+
+		month, err := strconv.Atoi(strings.TrimSpace(row[0]))
+		if err != nil && i == 0 {
+			continue // header
+		}
+		if err != nil || month < 1 || month > 12 {
+			return nil, nil, fmt.Errorf("row %d: invalid month %q: must be 1-12", i+1, row[0])
+		}
+		increment, err := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		if err != nil || !validIncrement(increment) {
+			return nil, nil, fmt.Errorf("row %d: invalid increment %q: must be a number from 0 to %g", i+1, row[1], maxIncrement)
+		}
+
+		target := monthly
+		if serial != "" {
+			if bySerial == nil {
+				bySerial = make(map[string]map[int]float64)
+			}
+			if bySerial[serial] == nil {
+				bySerial[serial] = make(map[int]float64)
+			}
+			target = bySerial[serial]
+		}
+		if _, dup := target[month]; dup {
+			return nil, nil, fmt.Errorf("row %d: duplicate month %d", i+1, month)
+		}
+		target[month] = increment
+	}
+	return monthly, bySerial, nil
+}
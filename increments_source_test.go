@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIncrementsCSV(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantMonthly  map[int]float64
+		wantBySerial map[string]map[int]float64
+		wantErr      bool
+	}{
+		{"monthly rows", "1,100\n2,95.5", map[int]float64{1: 100, 2: 95.5}, nil, false},
+		{"header row", "month,increment\n3, 80", map[int]float64{3: 80}, nil, false},
+		{"per-counter rows", "1,100\nC1,1,40", map[int]float64{1: 100}, map[string]map[int]float64{"C1": {1: 40}}, false},
+		{"month out of range", "13,100", nil, nil, true},
+		{"bad month after the header", "month,increment\nJan,100", nil, nil, true},
+		{"negative increment", "1,-5", nil, nil, true},
+		{"non-finite increment", "1,NaN", nil, nil, true},
+		{"huge increment", "1,1e9", nil, nil, true},
+		{"duplicate month", "1,100\n1,90", nil, nil, true},
+		{"too many columns", "C1,1,40,extra", nil, nil, true},
+		{"unterminated quote", "\"1,100", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monthly, bySerial, err := parseIncrementsCSV(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIncrementsCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(monthly, tt.wantMonthly) || !reflect.DeepEqual(bySerial, tt.wantBySerial) {
+				t.Errorf("parseIncrementsCSV() = %v, %v, want %v, %v", monthly, bySerial, tt.wantMonthly, tt.wantBySerial)
+			}
+		})
+	}
+}
+
+func TestValidateIncrementsURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://docs.google.com/spreadsheets/d/abc/pub?output=csv", false},
+		{"http://example.com/increments.csv", true},
+		{"https://", true},
+		{"file:///etc/passwd", true},
+		{"://bad", true},
+	}
+	for _, tt := range tests {
+		if err := validateIncrementsURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("validateIncrementsURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIsReservedAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", false},
+		{"2a00:1450:4001::1", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.31.255.255", true},
+		{"172.32.0.1", false},
+		{"192.168.1.1", true},
+		{"100.64.0.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"::", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::a00:1", true},
+	}
+	for _, tt := range tests {
+		if got := isReservedAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isReservedAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestValidIncrement(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want bool
+	}{
+		{0, true},
+		{42.5, true},
+		{maxIncrement, true},
+		{maxIncrement + 1, false},
+		{-0.1, false},
+		{math.NaN(), false},
+		{math.Inf(1), false},
+		{math.Inf(-1), false},
+	}
+	for _, tt := range tests {
+		if got := validIncrement(tt.v); got != tt.want {
+			t.Errorf("validIncrement(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestFetchIncrementsRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1,100"))
+	}))
+	defer srv.Close()
+
+	_, _, err := fetchIncrements(context.Background(), srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("fetchIncrements(%s) error = %v, want the address refused", srv.URL, err)
+	}
+}
+
+// setIncrementsCacheForTest seeds the increments cache and restores it afterwards
+func setIncrementsCacheForTest(t *testing.T, url string, entry *cachedIncrements) {
+	t.Helper()
+	incrementsCacheMu.Lock()
+	prev, had := incrementsCache[url]
+	incrementsCache[url] = entry
+	incrementsCacheMu.Unlock()
+	t.Cleanup(func() {
+		incrementsCacheMu.Lock()
+		defer incrementsCacheMu.Unlock()
+		if had {
+			incrementsCache[url] = prev
+		} else {
+			delete(incrementsCache, url)
+		}
+	})
+}
+
+func TestApplyIncrementsSource(t *testing.T) {
+	// Loopback is refused by incrementsClient, so every fetch here fails fast
+	const url = "https://127.0.0.1:1/increments.csv"
+	stored := map[int]float64{1: 10}
+	cached := map[int]float64{1: 99}
+
+	tests := []struct {
+		name   string
+		cache  *cachedIncrements
+		want   map[int]float64
+		wantIn string
+	}{
+		{"fresh cache", &cachedIncrements{monthly: cached, fetchedAt: time.Now()}, cached, ""},
+		{"stale cache and failed fetch", &cachedIncrements{monthly: cached, fetchedAt: time.Now().Add(-time.Hour)}, cached, "using copy from"},
+		{"no cache and failed fetch", nil, stored, "using stored values"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cache != nil {
+				setIncrementsCacheForTest(t, url, tt.cache)
+			}
+			config := &Config{IncrementsURL: url, MonthlyIncrements: stored}
+			logger := &testLogger{}
+			applyIncrementsSource(context.Background(), config, logger)
+			if !reflect.DeepEqual(config.MonthlyIncrements, tt.want) {
+				t.Errorf("MonthlyIncrements = %v, want %v", config.MonthlyIncrements, tt.want)
+			}
+			if log := strings.Join(logger.lines, "\n"); !strings.Contains(log, tt.wantIn) {
+				t.Errorf("log %q does not mention %q", log, tt.wantIn)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// maxQueuedJobs is how many pending and running jobs a user may have before
+// new API jobs are refused; 0 (the default) means no limit
+var maxQueuedJobs int
+
+// SetMaxQueuedJobs sets the per-user limit of pending and running jobs; 0 removes it
+func SetMaxQueuedJobs(n int) {
+	if n < 0 {
+		n = 0
+	}
+	maxQueuedJobs = n
+}
+
+// JobQueueStatus is a user's job queue. A user's jobs run one at a time, in
+// the order of PendingJobIDs.
+type JobQueueStatus struct {
+	RunningJobID  *string  `json:"running_job_id"`
+	PendingCount  int      `json:"pending_count"`
+	PendingJobIDs []string `json:"pending_job_ids"`
+	MaxQueued     int      `json:"max_queued"` // 0 = no limit
+}
+
+// handleJobQueue returns the user's running job and the jobs queued behind it
+func handleJobQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	queue, err := GetUserJobQueue(userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get job queue", "user_id", userID, "error", err)
+		jsonError(w, "Failed to get job queue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(queue)
+}
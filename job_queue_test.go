@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// setMaxQueuedJobsForTest sets the per-user job limit for the test
+func setMaxQueuedJobsForTest(t *testing.T, n int) {
+	t.Helper()
+	prev := maxQueuedJobs
+	t.Cleanup(func() { maxQueuedJobs = prev })
+	SetMaxQueuedJobs(n)
+}
+
+func TestSetMaxQueuedJobs(t *testing.T) {
+	tests := []struct {
+		n, want int
+	}{
+		{3, 3},
+		{0, 0},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		setMaxQueuedJobsForTest(t, tt.n)
+		if maxQueuedJobs != tt.want {
+			t.Errorf("SetMaxQueuedJobs(%d) = %d, want %d", tt.n, maxQueuedJobs, tt.want)
+		}
+	}
+}
+
+func TestHandleJobQueueRejectsRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		userID int64
+		want   int
+	}{
+		{"wrong method", http.MethodPost, 1, http.StatusMethodNotAllowed},
+		{"no user", http.MethodGet, 0, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleJobQueue(rec, newAuthedRequest(tt.method, "/api/jobs/queue", "", tt.userID))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestHandleJobQueueDatabaseError(t *testing.T) {
+	closed, err := sql.Open("postgres", "postgres://localhost/none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	prev := db
+	t.Cleanup(func() { db = prev })
+	db = closed
+
+	rec := httptest.NewRecorder()
+	handleJobQueue(rec, newAuthedRequest(http.MethodGet, "/api/jobs/queue", "", 1))
+	if rec.Code != http.StatusInternalServerError || errorMessage(t, rec) != "Failed to get job queue" {
+		t.Errorf("status = %d %s, want 500", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateJobWithinLimit(t *testing.T) {
+	user := createTestUser(t)
+	finished := createTestJob(t, user.ID, "full")
+	if err := UpdateJobStatus(finished.ID, "completed", nil); err != nil {
+		t.Fatalf("UpdateJobStatus: %v", err)
+	}
+
+	// Finished jobs don't count towards the limit of 2
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{"first", nil},
+		{"second", nil},
+		{"over the limit", errJobQueueFull},
+	}
+	for _, tt := range tests {
+		job, err := CreateJobWithinLimit(uuid.NewString(), user.ID, "full", JobSourceAPI, 2)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr == nil && (job == nil || job.Status != "pending" || job.Source != JobSourceAPI) {
+			t.Errorf("%s: job = %+v, want a pending API job", tt.name, job)
+		}
+	}
+
+	queue, err := GetUserJobQueue(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserJobQueue: %v", err)
+	}
+	if queue.PendingCount != 2 {
+		t.Errorf("pending jobs = %d, want 2", queue.PendingCount)
+	}
+}
+
+func TestQueueJobRefusedWhenQueueFull(t *testing.T) {
+	user := createTestUser(t)
+	setEncryptionKeyForTest(t, "test-secret")
+	if err := SaveUserConfig(&UserConfig{UserID: user.ID, GasolinaEmail: "me@example.com", GasolinaPassword: "secret"}); err != nil {
+		t.Fatalf("SaveUserConfig: %v", err)
+	}
+	prev := jobManager
+	t.Cleanup(func() { jobManager = prev })
+	jobManager = NewJobManagerWithExecutor(&fakeExecutor{})
+	setMaxQueuedJobsForTest(t, 1)
+	createTestJob(t, user.ID, "full")
+
+	rec := httptest.NewRecorder()
+	handleCreateDryRunJob(rec, newAuthedRequest(http.MethodPost, "/api/jobs/dry-run", "", user.ID))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 (%s)", rec.Code, rec.Body)
+	}
+	if total, err := countUserJobs(user.ID); err != nil || total != 1 {
+		t.Errorf("user has %d jobs (%v), want only the queued one", total, err)
+	}
+}
+
+// countUserJobs returns how many jobs the user has
+func countUserJobs(userID int64) (int, error) {
+	_, total, err := GetUserJobs(userID, 1, 0, JobFilter{})
+	return total, err
+}
+
+func TestHandleJobQueue(t *testing.T) {
+	user := createTestUser(t)
+	setMaxQueuedJobsForTest(t, 5)
+
+	finished := createTestJob(t, user.ID, "full")
+	if err := UpdateJobStatus(finished.ID, "completed", nil); err != nil {
+		t.Fatalf("UpdateJobStatus: %v", err)
+	}
+	running := createTestJob(t, user.ID, "full")
+	if claimed, err := ClaimJob(running.ID); err != nil || !claimed {
+		t.Fatalf("ClaimJob = %v, %v", claimed, err)
+	}
+	first := createTestJob(t, user.ID, "full")
+	second := createTestJob(t, user.ID, "dry-run")
+
+	rec := httptest.NewRecorder()
+	handleJobQueue(rec, newAuthedRequest(http.MethodGet, "/api/jobs/queue", "", user.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got JobQueueStatus
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.RunningJobID == nil || *got.RunningJobID != running.ID {
+		t.Errorf("running_job_id = %v, want %s", got.RunningJobID, running.ID)
+	}
+	if got.PendingCount != 2 || len(got.PendingJobIDs) != 2 ||
+		got.PendingJobIDs[0] != first.ID || got.PendingJobIDs[1] != second.ID {
+		t.Errorf("pending = %d %v, want [%s %s]", got.PendingCount, got.PendingJobIDs, first.ID, second.ID)
+	}
+	if got.MaxQueued != 5 {
+		t.Errorf("max_queued = %d, want 5", got.MaxQueued)
+	}
+}
+
+func TestHandleJobQueueEmpty(t *testing.T) {
+	user := createTestUser(t)
+	rec := httptest.NewRecorder()
+	handleJobQueue(rec, newAuthedRequest(http.MethodGet, "/api/jobs/queue", "", user.ID))
+
+	// An idle queue is an explicit null and an empty list, not missing fields
+	var got map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if v, ok := got["running_job_id"]; !ok || v != nil {
+		t.Errorf("running_job_id = %v (present %v), want null", v, ok)
+	}
+	if ids, ok := got["pending_job_ids"].([]interface{}); !ok || len(ids) != 0 {
+		t.Errorf("pending_job_ids = %v, want []", got["pending_job_ids"])
+	}
+}
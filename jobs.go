@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
@@ -13,128 +15,557 @@ import (
 	"github.com/google/uuid"
 )
 
+// defaultJobTimeout bounds how long a single job may run
+const defaultJobTimeout = 5 * time.Minute
+
+// dryRunRampRuns is how many live-eligible runs are forced to dry-run for each user
+var dryRunRampRuns = 1
+
+// globalForceDryRun makes every job a dry-run, whatever users or jobs ask for
+var globalForceDryRun bool
+
+// SetGlobalForceDryRun enables the server-wide dry-run kill switch
+func SetGlobalForceDryRun(enabled bool) {
+	globalForceDryRun = enabled
+}
+
+// Job retry policy: login, reading and record checks are retried freely up to
+// jobMaxAttempts; a failure after submitting is only retried if enabled and
+// the indicator table confirms nothing was recorded
+var (
+	jobMaxAttempts   = 3
+	retryAfterSubmit = true
+)
+
+// SetJobRetry configures how often job phases are attempted
+func SetJobRetry(maxAttempts int, afterSubmit bool) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	jobMaxAttempts = maxAttempts
+	retryAfterSubmit = afterSubmit
+}
+
+// SetDryRunRampRuns sets the number of forced dry runs before live submissions start
+func SetDryRunRampRuns(n int) {
+	if n < 0 {
+		n = 0
+	}
+	dryRunRampRuns = n
+}
+
 // JobManager handles job execution with per-user queues
 type JobManager struct {
-	mu       sync.Mutex
-	queues   map[int64]chan *Job
-	workers  map[int64]bool
-	wg       sync.WaitGroup
-	shutdown chan struct{}
+	mu         sync.Mutex
+	queues     map[int64]chan *Job
+	workers    map[int64]bool
+	wg         sync.WaitGroup
+	shutdown   chan struct{}
+	executor   JobExecutor
+	jobTimeout time.Duration
+
+	// Maintenance mode: workers stop dequeuing until resumed is closed
+	maintenance bool
+	resumed     chan struct{}
+
+	// Cancel functions and loggers of in-flight jobs, keyed by job ID
+	running map[string]context.CancelFunc
+	loggers map[string]*JobLogger
+
+	// slots caps the jobs executing at once across all users; nil means no cap
+	slots chan struct{}
+
+	// Pending jobs recovered by Start, per user, run before anything queued later
+	recovered map[int64][]*Job
 }
 
 var jobManager *JobManager
 
-// NewJobManager creates a new job manager
+// NewJobManager creates a new job manager that runs jobs in Chrome
 func NewJobManager() *JobManager {
+	return NewJobManagerWithExecutor(&chromedpExecutor{})
+}
+
+// NewJobManagerWithExecutor creates a job manager with a custom executor
+func NewJobManagerWithExecutor(executor JobExecutor) *JobManager {
+	resumed := make(chan struct{})
+	close(resumed)
 	return &JobManager{
-		queues:   make(map[int64]chan *Job),
-		workers:  make(map[int64]bool),
-		shutdown: make(chan struct{}),
+		queues:     make(map[int64]chan *Job),
+		workers:    make(map[int64]bool),
+		running:    make(map[string]context.CancelFunc),
+		loggers:    make(map[string]*JobLogger),
+		recovered:  make(map[int64][]*Job),
+		shutdown:   make(chan struct{}),
+		executor:   executor,
+		jobTimeout: defaultJobTimeout,
+		resumed:    resumed,
+	}
+}
+
+// SetMaintenance pauses or resumes job processing. Queued jobs stay pending
+// while paused and are drained once processing resumes.
+func (jm *JobManager) SetMaintenance(enabled bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	if enabled == jm.maintenance {
+		return
+	}
+	jm.maintenance = enabled
+	if enabled {
+		jm.resumed = make(chan struct{})
+		slog.Info("Job manager: maintenance mode enabled, job processing paused")
+	} else {
+		close(jm.resumed)
+		slog.Info("Job manager: maintenance mode disabled, job processing resumed")
+	}
+}
+
+// InMaintenance reports whether job processing is paused
+func (jm *JobManager) InMaintenance() bool {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	return jm.maintenance
+}
+
+// SetMaxConcurrentJobs caps the jobs executing at once across all users; 0
+// removes the cap. Call it before Start.
+func (jm *JobManager) SetMaxConcurrentJobs(n int) {
+	if n <= 0 {
+		jm.slots = nil
+		return
+	}
+	jm.slots = make(chan struct{}, n)
+}
+
+// acquireSlot blocks until a job may execute under the global cap. Returns
+// false on shutdown or when ctx is done.
+func (jm *JobManager) acquireSlot(ctx context.Context) bool {
+	if jm.slots == nil {
+		return true
+	}
+	select {
+	case jm.slots <- struct{}{}:
+		return true
+	case <-jm.shutdown:
+		return false
+	case <-ctx.Done():
+		return false
 	}
 }
 
-// Start initializes the job manager
-func (jm *JobManager) Start() {
-	log.Println("Job manager started")
+// releaseSlot frees a slot taken by acquireSlot
+func (jm *JobManager) releaseSlot() {
+	if jm.slots != nil {
+		<-jm.slots
+	}
+}
+
+// waitResumed blocks while in maintenance mode. Returns false on shutdown.
+func (jm *JobManager) waitResumed() bool {
+	jm.mu.Lock()
+	resumed := jm.resumed
+	jm.mu.Unlock()
+
+	select {
+	case <-jm.shutdown:
+		return false
+	case <-resumed:
+		return true
+	}
+}
+
+// Start initializes the job manager and recovers jobs left over from a
+// previous run: running jobs are failed as interrupted, pending ones requeued.
+// It fails if the encryption key is not set yet.
+func (jm *JobManager) Start() error {
+	// Jobs decrypt credentials; without the key they would all run unconfigured
+	if !encryptionReady() {
+		return fmt.Errorf("cannot start job manager: %w", errEncryptionNotInitialized)
+	}
+
+	if ids, err := FailInterruptedJobs("interrupted by server restart"); err != nil {
+		slog.Error("Failed to mark interrupted jobs", "error", err)
+	} else if len(ids) > 0 {
+		slog.Info("Marked interrupted jobs as failed", "count", len(ids))
+	}
+
+	pending, err := ListPendingJobs()
+	if err != nil {
+		slog.Error("Failed to load pending jobs", "error", err)
+	} else if len(pending) > 0 {
+		slog.Info("Requeueing pending jobs", "count", len(pending))
+		// Each user's worker runs its recovered jobs, oldest first, before
+		// anything queued after startup; no queue channel is involved, so one
+		// user's backlog can't hold up another's
+		jm.mu.Lock()
+		for _, job := range pending {
+			jm.recovered[job.UserID] = append(jm.recovered[job.UserID], job)
+		}
+		for userID := range jm.recovered {
+			jm.startWorkerLocked(userID)
+		}
+		jm.mu.Unlock()
+	}
+
+	slog.Info("Job manager started")
+	return nil
 }
 
 // Stop shuts down the job manager gracefully
 func (jm *JobManager) Stop() {
 	close(jm.shutdown)
 	jm.wg.Wait()
-	log.Println("Job manager stopped")
+	slog.Info("Job manager stopped")
 }
 
-// CreateJob creates a new job and queues it for execution
-func (jm *JobManager) CreateJob(userID int64, jobType string) (*Job, error) {
+// CreateJob creates a new job and queues it for execution; source records what created it
+func (jm *JobManager) CreateJob(userID int64, jobType, source string) (*Job, error) {
 	jobID := uuid.New().String()
 
-	job, err := CreateJob(jobID, userID, jobType)
+	job, err := CreateJob(jobID, userID, jobType, source)
 	if err != nil {
 		return nil, err
 	}
 
-	// Ensure user has a queue and worker
+	jm.enqueue(job)
+	return job, nil
+}
+
+// CreateJobWithinLimit is CreateJob refusing with errJobQueueFull once the user
+// has maxActive pending or running jobs; 0 means no limit
+func (jm *JobManager) CreateJobWithinLimit(userID int64, jobType, source string, maxActive int) (*Job, error) {
+	if maxActive <= 0 {
+		return jm.CreateJob(userID, jobType, source)
+	}
+
+	job, err := CreateJobWithinLimit(uuid.New().String(), userID, jobType, source, maxActive)
+	if err != nil {
+		return nil, err
+	}
+
+	jm.enqueue(job)
+	return job, nil
+}
+
+// enqueue queues a job on its user's queue, starting the user's worker if needed
+func (jm *JobManager) enqueue(job *Job) {
 	jm.mu.Lock()
-	if _, ok := jm.queues[userID]; !ok {
-		jm.queues[userID] = make(chan *Job, 10)
+	queue := jm.startWorkerLocked(job.UserID)
+	jm.mu.Unlock()
+
+	// Queue the job
+	queue <- job
+}
+
+// startWorkerLocked ensures the user has a queue and a worker and returns the
+// queue. Callers hold jm.mu.
+func (jm *JobManager) startWorkerLocked(userID int64) chan *Job {
+	queue, ok := jm.queues[userID]
+	if !ok {
+		queue = make(chan *Job, 10)
+		jm.queues[userID] = queue
 	}
 	if !jm.workers[userID] {
 		jm.workers[userID] = true
 		jm.wg.Add(1)
-		go jm.workerLoop(userID)
+		go jm.workerLoop(userID, queue)
 	}
+	return queue
+}
+
+// takeRecovered pops the user's oldest job recovered at startup, if any
+func (jm *JobManager) takeRecovered(userID int64) *Job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	jobs := jm.recovered[userID]
+	if len(jobs) == 0 {
+		return nil
+	}
+	if len(jobs) == 1 {
+		delete(jm.recovered, userID)
+	} else {
+		jm.recovered[userID] = jobs[1:]
+	}
+	return jobs[0]
+}
+
+// CancelPending cancels all of a user's pending jobs and drains them from the
+// in-memory queue. Running jobs are not affected. Returns the number cancelled.
+func (jm *JobManager) CancelPending(userID int64) (int, error) {
+	ids, err := CancelPendingJobs(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	jm.dequeue(userID, ids...)
+
+	slog.Info("Cancelled pending jobs", "user_id", userID, "count", len(ids))
+	return len(ids), nil
+}
+
+// ErrJobNotCancellable is returned when cancelling a job that already finished
+var ErrJobNotCancellable = errors.New("job is not pending or running")
+
+// CancelJob stops a single job. A running job has its context cancelled and is
+// marked cancelled when it winds down; a queued job is dequeued and marked
+// cancelled straight away. Returns whether the job was running.
+func (jm *JobManager) CancelJob(job *Job) (bool, error) {
+	jm.mu.Lock()
+	cancel, ok := jm.running[job.ID]
 	jm.mu.Unlock()
+	if ok {
+		cancel()
+		slog.Info("Cancelling running job", jobLogAttrs(job.ID, job.UserID)...)
+		return true, nil
+	}
 
-	// Queue the job
-	jm.queues[userID] <- job
+	cancelled, err := CancelPendingJob(job.ID)
+	if err != nil {
+		return false, err
+	}
+	if !cancelled {
+		return false, ErrJobNotCancellable
+	}
+	jm.dequeue(job.UserID, job.ID)
 
-	return job, nil
+	logger := NewJobLogger(job.ID, job.UserID)
+	logger.LogAt(LogLevelQuiet, "Job cancelled before it started")
+	logger.Save()
+
+	slog.Info("Cancelled pending job", jobLogAttrs(job.ID, job.UserID)...)
+	return false, nil
 }
 
-// workerLoop processes jobs for a specific user
-func (jm *JobManager) workerLoop(userID int64) {
-	defer jm.wg.Done()
+// dequeue drops the given jobs from a user's in-memory queue; anything else
+// goes back in order. The worker also skips jobs it can't claim, so a job it
+// already took is safe.
+func (jm *JobManager) dequeue(userID int64, ids ...string) {
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
 
-	queue := jm.queues[userID]
+	jm.mu.Lock()
+	queue, ok := jm.queues[userID]
+	jm.mu.Unlock()
+	if !ok {
+		return
+	}
 
+	var keep []*Job
+drain:
 	for {
 		select {
-		case <-jm.shutdown:
-			return
 		case job := <-queue:
-			jm.executeJob(job)
+			if !drop[job.ID] {
+				keep = append(keep, job)
+			}
+		default:
+			break drain
 		}
 	}
+	for _, job := range keep {
+		queue <- job
+	}
 }
 
-// executeJob runs a job
-func (jm *JobManager) executeJob(job *Job) {
-	log.Printf("Starting job %s (type: %s) for user %d", job.ID, job.Type, job.UserID)
+// workerLoop processes jobs for a specific user: first those recovered at
+// startup, then the queue
+func (jm *JobManager) workerLoop(userID int64, queue chan *Job) {
+	defer jm.wg.Done()
 
-	// Update status to running
-	UpdateJobStatus(job.ID, "running", nil)
+	for {
+		if !jm.waitResumed() {
+			return
+		}
+		job := jm.takeRecovered(userID)
+		if job == nil {
+			select {
+			case <-jm.shutdown:
+				return
+			case job = <-queue:
+			}
+			// Maintenance may have started while we were waiting for the job
+			if !jm.waitResumed() {
+				return
+			}
+		}
+		// Only this user's worker waits for a slot; other users' jobs
+		// keep being picked up as slots free
+		if !jm.acquireSlot(context.Background()) {
+			return
+		}
+		notify := jm.executeJob(job)
+		jm.releaseSlot()
+		// A slow mail server or webhook holds up only this user's queue
+		if notify != nil {
+			notify()
+		}
+	}
+}
+
+// JobExecutor performs the browser automation for a job. The JobManager owns
+// queueing, status updates and logging; an executor only runs the work and
+// reports the result, so a fake can stand in for Chrome in tests.
+type JobExecutor interface {
+	Execute(ctx context.Context, job *Job, cfg *UserConfig, logger *JobLogger) error
+}
+
+// executeJob runs a job. It returns the job's notification, if any, for the
+// worker to send once it has released its slot.
+func (jm *JobManager) executeJob(job *Job) (notify func()) {
+	// Register the job's context before claiming it so a cancel request that
+	// lands in between still reaches it
+	jobCtx, jobCancel := context.WithTimeout(context.Background(), jm.jobTimeout)
+	defer jobCancel()
+
+	jm.mu.Lock()
+	jm.running[job.ID] = jobCancel
+	jm.mu.Unlock()
+	defer func() {
+		jm.mu.Lock()
+		delete(jm.running, job.ID)
+		jm.mu.Unlock()
+	}()
+
+	// Claim the job; it may have been cancelled while it sat in the queue
+	claimed, err := ClaimJob(job.ID)
+	if err != nil {
+		slog.Error("Failed to claim job", append(jobLogAttrs(job.ID, job.UserID), "error", err)...)
+		return nil
+	}
+	if !claimed {
+		slog.Info("Skipping job: no longer pending", jobLogAttrs(job.ID, job.UserID)...)
+		return nil
+	}
+
+	slog.Info("Starting job", append(jobLogAttrs(job.ID, job.UserID), "type", job.Type)...)
 
 	// Create job logger
-	logger := NewJobLogger(job.ID)
+	logger := NewJobLogger(job.ID, job.UserID)
+	jm.trackLogger(job.ID, logger)
+	defer jm.untrackLogger(job.ID, logger)
+	stopFlusher := logger.startFlusher()
+	defer stopFlusher()
 
 	// Get user config
-	cfg, err := GetUserConfig(job.UserID)
+	cfg, err := GetUserConfig(context.Background(), job.UserID)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to get user config: %v", err)
-		logger.Log(errMsg)
+		logger.LogAt(LogLevelQuiet, errMsg)
 		UpdateJobStatus(job.ID, "failed", &errMsg)
 		logger.Save()
-		return
+		return nil
+	}
+
+	jobErr := jm.executor.Execute(jobCtx, job, cfg, logger)
+	if jobErr != nil && errors.Is(jobCtx.Err(), context.DeadlineExceeded) {
+		jobErr = fmt.Errorf("job timed out after %v: %w", jm.jobTimeout, jobErr)
 	}
 
+	// A job that finished before noticing the cancel keeps its real status
+	if jobErr != nil && errors.Is(jobCtx.Err(), context.Canceled) {
+		logger.LogAt(LogLevelQuiet, "Job cancelled")
+		UpdateJobStatus(job.ID, "cancelled", nil)
+		job.Status = "cancelled"
+		if result := logger.checkResult(); result != nil {
+			job.applyResult(result)
+			if err := UpdateJobResult(job.ID, result); err != nil {
+				slog.Error("Failed to save job result", append(jobLogAttrs(job.ID, job.UserID), "error", err)...)
+			}
+		}
+		logger.Save()
+		slog.Info("Job cancelled", jobLogAttrs(job.ID, job.UserID)...)
+		return nil
+	}
+
+	if jobErr != nil {
+		errMsg := jobErr.Error()
+		logger.LogAt(LogLevelQuiet, fmt.Sprintf("Job failed: %s", errMsg))
+		UpdateJobStatus(job.ID, "failed", &errMsg)
+		job.Status, job.Error = "failed", &errMsg
+		job.ErrorCode = errorCodeFor(jobErr)
+		if err := SetJobErrorCode(job.ID, job.ErrorCode); err != nil {
+			slog.Error("Failed to save job error code", append(jobLogAttrs(job.ID, job.UserID), "error", err)...)
+		}
+	} else {
+		logger.LogAt(LogLevelQuiet, "Job completed successfully")
+		UpdateJobStatus(job.ID, "completed", nil)
+		job.Status = "completed"
+	}
+
+	if result := logger.checkResult(); result != nil {
+		job.applyResult(result)
+		if err := UpdateJobResult(job.ID, result); err != nil {
+			slog.Error("Failed to save job result", append(jobLogAttrs(job.ID, job.UserID), "error", err)...)
+		}
+	}
+
+	// Keep the detailed outcome (e.g. already_exists) rather than plain success
+	job.Outcome = logger.outcomeFor(jobErr)
+	if job.Outcome != OutcomeSuccess && job.Outcome != OutcomeFailure {
+		if err := SetJobOutcome(job.ID, job.Outcome); err != nil {
+			slog.Error("Failed to save job outcome", append(jobLogAttrs(job.ID, job.UserID), "error", err)...)
+		}
+	}
+
+	if missing, checked := logger.structureCheck(); checked {
+		recordStructureCheck(job.UserID, missing)
+	}
+
+	logger.Save()
+	slog.Info("Job finished", append(jobLogAttrs(job.ID, job.UserID), "status", job.Status, "outcome", job.Outcome)...)
+
+	// Each channel checks its own feature flag; a failed notification never fails the job
+	if !cfg.ShouldNotify(job.Outcome) {
+		return nil
+	}
+	return func() {
+		dispatchJobNotification(job, cfg, logger)
+		logger.Save()
+	}
+}
+
+// chromedpExecutor runs jobs in a real browser via chromedp
+type chromedpExecutor struct{}
+
+// Execute opens a browser tab for the job and runs the job type's automation
+func (e *chromedpExecutor) Execute(ctx context.Context, job *Job, cfg *UserConfig, logger *JobLogger) error {
 	// Create screenshot directory
 	screenshotDir := filepath.Join(screenshotsPath, fmt.Sprintf("%d", job.UserID), job.ID)
 	if err := os.MkdirAll(screenshotDir, 0755); err != nil {
-		errMsg := fmt.Sprintf("Failed to create screenshot dir: %v", err)
-		logger.Log(errMsg)
-		UpdateJobStatus(job.ID, "failed", &errMsg)
-		logger.Save()
-		return
+		return fmt.Errorf("failed to create screenshot dir: %w", err)
 	}
 
 	// Create browser context
-	ctx, cancel := createJobBrowserContext()
+	tabCtx, cancel, err := newUserBrowserContext(ctx, job.UserID, cfg.Fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to create browser context: %w", err)
+	}
 	defer cancel()
 
-	// Set job timeout
-	jobCtx, jobCancel := context.WithTimeout(ctx, 5*time.Minute)
-	defer jobCancel()
+	// Bind the browser tab to the job's deadline and cancellation
+	runCtx, runCancel := context.WithCancel(tabCtx)
+	defer runCancel()
+	if deadline, ok := ctx.Deadline(); ok {
+		runCtx, runCancel = context.WithDeadline(runCtx, deadline)
+		defer runCancel()
+	}
+	stop := context.AfterFunc(ctx, runCancel)
+	defer stop()
 
 	// Create screenshot helper
 	saveScreenshot := func(name string) {
 		filename := fmt.Sprintf("%s.png", name)
 		path := filepath.Join(screenshotDir, filename)
-		if err := SaveScreenshotToPath(jobCtx, path); err != nil {
+		if err := SaveScreenshotToPath(runCtx, path); err != nil {
 			logger.Log(fmt.Sprintf("Failed to save screenshot %s: %v", name, err))
 		} else {
 			CreateScreenshot(job.ID, job.UserID, filename)
-			logger.Log(fmt.Sprintf("Screenshot saved: %s", name))
+			logVerbose(logger, fmt.Sprintf("Screenshot saved: %s", name))
 		}
 	}
 
@@ -142,32 +573,36 @@ func (jm *JobManager) executeJob(job *Job) {
 
 	switch job.Type {
 	case "test-login":
-		jobErr = jm.runTestLoginJob(jobCtx, cfg, logger, saveScreenshot)
+		jobErr = e.runTestLoginJob(runCtx, cfg, logger, saveScreenshot)
 	case "test-check":
-		jobErr = jm.runTestCheckJob(jobCtx, cfg, logger, saveScreenshot)
-	case "full":
-		jobErr = jm.runFullJob(jobCtx, cfg, logger, saveScreenshot)
+		jobErr = e.runTestCheckJob(runCtx, job, cfg, logger, saveScreenshot)
+	case "test-submit":
+		jobErr = e.runTestSubmitJob(runCtx, job, cfg, logger, saveScreenshot)
+	case "full", "dry-run":
+		jobErr = e.runFullJob(runCtx, job, cfg, logger, saveScreenshot)
+	default:
+		jobErr = fmt.Errorf("unknown job type %q", job.Type)
 	}
 
 	if jobErr != nil {
-		errMsg := jobErr.Error()
-		logger.Log(fmt.Sprintf("Job failed: %s", errMsg))
 		saveScreenshot("error_final")
-		UpdateJobStatus(job.ID, "failed", &errMsg)
-	} else {
-		logger.Log("Job completed successfully")
-		UpdateJobStatus(job.ID, "completed", nil)
-	}
 
-	logger.Save()
-	log.Printf("Job %s completed", job.ID)
+		dumpPath := filepath.Join(screenshotDir, "error_final.html")
+		if err := SaveHTMLDumpToPath(runCtx, dumpPath); err != nil {
+			logger.Log(fmt.Sprintf("Failed to save HTML dump: %v", err))
+		} else {
+			CreateScreenshot(job.ID, job.UserID, "error_final.html")
+			logger.Log("HTML dump saved: error_final.html")
+		}
+	}
+	return jobErr
 }
 
 // runTestLoginJob tests only the login functionality
-func (jm *JobManager) runTestLoginJob(ctx context.Context, cfg *UserConfig, logger *JobLogger, saveScreenshot func(string)) error {
+func (e *chromedpExecutor) runTestLoginJob(ctx context.Context, cfg *UserConfig, logger *JobLogger, saveScreenshot func(string)) error {
 	logger.Log("Starting login test")
 
-	if err := GasolinaLogin(ctx, cfg.GasolinaEmail, cfg.GasolinaPassword, cfg.AccountNumber, logger, saveScreenshot); err != nil {
+	if err := GasolinaLogin(ctx, cfg.ToConfig(), logger, saveScreenshot); err != nil {
 		return fmt.Errorf("login failed: %w", err)
 	}
 
@@ -177,27 +612,25 @@ func (jm *JobManager) runTestLoginJob(ctx context.Context, cfg *UserConfig, logg
 }
 
 // runTestCheckJob tests login and check functionality
-func (jm *JobManager) runTestCheckJob(ctx context.Context, cfg *UserConfig, logger *JobLogger, saveScreenshot func(string)) error {
+func (e *chromedpExecutor) runTestCheckJob(ctx context.Context, job *Job, cfg *UserConfig, logger *JobLogger, saveScreenshot func(string)) error {
 	logger.Log("Starting check test")
 
-	if err := GasolinaLogin(ctx, cfg.GasolinaEmail, cfg.GasolinaPassword, cfg.AccountNumber, logger, saveScreenshot); err != nil {
+	if err := GasolinaLogin(ctx, cfg.ToConfig(), logger, saveScreenshot); err != nil {
 		return fmt.Errorf("login failed: %w", err)
 	}
 
 	// Convert UserConfig to legacy Config for CheckAndUpdateIfNeeded
-	legacyCfg := &Config{
-		Email:             cfg.GasolinaEmail,
-		Password:          cfg.GasolinaPassword,
-		AccountNumber:     cfg.AccountNumber,
-		CheckURL:          cfg.CheckURL,
-		CronSchedule:      cfg.CronSchedule,
-		DryRun:            cfg.DryRun,
-		MonthlyIncrements: cfg.MonthlyIncrements,
-	}
+	legacyCfg := cfg.ToConfig()
+	legacyCfg.Submissions = newJobSubmissionTracker(job)
+	applyIncrementsSource(ctx, legacyCfg, logger)
+	rampRun := applyDryRunPolicy(job, cfg, legacyCfg, logger)
 
 	if err := CheckAndUpdateIfNeededWithLogger(ctx, legacyCfg, logger, saveScreenshot); err != nil {
 		return fmt.Errorf("check failed: %w", err)
 	}
+	if rampRun {
+		recordRampRun(job, logger)
+	}
 
 	saveScreenshot("check_success")
 	logger.Log("Check test passed")
@@ -205,108 +638,435 @@ func (jm *JobManager) runTestCheckJob(ctx context.Context, cfg *UserConfig, logg
 }
 
 // runFullJob runs the complete automation job
-func (jm *JobManager) runFullJob(ctx context.Context, cfg *UserConfig, logger *JobLogger, saveScreenshot func(string)) error {
+func (e *chromedpExecutor) runFullJob(ctx context.Context, job *Job, cfg *UserConfig, logger *JobLogger, saveScreenshot func(string)) error {
 	logger.Log("Starting full job")
 
 	// Login with retry
 	var loginErr error
-	for i := 0; i < 3; i++ {
+	for i := 0; i < jobMaxAttempts; i++ {
 		if i > 0 {
 			waitTime := time.Duration(i*2) * time.Second
-			logger.Log(fmt.Sprintf("Retry %d/3 after %v...", i+1, waitTime))
-			time.Sleep(waitTime)
+			logger.Log(fmt.Sprintf("Retry %d/%d after %v...", i+1, jobMaxAttempts, waitTime))
+			if err := sleepCtx(ctx, waitTime); err != nil {
+				return fmt.Errorf("login retry aborted: %w", err)
+			}
 		}
 
-		loginErr = GasolinaLogin(ctx, cfg.GasolinaEmail, cfg.GasolinaPassword, cfg.AccountNumber, logger, saveScreenshot)
+		loginErr = GasolinaLogin(ctx, cfg.ToConfig(), logger, saveScreenshot)
 		if loginErr == nil {
 			break
 		}
-		logger.Log(fmt.Sprintf("Login attempt %d/3 failed: %v", i+1, loginErr))
+		logger.Log(fmt.Sprintf("Login attempt %d/%d failed: %v", i+1, jobMaxAttempts, loginErr))
+		// Neither will change between attempts; the config needs fixing, and
+		// retrying a wrong password risks locking the account
+		if errors.Is(loginErr, ErrAmbiguousAccount) || errors.Is(loginErr, ErrInvalidCredentials) {
+			break
+		}
 	}
 
 	if loginErr != nil {
 		return fmt.Errorf("login failed after retries: %w", loginErr)
 	}
 
-	// Convert UserConfig to legacy Config
-	legacyCfg := &Config{
-		Email:             cfg.GasolinaEmail,
-		Password:          cfg.GasolinaPassword,
-		AccountNumber:     cfg.AccountNumber,
-		CheckURL:          cfg.CheckURL,
-		CronSchedule:      cfg.CronSchedule,
-		DryRun:            cfg.DryRun,
-		MonthlyIncrements: cfg.MonthlyIncrements,
-	}
+	// Convert UserConfig to legacy Config. The submission tracker keeps one
+	// submission ID for the whole job so check retries can't double-submit.
+	legacyCfg := cfg.ToConfig()
+	legacyCfg.Submissions = newJobSubmissionTracker(job)
+	applyIncrementsSource(ctx, legacyCfg, logger)
+	rampRun := applyDryRunPolicy(job, cfg, legacyCfg, logger)
 
-	// Check and update with retry
+	// Check and update with retry. Everything up to the submit click is
+	// idempotent; after it, only retry once the reading is known to be missing.
 	var checkErr error
-	for i := 0; i < 3; i++ {
+	for i := 0; i < jobMaxAttempts; i++ {
 		if i > 0 {
 			waitTime := time.Duration(i*2) * time.Second
-			logger.Log(fmt.Sprintf("Retry %d/3 after %v...", i+1, waitTime))
-			time.Sleep(waitTime)
+			logger.Log(fmt.Sprintf("Retry %d/%d after %v...", i+1, jobMaxAttempts, waitTime))
+			if err := sleepCtx(ctx, waitTime); err != nil {
+				return fmt.Errorf("check retry aborted: %w", err)
+			}
 		}
 
 		checkErr = CheckAndUpdateIfNeededWithLogger(ctx, legacyCfg, logger, saveScreenshot)
 		if checkErr == nil {
 			break
 		}
-		logger.Log(fmt.Sprintf("Check attempt %d/3 failed: %v", i+1, checkErr))
+		logger.Log(fmt.Sprintf("Check attempt %d/%d failed: %v", i+1, jobMaxAttempts, checkErr))
+
+		if !errors.Is(checkErr, ErrSubmitUncertain) || i == jobMaxAttempts-1 {
+			continue
+		}
+		landed, retry := reconfirmAfterSubmit(ctx, legacyCfg, logger)
+		if landed {
+			checkErr = nil
+			break
+		}
+		if !retry {
+			break
+		}
 	}
 
 	if checkErr != nil {
 		return fmt.Errorf("check and update failed after retries: %w", checkErr)
 	}
 
+	if rampRun {
+		recordRampRun(job, logger)
+	}
+
 	logger.Log("Full job completed successfully")
 	return nil
 }
 
+// reconfirmAfterSubmit checks the indicator table after a failure past the
+// submit click. Reports whether the reading landed after all, and whether
+// submitting again is safe.
+func reconfirmAfterSubmit(ctx context.Context, cfg *Config, logger Logger) (landed, retry bool) {
+	if !retryAfterSubmit {
+		logger.Log("Not retrying: the submission may have gone through (JOB_RETRY_AFTER_SUBMIT=false)")
+		return false, false
+	}
+
+	logger.Log("Failure after submitting - re-confirming via the indicator table before any retry")
+	now := cfg.submissionNow()
+	exists, err := reverifySubmission(ctx, cfg, now, logger)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Could not re-confirm the submission, not retrying to avoid a double submission: %v", err))
+		return false, false
+	}
+	if !exists {
+		logger.Log("No record was created - the submission can be retried")
+		return false, true
+	}
+
+	logger.Log("The reading is in the indicator table - treating the submission as successful")
+	if cfg.Submissions != nil {
+		if err := cfg.Submissions.ConfirmPeriod(now.Year(), int(now.Month())); err != nil {
+			logger.Log(fmt.Sprintf("Warning: failed to confirm pending submissions: %v", err))
+		}
+	}
+	reportPhase(logger, PhaseSubmitted)
+	return true, false
+}
+
+// applyDryRunPolicy decides whether a job may submit. The global kill switch
+// wins over everything, then dry-run jobs, then the user's safety ramp.
+// Reports whether the run counts towards the ramp.
+func applyDryRunPolicy(job *Job, cfg *UserConfig, legacyCfg *Config, logger Logger) bool {
+	if globalForceDryRun {
+		if !legacyCfg.DryRun && job.Type != "dry-run" {
+			slog.Warn("GLOBAL_FORCE_DRY_RUN: suppressing live submission", jobLogAttrs(job.ID, job.UserID)...)
+		}
+		logger.Log("GLOBAL_FORCE_DRY_RUN is set on this server: the form will be filled but not submitted")
+		legacyCfg.DryRun = true
+		return false
+	}
+	if job.Type == "dry-run" {
+		// Preview only: never submit, whatever the user's config says
+		logger.Log("Dry-run job: the form will be filled but not submitted")
+		legacyCfg.DryRun = true
+		return false
+	}
+	return applyDryRunRamp(cfg, legacyCfg, logger)
+}
+
+// applyDryRunRamp forces dry-run for a user's first live-eligible runs so they
+// can review what would be submitted. Reports whether the run was forced.
+func applyDryRunRamp(cfg *UserConfig, legacyCfg *Config, logger Logger) bool {
+	if !dryRunRampApplies(cfg) {
+		return false
+	}
+	logger.Log(fmt.Sprintf("Safety ramp: live run %d of %d is forced to dry-run (set skip_dry_run_ramp to override)",
+		cfg.RampRunsDone+1, dryRunRampRuns))
+	legacyCfg.DryRun = true
+	return true
+}
+
+// dryRunRampApplies reports whether the user's next live-eligible run is forced to dry-run
+func dryRunRampApplies(cfg *UserConfig) bool {
+	return !cfg.DryRun && !cfg.SkipDryRunRamp && cfg.RampRunsDone < dryRunRampRuns && IsEnabled(FlagDryRunRamp)
+}
+
+// recordRampRun counts a completed safety ramp run towards the user's limit
+func recordRampRun(job *Job, logger Logger) {
+	if err := IncrementRampRuns(job.UserID); err != nil {
+		logger.Log(fmt.Sprintf("Warning: failed to record safety ramp run: %v", err))
+	}
+}
+
 // createJobBrowserContext creates a browser context for job execution
 func createJobBrowserContext() (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.UserAgent("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-	)
-
-	allocCtx, _ := chromedp.NewExecAllocator(context.Background(), opts...)
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), browserAllocatorOptions()...)
 	ctx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
 
-	return ctx, cancel
+	// Closing the tab alone would leave the Chrome process running
+	return ctx, func() {
+		cancel()
+		allocCancel()
+	}
+}
+
+// jobSubmissionTracker persists a job's submission attempts to the database
+type jobSubmissionTracker struct {
+	jobID        string
+	userID       int64
+	submissionID string
+}
+
+func newJobSubmissionTracker(job *Job) *jobSubmissionTracker {
+	return &jobSubmissionTracker{
+		jobID:        job.ID,
+		userID:       job.UserID,
+		submissionID: uuid.New().String(),
+	}
+}
+
+func (t *jobSubmissionTracker) Pending(counterSerial string, year, month int) (*Submission, error) {
+	return GetPendingSubmission(t.userID, counterSerial, year, month)
+}
+
+func (t *jobSubmissionTracker) Attempt(counterSerial string, year, month int, previousValue, newValue, increment float64) (string, error) {
+	err := RecordSubmissionAttempt(&Submission{
+		ID:             t.submissionID,
+		JobID:          t.jobID,
+		UserID:         t.userID,
+		CounterSerial:  counterSerial,
+		Year:           year,
+		Month:          month,
+		PreviousValue:  previousValue,
+		SubmittedValue: newValue,
+		Increment:      increment,
+	})
+	if err != nil {
+		return "", err
+	}
+	return t.submissionID, nil
+}
+
+func (t *jobSubmissionTracker) Confirm(id string) error {
+	return SetSubmissionStatus(id, SubmissionConfirmed)
+}
+
+func (t *jobSubmissionTracker) Abandon(id string) error {
+	return SetSubmissionStatus(id, SubmissionAbandoned)
+}
+
+func (t *jobSubmissionTracker) ConfirmPeriod(year, month int) error {
+	return ConfirmPendingSubmissions(t.userID, year, month)
+}
+
+// phaseProgress maps job phases to a coarse progress percentage
+var phaseProgress = map[string]int{
+	PhaseLoginDone:     25,
+	PhaseValueRead:     50,
+	PhaseRecordChecked: 70,
+	PhaseSubmitted:     100,
+}
+
+// jobLogVerbosity is the most detailed log level JobLoggers record
+var jobLogVerbosity = LogLevelNormal
+
+// SetJobLogVerbosity sets the verbosity of new job loggers
+func SetJobLogVerbosity(level LogLevel) {
+	jobLogVerbosity = level
 }
 
 // JobLogger collects logs for a job
 type JobLogger struct {
-	jobID string
-	logs  []string
-	mu    sync.Mutex
+	jobID     string
+	userID    int64
+	logs      []string
+	progress  int
+	outcome   string
+	result    *CheckResult
+	structure []string // missing selectors; nil when no structure check ran
+	verbosity LogLevel
+	mu        sync.Mutex
+
+	// Live log streams; closed once the job finishes
+	subscribers map[chan string]struct{}
+	finished    bool
+
+	// Incremental persistence: lines saved so far and the flusher's wake-up
+	saveMu   sync.Mutex
+	saved    int
+	flushNow chan struct{}
 }
 
 // NewJobLogger creates a new job logger
-func NewJobLogger(jobID string) *JobLogger {
+func NewJobLogger(jobID string, userID int64) *JobLogger {
 	return &JobLogger{
-		jobID: jobID,
-		logs:  make([]string, 0),
+		jobID:     jobID,
+		userID:    userID,
+		logs:      make([]string, 0),
+		verbosity: jobLogVerbosity,
 	}
 }
 
-// Log adds a log entry
+// Log adds a log entry at normal level
 func (jl *JobLogger) Log(message string) {
+	jl.LogAt(LogLevelNormal, message)
+}
+
+// LogAt adds a log entry if level is within the logger's verbosity
+func (jl *JobLogger) LogAt(level LogLevel, message string) {
+	if level > jl.verbosity {
+		return
+	}
+
 	jl.mu.Lock()
 	defer jl.mu.Unlock()
 
-	entry := fmt.Sprintf("%s %s", time.Now().Format(time.RFC3339), message)
+	entry := fmt.Sprintf("%s %s", time.Now().UTC().Format(time.RFC3339), message)
 	jl.logs = append(jl.logs, entry)
-	log.Printf("[Job %s] %s", jl.jobID, message)
+	jl.publish(entry)
+	if jl.flushNow != nil && len(jl.logs)-jl.saved >= logFlushEntries {
+		select {
+		case jl.flushNow <- struct{}{}:
+		default:
+		}
+	}
+	slogLevel := slog.LevelInfo
+	if level >= LogLevelVerbose {
+		slogLevel = slog.LevelDebug
+	}
+	slog.Log(context.Background(), slogLevel, message, jobLogAttrs(jl.jobID, jl.userID)...)
 }
 
-// Save persists logs to database
-func (jl *JobLogger) Save() {
+// Phase records a phase transition and persists the job's progress.
+// Only forward movement is written, so repeated phases (e.g. retries) cost nothing.
+func (jl *JobLogger) Phase(name string) {
+	pct, ok := phaseProgress[name]
+	if !ok {
+		return
+	}
+
+	jl.mu.Lock()
+	if pct <= jl.progress {
+		jl.mu.Unlock()
+		return
+	}
+	jl.progress = pct
+	jl.mu.Unlock()
+
+	jl.LogAt(LogLevelQuiet, fmt.Sprintf("Phase %s reached (%d%%)", name, pct))
+
+	if err := UpdateJobProgress(jl.jobID, pct); err != nil {
+		slog.Error("Failed to update job progress", append(jobLogAttrs(jl.jobID, jl.userID), "error", err)...)
+	}
+	notifyProgress(jl.jobID, name, pct)
+}
+
+// Outcome records the job's outcome as reported by the checker
+func (jl *JobLogger) Outcome(name string) {
+	jl.mu.Lock()
+	jl.outcome = name
+	jl.mu.Unlock()
+}
+
+// Result keeps the check result reported by the checker
+func (jl *JobLogger) Result(result *CheckResult) {
+	jl.mu.Lock()
+	jl.result = result
+	jl.mu.Unlock()
+}
+
+// Structure keeps the result of the page structure check
+func (jl *JobLogger) Structure(missing []string) {
+	if missing == nil {
+		missing = []string{}
+	}
+	jl.mu.Lock()
+	jl.structure = missing
+	jl.mu.Unlock()
+}
+
+// structureCheck returns the missing selectors and whether a check ran
+func (jl *JobLogger) structureCheck() ([]string, bool) {
+	jl.mu.Lock()
+	defer jl.mu.Unlock()
+	return jl.structure, jl.structure != nil
+}
+
+// checkResult returns the reported check result, if any
+func (jl *JobLogger) checkResult() *CheckResult {
 	jl.mu.Lock()
 	defer jl.mu.Unlock()
-	AppendJobLogs(jl.jobID, jl.logs)
+	return jl.result
+}
+
+// outcomeFor returns the job's outcome given its final error
+func (jl *JobLogger) outcomeFor(jobErr error) string {
+	if jobErr != nil {
+		return OutcomeFailure
+	}
+	jl.mu.Lock()
+	defer jl.mu.Unlock()
+	if jl.outcome != "" {
+		return jl.outcome
+	}
+	return OutcomeSuccess
+}
+
+// Save persists logs to database. It writes the full log each time, so
+// repeated saves are harmless; saves are serialized so an older snapshot can
+// never overwrite a newer one.
+func (jl *JobLogger) Save() {
+	jl.saveMu.Lock()
+	defer jl.saveMu.Unlock()
+
+	jl.mu.Lock()
+	if len(jl.logs) == jl.saved {
+		jl.mu.Unlock()
+		return
+	}
+	logs := append([]string(nil), jl.logs...)
+	jl.mu.Unlock()
+
+	if err := AppendJobLogs(jl.jobID, logs); err != nil {
+		slog.Error("Failed to save job logs", append(jobLogAttrs(jl.jobID, jl.userID), "error", err)...)
+		return
+	}
+
+	jl.mu.Lock()
+	jl.saved = len(logs)
+	jl.mu.Unlock()
+}
+
+// Periodic log persistence, so a crash mid-job keeps the logs so far
+const (
+	logFlushInterval = 5 * time.Second
+	logFlushEntries  = 20
+)
+
+// startFlusher saves the logs every logFlushInterval, or sooner once
+// logFlushEntries unsaved lines pile up. The returned func stops it.
+func (jl *JobLogger) startFlusher() (stop func()) {
+	done := make(chan struct{})
+	flushNow := make(chan struct{}, 1)
+
+	jl.mu.Lock()
+	jl.flushNow = flushNow
+	jl.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(logFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			case <-flushNow:
+			}
+			jl.Save()
+		}
+	}()
+
+	return func() {
+		jl.mu.Lock()
+		jl.flushNow = nil
+		jl.mu.Unlock()
+		close(done)
+	}
 }
@@ -0,0 +1,803 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhaseProgressIncreases(t *testing.T) {
+	phases := []string{PhaseLoginDone, PhaseValueRead, PhaseRecordChecked, PhaseSubmitted}
+	prev := 0
+	for _, phase := range phases {
+		pct, ok := phaseProgress[phase]
+		if !ok {
+			t.Fatalf("phase %q has no progress", phase)
+		}
+		if pct <= prev || pct > 100 {
+			t.Errorf("phase %q progress %d, want above %d and at most 100", phase, pct, prev)
+		}
+		prev = pct
+	}
+}
+
+func TestJobLoggerPhaseProgress(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "full")
+	logger := NewJobLogger(job.ID, user.ID)
+
+	steps := []struct {
+		phase string
+		want  int
+	}{
+		{PhaseLoginDone, 25},
+		{PhaseValueRead, 50},
+		{PhaseLoginDone, 50}, // a retried login doesn't move progress back
+		{"unknown", 50},
+		{PhaseRecordChecked, 70},
+	}
+	for _, step := range steps {
+		reportPhase(logger, step.phase)
+		got, err := GetJob(context.Background(), job.ID)
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if got.Progress != step.want {
+			t.Errorf("after %s progress = %d, want %d", step.phase, got.Progress, step.want)
+		}
+	}
+
+	if err := UpdateJobStatus(job.ID, "completed", nil); err != nil {
+		t.Fatalf("UpdateJobStatus: %v", err)
+	}
+	if got, _ := GetJob(context.Background(), job.ID); got.Progress != 100 {
+		t.Errorf("completed job progress = %d, want 100", got.Progress)
+	}
+}
+
+// fakeExecutor stands in for Chrome, running fn as the job's work
+type fakeExecutor struct {
+	fn func(ctx context.Context, job *Job, logger *JobLogger) error
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, job *Job, cfg *UserConfig, logger *JobLogger) error {
+	return e.fn(ctx, job, logger)
+}
+
+func TestExecuteJobWithFakeExecutor(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(ctx context.Context, job *Job, logger *JobLogger) error
+		wantStatus  string
+		wantOutcome string
+		wantCode    string
+	}{
+		{
+			name:        "success",
+			run:         func(context.Context, *Job, *JobLogger) error { return nil },
+			wantStatus:  "completed",
+			wantOutcome: OutcomeSuccess,
+		},
+		{
+			name: "reported outcome",
+			run: func(_ context.Context, _ *Job, logger *JobLogger) error {
+				reportOutcome(logger, OutcomeAlreadyExists)
+				return nil
+			},
+			wantStatus:  "completed",
+			wantOutcome: OutcomeAlreadyExists,
+		},
+		{
+			name: "classified failure",
+			run: func(context.Context, *Job, *JobLogger) error {
+				return fmt.Errorf("login: %w", ErrInvalidCredentials)
+			},
+			wantStatus:  "failed",
+			wantOutcome: OutcomeFailure,
+			wantCode:    ErrInvalidCredentials.Error(),
+		},
+		{
+			name: "timeout",
+			run: func(ctx context.Context, _ *Job, _ *JobLogger) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantStatus:  "failed",
+			wantOutcome: OutcomeFailure,
+			wantCode:    ErrorCodeTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t)
+			job := createTestJob(t, user.ID, "full")
+
+			jm := NewJobManagerWithExecutor(&fakeExecutor{fn: tt.run})
+			jm.jobTimeout = 50 * time.Millisecond
+			jm.executeJob(job)
+
+			got, err := GetJob(context.Background(), job.ID)
+			if err != nil {
+				t.Fatalf("GetJob: %v", err)
+			}
+			if got.Status != tt.wantStatus || got.Outcome != tt.wantOutcome || got.ErrorCode != tt.wantCode {
+				t.Errorf("job = status %q outcome %q code %q, want %q %q %q",
+					got.Status, got.Outcome, got.ErrorCode, tt.wantStatus, tt.wantOutcome, tt.wantCode)
+			}
+			if len(got.Logs) == 0 {
+				t.Error("job has no saved logs")
+			}
+		})
+	}
+}
+
+func TestExecuteJobStoresCheckResult(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "dry-run")
+	want := &CheckResult{Month: 2, PreviousValue: 1200, NewValue: 1250, Increment: 50, DryRun: true}
+
+	jm := NewJobManagerWithExecutor(&fakeExecutor{fn: func(_ context.Context, _ *Job, logger *JobLogger) error {
+		reportResult(logger, want)
+		return nil
+	}})
+	jm.executeJob(job)
+
+	got, err := GetJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if got.Result == nil || *got.Result != *want {
+		t.Errorf("job result = %+v, want %+v", got.Result, want)
+	}
+}
+
+func TestExecuteJobCancelled(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "full")
+
+	var jm *JobManager
+	jm = NewJobManagerWithExecutor(&fakeExecutor{fn: func(ctx context.Context, job *Job, _ *JobLogger) error {
+		if running, err := jm.CancelJob(job); err != nil || !running {
+			t.Errorf("CancelJob = %v, %v; want running", running, err)
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	jm.executeJob(job)
+
+	got, err := GetJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if got.Status != "cancelled" {
+		t.Errorf("status = %q, want cancelled", got.Status)
+	}
+}
+
+func TestCancelJobNotRunning(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		wantErr    error
+		wantStatus string
+		wantQueued int
+	}{
+		{"queued job", "pending", nil, "cancelled", 0},
+		{"finished job", "completed", ErrJobNotCancellable, "completed", 1},
+		{"already cancelled", "cancelled", ErrJobNotCancellable, "cancelled", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t)
+			job := createTestJob(t, user.ID, "full")
+			if tt.status != "pending" {
+				if err := UpdateJobStatus(job.ID, tt.status, nil); err != nil {
+					t.Fatalf("UpdateJobStatus: %v", err)
+				}
+			}
+
+			jm := NewJobManagerWithExecutor(&fakeExecutor{})
+			queue := make(chan *Job, 10)
+			jm.queues[user.ID] = queue
+			queue <- job
+
+			running, err := jm.CancelJob(job)
+			if running || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CancelJob = %v, %v, want false, %v", running, err, tt.wantErr)
+			}
+			if len(queue) != tt.wantQueued {
+				t.Errorf("%d jobs left in the queue, want %d", len(queue), tt.wantQueued)
+			}
+			if got, err := GetJob(context.Background(), job.ID); err != nil || got.Status != tt.wantStatus {
+				t.Errorf("job status = %v (%v), want %q", got, err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestExecuteJobSkipsUnclaimableJob(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "full")
+	if err := UpdateJobStatus(job.ID, "cancelled", nil); err != nil {
+		t.Fatalf("UpdateJobStatus: %v", err)
+	}
+
+	ran := false
+	jm := NewJobManagerWithExecutor(&fakeExecutor{fn: func(context.Context, *Job, *JobLogger) error {
+		ran = true
+		return nil
+	}})
+	jm.executeJob(job)
+	if ran {
+		t.Error("executor ran a job that was no longer pending")
+	}
+}
+
+// testLogger collects log lines in memory
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Log(message string) {
+	l.lines = append(l.lines, message)
+}
+
+func TestApplyDryRunRamp(t *testing.T) {
+	prevRuns := dryRunRampRuns
+	t.Cleanup(func() { dryRunRampRuns = prevRuns })
+	SetDryRunRampRuns(2)
+
+	tests := []struct {
+		name       string
+		cfg        UserConfig
+		flagOff    bool
+		wantForced bool
+	}{
+		{"first live run", UserConfig{}, false, true},
+		{"last ramp run", UserConfig{RampRunsDone: 1}, false, true},
+		{"ramp done", UserConfig{RampRunsDone: 2}, false, false},
+		{"user already dry-run", UserConfig{DryRun: true}, false, false},
+		{"user skipped the ramp", UserConfig{SkipDryRunRamp: true}, false, false},
+		{"flag off", UserConfig{}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagDryRunRamp: !tt.flagOff})
+			legacy := &Config{DryRun: tt.cfg.DryRun}
+			forced := applyDryRunRamp(&tt.cfg, legacy, &testLogger{})
+			if forced != tt.wantForced {
+				t.Errorf("forced = %v, want %v", forced, tt.wantForced)
+			}
+			if wantDryRun := tt.cfg.DryRun || tt.wantForced; legacy.DryRun != wantDryRun {
+				t.Errorf("DryRun = %v, want %v", legacy.DryRun, wantDryRun)
+			}
+		})
+	}
+}
+
+func TestApplyDryRunPolicy(t *testing.T) {
+	prevForce, prevRuns := globalForceDryRun, dryRunRampRuns
+	t.Cleanup(func() {
+		SetGlobalForceDryRun(prevForce)
+		dryRunRampRuns = prevRuns
+	})
+	SetDryRunRampRuns(1)
+	setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagDryRunRamp: true})
+
+	tests := []struct {
+		name       string
+		force      bool
+		jobType    string
+		cfg        UserConfig
+		wantDryRun bool
+		wantRamp   bool
+	}{
+		{"live run after the ramp", false, "full", UserConfig{RampRunsDone: 1}, false, false},
+		{"ramp run", false, "full", UserConfig{}, true, true},
+		{"dry-run job skips the ramp", false, "dry-run", UserConfig{}, true, false},
+		{"kill switch beats a live user", true, "full", UserConfig{RampRunsDone: 1}, true, false},
+		{"kill switch does not count as a ramp run", true, "full", UserConfig{}, true, false},
+		{"kill switch with a dry-run job", true, "dry-run", UserConfig{}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetGlobalForceDryRun(tt.force)
+			legacy := &Config{DryRun: tt.cfg.DryRun}
+			ramp := applyDryRunPolicy(&Job{ID: "job-1", Type: tt.jobType}, &tt.cfg, legacy, &testLogger{})
+			if ramp != tt.wantRamp {
+				t.Errorf("ramp = %v, want %v", ramp, tt.wantRamp)
+			}
+			if legacy.DryRun != tt.wantDryRun {
+				t.Errorf("DryRun = %v, want %v", legacy.DryRun, tt.wantDryRun)
+			}
+		})
+	}
+}
+
+func TestSetDryRunRampRunsClampsNegative(t *testing.T) {
+	prevRuns := dryRunRampRuns
+	t.Cleanup(func() { dryRunRampRuns = prevRuns })
+	SetDryRunRampRuns(-1)
+	if dryRunRampRuns != 0 {
+		t.Errorf("dryRunRampRuns = %d, want 0", dryRunRampRuns)
+	}
+	if dryRunRampApplies(&UserConfig{}) {
+		t.Error("ramp applies with zero ramp runs")
+	}
+}
+
+func TestWaitResumed(t *testing.T) {
+	tests := []struct {
+		name  string
+		pause bool
+		// action runs while waitResumed may be blocked
+		action func(jm *JobManager)
+		want   bool
+	}{
+		{"not paused", false, func(*JobManager) {}, true},
+		{"resumed", true, func(jm *JobManager) { jm.SetMaintenance(false) }, true},
+		{"shut down while paused", true, func(jm *JobManager) { close(jm.shutdown) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jm := NewJobManagerWithExecutor(&fakeExecutor{})
+			jm.SetMaintenance(tt.pause)
+			if jm.InMaintenance() != tt.pause {
+				t.Fatalf("InMaintenance() = %v, want %v", jm.InMaintenance(), tt.pause)
+			}
+
+			done := make(chan bool, 1)
+			go func() { done <- jm.waitResumed() }()
+			if tt.pause {
+				select {
+				case <-done:
+					t.Fatal("waitResumed returned while in maintenance")
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+			tt.action(jm)
+			select {
+			case got := <-done:
+				if got != tt.want {
+					t.Errorf("waitResumed() = %v, want %v", got, tt.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("waitResumed did not return")
+			}
+		})
+	}
+}
+
+func TestSetMaintenanceIsIdempotent(t *testing.T) {
+	jm := NewJobManagerWithExecutor(&fakeExecutor{})
+	jm.SetMaintenance(false) // already resumed: must not close resumed twice
+	jm.SetMaintenance(true)
+	jm.SetMaintenance(true)
+	jm.SetMaintenance(false)
+	if jm.InMaintenance() {
+		t.Error("InMaintenance() = true after resuming")
+	}
+}
+
+func TestDequeueKeepsOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued []string
+		drop   []string
+		want   []string
+	}{
+		{"nothing to drop", []string{"a", "b"}, nil, []string{"a", "b"}},
+		{"drop middle", []string{"a", "b", "c"}, []string{"b"}, []string{"a", "c"}},
+		{"drop all", []string{"a", "b"}, []string{"b", "a"}, nil},
+		{"unknown id", []string{"a"}, []string{"z"}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jm := NewJobManagerWithExecutor(&fakeExecutor{})
+			queue := make(chan *Job, 10)
+			jm.queues[1] = queue
+			for _, id := range tt.queued {
+				queue <- &Job{ID: id, UserID: 1}
+			}
+
+			jm.dequeue(1, tt.drop...)
+			jm.dequeue(2, tt.drop...) // a user without a queue is a no-op
+
+			close(queue)
+			var got []string
+			for job := range queue {
+				got = append(got, job.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCancelPending(t *testing.T) {
+	user := createTestUser(t)
+	pending := createTestJob(t, user.ID, "full")
+	running := createTestJob(t, user.ID, "full")
+	if claimed, err := ClaimJob(running.ID); err != nil || !claimed {
+		t.Fatalf("ClaimJob = %v, %v", claimed, err)
+	}
+
+	jm := NewJobManagerWithExecutor(&fakeExecutor{})
+	queue := make(chan *Job, 10)
+	jm.queues[user.ID] = queue
+	queue <- pending
+
+	n, err := jm.CancelPending(user.ID)
+	if err != nil {
+		t.Fatalf("CancelPending: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("cancelled %d jobs, want 1", n)
+	}
+	if len(queue) != 0 {
+		t.Errorf("%d jobs left in the queue", len(queue))
+	}
+	for id, want := range map[string]string{pending.ID: "cancelled", running.ID: "running"} {
+		job, err := GetJob(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if job.Status != want {
+			t.Errorf("job %s status = %q, want %q", id, job.Status, want)
+		}
+	}
+}
+
+func TestJobLoggerTimestampsAreUTC(t *testing.T) {
+	jl := NewJobLogger("job", 1)
+	jl.LogAt(LogLevelQuiet, "Job completed successfully")
+	stamp, _, _ := strings.Cut(jl.logs[0], " ")
+	ts, err := time.Parse(time.RFC3339, stamp)
+	if err != nil {
+		t.Fatalf("log line %q has no RFC 3339 timestamp: %v", jl.logs[0], err)
+	}
+	if !strings.HasSuffix(stamp, "Z") || ts.Location() != time.UTC {
+		t.Errorf("timestamp %q is not UTC", stamp)
+	}
+}
+
+func TestSetJobRetry(t *testing.T) {
+	prevAttempts, prevAfterSubmit := jobMaxAttempts, retryAfterSubmit
+	t.Cleanup(func() { SetJobRetry(prevAttempts, prevAfterSubmit) })
+
+	tests := []struct {
+		maxAttempts  int
+		afterSubmit  bool
+		wantAttempts int
+	}{
+		{3, true, 3},
+		{5, false, 5},
+		{1, true, 1},
+		{0, true, 1},
+		{-4, false, 1},
+	}
+	for _, tt := range tests {
+		SetJobRetry(tt.maxAttempts, tt.afterSubmit)
+		if jobMaxAttempts != tt.wantAttempts || retryAfterSubmit != tt.afterSubmit {
+			t.Errorf("SetJobRetry(%d, %v) = %d, %v, want %d, %v", tt.maxAttempts, tt.afterSubmit,
+				jobMaxAttempts, retryAfterSubmit, tt.wantAttempts, tt.afterSubmit)
+		}
+	}
+}
+
+func TestReconfirmAfterSubmitNeverRetriesBlind(t *testing.T) {
+	prevAttempts, prevAfterSubmit := jobMaxAttempts, retryAfterSubmit
+	t.Cleanup(func() { SetJobRetry(prevAttempts, prevAfterSubmit) })
+
+	tests := []struct {
+		name        string
+		afterSubmit bool
+		wantLog     string
+	}{
+		{"retry after submit disabled", false, "JOB_RETRY_AFTER_SUBMIT=false"},
+		// Without a browser the indicator table can't be read, so nothing is retried
+		{"re-confirmation fails", true, "Could not re-confirm the submission"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetJobRetry(3, tt.afterSubmit)
+			logger := &testLogger{}
+			landed, retry := reconfirmAfterSubmit(context.Background(), &Config{CheckURL: "https://example.test/indicators"}, logger)
+			if landed || retry {
+				t.Errorf("reconfirmAfterSubmit() = %v, %v, want false, false", landed, retry)
+			}
+			if !strings.Contains(strings.Join(logger.lines, "\n"), tt.wantLog) {
+				t.Errorf("log %q does not mention %q", logger.lines, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestTakeRecovered(t *testing.T) {
+	jm := NewJobManagerWithExecutor(&fakeExecutor{})
+	jm.recovered[1] = []*Job{{ID: "a", UserID: 1}, {ID: "b", UserID: 1}}
+	jm.recovered[2] = []*Job{{ID: "c", UserID: 2}}
+
+	steps := []struct {
+		userID int64
+		want   string // "" for no job
+	}{
+		{1, "a"},
+		{2, "c"},
+		{2, ""},
+		{1, "b"},
+		{1, ""},
+		{3, ""},
+	}
+	for i, step := range steps {
+		got := ""
+		if job := jm.takeRecovered(step.userID); job != nil {
+			got = job.ID
+		}
+		if got != step.want {
+			t.Errorf("step %d: takeRecovered(%d) = %q, want %q", i, step.userID, got, step.want)
+		}
+	}
+	if len(jm.recovered) != 0 {
+		t.Errorf("recovered = %v, want empty", jm.recovered)
+	}
+}
+
+func TestStartRequiresEncryptionKey(t *testing.T) {
+	prev := encryptionKey.Load()
+	t.Cleanup(func() { encryptionKey.Store(prev) })
+	encryptionKey.Store(nil)
+
+	if err := NewJobManagerWithExecutor(&fakeExecutor{}).Start(); err == nil {
+		t.Error("Start() error = nil, want an error without the encryption key")
+	}
+}
+
+func TestStartRecoversJobs(t *testing.T) {
+	user := createTestUser(t)
+	prev := encryptionKey.Load()
+	t.Cleanup(func() { encryptionKey.Store(prev) })
+	SetEncryptionKey("test-secret")
+
+	first := createTestJob(t, user.ID, "full")
+	second := createTestJob(t, user.ID, "full")
+	interrupted := createTestJob(t, user.ID, "full")
+	if claimed, err := ClaimJob(interrupted.ID); err != nil || !claimed {
+		t.Fatalf("ClaimJob = %v, %v", claimed, err)
+	}
+
+	ran := make(chan string, 10)
+	jm := NewJobManagerWithExecutor(&fakeExecutor{fn: func(_ context.Context, job *Job, _ *JobLogger) error {
+		if job.UserID == user.ID {
+			ran <- job.ID
+		}
+		return nil
+	}})
+	if err := jm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer jm.Stop()
+	queued, err := jm.CreateJob(user.ID, "full", JobSourceAPI)
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+
+	// Recovered jobs run oldest first and ahead of anything queued after startup
+	for _, want := range []string{first.ID, second.ID, queued.ID} {
+		select {
+		case got := <-ran:
+			if got != want {
+				t.Errorf("ran job %s, want %s", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for job %s", want)
+		}
+	}
+
+	got, err := GetJob(context.Background(), interrupted.ID)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if got.Status != "failed" {
+		t.Errorf("interrupted job status = %q, want failed", got.Status)
+	}
+}
+
+func TestJobLoggerRequestsFlushAfterEnoughLines(t *testing.T) {
+	jl := NewJobLogger("job", 1)
+	// Stand in for startFlusher's wake-up channel without running its goroutine
+	jl.flushNow = make(chan struct{}, 1)
+
+	steps := []struct {
+		lines     int
+		wantFlush bool
+	}{
+		{logFlushEntries - 1, false},
+		{1, true},
+	}
+	for _, step := range steps {
+		for i := 0; i < step.lines; i++ {
+			jl.LogAt(LogLevelQuiet, "line")
+		}
+		if flush := len(jl.flushNow) == 1; flush != step.wantFlush {
+			t.Errorf("after %d lines flush requested = %v, want %v", len(jl.logs), flush, step.wantFlush)
+		}
+	}
+}
+
+func TestJobLoggerFlusherPersistsLogs(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "full")
+	logger := NewJobLogger(job.ID, user.ID)
+	stop := logger.startFlusher()
+	defer stop()
+
+	for i := 0; i < logFlushEntries; i++ {
+		logger.LogAt(LogLevelQuiet, fmt.Sprintf("line %d", i))
+	}
+
+	// The flush runs in the background; the job is still running
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, err := GetJob(context.Background(), job.ID)
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if len(got.Logs) == logFlushEntries {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("saved %d log lines, want %d", len(got.Logs), logFlushEntries)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestAcquireSlot(t *testing.T) {
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		max      int
+		held     int
+		ctx      context.Context
+		shutdown bool
+		want     bool
+	}{
+		{"no cap", 0, 0, expired, false, true},
+		{"free slot", 2, 1, context.Background(), false, true},
+		{"all taken, context done", 1, 1, expired, false, false},
+		{"all taken, shutting down", 1, 1, context.Background(), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jm := NewJobManagerWithExecutor(&fakeExecutor{})
+			jm.SetMaxConcurrentJobs(tt.max)
+			for i := 0; i < tt.held; i++ {
+				jm.acquireSlot(context.Background())
+			}
+			if tt.shutdown {
+				close(jm.shutdown)
+			}
+			if got := jm.acquireSlot(tt.ctx); got != tt.want {
+				t.Errorf("acquireSlot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseSlotFreesItForTheNextJob(t *testing.T) {
+	jm := NewJobManagerWithExecutor(&fakeExecutor{})
+	jm.SetMaxConcurrentJobs(1)
+	if !jm.acquireSlot(context.Background()) {
+		t.Fatal("first acquireSlot() = false")
+	}
+
+	acquired := make(chan bool)
+	go func() { acquired <- jm.acquireSlot(context.Background()) }()
+	select {
+	case <-acquired:
+		t.Fatal("second acquireSlot() returned while the slot was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	jm.releaseSlot()
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Error("second acquireSlot() = false after release")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second acquireSlot() still blocked after release")
+	}
+}
+
+func TestMaxConcurrentJobsAcrossUsers(t *testing.T) {
+	first := createTestUser(t)
+	second := createTestUser(t)
+
+	started := make(chan int64, 2)
+	release := make(chan struct{})
+	jm := NewJobManagerWithExecutor(&fakeExecutor{fn: func(_ context.Context, job *Job, _ *JobLogger) error {
+		started <- job.UserID
+		if job.UserID == first.ID {
+			<-release
+		}
+		return nil
+	}})
+	jm.SetMaxConcurrentJobs(1)
+	defer jm.Stop()
+	released := false
+	defer func() {
+		// Never leave Stop waiting on the blocked job when the test bails out
+		if !released {
+			close(release)
+		}
+	}()
+
+	if _, err := jm.CreateJob(first.ID, "full", JobSourceAPI); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	select {
+	case got := <-started:
+		if got != first.ID {
+			t.Fatalf("job of user %d started first, want %d", got, first.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("first job never started")
+	}
+
+	if _, err := jm.CreateJob(second.ID, "full", JobSourceAPI); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	select {
+	case <-started:
+		t.Fatal("second user's job started while the only slot was taken")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(release)
+	released = true
+	select {
+	case got := <-started:
+		if got != second.ID {
+			t.Errorf("job of user %d started, want %d", got, second.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second job never started after the slot was released")
+	}
+}
+
+func TestStopWhileWorkerWaitsForSlot(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	jm := NewJobManagerWithExecutor(&fakeExecutor{fn: func(context.Context, *Job, *JobLogger) error {
+		ran <- struct{}{}
+		return nil
+	}})
+	jm.SetMaxConcurrentJobs(1)
+	jm.acquireSlot(context.Background()) // held by someone else, e.g. a credential check
+
+	jm.enqueue(&Job{ID: "queued", UserID: 1, Type: "full"})
+	time.Sleep(20 * time.Millisecond) // let the worker pick the job up and block on the slot
+
+	stopped := make(chan struct{})
+	go func() {
+		jm.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked on a worker waiting for a slot")
+	}
+	select {
+	case <-ran:
+		t.Error("job ran after Stop()")
+	default:
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import "time"
+
+// AccountLockout locks an account after Threshold failed logins within Window.
+// The lock expires on its own after Duration.
+type AccountLockout struct {
+	Threshold int
+	Window    time.Duration
+	Duration  time.Duration
+}
+
+var accountLockout = AccountLockout{Threshold: 10, Window: time.Hour, Duration: 30 * time.Minute}
+
+// SetAccountLockout configures account lockout; a zero Threshold disables it
+func SetAccountLockout(l AccountLockout) {
+	accountLockout = l
+}
+
+// lockedFor returns how much longer the user is locked out, or 0
+func lockedFor(user *User, now time.Time) time.Duration {
+	if accountLockout.Threshold <= 0 || user.LockedUntil == nil {
+		return 0
+	}
+	if d := user.LockedUntil.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockedFor(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	tests := []struct {
+		name        string
+		threshold   int
+		lockedUntil *time.Time
+		want        time.Duration
+	}{
+		{"never locked", 10, nil, 0},
+		{"locked", 10, at(20 * time.Minute), 20 * time.Minute},
+		{"lock expired", 10, at(-time.Second), 0},
+		{"lockout disabled", 0, at(20 * time.Minute), 0},
+	}
+	prev := accountLockout
+	t.Cleanup(func() { SetAccountLockout(prev) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetAccountLockout(AccountLockout{Threshold: tt.threshold, Window: time.Hour, Duration: 30 * time.Minute})
+			if got := lockedFor(&User{LockedUntil: tt.lockedUntil}, now); got != tt.want {
+				t.Errorf("lockedFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordFailedLoginLocksAtThreshold(t *testing.T) {
+	user := createTestUser(t)
+	for i := 1; i <= 3; i++ {
+		lockedUntil, err := RecordFailedLogin(user.ID, 3, time.Hour, 30*time.Minute)
+		if err != nil {
+			t.Fatalf("RecordFailedLogin: %v", err)
+		}
+		if locked := lockedUntil != nil; locked != (i == 3) {
+			t.Fatalf("failure %d: locked = %v", i, locked)
+		}
+	}
+
+	if err := ClearFailedLogins(user.ID); err != nil {
+		t.Fatalf("ClearFailedLogins: %v", err)
+	}
+	lockedUntil, err := RecordFailedLogin(user.ID, 3, time.Hour, 30*time.Minute)
+	if err != nil || lockedUntil != nil {
+		t.Errorf("first failure after clearing = %v, %v, want no lock", lockedUntil, err)
+	}
+}
+
+func TestHandleLoginLockedAccount(t *testing.T) {
+	user := createTestUser(t)
+	prevLockout, prevLimiter := accountLockout, loginLimiter
+	SetAccountLockout(AccountLockout{Threshold: 2, Window: time.Hour, Duration: 30 * time.Minute})
+	SetLoginLimiter(NewLoginLimiter(0, time.Minute))
+	t.Cleanup(func() {
+		SetAccountLockout(prevLockout)
+		SetLoginLimiter(prevLimiter)
+	})
+
+	login := func(password string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		body := `{"email":"` + user.Email + `","password":"` + password + `"}`
+		handleLogin(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(body)))
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		password   string
+		wantStatus int
+	}{
+		{"first wrong password", "wrong", http.StatusUnauthorized},
+		{"wrong password locks", "wrong", http.StatusUnauthorized},
+		// A wrong password on a locked account must not reveal the lock
+		{"wrong password while locked", "wrong", http.StatusUnauthorized},
+		{"right password while locked", "password123", http.StatusLocked},
+	}
+	for _, tt := range tests {
+		if rec := login(tt.password); rec.Code != tt.wantStatus {
+			t.Fatalf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.wantStatus, rec.Body.String())
+		}
+	}
+}
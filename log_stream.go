@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// logSubscriberBuffer is how many lines a slow stream may fall behind before
+// lines are dropped for it; the job itself never waits on a subscriber
+const logSubscriberBuffer = 100
+
+// logStreamKeepalive is how often an idle stream sends a comment so proxies keep it open
+const logStreamKeepalive = 15 * time.Second
+
+// Subscribe returns the lines logged so far and a channel receiving every new
+// line. The channel is closed when the job finishes; call unsubscribe when done.
+func (jl *JobLogger) Subscribe() (backlog []string, lines <-chan string, unsubscribe func()) {
+	jl.mu.Lock()
+	defer jl.mu.Unlock()
+
+	backlog = append([]string(nil), jl.logs...)
+	ch := make(chan string, logSubscriberBuffer)
+	if jl.finished {
+		close(ch)
+		return backlog, ch, func() {}
+	}
+	if jl.subscribers == nil {
+		jl.subscribers = make(map[chan string]struct{})
+	}
+	jl.subscribers[ch] = struct{}{}
+
+	return backlog, ch, func() {
+		jl.mu.Lock()
+		defer jl.mu.Unlock()
+		if _, ok := jl.subscribers[ch]; ok {
+			delete(jl.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish fans a line out to subscribers. The caller holds jl.mu.
+func (jl *JobLogger) publish(entry string) {
+	for ch := range jl.subscribers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// finish closes all subscriber channels; later subscribers get the backlog only
+func (jl *JobLogger) finish() {
+	jl.mu.Lock()
+	defer jl.mu.Unlock()
+
+	jl.finished = true
+	for ch := range jl.subscribers {
+		close(ch)
+	}
+	jl.subscribers = nil
+}
+
+// trackLogger makes a running job's logger available to log streams
+func (jm *JobManager) trackLogger(jobID string, logger *JobLogger) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.loggers[jobID] = logger
+}
+
+// untrackLogger removes a finished job's logger and ends its streams
+func (jm *JobManager) untrackLogger(jobID string, logger *JobLogger) {
+	jm.mu.Lock()
+	delete(jm.loggers, jobID)
+	jm.mu.Unlock()
+	logger.finish()
+}
+
+// jobLogger returns the logger of a running job, or nil
+func (jm *JobManager) jobLogger(jobID string) *JobLogger {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	return jm.loggers[jobID]
+}
+
+// writeSSE writes one server-sent event; multi-line data becomes several data fields
+func writeSSE(w http.ResponseWriter, event, data string) {
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// handleStreamJobLogs streams a job's log lines as server-sent events. Lines
+// already logged are sent first; a final "done" event carries the job status.
+func handleStreamJobLogs(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := requireOwnedJob(r.Context(), userID, jobID); err != nil {
+		writeJobLookupError(w, r, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	// The server's write timeout would cut the stream off
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		jsonError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(logStreamKeepalive)
+	defer keepalive.Stop()
+
+	// A queued job has no logger yet; wait for it to start or finish
+	var logger *JobLogger
+	for logger == nil {
+		if logger = jobManager.jobLogger(jobID); logger != nil {
+			break
+		}
+		job, err := GetJob(r.Context(), jobID)
+		if err != nil || job == nil {
+			writeSSE(w, "error", "job is no longer available")
+			flusher.Flush()
+			return
+		}
+		if job.Status != "pending" && job.Status != "running" {
+			for _, line := range job.Logs {
+				writeSSE(w, "log", line)
+			}
+			writeSSE(w, "done", job.Status)
+			flusher.Flush()
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": waiting\n\n")
+			flusher.Flush()
+		case <-time.After(time.Second):
+		}
+	}
+
+	backlog, lines, unsubscribe := logger.Subscribe()
+	defer unsubscribe()
+
+	for _, line := range backlog {
+		writeSSE(w, "log", line)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case line, ok := <-lines:
+			if !ok {
+				// The job saved its final status before its logger finished
+				status := "unknown"
+				if job, err := GetJob(r.Context(), jobID); err == nil && job != nil {
+					status = job.Status
+				}
+				writeSSE(w, "done", status)
+				flusher.Flush()
+				return
+			}
+			writeSSE(w, "log", line)
+			flusher.Flush()
+		}
+	}
+}
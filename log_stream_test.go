@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteSSE(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		data  string
+		want  string
+	}{
+		{"single line", "log", "Login succeeded", "event: log\ndata: Login succeeded\n\n"},
+		{"multi-line data", "log", "first\nsecond", "event: log\ndata: first\ndata: second\n\n"},
+		{"empty data", "done", "", "event: done\ndata: \n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeSSE(rec, tt.event, tt.data)
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("writeSSE() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// logText strips the timestamp from a job log line
+func logText(line string) string {
+	_, text, _ := strings.Cut(line, " ")
+	return text
+}
+
+func TestJobLoggerSubscribe(t *testing.T) {
+	jl := NewJobLogger("job", 1)
+	jl.LogAt(LogLevelQuiet, "before")
+
+	backlog, lines, unsubscribe := jl.Subscribe()
+	defer unsubscribe()
+	if len(backlog) != 1 || !strings.HasSuffix(backlog[0], "before") {
+		t.Fatalf("backlog = %q, want the line logged before subscribing", backlog)
+	}
+
+	jl.LogAt(LogLevelQuiet, "after")
+	jl.finish()
+
+	var got []string
+	for line := range lines {
+		got = append(got, logText(line))
+	}
+	if want := []string{"after"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed %q, want %q", got, want)
+	}
+
+	// A late subscriber gets the full backlog and an already closed channel
+	backlog, lines, _ = jl.Subscribe()
+	if len(backlog) != 2 {
+		t.Errorf("late backlog has %d lines, want 2", len(backlog))
+	}
+	if _, ok := <-lines; ok {
+		t.Error("late subscriber channel is open")
+	}
+}
+
+func TestJobLoggerUnsubscribe(t *testing.T) {
+	jl := NewJobLogger("job", 1)
+	_, lines, unsubscribe := jl.Subscribe()
+	unsubscribe()
+	unsubscribe() // a second call is a no-op
+	if _, ok := <-lines; ok {
+		t.Error("channel is open after unsubscribe")
+	}
+
+	// Finishing after the subscriber left must not close its channel twice
+	jl.LogAt(LogLevelQuiet, "still logging")
+	jl.finish()
+}
+
+func TestJobLoggerSlowSubscriberDropsLines(t *testing.T) {
+	jl := NewJobLogger("job", 1)
+	_, lines, unsubscribe := jl.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < logSubscriberBuffer+10; i++ {
+		jl.LogAt(LogLevelQuiet, "line")
+	}
+	if len(lines) != logSubscriberBuffer {
+		t.Errorf("subscriber buffered %d lines, want %d", len(lines), logSubscriberBuffer)
+	}
+}
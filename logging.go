@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log output formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// ParseLogFormat parses LOG_FORMAT: "text" (default) or "json"
+func ParseLogFormat(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", LogFormatText:
+		return LogFormatText, nil
+	case LogFormatJSON:
+		return LogFormatJSON, nil
+	}
+	return "", fmt.Errorf("must be %q or %q, got %q", LogFormatText, LogFormatJSON, s)
+}
+
+// ParseLogLevel parses LOG_LEVEL: debug, info (default), warn or error
+func ParseLogLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("must be debug, info, warn or error, got %q", s)
+	}
+	return level, nil
+}
+
+// SetupLogging routes all logging, including the standard log package,
+// through a structured slog handler writing to stderr
+func SetupLogging(format string, level slog.Level) {
+	opts := &slog.HandlerOptions{Level: level, AddSource: true}
+	var handler slog.Handler
+	if format == LogFormatJSON {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	// log.Printf lines become info records of this handler
+	slog.SetDefault(slog.New(requestIDHandler{handler}))
+}
+
+// requestIDHandler adds the request ID to records logged with the request's
+// context, e.g. slog.InfoContext(r.Context(), ...)
+type requestIDHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request_id attribute when the context has one
+func (h requestIDHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id, ok := GetRequestIDFromContext(ctx); ok {
+		rec.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+// WithAttrs keeps the request ID handler around the derived handler
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the request ID handler around the derived handler
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
+
+// loggingFromEnv configures logging from LOG_FORMAT and LOG_LEVEL, for CLI mode
+func loggingFromEnv() error {
+	format, err := ParseLogFormat(os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		return fmt.Errorf("invalid LOG_FORMAT: %w", err)
+	}
+	level, err := ParseLogLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	SetupLogging(format, level)
+	return nil
+}
+
+// jobLogAttrs are the structured fields identifying a job in log records
+func jobLogAttrs(jobID string, userID int64) []any {
+	return []any{"job_id", jobID, "user_id", userID}
+}
+
+// fatal logs msg at error level and exits, the slog counterpart of log.Fatal
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/chromedp/chromedp"
@@ -15,16 +20,100 @@ type Logger interface {
 	Log(message string)
 }
 
+// LogLevel is the importance of a job log line
+type LogLevel int
+
+// Log levels double as verbosity settings: a logger records lines at or below its verbosity
+const (
+	LogLevelQuiet   LogLevel = iota // always recorded: phases, errors and the outcome
+	LogLevelNormal                  // progress of each step
+	LogLevelVerbose                 // selectors, screenshots and other debugging detail
+)
+
+// ParseLogVerbosity parses "quiet", "normal" or "verbose"
+func ParseLogVerbosity(s string) (LogLevel, error) {
+	switch s {
+	case "quiet":
+		return LogLevelQuiet, nil
+	case "normal", "":
+		return LogLevelNormal, nil
+	case "verbose":
+		return LogLevelVerbose, nil
+	}
+	return 0, fmt.Errorf("log verbosity must be quiet, normal or verbose, got %q", s)
+}
+
+// levelLogger is implemented by loggers that filter lines by verbosity
+type levelLogger interface {
+	LogAt(level LogLevel, message string)
+}
+
+// logAt logs message at the given level; loggers without levels record everything
+func logAt(logger Logger, level LogLevel, message string) {
+	if ll, ok := logger.(levelLogger); ok {
+		ll.LogAt(level, message)
+		return
+	}
+	logger.Log(message)
+}
+
+// logVerbose logs debugging detail that is only kept in verbose mode
+func logVerbose(logger Logger, message string) {
+	logAt(logger, LogLevelVerbose, message)
+}
+
+// Job phases reported by login and the checker
+const (
+	PhaseLoginDone     = "login_done"
+	PhaseValueRead     = "value_read"
+	PhaseRecordChecked = "record_checked"
+	PhaseSubmitted     = "submitted"
+)
+
+// phaseReporter is implemented by loggers that track job phase transitions
+type phaseReporter interface {
+	Phase(name string)
+}
+
+// reportPhase notifies the logger of a phase transition if it supports it
+func reportPhase(logger Logger, phase string) {
+	if pr, ok := logger.(phaseReporter); ok {
+		pr.Phase(phase)
+	}
+}
+
+// Job outcomes, used to decide which results trigger a notification
+const (
+	OutcomeSuccess       = "success"
+	OutcomeFailure       = "failure"
+	OutcomeAlreadyExists = "already_exists"
+	OutcomeNoChange      = "no_change"
+	// OutcomeAnomalyWarning is a dry run whose reading a live run would reject
+	OutcomeAnomalyWarning = "anomaly_warning"
+)
+
+// outcomeReporter is implemented by loggers that record a job's outcome
+type outcomeReporter interface {
+	Outcome(name string)
+}
+
+// reportOutcome records a non-default outcome (e.g. already_exists) if the logger supports it
+func reportOutcome(logger Logger, outcome string) {
+	if or, ok := logger.(outcomeReporter); ok {
+		or.Outcome(outcome)
+	}
+}
+
 // defaultLogger implements Logger using standard log
 type defaultLogger struct{}
 
 func (d *defaultLogger) Log(message string) {
-	log.Println(message)
+	slog.Info(message)
 }
 
 // GasolinaLogin performs authentication on gasolina-online.com
 // This is the refactored version that accepts logger and screenshot callback
-func GasolinaLogin(ctx context.Context, email, password, accountNumber string, logger Logger, saveScreenshot func(string)) error {
+func GasolinaLogin(ctx context.Context, config *Config, logger Logger, saveScreenshot func(string)) error {
 	if logger == nil {
 		logger = &defaultLogger{}
 	}
@@ -32,86 +121,41 @@ func GasolinaLogin(ctx context.Context, email, password, accountNumber string, l
 		saveScreenshot = func(name string) {}
 	}
 
+	email, password, accountNumber := config.Email, config.Password, config.AccountNumber
+	sel := config.selectors()
 	logger.Log(fmt.Sprintf("Attempting to login as %s...", email))
 
-	var loginURL = "https://gasolina-online.com/"
+	loginURL := config.LoginURL
+	if loginURL == "" {
+		loginURL = defaultBaseURL
+	}
+	logger.Log(fmt.Sprintf("Opening login page: %s", loginURL))
 
-	// Navigate and wait for page load
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(loginURL),
-		chromedp.Sleep(3*time.Second),
-	)
-	if err != nil {
+	if err := chromedp.Run(ctx, chromedp.Navigate(loginURL)); err != nil {
 		return fmt.Errorf("failed to navigate: %w", err)
 	}
 
+	// Wait for the form instead of sleeping a fixed time
+	fields, err := waitForLoginPage(ctx, logger)
+
 	// Save screenshot to see the page state
 	saveScreenshot("debug_before_login")
-	logger.Log("Screenshot saved: debug_before_login")
-
-	// Check what elements are on the page
-	var pageHTML string
-	err = chromedp.Run(ctx,
-		chromedp.Evaluate(`document.documentElement.outerHTML`, &pageHTML),
-	)
+	logVerbose(logger, "Screenshot saved: debug_before_login")
 	if err != nil {
-		logger.Log(fmt.Sprintf("Warning: couldn't get page HTML: %v", err))
-	} else {
-		logger.Log(fmt.Sprintf("Page HTML length: %d characters", len(pageHTML)))
+		return err
 	}
 
-	// Try to find input fields with various selectors
-	emailSelectors := []string{
-		`input[type="email"]`,
-		`input[name="email"]`,
-		`input[id="email"]`,
-		`input[placeholder*="email" i]`,
-		`input[placeholder*="пошта" i]`,
-		`input[placeholder*="Email" i]`,
-	}
+	// A consent overlay would swallow the clicks below
+	dismissCookieBanner(ctx, logger)
 
-	passwordSelectors := []string{
-		`input[type="password"]`,
-		`input[name="password"]`,
-		`input[id="password"]`,
-		`input[placeholder*="пароль" i]`,
-		`input[placeholder*="Password" i]`,
+	logVerbose(logger, fmt.Sprintf("Email field found with selector: %s", fields.Email))
+	if err := chromedp.Run(ctx, chromedp.SendKeys(fields.Email, email, chromedp.ByQuery)); err != nil {
+		return fmt.Errorf("failed to fill email field: %w", err)
 	}
 
-	// Try each email selector
-	emailFound := false
-	for _, selector := range emailSelectors {
-		err = chromedp.Run(ctx,
-			chromedp.WaitVisible(selector, chromedp.ByQuery),
-			chromedp.SendKeys(selector, email, chromedp.ByQuery),
-		)
-		if err == nil {
-			logger.Log(fmt.Sprintf("Email field found with selector: %s", selector))
-			emailFound = true
-			break
-		}
-	}
-
-	if !emailFound {
-		return fmt.Errorf("email field not found - check debug_before_login screenshot")
-	}
-
-	// Try each password selector
-	passwordFound := false
-	for _, selector := range passwordSelectors {
-		err = chromedp.Run(ctx,
-			chromedp.WaitVisible(selector, chromedp.ByQuery),
-			chromedp.SendKeys(selector, password, chromedp.ByQuery),
-		)
-		if err == nil {
-			logger.Log(fmt.Sprintf("Password field found with selector: %s", selector))
-			passwordFound = true
-			break
-		}
-	}
-
-	if !passwordFound {
-		return fmt.Errorf("password field not found - check debug_before_login screenshot")
+	logVerbose(logger, fmt.Sprintf("Password field found with selector: %s", fields.Password))
+	if err := chromedp.Run(ctx, chromedp.SendKeys(fields.Password, password, chromedp.ByQuery)); err != nil {
+		return fmt.Errorf("failed to fill password field: %w", err)
 	}
 
 	// Try to find and click the login button
@@ -127,11 +171,12 @@ func GasolinaLogin(ctx context.Context, email, password, accountNumber string, l
 
 	buttonFound := false
 	for _, selector := range buttonSelectors {
+		// Wait for the navigation after login as part of a successful click
 		err = chromedp.Run(ctx,
-			chromedp.Click(selector, chromedp.ByQuery),
+			settleAfter(chromedp.Click(selector, chromedp.ByQuery), 3*time.Second),
 		)
 		if err == nil {
-			logger.Log(fmt.Sprintf("Login button found with selector: %s", selector))
+			logVerbose(logger, fmt.Sprintf("Login button found with selector: %s", selector))
 			buttonFound = true
 			break
 		}
@@ -141,81 +186,359 @@ func GasolinaLogin(ctx context.Context, email, password, accountNumber string, l
 		logger.Log("Warning: login button not found, trying to submit form with Enter key")
 		// Try pressing Enter in the password field
 		err = chromedp.Run(ctx,
-			chromedp.SendKeys(`input[type="password"]`, "\n", chromedp.ByQuery),
+			settleAfter(chromedp.SendKeys(`input[type="password"]`, "\n", chromedp.ByQuery), 3*time.Second),
 		)
 		if err != nil {
 			return fmt.Errorf("couldn't submit login form")
 		}
 	}
 
-	// Wait for navigation after login
-	chromedp.Run(ctx, chromedp.Sleep(3*time.Second))
-
 	// Save screenshot after login attempt
 	saveScreenshot("debug_after_login")
-	logger.Log("Screenshot saved: debug_after_login")
+	logVerbose(logger, "Screenshot saved: debug_after_login")
+
+	if err := verifyLoginSucceeded(ctx, sel, logger); err != nil {
+		saveScreenshot("error_login_rejected")
+		return err
+	}
 
 	// Select account from dropdown
 	if accountNumber != "" {
 		logger.Log(fmt.Sprintf("Selecting account containing: %s", accountNumber))
+		dismissCookieBanner(ctx, logger)
 
 		// First, click the hamburger menu to open navigation using JavaScript
+		toggleJSON, _ := json.Marshal(sel.NavbarToggle)
 		err = chromedp.Run(ctx,
-			chromedp.Evaluate(`document.querySelector('.navbar-toggler').click()`, nil),
-			chromedp.Sleep(1*time.Second),
+			chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%s).click()`, toggleJSON), nil),
 		)
+		if err == nil {
+			err = waitVisible(ctx, sel.AccountDropdown)
+		}
 		if err != nil {
 			logger.Log(fmt.Sprintf("Hamburger menu click failed: %v", err))
 		}
 
 		saveScreenshot("debug_menu_open")
-		logger.Log("Screenshot saved: debug_menu_open")
+		logVerbose(logger, "Screenshot saved: debug_menu_open")
 
 		// Click the account dropdown toggle button using JavaScript
+		dropdownJSON, _ := json.Marshal(sel.AccountDropdown)
 		err = chromedp.Run(ctx,
-			chromedp.Evaluate(`document.querySelector('#dropdown01').click()`, nil),
-			chromedp.Sleep(1*time.Second),
+			chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%s).click()`, dropdownJSON), nil),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to click account dropdown: %w", err)
 		}
+		if err := waitVisible(ctx, sel.AccountItem); err != nil {
+			saveScreenshot("error_account_dropdown")
+			return fmt.Errorf("account dropdown did not open: %w", err)
+		}
 
 		saveScreenshot("debug_dropdown_open")
-		logger.Log("Screenshot saved: debug_dropdown_open")
-
-		// Find and click the dropdown item containing the account number using JavaScript
-		jsClick := fmt.Sprintf(`
-			const links = document.querySelectorAll('.dropdown-menu a.dropdown-item');
-			for (const link of links) {
-				if (link.textContent.includes('%s')) {
-					link.click();
-					break;
-				}
-			}
-		`, accountNumber)
-		err = chromedp.Run(ctx,
-			chromedp.Evaluate(jsClick, nil),
-			chromedp.Sleep(2*time.Second),
-		)
-		if err != nil {
-			return fmt.Errorf("failed to select account %s: %w", accountNumber, err)
+		logVerbose(logger, "Screenshot saved: debug_dropdown_open")
+
+		// Click the one dropdown item matching the account number
+		if err := selectAccount(ctx, sel, accountNumber, logger); err != nil {
+			saveScreenshot("error_account_selection")
+			return err
 		}
 
 		saveScreenshot("debug_account_selected")
 		logger.Log(fmt.Sprintf("Account %s selected successfully", accountNumber))
 	}
 
+	if err := verifyLanding(ctx, config, logger); err != nil {
+		saveScreenshot("error_unexpected_landing")
+		return err
+	}
+
 	logger.Log("Login sequence completed")
+	reportPhase(logger, PhaseLoginDone)
+	return nil
+}
+
+// Login page readiness errors
+var (
+	// ErrLoginPageNotLoaded means the login page didn't finish loading in time
+	ErrLoginPageNotLoaded = errors.New("login_page_not_loaded")
+	// ErrLoginFieldsNotFound means the page loaded but has no email/password fields
+	ErrLoginFieldsNotFound = errors.New("login_fields_not_found")
+)
+
+// defaultLoginPageTimeout bounds the wait for the login form
+const defaultLoginPageTimeout = 20 * time.Second
+
+// loginPageTimeout is how long to wait for the login form after navigation
+var loginPageTimeout = defaultLoginPageTimeout
+
+// SetLoginPageTimeout sets how long to wait for the login form after navigation
+func SetLoginPageTimeout(d time.Duration) {
+	loginPageTimeout = d
+}
+
+// ParseLoginPageTimeout parses LOGIN_PAGE_TIMEOUT; empty means the default
+func ParseLoginPageTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultLoginPageTimeout, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+var loginEmailSelectors = []string{
+	`input[type="email"]`,
+	`input[name="email"]`,
+	`input[id="email"]`,
+	`input[placeholder*="email" i]`,
+	`input[placeholder*="пошта" i]`,
+}
+
+var loginPasswordSelectors = []string{
+	`input[type="password"]`,
+	`input[name="password"]`,
+	`input[id="password"]`,
+	`input[placeholder*="пароль" i]`,
+	`input[placeholder*="Password" i]`,
+}
+
+// loginContainerSelectors identify the login form even when its inputs changed
+var loginContainerSelectors = []string{
+	`form[action*="login" i]`,
+	`.login-form`,
+	`#login-form`,
+	`.auth-form`,
+}
+
+// loginFields are the selectors that matched the login inputs
+type loginFields struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// loginPageStateJS reports the document state and the first visible login
+// inputs and container matching the given selectors
+const loginPageStateJS = `
+	(function(emails, passwords, containers) {
+		const first = (selectors) => {
+			for (const selector of selectors) {
+				const el = document.querySelector(selector);
+				if (el && el.offsetParent !== null) return selector;
+			}
+			return '';
+		};
+		return {
+			ready: document.readyState,
+			email: first(emails),
+			password: first(passwords),
+			container: first(containers),
+		};
+	})(%s, %s, %s)
+`
+
+// waitForLoginPage polls until the login inputs are visible, up to
+// loginPageTimeout. On timeout it tells a page that never loaded
+// (ErrLoginPageNotLoaded) from a loaded page without the fields
+// (ErrLoginFieldsNotFound).
+func waitForLoginPage(ctx context.Context, logger Logger) (*loginFields, error) {
+	emails, _ := json.Marshal(loginEmailSelectors)
+	passwords, _ := json.Marshal(loginPasswordSelectors)
+	containers, _ := json.Marshal(loginContainerSelectors)
+	script := fmt.Sprintf(loginPageStateJS, emails, passwords, containers)
+
+	deadline := time.Now().Add(loginPageTimeout)
+	var state struct {
+		Ready     string `json:"ready"`
+		Email     string `json:"email"`
+		Password  string `json:"password"`
+		Container string `json:"container"`
+	}
+	for {
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &state)); err != nil {
+			// The document may be mid-navigation; keep polling until the deadline
+			logVerbose(logger, fmt.Sprintf("Login page not ready yet: %v", err))
+		} else if state.Email != "" && state.Password != "" {
+			return &loginFields{Email: state.Email, Password: state.Password}, nil
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+		if err := sleepCtx(ctx, 250*time.Millisecond); err != nil {
+			return nil, err
+		}
+	}
+
+	if state.Ready == "complete" || state.Container != "" {
+		missing := "email"
+		if state.Email != "" {
+			missing = "password"
+		}
+		return nil, fmt.Errorf("%w: %s field not found on the loaded page - check debug_before_login screenshot",
+			ErrLoginFieldsNotFound, missing)
+	}
+	return nil, fmt.Errorf("%w: no login form after %v (document state %q)",
+		ErrLoginPageNotLoaded, loginPageTimeout, state.Ready)
+}
+
+// ErrInvalidCredentials is returned when the site rejects the login
+var ErrInvalidCredentials = errors.New("invalid_credentials")
+
+// loginResultTimeout bounds the wait for the site to accept or reject the login
+const loginResultTimeout = 10 * time.Second
+
+// loginErrorSelectors match the alerts the site shows for a rejected login
+var loginErrorSelectors = []string{
+	`.alert-danger`,
+	`.invalid-feedback`,
+	`.error-message`,
+	`[role="alert"]`,
+}
+
+// loggedInSelectors are only on pages behind the login; the account
+// dropdown selector is checked alongside them
+var loggedInSelectors = []string{
+	`a[href*="logout" i]`,
+}
+
+// loginResultJS reports whether the password field is still visible, the text
+// of any visible error alert and whether a logged-in-only element exists
+const loginResultJS = `
+	(function(passwords, alerts, loggedIn) {
+		const visible = (selectors) => {
+			for (const selector of selectors) {
+				const el = document.querySelector(selector);
+				if (el && el.offsetParent !== null) return el;
+			}
+			return null;
+		};
+		const alert = visible(alerts);
+		return {
+			password: visible(passwords) !== null,
+			alert: alert ? alert.innerText.trim() : '',
+			loggedIn: loggedIn.some(selector => document.querySelector(selector) !== null),
+		};
+	})(%s, %s, %s)
+`
+
+// loginResult is the page state read by loginResultJS
+type loginResult struct {
+	Password bool   `json:"password"`
+	Alert    string `json:"alert"`
+	LoggedIn bool   `json:"loggedIn"`
+}
+
+// outcome reports whether the login is decided and, if so, whether it was
+// rejected. An undecided page is polled again.
+func (r loginResult) outcome() (bool, error) {
+	switch {
+	case r.LoggedIn || !r.Password:
+		return true, nil
+	case r.Alert != "":
+		return true, fmt.Errorf("%w: the site rejected the login: %s", ErrInvalidCredentials, r.Alert)
+	}
+	return false, nil
+}
+
+// verifyLoginSucceeded polls the page after the login submit until it shows
+// we're logged in (a logged-in-only element, or the login form is gone) or the
+// site rejected the credentials (an error alert, or the form never goes away)
+func verifyLoginSucceeded(ctx context.Context, sel Selectors, logger Logger) error {
+	passwords, _ := json.Marshal(loginPasswordSelectors)
+	alerts, _ := json.Marshal(loginErrorSelectors)
+	loggedIn, _ := json.Marshal(append([]string{sel.AccountDropdown}, loggedInSelectors...))
+	script := fmt.Sprintf(loginResultJS, passwords, alerts, loggedIn)
+
+	deadline := time.Now().Add(loginResultTimeout)
+	var state loginResult
+	read := false
+	for {
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &state)); err != nil {
+			// Still navigating after the submit; keep polling until the deadline
+			logVerbose(logger, fmt.Sprintf("Login result not readable yet: %v", err))
+		} else {
+			read = true
+			if done, err := state.outcome(); done {
+				if err == nil {
+					logVerbose(logger, "Login accepted")
+				}
+				return err
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if !read {
+				return fmt.Errorf("could not read the page after submitting the login within %v", loginResultTimeout)
+			}
+			return fmt.Errorf("%w: the login form is still shown %v after submitting - check debug_after_login screenshot",
+				ErrInvalidCredentials, loginResultTimeout)
+		}
+		if err := sleepCtx(ctx, 250*time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
+
+// ErrUnexpectedLanding is returned when the browser ends up somewhere other than
+// the expected page after login, e.g. an error or captcha page
+var ErrUnexpectedLanding = errors.New("unexpected_landing")
+
+// accountPlaceholder in a landing pattern is replaced with the quoted account number
+const accountPlaceholder = "{account}"
+
+// compileLandingPattern compiles a landing URL pattern for the given account
+func compileLandingPattern(pattern, accountNumber string) (*regexp.Regexp, error) {
+	return regexp.Compile(strings.ReplaceAll(pattern, accountPlaceholder, regexp.QuoteMeta(accountNumber)))
+}
+
+// verifyLanding checks the post-login URL. Without a configured pattern it only
+// requires that the browser is still on the Gasolina site.
+func verifyLanding(ctx context.Context, config *Config, logger Logger) error {
+	var landingURL string
+	if err := chromedp.Run(ctx, chromedp.Location(&landingURL)); err != nil {
+		return fmt.Errorf("failed to read landing URL: %w", err)
+	}
+	logVerbose(logger, fmt.Sprintf("Landed on %s", landingURL))
+
+	if config.LandingURLPattern == "" {
+		if config.BaseURL != "" {
+			// A non-default site (e.g. a sandbox) only needs to keep us on its host
+			base, _ := url.Parse(config.BaseURL)
+			landing, err := url.Parse(landingURL)
+			if err != nil || base == nil || landing.Host != base.Host {
+				return fmt.Errorf("%w: %s is not on %s", ErrUnexpectedLanding, landingURL, config.BaseURL)
+			}
+			return nil
+		}
+		if err := validateSiteURL(landingURL); err != nil {
+			return fmt.Errorf("%w: %s (%v)", ErrUnexpectedLanding, landingURL, err)
+		}
+		return nil
+	}
+
+	re, err := compileLandingPattern(config.LandingURLPattern, config.AccountNumber)
+	if err != nil {
+		return fmt.Errorf("invalid landing URL pattern: %w", err)
+	}
+	if !re.MatchString(landingURL) {
+		return fmt.Errorf("%w: %s does not match %q", ErrUnexpectedLanding, landingURL, config.LandingURLPattern)
+	}
 	return nil
 }
 
 // Login is the legacy function for backwards compatibility with CLI mode
-func Login(ctx context.Context, email, password, accountNumber string) error {
+func Login(ctx context.Context, config *Config) error {
 	// Use old-style screenshot saving for CLI mode
 	saveScreenshot := func(name string) {
 		SaveScreenshot(ctx, name+".png")
 	}
-	return GasolinaLogin(ctx, email, password, accountNumber, nil, saveScreenshot)
+	return GasolinaLogin(ctx, config, nil, saveScreenshot)
 }
 
 // SaveScreenshot saves a screenshot for debugging purposes (legacy, for CLI mode)
@@ -229,7 +552,7 @@ func SaveScreenshot(ctx context.Context, filename string) error {
 		return fmt.Errorf("failed to save screenshot: %w", err)
 	}
 
-	log.Printf("Screenshot saved: %s", filename)
+	slog.Info("Screenshot saved", "path", filename)
 	return nil
 }
 
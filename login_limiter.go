@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LoginLimiter counts failed logins per key (client IP or email) in a sliding
+// window and blocks further attempts once the limit is reached
+type LoginLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	failures map[string][]time.Time
+	now      func() time.Time
+}
+
+// NewLoginLimiter allows limit failed attempts per key within window.
+// A limit of 0 disables limiting.
+func NewLoginLimiter(limit int, window time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		limit:    limit,
+		window:   window,
+		failures: make(map[string][]time.Time),
+		now:      time.Now,
+	}
+}
+
+// loginLimiter guards /api/auth/login
+var loginLimiter = NewLoginLimiter(5, 15*time.Minute)
+
+// SetLoginLimiter replaces the login rate limiter
+func SetLoginLimiter(l *LoginLimiter) {
+	loginLimiter = l
+}
+
+// Allow reports whether another attempt is allowed for all keys. When it
+// isn't, retryAfter is how long until the oldest counted failure expires.
+func (l *LoginLimiter) Allow(keys ...string) (retryAfter time.Duration, ok bool) {
+	if l.limit <= 0 {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for _, key := range keys {
+		recent := l.recentLocked(key, now)
+		if len(recent) >= l.limit {
+			if wait := recent[len(recent)-l.limit].Add(l.window).Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+	return retryAfter, retryAfter == 0
+}
+
+// RecordFailure counts a failed attempt against every key
+func (l *LoginLimiter) RecordFailure(keys ...string) {
+	if l.limit <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for _, key := range keys {
+		l.failures[key] = append(l.recentLocked(key, now), now)
+	}
+}
+
+// Reset forgets the failures of a key, e.g. after a successful login
+func (l *LoginLimiter) Reset(key string) {
+	l.mu.Lock()
+	delete(l.failures, key)
+	l.mu.Unlock()
+}
+
+// recentLocked drops expired failures of key and returns the rest
+func (l *LoginLimiter) recentLocked(key string, now time.Time) []time.Time {
+	times := l.failures[key]
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	if i == len(times) {
+		delete(l.failures, key)
+		return nil
+	}
+	if i > 0 {
+		times = append([]time.Time(nil), times[i:]...)
+		l.failures[key] = times
+	}
+	return times
+}
+
+// prune drops keys whose failures have all expired
+func (l *LoginLimiter) prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key := range l.failures {
+		l.recentLocked(key, now)
+	}
+}
+
+// RunPruner periodically frees memory held by expired failures until ctx is done
+func (l *LoginLimiter) RunPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.prune()
+		}
+	}
+}
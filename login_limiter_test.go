@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestLoginLimiter returns a limiter whose clock is advanced by hand
+func newTestLoginLimiter(limit int, window time.Duration) (*LoginLimiter, func(time.Duration)) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	l := NewLoginLimiter(limit, window)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLoginLimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		// steps records failures and moves the clock before Allow is checked
+		steps          func(l *LoginLimiter, advance func(time.Duration))
+		keys           []string
+		wantOK         bool
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:   "under the limit",
+			steps:  func(l *LoginLimiter, _ func(time.Duration)) { l.RecordFailure("ip:a", "email:x") },
+			keys:   []string{"ip:a", "email:x"},
+			wantOK: true,
+		},
+		{
+			name: "limit reached",
+			steps: func(l *LoginLimiter, advance func(time.Duration)) {
+				l.RecordFailure("ip:a")
+				advance(time.Minute)
+				l.RecordFailure("ip:a")
+			},
+			keys:           []string{"ip:a"},
+			wantRetryAfter: 14 * time.Minute,
+		},
+		{
+			name: "failures expire",
+			steps: func(l *LoginLimiter, advance func(time.Duration)) {
+				l.RecordFailure("ip:a")
+				l.RecordFailure("ip:a")
+				advance(15 * time.Minute)
+			},
+			keys:   []string{"ip:a"},
+			wantOK: true,
+		},
+		{
+			name: "any blocked key blocks",
+			steps: func(l *LoginLimiter, _ func(time.Duration)) {
+				l.RecordFailure("ip:a", "email:x")
+				l.RecordFailure("ip:b", "email:x")
+			},
+			keys:           []string{"ip:c", "email:x"},
+			wantRetryAfter: 15 * time.Minute,
+		},
+		{
+			name: "reset forgets failures",
+			steps: func(l *LoginLimiter, _ func(time.Duration)) {
+				l.RecordFailure("email:x")
+				l.RecordFailure("email:x")
+				l.Reset("email:x")
+			},
+			keys:   []string{"email:x"},
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, advance := newTestLoginLimiter(2, 15*time.Minute)
+			tt.steps(l, advance)
+			retryAfter, ok := l.Allow(tt.keys...)
+			if ok != tt.wantOK || retryAfter != tt.wantRetryAfter {
+				t.Errorf("Allow() = %v, %v, want %v, %v", retryAfter, ok, tt.wantRetryAfter, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLoginLimiterDisabled(t *testing.T) {
+	l, _ := newTestLoginLimiter(0, 15*time.Minute)
+	for i := 0; i < 10; i++ {
+		l.RecordFailure("ip:a")
+	}
+	if _, ok := l.Allow("ip:a"); !ok {
+		t.Error("Allow() = false with limiting disabled")
+	}
+}
+
+func TestLoginLimiterPrune(t *testing.T) {
+	l, advance := newTestLoginLimiter(2, 15*time.Minute)
+	l.RecordFailure("ip:a")
+	advance(10 * time.Minute)
+	l.RecordFailure("ip:b")
+	advance(6 * time.Minute)
+	l.prune()
+	if _, ok := l.failures["ip:a"]; ok {
+		t.Error("expired key ip:a was kept")
+	}
+	if _, ok := l.failures["ip:b"]; !ok {
+		t.Error("key ip:b with a recent failure was dropped")
+	}
+}
+
+func TestHandleLoginRateLimited(t *testing.T) {
+	l, _ := newTestLoginLimiter(1, time.Minute)
+	prev := loginLimiter
+	SetLoginLimiter(l)
+	t.Cleanup(func() { SetLoginLimiter(prev) })
+	l.RecordFailure("email:user@example.com")
+
+	rec := httptest.NewRecorder()
+	handleLogin(rec, newAuthedRequest(http.MethodPost, "/api/auth/login", `{"email":" User@Example.com ","password":"x"}`, 0))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 (%s)", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+}
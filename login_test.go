@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLogVerbosity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    LogLevel
+		wantErr bool
+	}{
+		{"", LogLevelNormal, false},
+		{"quiet", LogLevelQuiet, false},
+		{"normal", LogLevelNormal, false},
+		{"verbose", LogLevelVerbose, false},
+		{"debug", 0, true},
+		{"Verbose", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLogVerbosity(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLogVerbosity(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLogVerbosity(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobLoggerVerbosity(t *testing.T) {
+	tests := []struct {
+		verbosity LogLevel
+		wantLines int
+	}{
+		{LogLevelQuiet, 1},
+		{LogLevelNormal, 2},
+		{LogLevelVerbose, 3},
+	}
+	prev := jobLogVerbosity
+	t.Cleanup(func() { SetJobLogVerbosity(prev) })
+	for _, tt := range tests {
+		SetJobLogVerbosity(tt.verbosity)
+		jl := NewJobLogger("job", 1)
+		jl.LogAt(LogLevelQuiet, "Job failed")
+		jl.Log("Filling login form")
+		logVerbose(jl, "Screenshot saved: login")
+		if got := len(jl.logs); got != tt.wantLines {
+			t.Errorf("verbosity %d: recorded %d lines, want %d (%q)", tt.verbosity, got, tt.wantLines, jl.logs)
+		}
+	}
+}
+
+func TestLogVerboseWithoutLevels(t *testing.T) {
+	// Loggers that don't filter by level record verbose lines too
+	logger := &testLogger{}
+	logVerbose(logger, "Screenshot saved: login")
+	if len(logger.lines) != 1 {
+		t.Errorf("recorded %q, want the verbose line", logger.lines)
+	}
+}
+
+func TestCompileLandingPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		account string
+		url     string
+		want    bool
+		wantErr bool
+	}{
+		{"empty matches anything", "", "123", "https://gasolina-online.com/account", true, false},
+		{"account placeholder", `/account/{account}$`, "123", "https://gasolina-online.com/account/123", true, false},
+		{"other account", `/account/{account}$`, "123", "https://gasolina-online.com/account/124", false, false},
+		{"account is quoted", `/account/{account}$`, "1.3", "https://gasolina-online.com/account/123", false, false},
+		{"error page", `/cabinet`, "", "https://gasolina-online.com/error?code=500", false, false},
+		{"invalid regexp", `/account/(`, "", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compileLandingPattern(tt.pattern, tt.account)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileLandingPattern() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := re.MatchString(tt.url); got != tt.want {
+				t.Errorf("%q matches %q = %v, want %v", re, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLoginPageTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", defaultLoginPageTimeout, false},
+		{"45s", 45 * time.Second, false},
+		{"1m30s", 90 * time.Second, false},
+		{"0s", 0, true},
+		{"-5s", 0, true},
+		{"30", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLoginPageTimeout(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLoginPageTimeout(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLoginPageTimeout(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForLoginPageGivesUpAtTimeout(t *testing.T) {
+	prev := loginPageTimeout
+	t.Cleanup(func() { SetLoginPageTimeout(prev) })
+	SetLoginPageTimeout(50 * time.Millisecond)
+
+	// Without a browser the page state is never readable, as if it never loaded
+	start := time.Now()
+	_, err := waitForLoginPage(context.Background(), &testLogger{})
+	if !errors.Is(err, ErrLoginPageNotLoaded) {
+		t.Fatalf("waitForLoginPage() error = %v, want ErrLoginPageNotLoaded", err)
+	}
+	if got := errorCodeFor(err); got != "login_page_not_loaded" {
+		t.Errorf("errorCodeFor = %q, want login_page_not_loaded", got)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("waited %v with a 50ms timeout", elapsed)
+	}
+}
+
+func TestWaitForLoginPageStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := waitForLoginPage(ctx, &testLogger{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("waitForLoginPage() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestLoginResultOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   loginResult
+		wantDone bool
+		wantErr  error
+	}{
+		{"logged-in element", loginResult{Password: true, LoggedIn: true}, true, nil},
+		{"login form gone", loginResult{}, true, nil},
+		{"error alert", loginResult{Password: true, Alert: "Невірний пароль"}, true, ErrInvalidCredentials},
+		{"logged in despite a stale alert", loginResult{Password: true, Alert: "old", LoggedIn: true}, true, nil},
+		{"form still shown", loginResult{Password: true}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, err := tt.result.outcome()
+			if done != tt.wantDone || !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("outcome() = %v, %v, want %v, %v", done, err, tt.wantDone, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,7 +14,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/chromedp/chromedp"
 	"github.com/robfig/cron/v3"
 )
 
@@ -27,7 +28,7 @@ func main() {
 	flag.Parse()
 
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Println("Starting Gasolina Online Automation Service")
+	slog.Info("Starting Gasolina Online Automation Service")
 
 	// Check if running in server mode
 	if *serverMode {
@@ -44,65 +45,205 @@ func runServer() {
 	// Load app configuration
 	appCfg, err := LoadAppConfig()
 	if err != nil {
-		log.Fatalf("Failed to load app configuration: %v", err)
+		fatal("Failed to load app configuration", "error", err)
 	}
+	SetupLogging(appCfg.LogFormat, appCfg.LogLevel)
 
 	// Validate JWT secret
-	if appCfg.JWTSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is required for server mode")
+	if err := validateJWTSecret(appCfg.JWTSecret, appCfg.JWTSecretPolicy, appCfg.JWTSecretMinEntropy); err != nil {
+		fatal("Invalid JWT secret", "error", err)
 	}
-	if len(appCfg.JWTSecret) < 32 {
-		log.Fatal("JWT_SECRET must be at least 32 characters")
+	if appCfg.JWTSecretPolicy == JWTSecretPolicyLength {
+		if err := jwtSecretWeakness(appCfg.JWTSecret, 0); err != nil {
+			slog.Warn("Weak JWT secret; it also encrypts stored Gasolina passwords, so rotating it means users must re-enter them", "error", err)
+		}
 	}
 
 	// Validate database URL
 	if appCfg.DatabaseURL == "" {
-		log.Fatal("DATABASE_URL environment variable is required for server mode")
+		fatal("DATABASE_URL environment variable is required for server mode")
 	}
 
 	// Ensure screenshots directory exists
 	if err := os.MkdirAll(appCfg.ScreenshotsPath, 0755); err != nil {
-		log.Fatalf("Failed to create screenshots directory: %v", err)
+		fatal("Failed to create screenshots directory", "error", err)
 	}
 
 	// Initialize database
+	SetDBRetry(appCfg.DBRetryAttempts, appCfg.DBRetryBackoff)
 	if err := InitDB(appCfg.DatabaseURL); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("Failed to initialize database", "error", err)
 	}
 	defer CloseDB()
 
 	// Configure auth
 	SetJWTConfig(appCfg.JWTSecret, appCfg.JWTAccessExpiry, appCfg.JWTRefreshExpiry)
+	SetJWTLeeway(appCfg.JWTLeeway)
 	SetEncryptionKey(appCfg.JWTSecret)
 	SetScreenshotsPath(appCfg.ScreenshotsPath)
+	SetThumbnailMaxDimension(appCfg.ThumbnailMaxDimension)
+	SetCronSecondsEnabled(appCfg.CronWithSeconds)
+	SetAdminEmails(appCfg.AdminEmails)
+	if err := SetTrustedProxies(appCfg.TrustedProxies); err != nil {
+		fatal("Invalid TRUSTED_PROXIES", "error", err)
+	}
+	SetFeatureFlags(NewFeatureFlags(appCfg.FeatureFlags, GetFeatureFlagOverrides))
+	SetDryRunRampRuns(appCfg.DryRunRampRuns)
+	SetJobRetry(appCfg.JobMaxAttempts, appCfg.RetryAfterSubmit)
+	SetHTMLDumpRendering(appCfg.RenderHTMLDumps)
+	SetPrettyJSON(appCfg.PrettyJSON)
+	SetJobLogVerbosity(appCfg.JobLogVerbosity)
+	if err := SetReadingDecimalSeparator(appCfg.ReadingDecimalSeparator); err != nil {
+		fatal("Invalid GASOLINA_DECIMAL_SEPARATOR", "error", err)
+	}
+	if err := SetReadingIncrementRange(appCfg.MinIncrement, appCfg.MaxIncrement); err != nil {
+		fatal("Invalid GASOLINA_MIN_INCREMENT/GASOLINA_MAX_INCREMENT", "error", err)
+	}
+	if err := SetReadingPrecision(appCfg.ReadingPrecision); err != nil {
+		fatal("Invalid GASOLINA_READING_PRECISION", "error", err)
+	}
+	SetCookieBannerSelector(appCfg.CookieBannerSelector)
+	if err := SetValueInputMode(appCfg.ValueInputMode); err != nil {
+		fatal("Invalid GASOLINA_VALUE_INPUT_MODE", "error", err)
+	}
+	if err := SetAccountMatchMode(appCfg.AccountMatchMode); err != nil {
+		fatal("Invalid GASOLINA_ACCOUNT_MATCH", "error", err)
+	}
+	SetDefaultSelectors(appCfg.Selectors)
+	SetLoginPageTimeout(appCfg.LoginPageTimeout)
+	SetElementWaitTimeout(appCfg.ElementWaitTimeout)
+	SetPageSettle(appCfg.PageSettle)
+	SetMidJobRelogin(appCfg.MidJobRelogin)
+	SetEvidenceScreenshots(appCfg.EvidenceScreenshots)
+
+	// Configure email notifications
+	if appCfg.SMTPHost != "" {
+		smtpNotifier, err := NewSMTPNotifier(appCfg.SMTPHost, appCfg.SMTPPort, appCfg.SMTPUsername,
+			appCfg.SMTPPassword, appCfg.SMTPFrom, appCfg.SMTPTLSMode)
+		if err != nil {
+			fatal("Invalid SMTP configuration", "error", err)
+		}
+		AddNotifier(smtpNotifier)
+		SetPasswordResetSender(smtpNotifier)
+		slog.Info("Email notifications enabled", "host", appCfg.SMTPHost, "port", appCfg.SMTPPort)
+	}
+
+	SetTestSubmitSandbox(appCfg.TestSubmitSandbox)
+
+	// Telegram needs no server setup: each user brings their own bot token and chat ID
+	AddNotifier(NewTelegramNotifier(appCfg.TelegramAPIURL))
+
+	// Post per-phase progress to an external dashboard
+	if appCfg.ProgressWebhookURL != "" {
+		hook, err := NewProgressWebhook(appCfg.ProgressWebhookURL)
+		if err != nil {
+			fatal("Invalid PROGRESS_WEBHOOK_URL", "error", err)
+		}
+		SetProgressWebhook(hook)
+		if !IsEnabled(FlagWebhooks) {
+			slog.Info("PROGRESS_WEBHOOK_URL is set but the webhooks feature flag is off")
+		}
+	}
+
+	// Initialize browser pool
+	browserPool = NewBrowserPool(appCfg.BrowserTabsPerAllocator, appCfg.BrowserPoolSize, appCfg.BrowserMaxTabs)
+	defer browserPool.Close()
+
+	// Persistent per-user browser profiles; drop those of deleted users
+	SetUserDataBaseDir(appCfg.BrowserUserDataDir)
+	if n, err := PruneUserDataDirs(); err != nil {
+		slog.Error("Failed to prune browser profiles", "error", err)
+	} else if n > 0 {
+		slog.Info("Removed browser profiles of deleted users", "count", n)
+	}
 
 	// Initialize job manager
 	jobManager = NewJobManager()
-	jobManager.Start()
+	jobManager.SetMaintenance(appCfg.MaintenanceMode)
+	jobManager.SetMaxConcurrentJobs(appCfg.MaxConcurrentJobs)
+	SetGlobalForceDryRun(appCfg.GlobalForceDryRun)
+	SetScrapingBrokenThreshold(appCfg.ScrapingBrokenThreshold)
+	SetMaxQueuedJobs(appCfg.MaxQueuedJobs)
+	if appCfg.GlobalForceDryRun {
+		slog.Warn("GLOBAL_FORCE_DRY_RUN is set - no job will submit readings")
+	}
+	if err := jobManager.Start(); err != nil {
+		fatal("Failed to start job manager", "error", err)
+	}
 	defer jobManager.Stop()
 
+	// Limit failed logins; expired failures are pruned in the background
+	SetLoginLimiter(NewLoginLimiter(appCfg.LoginMaxFailures, appCfg.LoginFailureWindow))
+	SetAccountLockout(appCfg.AccountLockout)
+	SetPasswordReset(appCfg.PasswordResetTTL, appCfg.PasswordResetURL)
+	limiterCtx, stopLimiter := context.WithCancel(context.Background())
+	defer stopLimiter()
+	go loginLimiter.RunPruner(limiterCtx, time.Minute)
+	go credentialCheckLimiter.RunPruner(limiterCtx, time.Minute)
+	go passwordResetLimiter.RunPruner(limiterCtx, time.Minute)
+
+	// Purge old screenshots in the background
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	go RunScreenshotRetention(retentionCtx, appCfg.ScreenshotRetentionSuccessDays, appCfg.ScreenshotRetentionFailureDays)
+	go RunJobRetention(retentionCtx, appCfg.JobRetentionDays)
+
+	// Delete expired refresh tokens in the background
+	tokenCleanupCtx, stopTokenCleanup := context.WithCancel(context.Background())
+	defer stopTokenCleanup()
+	go RunRefreshTokenCleanup(tokenCleanupCtx, appCfg.RefreshTokenCleanupInterval)
+
+	// Deliver notifications held back by users' quiet hours
+	notificationsCtx, stopNotifications := context.WithCancel(context.Background())
+	defer stopNotifications()
+	go RunDeferredNotifications(notificationsCtx)
+
 	// Create router
 	mux := http.NewServeMux()
 
 	// Public routes
 	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/readyz", handleHealth)
+	mux.HandleFunc("/livez", handleLivez)
 	mux.HandleFunc("/api/auth/register", handleRegister)
 	mux.HandleFunc("/api/auth/login", handleLogin)
 	mux.HandleFunc("/api/auth/refresh", handleRefresh)
 	mux.HandleFunc("/api/auth/logout", handleLogout)
+	mux.HandleFunc("/api/auth/forgot-password", handleForgotPassword)
+	mux.HandleFunc("/api/auth/reset-password", handleResetPassword)
 
 	// Protected routes - wrapped with auth middleware
 	mux.Handle("/api/me", AuthMiddleware(http.HandlerFunc(handleGetMe)))
 	mux.Handle("/api/me/password", AuthMiddleware(http.HandlerFunc(handleChangePassword)))
+	mux.Handle("/api/me/sessions", AuthMiddleware(http.HandlerFunc(handleListSessions)))
+	mux.Handle("/api/me/sessions/", AuthMiddleware(http.HandlerFunc(handleRevokeSession)))
 	mux.Handle("/api/config", AuthMiddleware(http.HandlerFunc(handleConfig)))
+	mux.Handle("/api/config/rotate-credentials", AuthMiddleware(http.HandlerFunc(handleRotateCredentials)))
+	mux.Handle("/api/config/effective", AuthMiddleware(http.HandlerFunc(handleGetEffectiveConfig)))
+	mux.Handle("/api/config/validate", AuthMiddleware(http.HandlerFunc(handleValidateCredentials)))
+	mux.Handle("/api/config/records", AuthMiddleware(http.HandlerFunc(handleGetRecords)))
 	mux.Handle("/api/jobs", AuthMiddleware(http.HandlerFunc(handleJobs)))
 	mux.Handle("/api/jobs/", AuthMiddleware(http.HandlerFunc(handleJobsWithID)))
+	mux.Handle("/api/jobs/queue", AuthMiddleware(http.HandlerFunc(handleJobQueue)))
+	mux.Handle("/api/jobs/cancel-pending", AuthMiddleware(http.HandlerFunc(handleCancelPendingJobs)))
+	mux.Handle("/api/jobs/dry-run", AuthMiddleware(http.HandlerFunc(handleCreateDryRunJob)))
 	mux.Handle("/api/screenshots/", AuthMiddleware(http.HandlerFunc(handleScreenshotsRoute)))
+	mux.Handle("/api/submissions", AuthMiddleware(http.HandlerFunc(handleListSubmissions)))
+	mux.Handle("/api/analytics/consumption", AuthMiddleware(http.HandlerFunc(handleConsumptionAnalytics)))
 	mux.Handle("/api/status", AuthMiddleware(http.HandlerFunc(handleStatus)))
 	mux.Handle("/api/gasolina-info", AuthMiddleware(http.HandlerFunc(handleGetGasolinaInfo)))
 
-	// Apply CORS middleware
-	handler := CORSMiddleware(appCfg.CORSAllowedOrigins)(mux)
+	// Admin routes
+	mux.Handle("/api/admin/warmup", AuthMiddleware(AdminMiddleware(http.HandlerFunc(handleAdminWarmup))))
+	mux.Handle("/api/admin/maintenance", AuthMiddleware(AdminMiddleware(http.HandlerFunc(handleAdminMaintenance))))
+	mux.Handle("/api/admin/flags", AuthMiddleware(AdminMiddleware(http.HandlerFunc(handleAdminFlags))))
+	mux.Handle("/api/admin/scraping-health", AuthMiddleware(AdminMiddleware(http.HandlerFunc(handleAdminScrapingHealth))))
+
+	// Apply CORS middleware; the request timeout sits outside PrettyJSON so
+	// handlers still see the pretty writer. The request ID is set outermost so
+	// every response carries it, and again inside the timeout so handlers see it.
+	handler := RequestIDMiddleware(CORSMiddleware(appCfg.CORSAllowedOrigins)(
+		RequestTimeoutMiddleware(appCfg.RequestTimeout)(PrettyJSONMiddleware(RequestIDMiddleware(mux)))))
 
 	// Create server
 	server := &http.Server{
@@ -115,9 +256,9 @@ func runServer() {
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("HTTP server listening on port %s", appCfg.HTTPPort)
+		slog.Info("HTTP server listening", "port", appCfg.HTTPPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			fatal("Server error", "error", err)
 		}
 	}()
 
@@ -126,14 +267,14 @@ func runServer() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	log.Println("Shutting down gracefully...")
+	slog.Info("Shutting down gracefully...")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+		slog.Error("Server shutdown error", "error", err)
 	}
-	log.Println("Shutdown complete")
+	slog.Info("Shutdown complete")
 }
 
 // handleConfig routes GET/PUT for /api/config
@@ -160,7 +301,7 @@ func handleJobs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleJobsWithID handles /api/jobs/{id}
+// handleJobsWithID handles /api/jobs/{id} and its sub-resources
 func handleJobsWithID(w http.ResponseWriter, r *http.Request) {
 	// Extract job ID from path
 	path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
@@ -168,95 +309,174 @@ func handleJobsWithID(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "Job ID required", http.StatusBadRequest)
 		return
 	}
-	handleGetJob(w, r, path)
+
+	parts := strings.Split(path, "/")
+	jobID := parts[0]
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		handleDeleteJob(w, r, jobID)
+	case len(parts) == 1:
+		handleGetJob(w, r, jobID)
+	case len(parts) == 2 && parts[1] == "cancel":
+		handleCancelJob(w, r, jobID)
+	case len(parts) == 3 && parts[1] == "logs" && parts[2] == "stream":
+		handleStreamJobLogs(w, r, jobID)
+	case len(parts) == 2 && parts[1] == "result":
+		handleGetJobResult(w, r, jobID)
+	case len(parts) == 2 && parts[1] == "note":
+		handleSetJobNote(w, r, jobID)
+	case len(parts) == 4 && parts[1] == "screenshots" && parts[2] != "" && parts[3] == "thumbnail":
+		handleGetScreenshotThumbnail(w, r, jobID, parts[2])
+	default:
+		jsonError(w, "Not found", http.StatusNotFound)
+	}
 }
 
 // handleScreenshotsRoute handles /api/screenshots/{job_id} and /api/screenshots/{job_id}/{filename}
 func handleScreenshotsRoute(w http.ResponseWriter, r *http.Request) {
-	// Extract path parts
+	// Extract path parts: {job_id} or {job_id}/{filename}
 	path := strings.TrimPrefix(r.URL.Path, "/api/screenshots/")
-	parts := strings.SplitN(path, "/", 2)
+	jobID, filename, hasFilename := strings.Cut(path, "/")
 
-	if len(parts) == 0 || parts[0] == "" {
+	if jobID == "" {
 		jsonError(w, "Job ID required", http.StatusBadRequest)
 		return
 	}
 
-	jobID := parts[0]
-
-	if len(parts) == 1 {
+	if !hasFilename {
 		// List screenshots for job
 		handleListScreenshots(w, r, jobID)
-	} else {
-		// Get specific screenshot
-		handleGetScreenshot(w, r, jobID, parts[1])
+		return
 	}
+
+	// A trailing slash names no file; don't let it reach the file lookup as "."
+	if filename == "" {
+		jsonError(w, "Screenshot filename required", http.StatusBadRequest)
+		return
+	}
+	handleGetScreenshot(w, r, jobID, filename)
 }
 
 // runCLIMode runs the legacy CLI mode
 func runCLIMode() {
+	if err := loggingFromEnv(); err != nil {
+		fatal("Invalid logging configuration", "error", err)
+	}
+
 	// Load configuration
 	config, err := LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", "error", err)
 	}
 
-	log.Printf("Configuration loaded successfully")
-	log.Printf("Cron schedule: %s", config.CronSchedule)
-	log.Printf("Account number: %s", config.AccountNumber)
-	log.Printf("Target URL: %s", config.CheckURL)
-	log.Printf("Dry-run mode: %v", config.DryRun)
+	if err := SetReadingDecimalSeparator(getEnvOrDefault("GASOLINA_DECIMAL_SEPARATOR", ".")); err != nil {
+		fatal("Invalid GASOLINA_DECIMAL_SEPARATOR", "error", err)
+	}
+	if err := SetReadingIncrementRange(getEnvIntOrDefault("GASOLINA_MIN_INCREMENT", 0),
+		getEnvIntOrDefault("GASOLINA_MAX_INCREMENT", 10000)); err != nil {
+		fatal("Invalid GASOLINA_MIN_INCREMENT/GASOLINA_MAX_INCREMENT", "error", err)
+	}
+	if err := SetReadingPrecision(getEnvIntOrDefault("GASOLINA_READING_PRECISION", 3)); err != nil {
+		fatal("Invalid GASOLINA_READING_PRECISION", "error", err)
+	}
+	SetCookieBannerSelector(os.Getenv("GASOLINA_COOKIE_BANNER_SELECTOR"))
+	if err := SetValueInputMode(os.Getenv("GASOLINA_VALUE_INPUT_MODE")); err != nil {
+		fatal("Invalid GASOLINA_VALUE_INPUT_MODE", "error", err)
+	}
+	if err := SetAccountMatchMode(os.Getenv("GASOLINA_ACCOUNT_MATCH")); err != nil {
+		fatal("Invalid GASOLINA_ACCOUNT_MATCH", "error", err)
+	}
+	if path := os.Getenv("GASOLINA_SELECTORS_FILE"); path != "" {
+		selectors, err := LoadSelectorsFile(path)
+		if err != nil {
+			fatal("Invalid GASOLINA_SELECTORS_FILE", "error", err)
+		}
+		SetDefaultSelectors(selectors)
+	}
+	loginPageTimeout, err := ParseLoginPageTimeout(os.Getenv("LOGIN_PAGE_TIMEOUT"))
+	if err != nil {
+		fatal("Invalid LOGIN_PAGE_TIMEOUT", "error", err)
+	}
+	SetLoginPageTimeout(loginPageTimeout)
+	elementWaitTimeout, err := ParseElementWaitTimeout(os.Getenv("GASOLINA_ELEMENT_WAIT_TIMEOUT"))
+	if err != nil {
+		fatal("Invalid GASOLINA_ELEMENT_WAIT_TIMEOUT", "error", err)
+	}
+	SetElementWaitTimeout(elementWaitTimeout)
+	pageSettle, err := ParsePageSettle()
+	if err != nil {
+		fatal("Invalid page settle configuration", "error", err)
+	}
+	SetPageSettle(pageSettle)
+	SetMidJobRelogin(os.Getenv("GASOLINA_RELOGIN_MID_JOB") != "false")
+	SetEvidenceScreenshots(os.Getenv("GASOLINA_EVIDENCE_SCREENSHOTS") != "false")
+
+	if os.Getenv("GLOBAL_FORCE_DRY_RUN") == "true" && !config.DryRun {
+		slog.Warn("GLOBAL_FORCE_DRY_RUN is set - forcing dry-run, readings will not be submitted")
+		config.DryRun = true
+	}
+
+	slog.Info("Configuration loaded successfully")
+	slog.Info("CLI configuration",
+		"cron_schedule", config.CronSchedule,
+		"account_number", config.AccountNumber,
+		"target_url", config.CheckURL,
+		"dry_run", config.DryRun)
 
 	// Test mode handlers
 	if *testLogin {
-		log.Println("Running in TEST LOGIN mode")
+		slog.Info("Running in TEST LOGIN mode")
 		runTestLogin(config)
 		return
 	}
 
 	if *testCheck {
-		log.Println("Running in TEST CHECK mode")
+		slog.Info("Running in TEST CHECK mode")
 		runTestCheck(config)
 		return
 	}
 
 	if *runNow {
-		log.Println("Running job immediately")
+		slog.Info("Running job immediately")
 		runJob(config)
 		return
 	}
 
 	// Create cron scheduler
-	c := cron.New(cron.WithLogger(cron.VerbosePrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))))
+	c := cron.New(
+		cron.WithParser(newCronParser(config.CronWithSeconds)),
+		cron.WithLogger(cron.VerbosePrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))),
+	)
 
 	// Register the job
 	_, err = c.AddFunc(config.CronSchedule, func() {
-		log.Println("=== Scheduled job triggered ===")
+		slog.Info("=== Scheduled job triggered ===")
 		runJob(config)
 	})
 
 	if err != nil {
-		log.Fatalf("Failed to schedule job: %v", err)
+		fatal("Failed to schedule job", "error", err)
 	}
 
 	// Start the scheduler
 	c.Start()
-	log.Println("Scheduler started. Waiting for scheduled jobs...")
+	slog.Info("Scheduler started. Waiting for scheduled jobs...")
 
 	// Wait for termination signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	log.Println("Shutting down gracefully...")
+	slog.Info("Shutting down gracefully...")
 	ctx := c.Stop()
 	<-ctx.Done()
-	log.Println("Shutdown complete")
+	slog.Info("Shutdown complete")
 }
 
 // runJob executes the main automation job
 func runJob(config *Config) {
-	ctx, cancel := createBrowserContext()
+	ctx, cancel := createJobBrowserContext()
 	defer cancel()
 
 	// Set a timeout for the entire job
@@ -265,75 +485,63 @@ func runJob(config *Config) {
 
 	// Login
 	if err := retryWithBackoff(jobCtx, 3, func() error {
-		return Login(jobCtx, config.Email, config.Password, config.AccountNumber)
+		return Login(jobCtx, config)
 	}); err != nil {
-		log.Printf("ERROR: Login failed after retries: %v", err)
+		slog.Error("Login failed after retries", "error", err)
 		_ = SaveScreenshot(jobCtx, "error_login.png")
 		return
 	}
 
+	// Fetched increments apply to this run only; the configured ones stay the fallback
+	runConfig := *config
+	applyIncrementsSource(jobCtx, &runConfig, nil)
+
 	// Check and update if needed
 	if err := retryWithBackoff(jobCtx, 3, func() error {
-		return CheckAndUpdateIfNeeded(jobCtx, config)
+		return CheckAndUpdateIfNeeded(jobCtx, &runConfig)
 	}); err != nil {
-		log.Printf("ERROR: Check and update failed after retries: %v", err)
+		slog.Error("Check and update failed after retries", "error", err)
 		_ = SaveScreenshot(jobCtx, "error_check.png")
 		return
 	}
 
-	log.Println("=== Job completed successfully ===")
+	slog.Info("=== Job completed successfully ===")
 }
 
 // runTestLogin tests only the login functionality
 func runTestLogin(config *Config) {
-	ctx, cancel := createBrowserContext()
+	ctx, cancel := createJobBrowserContext()
 	defer cancel()
 
-	if err := Login(ctx, config.Email, config.Password, config.AccountNumber); err != nil {
-		log.Printf("Login test FAILED: %v", err)
+	if err := Login(ctx, config); err != nil {
+		slog.Error("Login test FAILED", "error", err)
 		_ = SaveScreenshot(ctx, "test_login_error.png")
 		os.Exit(1)
 	}
 
 	// Save screenshot on success
 	_ = SaveScreenshot(ctx, "test_login_success.png")
-	log.Println("Login test PASSED")
+	slog.Info("Login test PASSED")
 }
 
 // runTestCheck tests only the checker functionality (assumes already logged in or public page)
 func runTestCheck(config *Config) {
-	ctx, cancel := createBrowserContext()
+	ctx, cancel := createJobBrowserContext()
 	defer cancel()
 
 	// Try to login first
-	if err := Login(ctx, config.Email, config.Password, config.AccountNumber); err != nil {
-		log.Printf("Warning: Login failed: %v", err)
+	if err := Login(ctx, config); err != nil {
+		slog.Warn("Login failed", "error", err)
 	}
 
 	if err := CheckAndUpdateIfNeeded(ctx, config); err != nil {
-		log.Printf("Check test FAILED: %v", err)
+		slog.Error("Check test FAILED", "error", err)
 		_ = SaveScreenshot(ctx, "test_check_error.png")
 		os.Exit(1)
 	}
 
 	_ = SaveScreenshot(ctx, "test_check_success.png")
-	log.Println("Check test PASSED")
-}
-
-// createBrowserContext creates a new browser context for automation
-func createBrowserContext() (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.UserAgent("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-	)
-
-	allocCtx, _ := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
-
-	return ctx, cancel
+	slog.Info("Check test PASSED")
 }
 
 // retryWithBackoff retries a function with exponential backoff
@@ -342,8 +550,10 @@ func retryWithBackoff(ctx context.Context, maxRetries int, fn func() error) erro
 	for i := 0; i < maxRetries; i++ {
 		if i > 0 {
 			waitTime := time.Duration(i*2) * time.Second
-			log.Printf("Retry %d/%d after %v...", i+1, maxRetries, waitTime)
-			time.Sleep(waitTime)
+			slog.Info("Retrying", "attempt", i+1, "max_retries", maxRetries, "wait", waitTime)
+			if err := sleepCtx(ctx, waitTime); err != nil {
+				return err
+			}
 		}
 
 		err = fn()
@@ -351,12 +561,34 @@ func retryWithBackoff(ctx context.Context, maxRetries int, fn func() error) erro
 			return nil
 		}
 
-		log.Printf("Attempt %d/%d failed: %v", i+1, maxRetries, err)
+		slog.Warn("Attempt failed", "attempt", i+1, "max_retries", maxRetries, "error", err)
+		if errors.Is(err, ErrSubmitUncertain) {
+			// Re-running could submit the reading twice
+			slog.Info("Not retrying: the submission may have gone through")
+			return err
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			// Repeating a rejected login risks locking the account
+			slog.Info("Not retrying: the site rejected the credentials")
+			return err
+		}
 	}
 
 	return err
 }
 
+// sleepCtx waits for d, returning ctx's error early if it is cancelled
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func init() {
 	// Print usage help
 	flag.Usage = func() {
@@ -369,10 +601,70 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  CLI mode (default): Requires GASOLINA_* env vars, runs cron scheduler\n")
 		fmt.Fprintf(os.Stderr, "  Server mode (-server): Runs HTTP API, requires JWT_SECRET env var\n")
 		fmt.Fprintf(os.Stderr, "\nEnvironment Variables (Server mode):\n")
-		fmt.Fprintf(os.Stderr, "  JWT_SECRET            Required. Secret for JWT signing (min 32 chars)\n")
+		fmt.Fprintf(os.Stderr, "  JWT_SECRET            Required. Secret for JWT signing (32+ random chars recommended)\n")
+		fmt.Fprintf(os.Stderr, "  JWT_SECRET_POLICY     Secret strength check: length (warn only), basic, strict (default: length)\n")
+		fmt.Fprintf(os.Stderr, "  JWT_SECRET_MIN_ENTROPY  Min bits/char required by the strict policy (default: 3.5)\n")
+		fmt.Fprintf(os.Stderr, "  JWT_LEEWAY            Clock skew tolerated on access token expiry, max 5m (default: 30s)\n")
 		fmt.Fprintf(os.Stderr, "  DATABASE_URL          Required. PostgreSQL connection URL\n")
+		fmt.Fprintf(os.Stderr, "  SECRET_PROVIDER       Where JWT_SECRET, DATABASE_URL, SMTP_PASSWORD come from: env or file (default: env)\n")
+		fmt.Fprintf(os.Stderr, "  SECRETS_DIR           Directory of secret files for SECRET_PROVIDER=file (default: /run/secrets)\n")
 		fmt.Fprintf(os.Stderr, "  HTTP_PORT             HTTP port (default: 8080)\n")
 		fmt.Fprintf(os.Stderr, "  SCREENSHOTS_PATH      Screenshots directory (default: ./data/screenshots)\n")
 		fmt.Fprintf(os.Stderr, "  CORS_ALLOWED_ORIGINS  Comma-separated CORS origins (default: *)\n")
+		fmt.Fprintf(os.Stderr, "  CRON_WITH_SECONDS     Accept an optional seconds field in cron schedules (default: false)\n")
+		fmt.Fprintf(os.Stderr, "  SCREENSHOT_RETENTION_SUCCESS_DAYS  Days to keep screenshots of successful jobs (default: 7, 0 = forever)\n")
+		fmt.Fprintf(os.Stderr, "  SCREENSHOT_RETENTION_FAILURE_DAYS  Days to keep screenshots of failed jobs (default: 90, 0 = forever)\n")
+		fmt.Fprintf(os.Stderr, "  JOB_RETENTION_DAYS    Days to keep finished jobs without evidence screenshots (default: 0 = forever)\n")
+		fmt.Fprintf(os.Stderr, "  THUMBNAIL_MAX_DIMENSION  Max screenshot thumbnail size in px (default: 320)\n")
+		fmt.Fprintf(os.Stderr, "  BROWSER_TABS_PER_ALLOCATOR  Concurrent tabs per Chrome process (default: 1)\n")
+		fmt.Fprintf(os.Stderr, "  BROWSER_POOL_SIZE     Chrome processes pre-launched by /api/admin/warmup (default: 1)\n")
+		fmt.Fprintf(os.Stderr, "  REQUEST_TIMEOUT       Max time per API request before a 503, 0 = off (default: 10s)\n")
+		fmt.Fprintf(os.Stderr, "  BROWSER_MAX_TABS      Concurrent browser tabs; jobs wait for a free one (default: 4, 0 = no cap)\n")
+		fmt.Fprintf(os.Stderr, "  MAX_CONCURRENT_JOBS   Jobs running at once across all users; others wait (default: 4, 0 = no cap)\n")
+		fmt.Fprintf(os.Stderr, "  MAX_QUEUED_JOBS       Pending and running jobs per user before new ones get a 409 (default: 0 = no limit)\n")
+		fmt.Fprintf(os.Stderr, "  BROWSER_USER_DATA_DIR Keep a persistent browser profile per user under this dir (default: ephemeral)\n")
+		fmt.Fprintf(os.Stderr, "  TRUSTED_PROXIES       Comma-separated proxy CIDRs whose X-Forwarded-For is trusted\n")
+		fmt.Fprintf(os.Stderr, "  ADMIN_EMAILS          Comma-separated emails of admin users\n")
+		fmt.Fprintf(os.Stderr, "  LOGIN_MAX_FAILURES    Failed logins per IP and per email before 429 (default: 5, 0 = unlimited)\n")
+		fmt.Fprintf(os.Stderr, "  LOGIN_FAILURE_WINDOW  Sliding window for LOGIN_MAX_FAILURES (default: 15m)\n")
+		fmt.Fprintf(os.Stderr, "  ACCOUNT_LOCKOUT_THRESHOLD  Failed logins within ACCOUNT_LOCKOUT_WINDOW that lock an account (default: 10, 0 = off)\n")
+		fmt.Fprintf(os.Stderr, "  ACCOUNT_LOCKOUT_WINDOW, ACCOUNT_LOCKOUT_DURATION  Counting window and lock length (default: 1h, 30m)\n")
+		fmt.Fprintf(os.Stderr, "  PASSWORD_RESET_TTL    Lifetime of emailed password reset tokens (default: 1h)\n")
+		fmt.Fprintf(os.Stderr, "  PASSWORD_RESET_URL    Link prefix for reset emails, e.g. https://app.example.com/reset?token=\n")
+		fmt.Fprintf(os.Stderr, "  REFRESH_TOKEN_CLEANUP_INTERVAL  How often expired refresh tokens are deleted (default: 1h)\n")
+		fmt.Fprintf(os.Stderr, "  DB_RETRY_ATTEMPTS     Tries per DB call on transient errors, 1-5 (default: 3)\n")
+		fmt.Fprintf(os.Stderr, "  DB_RETRY_BACKOFF      Initial backoff between DB retries, doubled each time (default: 100ms)\n")
+		fmt.Fprintf(os.Stderr, "  JOB_LOG_VERBOSITY     Job log detail: quiet, normal or verbose (default: normal)\n")
+		fmt.Fprintf(os.Stderr, "  LOG_FORMAT            Server log format: text or json (default: text)\n")
+		fmt.Fprintf(os.Stderr, "  LOG_LEVEL             Minimum server log level: debug, info, warn or error (default: info)\n")
+		fmt.Fprintf(os.Stderr, "  RENDER_HTML_DUMPS     Allow ?render=html for failure HTML dumps (default: false, served as text)\n")
+		fmt.Fprintf(os.Stderr, "  MAINTENANCE_MODE      Start with job processing paused (default: false)\n")
+		fmt.Fprintf(os.Stderr, "  GLOBAL_FORCE_DRY_RUN  Force every job to dry-run, overriding users and job types (default: false)\n")
+		fmt.Fprintf(os.Stderr, "  SCRAPING_BROKEN_THRESHOLD  Consecutive failed page structure checks before alerting (default: 2)\n")
+		fmt.Fprintf(os.Stderr, "  LOGIN_PAGE_TIMEOUT    Max wait for the login form after navigation (default: 20s)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_ELEMENT_WAIT_TIMEOUT  Max wait for the modal, dropdown or table before failing (default: 10s)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_PAGE_SETTLE  Wait after navigation: sleep or network-idle (default: sleep)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_ACCOUNT_MATCH  Account dropdown matching: contains, word or exact (default: contains)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_SELECTORS_FILE  JSON file overriding the site's CSS selectors (default: builtin)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_EVIDENCE_SCREENSHOTS  Keep before/after submit screenshots past retention (default: true)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_RELOGIN_MID_JOB  Log in again if the session drops mid-job (default: true)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_NETWORK_IDLE_QUIET, GASOLINA_NETWORK_IDLE_TIMEOUT  Idle period and max wait (default: 500ms, 10s)\n")
+		fmt.Fprintf(os.Stderr, "  PROGRESS_WEBHOOK_URL  Receives a JSON event per job phase when the webhooks flag is on\n")
+		fmt.Fprintf(os.Stderr, "  FEATURE_FLAGS         Feature toggles, e.g. webhooks=true,dry_run_ramp=false\n")
+		fmt.Fprintf(os.Stderr, "  SMTP_HOST             SMTP server for job result emails (default: disabled)\n")
+		fmt.Fprintf(os.Stderr, "  SMTP_PORT             SMTP port (default: 587)\n")
+		fmt.Fprintf(os.Stderr, "  SMTP_USERNAME, SMTP_PASSWORD  SMTP credentials\n")
+		fmt.Fprintf(os.Stderr, "  SMTP_FROM             Sender address for notifications\n")
+		fmt.Fprintf(os.Stderr, "  DRY_RUN_RAMP_RUNS     Live runs forced to dry-run for each new user (default: 1)\n")
+		fmt.Fprintf(os.Stderr, "  JOB_MAX_ATTEMPTS      Attempts per job phase (default: 3)\n")
+		fmt.Fprintf(os.Stderr, "  JOB_RETRY_AFTER_SUBMIT  Retry a failed submit once the record is confirmed missing (default: true)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_DECIMAL_SEPARATOR  Decimal separator in site meter readings (default: .)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_MIN_INCREMENT, GASOLINA_MAX_INCREMENT  Accepted monthly increment range (default: 0, 10000)\n")
+		fmt.Fprintf(os.Stderr, "  GASOLINA_READING_PRECISION  Fractional digits kept in readings, 0-6 (default: 3)\n")
+		fmt.Fprintf(os.Stderr, "  SMTP_TLS_MODE         starttls, tls or none (default: starttls)\n")
+		fmt.Fprintf(os.Stderr, "  TELEGRAM_API_URL      Telegram Bot API base URL (default: https://api.telegram.org)\n")
+		fmt.Fprintf(os.Stderr, "  TEST_SUBMIT_SANDBOX_URL  Sandbox site test-submit jobs submit to (default: disabled)\n")
+		fmt.Fprintf(os.Stderr, "  TEST_SUBMIT_EMAIL, TEST_SUBMIT_PASSWORD, TEST_SUBMIT_ACCOUNT_NUMBER  Sandbox login\n")
+		fmt.Fprintf(os.Stderr, "  TEST_SUBMIT_ALLOW_REAL_SITE  Let the sandbox URL be the real Gasolina site (default: false)\n")
 	}
 }
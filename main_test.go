@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		errs      []error // returned by successive attempts
+		wantCalls int
+		wantErr   error
+	}{
+		{"first attempt succeeds", context.Background(), []error{nil}, 1, nil},
+		{"submit uncertain is not retried", context.Background(), []error{ErrSubmitUncertain}, 1, ErrSubmitUncertain},
+		{"rejected credentials are not retried", context.Background(), []error{ErrInvalidCredentials}, 1, ErrInvalidCredentials},
+		{"cancelled during backoff", cancelled, []error{errFlaky, nil}, 1, context.Canceled},
+		{"single attempt", context.Background(), []error{errFlaky}, 1, errFlaky},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			maxRetries := len(tt.errs)
+			start := time.Now()
+			err := retryWithBackoff(tt.ctx, maxRetries, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("retryWithBackoff() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("retryWithBackoff took %v; the backoff was not aborted", elapsed)
+			}
+		})
+	}
+}
+
+func TestSleepCtx(t *testing.T) {
+	if err := sleepCtx(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("sleepCtx() = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := sleepCtx(ctx, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("sleepCtx() = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleepCtx returned after %v, want as soon as ctx is done", elapsed)
+	}
+}
+
+func TestHandleScreenshotsRouteRejectsEmptyParts(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantMsg string
+	}{
+		{"no job ID", "/api/screenshots/", "Job ID required"},
+		{"empty job ID with file", "/api/screenshots//01_login.png", "Job ID required"},
+		{"trailing slash", "/api/screenshots/abc/", "Screenshot filename required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleScreenshotsRoute(rec, newAuthedRequest(http.MethodGet, tt.path, "", 1))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if got := errorMessage(t, rec); got != tt.wantMsg {
+				t.Errorf("error = %q, want %q", got, tt.wantMsg)
+			}
+		})
+	}
+}
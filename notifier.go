@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Notifier delivers job results to the user
+type Notifier interface {
+	NotifyJobComplete(job *Job, cfg *UserConfig) error
+}
+
+// noopNotifier drops all notifications; used when no channel is configured
+type noopNotifier struct{}
+
+func (noopNotifier) NotifyJobComplete(job *Job, cfg *UserConfig) error { return nil }
+
+var notifier Notifier = noopNotifier{}
+
+// SetNotifier sets the notifier used for finished jobs
+func SetNotifier(n Notifier) {
+	if n == nil {
+		n = noopNotifier{}
+	}
+	notifier = n
+}
+
+// multiNotifier fans a notification out to several channels; one failing
+// channel doesn't stop the others
+type multiNotifier []Notifier
+
+func (m multiNotifier) NotifyJobComplete(job *Job, cfg *UserConfig) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.NotifyJobComplete(job, cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AddNotifier adds a channel alongside the notifiers already set
+func AddNotifier(n Notifier) {
+	switch current := notifier.(type) {
+	case noopNotifier:
+		notifier = n
+	case multiNotifier:
+		notifier = append(current, n)
+	default:
+		notifier = multiNotifier{current, n}
+	}
+}
+
+// NotifyOnAll in a notify_on list enables every outcome
+const NotifyOnAll = "all"
+
+// defaultNotifyOn is used when the user hasn't chosen outcomes
+var defaultNotifyOn = []string{OutcomeSuccess, OutcomeFailure}
+
+// validateNotifyOn checks that every entry is a known outcome or "all"
+func validateNotifyOn(outcomes []string) error {
+	for _, o := range outcomes {
+		switch o {
+		case OutcomeSuccess, OutcomeFailure, OutcomeAlreadyExists, OutcomeNoChange, OutcomeAnomalyWarning, NotifyOnAll:
+		default:
+			return fmt.Errorf("unknown outcome %q: must be %s, %s, %s, %s, %s or %s",
+				o, OutcomeSuccess, OutcomeFailure, OutcomeAlreadyExists, OutcomeNoChange, OutcomeAnomalyWarning, NotifyOnAll)
+		}
+	}
+	return nil
+}
+
+// ShouldNotify reports whether the user wants a notification for outcome.
+// Anomaly warnings always notify: the user turned the check on to hear about them.
+func (c *UserConfig) ShouldNotify(outcome string) bool {
+	if outcome == OutcomeAnomalyWarning {
+		return true
+	}
+	notifyOn := c.NotifyOn
+	if len(notifyOn) == 0 {
+		notifyOn = defaultNotifyOn
+	}
+	for _, o := range notifyOn {
+		if o == outcome || o == NotifyOnAll {
+			return true
+		}
+	}
+	return false
+}
+
+// SMTP transport security modes
+const (
+	SMTPTLSModeStartTLS = "starttls"
+	SMTPTLSModeTLS      = "tls" // implicit TLS, usually port 465
+	SMTPTLSModeNone     = "none"
+)
+
+// mailSender sends a composed message; swapped out to avoid real SMTP connections
+type mailSender func(from string, to []string, msg []byte) error
+
+// SMTPNotifier emails job results to the user's notify_email
+type SMTPNotifier struct {
+	From string
+	send mailSender
+}
+
+// NewSMTPNotifier creates an SMTP notifier for the given server settings
+func NewSMTPNotifier(host string, port int, username, password, from, tlsMode string) (*SMTPNotifier, error) {
+	switch tlsMode {
+	case SMTPTLSModeStartTLS, SMTPTLSModeTLS, SMTPTLSModeNone:
+	default:
+		return nil, fmt.Errorf("invalid SMTP TLS mode %q", tlsMode)
+	}
+	if from == "" {
+		return nil, fmt.Errorf("SMTP from address is required")
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	addr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	return &SMTPNotifier{
+		From: from,
+		send: func(from string, to []string, msg []byte) error {
+			return sendSMTP(addr, host, tlsMode, auth, from, to, msg)
+		},
+	}, nil
+}
+
+// NotifyJobComplete emails the job result if the user has a notify_email
+func (n *SMTPNotifier) NotifyJobComplete(job *Job, cfg *UserConfig) error {
+	if !IsEnabled(FlagEmailNotifications) || cfg == nil || cfg.NotifyEmail == "" {
+		return nil
+	}
+	msg := composeJobEmail(n.From, cfg.NotifyEmail, job, time.Now())
+	return n.send(n.From, []string{cfg.NotifyEmail}, msg)
+}
+
+// SendPasswordReset emails a password reset token to the account address
+func (n *SMTPNotifier) SendPasswordReset(email, token string, expiresAt time.Time) error {
+	msg := composePasswordResetEmail(n.From, email, token, expiresAt, time.Now())
+	return n.send(n.From, []string{email}, msg)
+}
+
+// jobOutcomeText describes how a finished job went, for notification subjects
+func jobOutcomeText(job *Job) string {
+	switch {
+	case job.Status != "completed":
+		return "failed"
+	case job.Outcome == OutcomeAnomalyWarning:
+		return "found an anomalous reading"
+	}
+	return "succeeded"
+}
+
+// jobSummaryLines lists the job type, status, error and reading for a notification
+func jobSummaryLines(job *Job) []string {
+	lines := []string{fmt.Sprintf("Job %s (%s) %s.", job.ID, job.Type, jobOutcomeText(job))}
+	lines = append(lines, fmt.Sprintf("Status: %s", job.Status))
+	if job.Outcome != "" {
+		lines = append(lines, fmt.Sprintf("Outcome: %s", job.Outcome))
+	}
+	if job.StartedAt != nil {
+		lines = append(lines, fmt.Sprintf("Started: %s", job.StartedAt.Format(time.RFC3339)))
+	}
+	if job.Error != nil {
+		lines = append(lines, fmt.Sprintf("Error: %s", *job.Error))
+	}
+	if r := job.Result; r != nil {
+		switch {
+		case r.Submitted:
+			lines = append(lines, fmt.Sprintf("Submitted value: %s (previous %s, +%s)",
+				formatReading(r.NewValue), formatReading(r.PreviousValue), formatReading(r.Increment)))
+		case r.DryRun:
+			lines = append(lines, fmt.Sprintf("Dry-run value: %s (not submitted)", formatReading(r.NewValue)))
+		}
+		if r.Anomaly != "" {
+			lines = append(lines, fmt.Sprintf("Anomaly: %s", r.Anomaly))
+		}
+	}
+	return lines
+}
+
+// composeJobMessage builds a plain-text message for a finished job
+func composeJobMessage(job *Job) string {
+	return strings.Join(jobSummaryLines(job), "\n")
+}
+
+// composeJobEmail builds the RFC 5322 message for a finished job
+func composeJobEmail(from, to string, job *Job, now time.Time) []byte {
+	outcome := jobOutcomeText(job)
+
+	var body strings.Builder
+	for _, line := range jobSummaryLines(job) {
+		body.WriteString(line + "\r\n")
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: Gasolina job %s %s\r\n", job.Type, outcome)
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body.String())
+	return []byte(msg.String())
+}
+
+// SMTP timeouts: connecting, and the whole conversation after that
+const (
+	smtpDialTimeout = 30 * time.Second
+	smtpSendTimeout = 2 * time.Minute
+)
+
+// sendSMTP delivers msg over SMTP using the given transport security mode
+func sendSMTP(addr, host, tlsMode string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	var conn net.Conn
+	var err error
+	if tlsMode == SMTPTLSModeTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	// A stalled server must not hang the job worker
+	if err := conn.SetDeadline(time.Now().Add(smtpSendTimeout)); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set SMTP deadline: %w", err)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+
+	if tlsMode == SMTPTLSModeStartTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP auth failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
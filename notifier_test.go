@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setFeatureFlagsForTest replaces the global flags with env values for the test
+func setFeatureFlagsForTest(t *testing.T, env map[FeatureFlag]bool) {
+	t.Helper()
+	prev := featureFlags
+	SetFeatureFlags(NewFeatureFlags(env, nil))
+	t.Cleanup(func() { SetFeatureFlags(prev) })
+}
+
+func TestNewSMTPNotifierValidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		tlsMode string
+		wantErr bool
+	}{
+		{"starttls", "bot@example.com", SMTPTLSModeStartTLS, false},
+		{"implicit tls", "bot@example.com", SMTPTLSModeTLS, false},
+		{"plain", "bot@example.com", SMTPTLSModeNone, false},
+		{"unknown mode", "bot@example.com", "ssl", true},
+		{"no from", "", SMTPTLSModeStartTLS, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSMTPNotifier("smtp.example.com", 587, "user", "pass", tt.from, tt.tlsMode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSMTPNotifier() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSMTPNotifierNotifyJobComplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		flagOn   bool
+		cfg      *UserConfig
+		wantSent bool
+	}{
+		{"sends to notify_email", true, &UserConfig{NotifyEmail: "me@example.com"}, true},
+		{"no notify_email", true, &UserConfig{}, false},
+		{"no config", true, nil, false},
+		{"flag off", false, &UserConfig{NotifyEmail: "me@example.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagEmailNotifications: tt.flagOn})
+			var sentTo []string
+			n := &SMTPNotifier{From: "bot@example.com", send: func(from string, to []string, msg []byte) error {
+				sentTo = to
+				return nil
+			}}
+			if err := n.NotifyJobComplete(&Job{ID: "j1", Type: "full", Status: "completed"}, tt.cfg); err != nil {
+				t.Fatalf("NotifyJobComplete: %v", err)
+			}
+			if sent := sentTo != nil; sent != tt.wantSent {
+				t.Fatalf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if tt.wantSent && (len(sentTo) != 1 || sentTo[0] != tt.cfg.NotifyEmail) {
+				t.Errorf("sent to %v, want %s", sentTo, tt.cfg.NotifyEmail)
+			}
+		})
+	}
+}
+
+func TestComposeJobEmail(t *testing.T) {
+	errMsg := "login failed"
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		job         *Job
+		wantSubject string
+		wantBody    []string
+	}{
+		{
+			name:        "submitted",
+			job:         &Job{ID: "j1", Type: "full", Status: "completed", Result: &CheckResult{Submitted: true, NewValue: 112, PreviousValue: 100, Increment: 12}},
+			wantSubject: "Subject: Gasolina job full succeeded",
+			wantBody:    []string{"Job j1 (full) succeeded.", "Submitted value: 112 (previous 100, +12)"},
+		},
+		{
+			name:        "failed",
+			job:         &Job{ID: "j2", Type: "dry-run", Status: "failed", Error: &errMsg},
+			wantSubject: "Subject: Gasolina job dry-run failed",
+			wantBody:    []string{"Status: failed", "Error: login failed"},
+		},
+		{
+			name: "anomaly warning",
+			job: &Job{ID: "j3", Type: "dry-run", Status: "completed", Outcome: OutcomeAnomalyWarning,
+				Result: &CheckResult{DryRun: true, NewValue: 99999, Anomaly: "implausible_reading: increment 98887 is outside the allowed range 0..10000"}},
+			wantSubject: "Subject: Gasolina job dry-run found an anomalous reading",
+			wantBody: []string{"Outcome: anomaly_warning", "Dry-run value: 99999 (not submitted)",
+				"Anomaly: implausible_reading: increment 98887 is outside the allowed range 0..10000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := string(composeJobEmail("bot@example.com", "me@example.com", tt.job, now))
+			header, body, ok := strings.Cut(msg, "\r\n\r\n")
+			if !ok {
+				t.Fatalf("message has no header/body separator: %q", msg)
+			}
+			for _, want := range []string{"From: bot@example.com", "To: me@example.com", tt.wantSubject, "Date: Sun, 01 Mar 2026 09:00:00 +0000"} {
+				if !strings.Contains(header, want+"\r\n") {
+					t.Errorf("header missing %q:\n%s", want, header)
+				}
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(body, want+"\r\n") {
+					t.Errorf("body missing %q:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+// fakeSMTPServer accepts one plain SMTP session and returns the message data
+// on the channel. Replies are canned: it only checks the command order.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				var msg strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				data <- msg.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), data
+}
+
+func TestSendSMTP(t *testing.T) {
+	addr, data := fakeSMTPServer(t)
+	msg := []byte("Subject: hi\r\n\r\nhello\r\n")
+	if err := sendSMTP(addr, "localhost", SMTPTLSModeNone, nil, "bot@example.com", []string{"me@example.com"}, msg); err != nil {
+		t.Fatalf("sendSMTP: %v", err)
+	}
+	select {
+	case got := <-data:
+		if got != string(msg) {
+			t.Errorf("server received %q, want %q", got, msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server received no message")
+	}
+}
+
+func TestSendSMTPConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	err = sendSMTP(addr, "localhost", SMTPTLSModeNone, nil, "bot@example.com", []string{"me@example.com"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to connect") {
+		t.Errorf("sendSMTP to a closed port = %v, want a connect error", err)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Errorf("error %v does not wrap the dial error", err)
+	}
+}
+
+func TestValidateNotifyOn(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []string
+		wantErr  bool
+	}{
+		{"unset", nil, false},
+		{"known outcomes", []string{OutcomeSuccess, OutcomeNoChange, OutcomeAlreadyExists}, false},
+		{"all", []string{NotifyOnAll}, false},
+		{"anomaly warning", []string{OutcomeAnomalyWarning}, false},
+		{"unknown outcome", []string{OutcomeFailure, "timeout"}, true},
+		{"wrong case", []string{"Success"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateNotifyOn(tt.outcomes); (err != nil) != tt.wantErr {
+				t.Errorf("validateNotifyOn(%q) error = %v, wantErr %v", tt.outcomes, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifyOn []string
+		outcome  string
+		want     bool
+	}{
+		{"default success", nil, OutcomeSuccess, true},
+		{"default failure", nil, OutcomeFailure, true},
+		{"default no change", nil, OutcomeNoChange, false},
+		{"default already exists", nil, OutcomeAlreadyExists, false},
+		{"failures only", []string{OutcomeFailure}, OutcomeSuccess, false},
+		{"failures only on failure", []string{OutcomeFailure}, OutcomeFailure, true},
+		{"all", []string{NotifyOnAll}, OutcomeNoChange, true},
+		{"anomaly always notifies", []string{OutcomeFailure}, OutcomeAnomalyWarning, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &UserConfig{NotifyOn: tt.notifyOn}
+			if got := cfg.ShouldNotify(tt.outcome); got != tt.want {
+				t.Errorf("ShouldNotify(%q) = %v, want %v", tt.outcome, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobLoggerOutcomeFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		reported string
+		jobErr   error
+		want     string
+	}{
+		{"success by default", "", nil, OutcomeSuccess},
+		{"reported outcome", OutcomeNoChange, nil, OutcomeNoChange},
+		{"error wins over reported outcome", OutcomeNoChange, errors.New("login failed"), OutcomeFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jl := NewJobLogger("job", 1)
+			if tt.reported != "" {
+				jl.Outcome(tt.reported)
+			}
+			if got := jl.outcomeFor(tt.jobErr); got != tt.want {
+				t.Errorf("outcomeFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// How pages are given time to settle after navigation
+const (
+	// PageSettleSleep waits a fixed time after each navigation (default)
+	PageSettleSleep = "sleep"
+	// PageSettleNetworkIdle waits until no requests are in flight for a quiet period
+	PageSettleNetworkIdle = "network-idle"
+)
+
+// PageSettle configures how long to wait for pages after navigation
+type PageSettle struct {
+	Mode    string
+	Quiet   time.Duration // no-request period that counts as idle
+	Timeout time.Duration // give up waiting for idle after this long
+}
+
+var pageSettle = PageSettle{Mode: PageSettleSleep, Quiet: 500 * time.Millisecond, Timeout: 10 * time.Second}
+
+// SetPageSettle sets the page settle strategy used in login and the checker
+func SetPageSettle(s PageSettle) {
+	pageSettle = s
+}
+
+// ParsePageSettle reads GASOLINA_PAGE_SETTLE, GASOLINA_NETWORK_IDLE_QUIET and
+// GASOLINA_NETWORK_IDLE_TIMEOUT
+func ParsePageSettle() (PageSettle, error) {
+	s := PageSettle{Mode: getEnvOrDefault("GASOLINA_PAGE_SETTLE", PageSettleSleep)}
+	if s.Mode != PageSettleSleep && s.Mode != PageSettleNetworkIdle {
+		return s, fmt.Errorf("GASOLINA_PAGE_SETTLE must be %q or %q, got %q", PageSettleSleep, PageSettleNetworkIdle, s.Mode)
+	}
+	for _, d := range []struct {
+		env, def string
+		dst      *time.Duration
+	}{
+		{"GASOLINA_NETWORK_IDLE_QUIET", "500ms", &s.Quiet},
+		{"GASOLINA_NETWORK_IDLE_TIMEOUT", "10s", &s.Timeout},
+	} {
+		v, err := time.ParseDuration(getEnvOrDefault(d.env, d.def))
+		if err != nil || v <= 0 {
+			return s, fmt.Errorf("%s must be a positive duration, got %q", d.env, os.Getenv(d.env))
+		}
+		*d.dst = v
+	}
+	return s, nil
+}
+
+// navigateAndSettle navigates to url and waits for the page to settle. In sleep
+// mode it waits fallback; in network-idle mode it watches network events from
+// before the navigation starts, so the page's own requests are accounted for.
+func navigateAndSettle(url string, fallback time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if pageSettle.Mode != PageSettleNetworkIdle {
+			return chromedp.Run(ctx, chromedp.Navigate(url), chromedp.Sleep(fallback))
+		}
+		w := watchNetwork(ctx)
+		defer w.stop()
+		if err := chromedp.Navigate(url).Do(ctx); err != nil {
+			return err
+		}
+		return w.waitIdle(ctx, url)
+	})
+}
+
+// settleAfter runs action (e.g. a click that submits a form) and then waits for
+// the page to settle, like navigateAndSettle
+func settleAfter(action chromedp.Action, fallback time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if pageSettle.Mode != PageSettleNetworkIdle {
+			return chromedp.Run(ctx, action, chromedp.Sleep(fallback))
+		}
+		w := watchNetwork(ctx)
+		defer w.stop()
+		if err := action.Do(ctx); err != nil {
+			return err
+		}
+		return w.waitIdle(ctx, "page")
+	})
+}
+
+// networkWatch tracks in-flight requests of a tab
+type networkWatch struct {
+	mu           sync.Mutex
+	inflight     map[network.RequestID]bool
+	lastActivity time.Time
+	stop         context.CancelFunc
+}
+
+// watchNetwork starts tracking requests on the tab in ctx until stop is called
+func watchNetwork(ctx context.Context) *networkWatch {
+	lctx, cancel := context.WithCancel(ctx)
+	w := &networkWatch{inflight: make(map[network.RequestID]bool), lastActivity: time.Now(), stop: cancel}
+	chromedp.ListenTarget(lctx, func(ev interface{}) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			// Long-lived streams never finish and would keep the page "busy"
+			if e.Type == network.ResourceTypeEventSource || e.Type == network.ResourceTypeWebSocket {
+				return
+			}
+			w.inflight[e.RequestID] = true
+		case *network.EventLoadingFinished:
+			delete(w.inflight, e.RequestID)
+		case *network.EventLoadingFailed:
+			delete(w.inflight, e.RequestID)
+		default:
+			return
+		}
+		w.lastActivity = time.Now()
+	})
+	return w
+}
+
+// waitIdle blocks until no request has been in flight for the quiet period.
+// Pages that never go idle (polling) are given up on after the timeout
+// without failing the navigation.
+func (w *networkWatch) waitIdle(ctx context.Context, what string) error {
+	deadline := time.Now().Add(pageSettle.Timeout)
+	for {
+		w.mu.Lock()
+		idle := len(w.inflight) == 0 && time.Since(w.lastActivity) >= pageSettle.Quiet
+		pending := len(w.inflight)
+		w.mu.Unlock()
+
+		if idle {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			slog.Warn("Network did not go idle, continuing", "page", what, "timeout", pageSettle.Timeout, "in_flight", pending)
+			return nil
+		}
+		if err := sleepCtx(ctx, 50*time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestParsePageSettle(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    PageSettle
+		wantErr bool
+	}{
+		{"defaults", nil, PageSettle{Mode: PageSettleSleep, Quiet: 500 * time.Millisecond, Timeout: 10 * time.Second}, false},
+		{"network idle", map[string]string{
+			"GASOLINA_PAGE_SETTLE":          "network-idle",
+			"GASOLINA_NETWORK_IDLE_QUIET":   "250ms",
+			"GASOLINA_NETWORK_IDLE_TIMEOUT": "5s",
+		}, PageSettle{Mode: PageSettleNetworkIdle, Quiet: 250 * time.Millisecond, Timeout: 5 * time.Second}, false},
+		{"unknown mode", map[string]string{"GASOLINA_PAGE_SETTLE": "load"}, PageSettle{}, true},
+		{"bad quiet", map[string]string{"GASOLINA_NETWORK_IDLE_QUIET": "soon"}, PageSettle{}, true},
+		{"zero timeout", map[string]string{"GASOLINA_NETWORK_IDLE_TIMEOUT": "0s"}, PageSettle{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"GASOLINA_PAGE_SETTLE", "GASOLINA_NETWORK_IDLE_QUIET", "GASOLINA_NETWORK_IDLE_TIMEOUT"} {
+				t.Setenv(key, tt.env[key])
+			}
+			got, err := ParsePageSettle()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePageSettle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParsePageSettle() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkWatchWaitIdle(t *testing.T) {
+	prev := pageSettle
+	t.Cleanup(func() { SetPageSettle(prev) })
+	SetPageSettle(PageSettle{Mode: PageSettleNetworkIdle, Quiet: 30 * time.Millisecond, Timeout: 200 * time.Millisecond})
+
+	tests := []struct {
+		name         string
+		inflight     int
+		lastActivity time.Duration // before the wait starts
+		minWait      time.Duration
+		maxWait      time.Duration
+	}{
+		{"already idle", 0, -time.Second, 0, 100 * time.Millisecond},
+		{"waits for the quiet period", 0, 0, 30 * time.Millisecond, 150 * time.Millisecond},
+		{"gives up on a busy page", 1, -time.Second, 200 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &networkWatch{inflight: make(map[network.RequestID]bool), lastActivity: time.Now().Add(tt.lastActivity), stop: func() {}}
+			for i := 0; i < tt.inflight; i++ {
+				w.inflight[network.RequestID(rune('a'+i))] = true
+			}
+			start := time.Now()
+			if err := w.waitIdle(context.Background(), "test"); err != nil {
+				t.Fatalf("waitIdle: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minWait || elapsed > tt.maxWait {
+				t.Errorf("waitIdle took %v, want %v-%v", elapsed, tt.minWait, tt.maxWait)
+			}
+		})
+	}
+}
+
+func TestNetworkWatchWaitIdleCancelled(t *testing.T) {
+	w := &networkWatch{inflight: map[network.RequestID]bool{"a": true}, lastActivity: time.Now(), stop: func() {}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.waitIdle(ctx, "test"); !errors.Is(err, context.Canceled) {
+		t.Errorf("waitIdle() = %v, want context.Canceled", err)
+	}
+}
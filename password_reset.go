@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// passwordResetTTL is how long an emailed reset token stays valid
+var passwordResetTTL = time.Hour
+
+// passwordResetURL, when set, is prefixed to the token to form a link in the email
+var passwordResetURL string
+
+// SetPasswordReset configures reset token lifetime and the link sent to users
+func SetPasswordReset(ttl time.Duration, url string) {
+	if ttl > 0 {
+		passwordResetTTL = ttl
+	}
+	passwordResetURL = url
+}
+
+// PasswordResetSender delivers password reset tokens to users
+type PasswordResetSender interface {
+	SendPasswordReset(email, token string, expiresAt time.Time) error
+}
+
+// logPasswordResetSender is used when no mail channel is configured; the token
+// is not logged, so resets are impossible until a sender is set up
+type logPasswordResetSender struct{}
+
+func (logPasswordResetSender) SendPasswordReset(email, token string, expiresAt time.Time) error {
+	slog.Warn("Password reset requested but no sender is configured (set SMTP_HOST)", "email", email)
+	return nil
+}
+
+var passwordResetSender PasswordResetSender = logPasswordResetSender{}
+
+// SetPasswordResetSender sets how reset tokens are delivered
+func SetPasswordResetSender(s PasswordResetSender) {
+	if s == nil {
+		s = logPasswordResetSender{}
+	}
+	passwordResetSender = s
+}
+
+// ForgotPasswordRequest is the request body for requesting a reset token
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest is the request body for setting a new password with a token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// passwordResetLimiter caps reset requests per client IP and per email, so the
+// endpoint can't be used to flood someone's inbox; every request counts
+var passwordResetLimiter = NewLoginLimiter(5, time.Hour)
+
+// handleForgotPassword emails a single-use reset token. Unknown emails get the
+// same 200 response; only known ones store a token, and the email is sent in
+// the background so the response time gives little away. Requests are
+// rate-limited per client IP and per email, known or not.
+func handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+
+	ipKey, emailKey := "ip:"+clientIP(r), "email:"+email
+	if retryAfter, ok := passwordResetLimiter.Allow(ipKey, emailKey); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		jsonError(w, "Too many password reset requests. Please try again later.", http.StatusTooManyRequests)
+		return
+	}
+	passwordResetLimiter.RecordFailure(ipKey, emailKey)
+
+	token, err := newPasswordResetToken()
+	if err != nil {
+		jsonError(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	tokenHash := hashToken(token)
+	expiresAt := time.Now().Add(passwordResetTTL)
+
+	user, err := GetUserByEmail(r.Context(), email)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Password reset lookup failed", "error", err)
+	}
+	if user != nil {
+		if err := SavePasswordResetToken(user.ID, tokenHash, expiresAt); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save password reset token", "user_id", user.ID, "error", err)
+		} else {
+			// Deliver in the background so response time doesn't reveal the account exists
+			// The request context is cancelled once the handler returns; keep its
+			// values so the log line still carries the request ID
+			ctx := context.WithoutCancel(r.Context())
+			go func(email string, userID int64) {
+				if err := passwordResetSender.SendPasswordReset(email, token, expiresAt); err != nil {
+					slog.ErrorContext(ctx, "Failed to send password reset email", "user_id", userID, "error", err)
+				}
+			}(user.Email, user.ID)
+		}
+	} else {
+		slog.InfoContext(r.Context(), "Password reset requested for unknown email", "ip", clientIP(r))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]string{
+		"message": "If an account with that email exists, a password reset link has been sent",
+	})
+}
+
+// handleResetPassword sets a new password using a reset token, then revokes
+// the token and all of the user's sessions
+func handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ResetPasswordRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	if len(req.NewPassword) < 6 {
+		jsonError(w, "New password must be at least 6 characters", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		jsonError(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+
+	// Consuming the token up front makes it single-use even under concurrent requests
+	userID, expiresAt, err := ConsumePasswordResetToken(hashToken(req.Token))
+	if err != nil || time.Now().After(expiresAt) {
+		jsonError(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+
+	if err := UpdateUserPassword(userID, req.NewPassword); err != nil {
+		jsonError(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := DeleteUserPasswordResetTokens(userID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete password reset tokens", "user_id", userID, "error", err)
+	}
+	if err := DeleteUserRefreshTokens(userID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to revoke sessions", "user_id", userID, "error", err)
+	}
+	if err := ClearFailedLogins(userID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to clear failed logins", "user_id", userID, "error", err)
+	}
+
+	slog.InfoContext(r.Context(), "Password reset completed", "user_id", userID, "ip", clientIP(r))
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]string{"message": "Password updated"})
+}
+
+// newPasswordResetToken returns a random URL-safe token
+func newPasswordResetToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// composePasswordResetEmail builds the reset email sent by SMTPNotifier
+func composePasswordResetEmail(from, to, token string, expiresAt, now time.Time) []byte {
+	var body strings.Builder
+	body.WriteString("A password reset was requested for your Gasolina automation account.\r\n\r\n")
+	if passwordResetURL != "" {
+		fmt.Fprintf(&body, "Reset your password: %s%s\r\n", passwordResetURL, token)
+	} else {
+		fmt.Fprintf(&body, "Reset token: %s\r\n", token)
+	}
+	fmt.Fprintf(&body, "\r\nThe token expires at %s and can be used once.\r\n", expiresAt.UTC().Format(time.RFC3339))
+	body.WriteString("If you didn't request this, ignore this email.\r\n")
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	msg.WriteString("Subject: Gasolina password reset\r\n")
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body.String())
+	return []byte(msg.String())
+}
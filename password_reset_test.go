@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	a, err := newPasswordResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newPasswordResetToken()
+	if len(a) != 43 || strings.ContainsAny(a, "+/=") {
+		t.Errorf("token %q is not 32 URL-safe base64 bytes", a)
+	}
+	if a == b {
+		t.Error("two tokens are equal")
+	}
+}
+
+func TestComposePasswordResetEmail(t *testing.T) {
+	expires := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		url      string
+		wantLine string
+	}{
+		{"token only", "", "Reset token: tok123\r\n"},
+		{"link", "https://app.example.com/reset?token=", "Reset your password: https://app.example.com/reset?token=tok123\r\n"},
+	}
+	prev := passwordResetURL
+	t.Cleanup(func() { passwordResetURL = prev })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPasswordReset(0, tt.url)
+			msg := string(composePasswordResetEmail("bot@example.com", "user@example.com", "tok123", expires, expires.Add(-time.Hour)))
+			for _, want := range []string{
+				"To: user@example.com\r\n",
+				"Subject: Gasolina password reset\r\n",
+				tt.wantLine,
+				"expires at 2026-03-01T10:00:00Z",
+			} {
+				if !strings.Contains(msg, want) {
+					t.Errorf("message lacks %q:\n%s", want, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestSetPasswordResetKeepsTTLForZero(t *testing.T) {
+	prevTTL, prevURL := passwordResetTTL, passwordResetURL
+	t.Cleanup(func() { passwordResetTTL, passwordResetURL = prevTTL, prevURL })
+	SetPasswordReset(30*time.Minute, "")
+	SetPasswordReset(0, "")
+	if passwordResetTTL != 30*time.Minute {
+		t.Errorf("passwordResetTTL = %v, want 30m", passwordResetTTL)
+	}
+}
+
+func TestHandleResetPasswordRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantError string
+	}{
+		{"short password", `{"token":"t","new_password":"12345"}`, "New password must be at least 6 characters"},
+		{"missing token", `{"new_password":"123456"}`, "Invalid or expired reset token"},
+		{"unknown field", `{"token":"t","password":"123456"}`, `Unknown field "password"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleResetPassword(rec, httptest.NewRequest(http.MethodPost, "/api/auth/reset-password", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (%s)", rec.Code, rec.Body.String())
+			}
+			if got := errorMessage(t, rec); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleForgotPasswordRateLimited(t *testing.T) {
+	l, _ := newTestLoginLimiter(1, time.Hour)
+	prev := passwordResetLimiter
+	passwordResetLimiter = l
+	t.Cleanup(func() { passwordResetLimiter = prev })
+	l.RecordFailure("email:user@example.com")
+
+	rec := httptest.NewRecorder()
+	handleForgotPassword(rec, httptest.NewRequest(http.MethodPost, "/api/auth/forgot-password", strings.NewReader(`{"email":"USER@example.com"}`)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 (%s)", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header not set")
+	}
+}
+
+// chanResetSender hands sent tokens to the test
+type chanResetSender chan string
+
+func (s chanResetSender) SendPasswordReset(email, token string, expiresAt time.Time) error {
+	s <- token
+	return nil
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	user := createTestUser(t)
+	sender := make(chanResetSender, 1)
+	prevSender, prevLimiter := passwordResetSender, passwordResetLimiter
+	SetPasswordResetSender(sender)
+	passwordResetLimiter = NewLoginLimiter(0, time.Hour)
+	t.Cleanup(func() {
+		SetPasswordResetSender(prevSender)
+		passwordResetLimiter = prevLimiter
+	})
+
+	post := func(handler http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(handleForgotPassword, "/api/auth/forgot-password", `{"email":"`+user.Email+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("forgot-password status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	var token string
+	select {
+	case token = <-sender:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reset token was sent")
+	}
+
+	reset := `{"token":"` + token + `","new_password":"new-password"}`
+	if rec := post(handleResetPassword, "/api/auth/reset-password", reset); rec.Code != http.StatusOK {
+		t.Fatalf("reset-password status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	updated, err := GetUserByEmail(context.Background(), user.Email)
+	if err != nil || !VerifyPassword(updated.PasswordHash, "new-password") {
+		t.Errorf("new password not set (err %v)", err)
+	}
+
+	// Tokens are single-use
+	if rec := post(handleResetPassword, "/api/auth/reset-password", reset); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused token: status = %d, want 400", rec.Code)
+	}
+}
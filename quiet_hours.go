@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// deferredNotificationInterval is how often deferred notifications are checked for delivery
+const deferredNotificationInterval = time.Minute
+
+// deferredNotificationMaxAttempts is how many failed deliveries drop a deferred notification
+const deferredNotificationMaxAttempts = 5
+
+// validateQuietHours checks an optional [start, end) quiet hour window and its timezone
+func validateQuietHours(start, end *int, timezone string) error {
+	if (start == nil) != (end == nil) {
+		return fmt.Errorf("quiet hour start and end must be set together")
+	}
+	if start != nil {
+		if *start < 0 || *start > 23 {
+			return fmt.Errorf("quiet hour start must be 0-23")
+		}
+		if *end < 0 || *end > 23 {
+			return fmt.Errorf("quiet hour end must be 0-23")
+		}
+		if *start == *end {
+			return fmt.Errorf("quiet hour start and end must differ")
+		}
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", timezone)
+		}
+	}
+	return nil
+}
+
+// quietUntil reports whether now falls in the user's quiet hours and, if so,
+// when they end. The window wraps midnight when start > end (e.g. 22-7).
+func (c *UserConfig) quietUntil(now time.Time) (time.Time, bool) {
+	if c.QuietHourStart == nil || c.QuietHourEnd == nil {
+		return time.Time{}, false
+	}
+	if c.QuietTimezone != "" {
+		if loc, err := time.LoadLocation(c.QuietTimezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	start, end, hour := *c.QuietHourStart, *c.QuietHourEnd, now.Hour()
+
+	quiet := hour >= start && hour < end
+	if start > end {
+		quiet = hour >= start || hour < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(now.Year(), now.Month(), now.Day(), end, 0, 0, 0, now.Location())
+	if !until.After(now) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// isCriticalNotification reports whether a job's notification is urgent
+// enough to bypass quiet hours when the user allows it
+func isCriticalNotification(job *Job) bool {
+	return job.Status == "failed"
+}
+
+// dispatchJobNotification sends the job notification now, or queues it for
+// the end of the user's quiet hours
+func dispatchJobNotification(job *Job, cfg *UserConfig, logger Logger) {
+	if until, quiet := cfg.quietUntil(timeNow()); quiet && !(cfg.QuietBypassCritical && isCriticalNotification(job)) {
+		if err := DeferNotification(job.UserID, job.ID, until); err != nil {
+			logger.Log(fmt.Sprintf("Warning: failed to defer notification: %v", err))
+			return
+		}
+		logger.Log(fmt.Sprintf("Quiet hours: notification deferred until %s", until.Format(time.RFC3339)))
+		return
+	}
+
+	if err := notifier.NotifyJobComplete(job, cfg); err != nil {
+		logger.Log(fmt.Sprintf("Warning: failed to send notification: %v", err))
+	}
+}
+
+// RunDeferredNotifications delivers notifications held back by quiet hours
+// once they are due, until ctx is cancelled
+func RunDeferredNotifications(ctx context.Context) {
+	ticker := time.NewTicker(deferredNotificationInterval)
+	defer ticker.Stop()
+
+	for {
+		deliverDeferredNotifications(timeNow())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDeferredNotifications sends every deferred notification due at now.
+// Failed deliveries are retried on the next pass up to a limit.
+func deliverDeferredNotifications(now time.Time) {
+	due, err := ListDueNotifications(now)
+	if err != nil {
+		slog.Error("Failed to list deferred notifications", "error", err)
+		return
+	}
+
+	for _, n := range due {
+		if err := deliverDeferredNotification(n); err != nil {
+			slog.Error("Failed to deliver deferred notification", "job_id", n.JobID, "error", err)
+			if err := RecordNotificationFailure(n.ID, deferredNotificationMaxAttempts); err != nil {
+				slog.Error("Failed to record notification failure", "job_id", n.JobID, "error", err)
+			}
+			continue
+		}
+		if err := DeleteDeferredNotification(n.ID); err != nil {
+			slog.Error("Failed to delete deferred notification", "job_id", n.JobID, "error", err)
+		}
+	}
+}
+
+// deliverDeferredNotification sends one deferred notification with the
+// job and config as they are now
+func deliverDeferredNotification(n *DeferredNotification) error {
+	job, err := GetJob(context.Background(), n.JobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		// The job was deleted meanwhile; nothing left to report
+		return nil
+	}
+	cfg, err := GetUserConfig(context.Background(), n.UserID)
+	if err != nil {
+		return err
+	}
+	return notifier.NotifyJobComplete(job, cfg)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateQuietHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end *int
+		timezone   string
+		wantErr    bool
+	}{
+		{"unset", nil, nil, "", false},
+		{"overnight", intPtr(22), intPtr(7), "Europe/Kyiv", false},
+		{"daytime", intPtr(9), intPtr(17), "", false},
+		{"start only", intPtr(22), nil, "", true},
+		{"end out of range", intPtr(22), intPtr(24), "", true},
+		{"negative start", intPtr(-1), intPtr(7), "", true},
+		{"empty window", intPtr(8), intPtr(8), "", true},
+		{"unknown timezone", nil, nil, "Mars/Olympus", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateQuietHours(tt.start, tt.end, tt.timezone); (err != nil) != tt.wantErr {
+				t.Errorf("validateQuietHours() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuietUntil(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	utc := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		cfg       UserConfig
+		now       time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"no quiet hours", UserConfig{}, utc(2, 23, 0), false, time.Time{}},
+		{"daytime window", UserConfig{QuietHourStart: intPtr(9), QuietHourEnd: intPtr(17)}, utc(2, 12, 30), true, utc(2, 17, 0)},
+		{"after daytime window", UserConfig{QuietHourStart: intPtr(9), QuietHourEnd: intPtr(17)}, utc(2, 17, 0), false, time.Time{}},
+		{"overnight before midnight", UserConfig{QuietHourStart: intPtr(22), QuietHourEnd: intPtr(7)}, utc(2, 23, 15), true, utc(3, 7, 0)},
+		{"overnight after midnight", UserConfig{QuietHourStart: intPtr(22), QuietHourEnd: intPtr(7)}, utc(3, 3, 0), true, utc(3, 7, 0)},
+		{"overnight outside", UserConfig{QuietHourStart: intPtr(22), QuietHourEnd: intPtr(7)}, utc(2, 12, 0), false, time.Time{}},
+		// 20:30 UTC is 22:30 in Kyiv (UTC+2 in early March)
+		{"user timezone", UserConfig{QuietHourStart: intPtr(22), QuietHourEnd: intPtr(7), QuietTimezone: "Europe/Kyiv"},
+			utc(2, 20, 30), true, time.Date(2026, 3, 3, 7, 0, 0, 0, kyiv).UTC()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := tt.cfg.quietUntil(tt.now)
+			if quiet != tt.wantQuiet || !until.Equal(tt.wantUntil) {
+				t.Errorf("quietUntil(%v) = %v, %v, want %v, %v", tt.now, until, quiet, tt.wantUntil, tt.wantQuiet)
+			}
+		})
+	}
+}
+
+func TestIsCriticalNotification(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{"failed", true},
+		{"completed", false},
+		{"cancelled", false},
+	}
+	for _, tt := range tests {
+		if got := isCriticalNotification(&Job{Status: tt.status}); got != tt.want {
+			t.Errorf("isCriticalNotification(%s) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+// recordingNotifier collects the jobs it is asked to notify about
+type recordingNotifier struct {
+	mu   sync.Mutex
+	jobs []string
+	err  error
+}
+
+// count returns how many notifications were sent for jobID
+func (n *recordingNotifier) count(jobID string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	count := 0
+	for _, id := range n.jobs {
+		if id == jobID {
+			count++
+		}
+	}
+	return count
+}
+
+func (n *recordingNotifier) NotifyJobComplete(job *Job, cfg *UserConfig) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.jobs = append(n.jobs, job.ID)
+	return n.err
+}
+
+// setNotifierForTest installs n as the notifier and restores the previous one afterwards
+func setNotifierForTest(t *testing.T, n Notifier) {
+	t.Helper()
+	prev := notifier
+	SetNotifier(n)
+	t.Cleanup(func() { SetNotifier(prev) })
+}
+
+func TestDispatchJobNotificationSendsNow(t *testing.T) {
+	// Noon UTC; none of these need the deferred queue
+	setTimeNowForTest(t, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name   string
+		cfg    UserConfig
+		status string
+	}{
+		{"no quiet hours", UserConfig{}, "completed"},
+		{"outside quiet hours", UserConfig{QuietHourStart: intPtr(22), QuietHourEnd: intPtr(7)}, "completed"},
+		{"critical bypasses quiet hours", UserConfig{QuietHourStart: intPtr(9), QuietHourEnd: intPtr(17), QuietBypassCritical: true}, "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &recordingNotifier{}
+			setNotifierForTest(t, n)
+			dispatchJobNotification(&Job{ID: "job-1", UserID: 1, Status: tt.status}, &tt.cfg, &testLogger{})
+			if got := n.count("job-1"); got != 1 {
+				t.Errorf("sent %d notifications, want 1", got)
+			}
+		})
+	}
+}
+
+func TestDispatchJobNotificationSendFailureIsLogged(t *testing.T) {
+	setNotifierForTest(t, &recordingNotifier{err: errors.New("smtp down")})
+	logger := &testLogger{}
+	dispatchJobNotification(&Job{ID: "job-1", Status: "completed"}, &UserConfig{}, logger)
+	if len(logger.lines) != 1 {
+		t.Errorf("log = %q, want one warning", logger.lines)
+	}
+}
+
+func TestDeferredNotificationDelivery(t *testing.T) {
+	user := createTestUser(t)
+	job := createTestJob(t, user.ID, "full")
+	if err := UpdateJobStatus(job.ID, "completed", nil); err != nil {
+		t.Fatal(err)
+	}
+	job.Status = "completed"
+
+	n := &recordingNotifier{}
+	setNotifierForTest(t, n)
+	now := time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)
+	setTimeNowForTest(t, now)
+	cfg := &UserConfig{UserID: user.ID, QuietHourStart: intPtr(22), QuietHourEnd: intPtr(7)}
+
+	dispatchJobNotification(job, cfg, &testLogger{})
+	if n.count(job.ID) != 0 {
+		t.Fatalf("notification sent during quiet hours")
+	}
+
+	// Not due before the quiet hours end, delivered once they have
+	deliverDeferredNotifications(now.Add(time.Hour))
+	if n.count(job.ID) != 0 {
+		t.Fatalf("notification delivered before the quiet hours ended")
+	}
+	deliverDeferredNotifications(time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC))
+	if got := n.count(job.ID); got != 1 {
+		t.Errorf("job notification delivered %d times, want 1", got)
+	}
+
+	// Delivered notifications are removed from the queue
+	due, err := ListDueNotifications(time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ListDueNotifications: %v", err)
+	}
+	for _, d := range due {
+		if d.JobID == job.ID {
+			t.Error("delivered notification is still queued")
+		}
+	}
+}
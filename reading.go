@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// readingDecimalSeparator is the decimal separator used by the site when
+// formatting meter readings; the other of "." and "," is a thousands separator
+var readingDecimalSeparator = "."
+
+// SetReadingDecimalSeparator configures how meter readings are parsed
+func SetReadingDecimalSeparator(sep string) error {
+	if sep != "." && sep != "," {
+		return fmt.Errorf("decimal separator must be \".\" or \",\", got %q", sep)
+	}
+	readingDecimalSeparator = sep
+	return nil
+}
+
+// Bounds for a single month's increment; anything outside is treated as a
+// configuration or parsing mistake rather than real consumption
+var (
+	readingMinIncrement = 0.0
+	readingMaxIncrement = 10000.0
+)
+
+// SetReadingIncrementRange sets the accepted range for monthly increments
+func SetReadingIncrementRange(min, max int) error {
+	if min < 0 || max < min {
+		return fmt.Errorf("increment range must satisfy 0 <= min <= max, got %d..%d", min, max)
+	}
+	readingMinIncrement, readingMaxIncrement = float64(min), float64(max)
+	return nil
+}
+
+// readingPrecision is how many fractional digits of a reading are kept
+var readingPrecision = 3
+
+// maxReadingPrecision keeps readings well within float64's exact range
+const maxReadingPrecision = 6
+
+// SetReadingPrecision sets how many fractional digits readings and increments keep
+func SetReadingPrecision(digits int) error {
+	if digits < 0 || digits > maxReadingPrecision {
+		return fmt.Errorf("reading precision must be 0-%d digits, got %d", maxReadingPrecision, digits)
+	}
+	readingPrecision = digits
+	return nil
+}
+
+// roundReading rounds v to readingPrecision fractional digits, so sums of
+// readings and increments compare and print exactly
+func roundReading(v float64) float64 {
+	scale := math.Pow10(readingPrecision)
+	return math.Round(v*scale) / scale
+}
+
+// formatReading formats a reading with "." as the decimal point. Whole values
+// print without a fraction, e.g. 1234 and 1234.567.
+func formatReading(v float64) string {
+	return strconv.FormatFloat(roundReading(v), 'f', -1, 64)
+}
+
+// formatReadingForSite formats a reading the way the site writes numbers
+func formatReadingForSite(v float64) string {
+	return strings.Replace(formatReading(v), ".", readingDecimalSeparator, 1)
+}
+
+// ErrImplausibleReading is returned when a computed reading fails sanity checks
+var ErrImplausibleReading = errors.New("implausible_reading")
+
+// validateReading checks a computed reading before it's submitted. The new
+// value must exceed the current one (or equal it when allowEqual is set, for
+// forced re-submissions) and the increment must be within the configured range.
+func validateReading(currentValue, increment, newValue float64, allowEqual bool) error {
+	if increment < readingMinIncrement || increment > readingMaxIncrement {
+		return fmt.Errorf("%w: increment %s is outside the allowed range %s..%s", ErrImplausibleReading,
+			formatReading(increment), formatReading(readingMinIncrement), formatReading(readingMaxIncrement))
+	}
+	if newValue < currentValue || (newValue == currentValue && !allowEqual) {
+		return fmt.Errorf("%w: new value %s is not greater than the current value %s",
+			ErrImplausibleReading, formatReading(newValue), formatReading(currentValue))
+	}
+	return nil
+}
+
+// parseMeterReading extracts the meter reading from text like "1 234 м³",
+// "1,234.567" or "Показник: 1234". Units and labels are ignored, spaces,
+// apostrophes and the non-decimal separator are treated as thousands
+// separators, and the fractional part is kept to readingPrecision digits.
+func parseMeterReading(s, decimalSep string) (float64, error) {
+	thousandsSep := ","
+	if decimalSep == "," {
+		thousandsSep = "."
+	}
+
+	// Grab the first run of digits and separators
+	start, end := numberRun(s)
+	if start < 0 {
+		return 0, fmt.Errorf("no number found in %q", s)
+	}
+	raw := strings.TrimRight(s[start:end], ".,'    ")
+
+	// Drop group spacing, then split off the fractional part
+	raw = strings.Map(func(r rune) rune {
+		if isDigitGroupSpace(r) {
+			return -1
+		}
+		return r
+	}, raw)
+
+	intPart, frac := raw, ""
+	if strings.Count(raw, decimalSep) == 1 {
+		intPart = raw[:strings.Index(raw, decimalSep)]
+		if frac = raw[len(intPart)+1:]; strings.Contains(frac, thousandsSep) {
+			return 0, fmt.Errorf("malformed number %q in %q", raw, s)
+		}
+	} else {
+		// Repeated "decimal" separators can only be digit grouping
+		intPart = strings.ReplaceAll(intPart, decimalSep, "")
+	}
+	intPart = strings.ReplaceAll(intPart, thousandsSep, "")
+
+	number := intPart
+	if frac != "" {
+		number += "." + frac
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed number %q in %q", raw, s)
+	}
+	return roundReading(value), nil
+}
+
+// parseMeterReadingStrict is parseMeterReading for the reading we build on:
+// text around the number is allowed, but a second number (e.g. trailing
+// garbage or a date) makes it ambiguous and is rejected
+func parseMeterReadingStrict(s, decimalSep string) (float64, error) {
+	value, err := parseMeterReading(s, decimalSep)
+	if err != nil {
+		return 0, err
+	}
+	if _, end := numberRun(s); strings.IndexFunc(s[end:], isASCIIDigit) >= 0 {
+		return 0, fmt.Errorf("unexpected trailing characters %q after the number in %q", s[end:], s)
+	}
+	return value, nil
+}
+
+// numberRun returns the byte range of the first run of digits and separators
+// in s, or -1, -1 if s has no digits
+func numberRun(s string) (int, int) {
+	start := strings.IndexFunc(s, isASCIIDigit)
+	if start < 0 {
+		return -1, -1
+	}
+	end := len(s)
+	for i, r := range s[start:] {
+		if !isASCIIDigit(r) && r != '.' && r != ',' && !isDigitGroupSpace(r) {
+			end = start + i
+			break
+		}
+	}
+	return start, end
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isDigitGroupSpace reports whether r is used to group digits (regular,
+// no-break, narrow no-break and thin spaces, or an apostrophe)
+func isDigitGroupSpace(r rune) bool {
+	switch r {
+	case ' ', ' ', ' ', ' ', '\'':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMeterReading(t *testing.T) {
+	tests := []struct {
+		in      string
+		sep     string
+		want    float64
+		wantErr bool
+	}{
+		{"1234", ".", 1234, false},
+		{"1 234 м³", ".", 1234, false},
+		{"Показник: 1234", ".", 1234, false},
+		{"1,234.567", ".", 1234.567, false},
+		{"1.234,567", ",", 1234.567, false},
+		{"1 234,5 м³", ",", 1234.5, false},
+		{"1 234", ".", 1234, false},
+		{"1'234'567", ".", 1234567, false},
+		{"1.234.567", ".", 1234567, false},
+		{"1234.", ".", 1234, false},
+		{"1234.56789", ".", 1234.568, false},
+		{"м³", ".", 0, true},
+		{"", ".", 0, true},
+		{"1.234,5", ".", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseMeterReading(tt.in, tt.sep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMeterReading(%q, %q) error = %v, wantErr %v", tt.in, tt.sep, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMeterReading(%q, %q) = %v, want %v", tt.in, tt.sep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMeterReadingStrict(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"1234", 1234, false},
+		{"  1 234 м³ ", 1234, false},
+		{"Поточний показник: 1234.5", 1234.5, false},
+		{"1234 м³ (01.03.2026)", 0, true},
+		{"1234 / 5678", 0, true},
+		{"no digits", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseMeterReadingStrict(tt.in, ".")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMeterReadingStrict(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMeterReadingStrict(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetReadingIncrementRange(t *testing.T) {
+	prevMin, prevMax := readingMinIncrement, readingMaxIncrement
+	t.Cleanup(func() { readingMinIncrement, readingMaxIncrement = prevMin, prevMax })
+
+	tests := []struct {
+		min, max int
+		wantErr  bool
+	}{
+		{0, 10000, false},
+		{5, 5, false},
+		{-1, 100, true},
+		{100, 50, true},
+	}
+	for _, tt := range tests {
+		err := SetReadingIncrementRange(tt.min, tt.max)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SetReadingIncrementRange(%d, %d) error = %v, wantErr %v", tt.min, tt.max, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateReading(t *testing.T) {
+	prevMin, prevMax := readingMinIncrement, readingMaxIncrement
+	t.Cleanup(func() { readingMinIncrement, readingMaxIncrement = prevMin, prevMax })
+	if err := SetReadingIncrementRange(0, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		current    float64
+		increment  float64
+		newValue   float64
+		allowEqual bool
+		wantErr    bool
+	}{
+		{"normal increment", 1000, 25, 1025, false, false},
+		{"largest allowed increment", 1000, 100, 1100, false, false},
+		{"increment above the range", 1000, 101, 1101, false, true},
+		{"negative increment", 1000, -5, 995, false, true},
+		{"zero increment without force", 1000, 0, 1000, false, true},
+		{"zero increment with force", 1000, 0, 1000, true, false},
+		{"new value below current", 1000, 25, 900, true, true},
+		{"fractional increment", 1000.5, 0.25, 1000.75, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReading(tt.current, tt.increment, tt.newValue, tt.allowEqual)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateReading() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrImplausibleReading) {
+				t.Errorf("validateReading() error = %v, want ErrImplausibleReading", err)
+			}
+		})
+	}
+}
+
+func TestSetReadingPrecision(t *testing.T) {
+	prev := readingPrecision
+	t.Cleanup(func() { readingPrecision = prev })
+
+	tests := []struct {
+		digits  int
+		wantErr bool
+	}{
+		{0, false},
+		{3, false},
+		{maxReadingPrecision, false},
+		{maxReadingPrecision + 1, true},
+		{-1, true},
+	}
+	for _, tt := range tests {
+		if err := SetReadingPrecision(tt.digits); (err != nil) != tt.wantErr {
+			t.Errorf("SetReadingPrecision(%d) error = %v, wantErr %v", tt.digits, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatReading(t *testing.T) {
+	prevPrecision, prevSep := readingPrecision, readingDecimalSeparator
+	t.Cleanup(func() { readingPrecision, readingDecimalSeparator = prevPrecision, prevSep })
+
+	tests := []struct {
+		name      string
+		precision int
+		sep       string
+		in        float64
+		want      string
+		wantSite  string
+	}{
+		{"whole value", 3, ".", 1234, "1234", "1234"},
+		{"fraction", 3, ".", 1234.567, "1234.567", "1234.567"},
+		{"float sum is rounded", 3, ".", 0.1 + 0.2, "0.3", "0.3"},
+		{"extra digits are rounded", 3, ".", 1234.5678, "1234.568", "1234.568"},
+		{"comma decimal site", 3, ",", 1234.5, "1234.5", "1234,5"},
+		{"whole readings only", 0, ".", 1234.6, "1235", "1235"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetReadingPrecision(tt.precision); err != nil {
+				t.Fatal(err)
+			}
+			if err := SetReadingDecimalSeparator(tt.sep); err != nil {
+				t.Fatal(err)
+			}
+			if got := formatReading(tt.in); got != tt.want {
+				t.Errorf("formatReading(%v) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got := formatReadingForSite(tt.in); got != tt.wantSite {
+				t.Errorf("formatReadingForSite(%v) = %q, want %q", tt.in, got, tt.wantSite)
+			}
+		})
+	}
+}
+
+func TestRoundReadingMakesSumsComparable(t *testing.T) {
+	prev := readingPrecision
+	t.Cleanup(func() { readingPrecision = prev })
+	readingPrecision = 3
+
+	if got := roundReading(1234.1 + 0.2); got != 1234.3 {
+		t.Errorf("roundReading(1234.1 + 0.2) = %v, want 1234.3", got)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// RecordsResponse is the raw indicator table for one or more years
+type RecordsResponse struct {
+	Years     []int                  `json:"years"`
+	Records   map[int][]IndicatorRow `json:"records"`
+	FetchedAt string                 `json:"fetched_at"`
+}
+
+// handleGetRecords logs into gasolina-online.com and returns the indicator table.
+// GET /api/config/records?year=2025 returns one year; without year, every year.
+func handleGetRecords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	year := 0
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2000 || y > 2100 {
+			jsonError(w, "year must be a four-digit year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	cfg, err := GetUserConfig(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get user config", "user_id", userID, "error", err)
+		jsonError(w, "Failed to get user config", http.StatusInternalServerError)
+		return
+	}
+
+	if !cfg.Configured {
+		jsonError(w, "Gasolina credentials not configured", http.StatusBadRequest)
+		return
+	}
+
+	if !allowCredentialCheck(w, userID) {
+		return
+	}
+
+	// The browser login outlasts the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(recordsFetchTimeout + 5*time.Second))
+	records, err := fetchIndicatorRecords(r.Context(), cfg.ToConfig(), year)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch records", "user_id", userID, "error", err)
+		jsonError(w, fmt.Sprintf("Failed to fetch records: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(records)
+}
+
+// recordsFetchTimeout bounds the login and table reads behind GET /api/config/records
+const recordsFetchTimeout = 2 * time.Minute
+
+// fetchIndicatorRecords logs in and reads the indicator table for year, or for
+// every year in the filter when year is 0. Like a credential check it takes a
+// job slot and a pooled tab, which close when the client goes away.
+func fetchIndicatorRecords(reqCtx context.Context, config *Config, year int) (*RecordsResponse, error) {
+	ctx, cancel, err := newCredentialCheckContext(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	ctx, timeoutCancel := context.WithTimeout(ctx, recordsFetchTimeout)
+	defer timeoutCancel()
+
+	if err := Login(ctx, config); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(config.CheckURL),
+		chromedp.WaitReady("body"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to navigate to indicator page: %w", err)
+	}
+
+	years := []int{year}
+	if year == 0 {
+		var err error
+		if years, err = readIndicatorYears(ctx, config.selectors()); err != nil {
+			return nil, err
+		}
+	}
+
+	logger := &defaultLogger{}
+	resp := &RecordsResponse{
+		Years:     years,
+		Records:   make(map[int][]IndicatorRow, len(years)),
+		FetchedAt: time.Now().Format("02.01.2006 15:04:05"),
+	}
+	for _, y := range years {
+		rows, err := readIndicatorTableRows(ctx, config.selectors(), y, logger)
+		if err != nil {
+			return nil, fmt.Errorf("year %d: %w", y, err)
+		}
+		resp.Records[y] = rows
+	}
+	return resp, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetRecordsRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		userID     int64
+		wantStatus int
+		wantError  string
+	}{
+		{"wrong method", http.MethodPost, "/api/config/records", 1, http.StatusMethodNotAllowed, "Method not allowed"},
+		{"no user", http.MethodGet, "/api/config/records", 0, http.StatusUnauthorized, "User not found in context"},
+		{"year not a number", http.MethodGet, "/api/config/records?year=last", 1, http.StatusBadRequest, "year must be a four-digit year"},
+		{"two-digit year", http.MethodGet, "/api/config/records?year=25", 1, http.StatusBadRequest, "year must be a four-digit year"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleGetRecords(rec, newAuthedRequest(tt.method, tt.target, "", tt.userID))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := errorMessage(t, rec); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleGetRecordsRejectedDuringMaintenance(t *testing.T) {
+	user := createTestUser(t)
+	setEncryptionKeyForTest(t, "test-secret")
+	if err := SaveUserConfig(&UserConfig{UserID: user.ID, GasolinaEmail: "me@example.com", GasolinaPassword: "secret"}); err != nil {
+		t.Fatalf("SaveUserConfig: %v", err)
+	}
+	prev := jobManager
+	t.Cleanup(func() { jobManager = prev })
+	jobManager = NewJobManagerWithExecutor(&fakeExecutor{})
+	jobManager.SetMaintenance(true)
+
+	// Paused job processing must also stop the one-off browser login
+	rec := httptest.NewRecorder()
+	handleGetRecords(rec, newAuthedRequest(http.MethodGet, "/api/config/records?year=2026", "", user.ID))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503 (%s)", rec.Code, rec.Body.String())
+	}
+}
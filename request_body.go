@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DecodeError describes why a JSON request body could not be decoded
+type DecodeError struct {
+	Error  string `json:"error"`
+	Kind   string `json:"kind"`
+	Field  string `json:"field,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+}
+
+// Decode error kinds
+const (
+	DecodeErrorEmpty        = "empty_body"
+	DecodeErrorSyntax       = "syntax_error"
+	DecodeErrorUnknownField = "unknown_field"
+	DecodeErrorType         = "type_mismatch"
+	DecodeErrorInvalid      = "invalid_body"
+)
+
+// decodeJSONBody decodes the request body into dst, rejecting unknown fields.
+// On failure it writes a 400 describing the problem and returns false.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		return true
+	}
+
+	de := describeDecodeError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	newJSONEncoder(w).Encode(de)
+	return false
+}
+
+// describeDecodeError maps a json.Decoder error to a client-facing DecodeError
+func describeDecodeError(err error) DecodeError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return DecodeError{Error: "Request body is empty", Kind: DecodeErrorEmpty}
+	case errors.As(err, &syntaxErr):
+		return DecodeError{
+			Error:  fmt.Sprintf("Malformed JSON at offset %d", syntaxErr.Offset),
+			Kind:   DecodeErrorSyntax,
+			Offset: syntaxErr.Offset,
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return DecodeError{Error: "Malformed JSON: unexpected end of body", Kind: DecodeErrorSyntax}
+	case errors.As(err, &typeErr):
+		return DecodeError{
+			Error:  fmt.Sprintf("Field %q must be of type %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value),
+			Kind:   DecodeErrorType,
+			Field:  typeErr.Field,
+			Offset: typeErr.Offset,
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return DecodeError{
+			Error: fmt.Sprintf("Unknown field %q", field),
+			Kind:  DecodeErrorUnknownField,
+			Field: field,
+		}
+	}
+	return DecodeError{Error: "Invalid request body", Kind: DecodeErrorInvalid}
+}
+
+// Resettable is a request field that records whether it was sent at all, so
+// an explicit null or empty value can reset a setting to its default while an
+// omitted field keeps the stored value
+type Resettable[T any] struct {
+	Sent  bool
+	Value T // the zero value when sent as null
+}
+
+// UnmarshalJSON marks the field sent; null leaves Value at its zero value
+func (f *Resettable[T]) UnmarshalJSON(data []byte) error {
+	f.Sent = true
+	if string(data) == "null" {
+		var zero T
+		f.Value = zero
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+// Or returns the sent value, or current when the field was omitted
+func (f Resettable[T]) Or(current T) T {
+	if f.Sent {
+		return f.Value
+	}
+	return current
+}
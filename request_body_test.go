@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResettable(t *testing.T) {
+	type body struct {
+		NotifyEmail Resettable[string]             `json:"notify_email"`
+		Fingerprint Resettable[BrowserFingerprint] `json:"browser_fingerprint"`
+	}
+	stored := BrowserFingerprint{UserAgent: "stored"}
+	tests := []struct {
+		name            string
+		json            string
+		wantEmail       string
+		wantFingerprint BrowserFingerprint
+	}{
+		{"omitted keeps stored", `{}`, "old@example.com", stored},
+		{"null resets", `{"notify_email":null,"browser_fingerprint":null}`, "", BrowserFingerprint{}},
+		{"empty resets", `{"notify_email":"","browser_fingerprint":{}}`, "", BrowserFingerprint{}},
+		{"value replaces", `{"notify_email":"new@example.com","browser_fingerprint":{"locale":"uk-UA"}}`,
+			"new@example.com", BrowserFingerprint{Locale: "uk-UA"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b body
+			if err := json.Unmarshal([]byte(tt.json), &b); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if got := b.NotifyEmail.Or("old@example.com"); got != tt.wantEmail {
+				t.Errorf("notify_email = %q, want %q", got, tt.wantEmail)
+			}
+			if got := b.Fingerprint.Or(stored); got != tt.wantFingerprint {
+				t.Errorf("browser_fingerprint = %+v, want %+v", got, tt.wantFingerprint)
+			}
+		})
+	}
+}
+
+func TestResettableRejectsWrongType(t *testing.T) {
+	var r Resettable[string]
+	if err := json.Unmarshal([]byte(`42`), &r); err == nil {
+		t.Error("Unmarshal of a number into Resettable[string] succeeded")
+	}
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	type body struct {
+		Name     string `json:"name"`
+		Attempts int    `json:"attempts"`
+		Browser  struct {
+			Width int `json:"width"`
+		} `json:"browser"`
+	}
+	tests := []struct {
+		name   string
+		json   string
+		wantOK bool
+		want   DecodeError
+	}{
+		{"valid", `{"name":"x","attempts":2}`, true, DecodeError{}},
+		{"empty", ``, false, DecodeError{Error: "Request body is empty", Kind: DecodeErrorEmpty}},
+		{"syntax error", `{"name":x}`, false, DecodeError{Error: "Malformed JSON at offset 9", Kind: DecodeErrorSyntax, Offset: 9}},
+		{"truncated", `{"name":"x"`, false, DecodeError{Error: "Malformed JSON: unexpected end of body", Kind: DecodeErrorSyntax}},
+		{"wrong type", `{"attempts":"two"}`, false, DecodeError{Error: `Field "attempts" must be of type int, got string`, Kind: DecodeErrorType, Field: "attempts", Offset: 17}},
+		{"nested wrong type", `{"browser":{"width":1.5}}`, false, DecodeError{Error: `Field "browser.width" must be of type int, got number 1.5`, Kind: DecodeErrorType, Field: "browser.width", Offset: 23}},
+		{"unknown field", `{"nme":"x"}`, false, DecodeError{Error: `Unknown field "nme"`, Kind: DecodeErrorUnknownField, Field: "nme"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			var dst body
+			ok := decodeJSONBody(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.json)), &dst)
+			if ok != tt.wantOK {
+				t.Fatalf("decodeJSONBody() = %v, want %v (%s)", ok, tt.wantOK, rec.Body.String())
+			}
+			if ok {
+				return
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+			var got DecodeError
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("response = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+const requestIDKey contextKey = "requestID"
+
+// maxRequestIDLength caps a client-supplied request ID
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware tags each request with an ID, taken from X-Request-ID
+// or generated, and echoes it in the response header. A request that already
+// has an ID keeps it, so the middleware can sit both outside the request
+// timeout (whose writer has its own headers) and inside it.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := GetRequestIDFromContext(r.Context())
+		if !ok {
+			id = r.Header.Get(requestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.New().String()
+			}
+			r = r.WithContext(context.WithValue(r.Context(), requestIDKey, id))
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetRequestIDFromContext extracts the request ID from context
+func GetRequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+// validRequestID accepts a client ID of printable ASCII without spaces, so it
+// is safe to echo in a header and a log line
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
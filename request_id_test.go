@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// failingHandler answers every request with a JSON error
+var failingHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, "Something broke", http.StatusInternalServerError)
+})
+
+func TestRequestIDMiddleware(t *testing.T) {
+	maxID := strings.Repeat("a", maxRequestIDLength)
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{"client ID", "req-123", true},
+		{"longest client ID", maxID, true},
+		{"no ID", "", false},
+		{"too long", maxID + "a", false},
+		{"space", "req 123", false},
+		{"control character", "req\x01123", false},
+		{"non-ASCII", "запит-1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/jobs", nil)
+			if tt.incoming != "" {
+				r.Header.Set(requestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			RequestIDMiddleware(failingHandler).ServeHTTP(rec, r)
+
+			id := rec.Header().Get(requestIDHeader)
+			if tt.wantKept && id != tt.incoming {
+				t.Errorf("request ID = %q, want the client's %q", id, tt.incoming)
+			}
+			if !tt.wantKept {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Errorf("request ID = %q, want a generated UUID", id)
+				}
+			}
+
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body["request_id"] != id {
+				t.Errorf("body request_id = %q, want %q", body["request_id"], id)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddlewareWrappedTwice(t *testing.T) {
+	for _, incoming := range []string{"", "req-123"} {
+		var seen string
+		inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen, _ = GetRequestIDFromContext(r.Context())
+		})
+		r := httptest.NewRequest(http.MethodGet, "/api/jobs", nil)
+		if incoming != "" {
+			r.Header.Set(requestIDHeader, incoming)
+		}
+		rec := httptest.NewRecorder()
+		RequestIDMiddleware(RequestIDMiddleware(inner)).ServeHTTP(rec, r)
+
+		if id := rec.Header().Get(requestIDHeader); seen == "" || id != seen {
+			t.Errorf("incoming %q: header ID = %q, handler ID = %q, want one ID", incoming, id, seen)
+		}
+		if incoming != "" && seen != incoming {
+			t.Errorf("handler ID = %q, want %q", seen, incoming)
+		}
+	}
+}
+
+func TestJSONErrorWithoutRequestID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	jsonError(rec, "Nope", http.StatusBadRequest)
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := body["request_id"]; ok || body["error"] != "Nope" {
+		t.Errorf("body = %v, want only the error", body)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requestTimeoutBody is the 503 body sent when a handler runs past the timeout
+const requestTimeoutBody = `{"error":"Request timed out"}`
+
+// ownDeadlinePaths are endpoints that bound their own work (e.g. a browser
+// login) and extend their write deadline to match
+var ownDeadlinePaths = map[string]bool{
+	"/api/config/records":            true,
+	"/api/config/rotate-credentials": true,
+	"/api/config/validate":           true,
+}
+
+// isRequestTimeoutExempt reports whether a request is a long-lived stream that
+// must not be cut off or buffered, or sets its own deadline
+func isRequestTimeoutExempt(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, "/logs/stream") || ownDeadlinePaths[r.URL.Path]
+}
+
+// RequestTimeoutMiddleware answers 503 when a handler takes longer than timeout
+// and cancels the request's context so the handler can stop. Streaming and
+// self-limiting endpoints are exempt. A zero timeout disables it.
+func RequestTimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		limited := http.TimeoutHandler(next, timeout, requestTimeoutBody)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isRequestTimeoutExempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
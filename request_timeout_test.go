@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		path       string
+		delay      time.Duration
+		wantStatus int
+	}{
+		{"fast request", 50 * time.Millisecond, "/api/jobs", 0, http.StatusOK},
+		{"slow request", 20 * time.Millisecond, "/api/jobs", 200 * time.Millisecond, http.StatusServiceUnavailable},
+		{"disabled", 0, "/api/jobs", 50 * time.Millisecond, http.StatusOK},
+		{"log stream", 20 * time.Millisecond, "/api/jobs/abc/logs/stream", 100 * time.Millisecond, http.StatusOK},
+		{"credential check", 20 * time.Millisecond, "/api/config/validate", 100 * time.Millisecond, http.StatusOK},
+		{"credential rotation", 20 * time.Millisecond, "/api/config/rotate-credentials", 100 * time.Millisecond, http.StatusOK},
+		{"indicator records", 20 * time.Millisecond, "/api/config/records", 100 * time.Millisecond, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tt.delay):
+				case <-r.Context().Done():
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			rec := httptest.NewRecorder()
+			RequestTimeoutMiddleware(tt.timeout)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && rec.Body.String() != requestTimeoutBody {
+				t.Errorf("body = %q, want %q", rec.Body, requestTimeoutBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// prettyJSONAlways indents every JSON response (PRETTY_JSON, for local debugging)
+var prettyJSONAlways bool
+
+// SetPrettyJSON indents all JSON responses, not just those requested with ?pretty=1
+func SetPrettyJSON(enabled bool) {
+	prettyJSONAlways = enabled
+}
+
+// prettyResponseWriter marks a response whose JSON body should be indented
+type prettyResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Flush forwards to the underlying writer so streaming responses keep working
+func (w prettyResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w prettyResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// PrettyJSONMiddleware indents JSON responses for requests with ?pretty=1
+func PrettyJSONMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("pretty") {
+		case "1", "true":
+			w = prettyResponseWriter{w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newJSONEncoder returns the encoder for a JSON response body; output is compact
+// unless the request asked for ?pretty=1 or PRETTY_JSON is set
+func newJSONEncoder(w http.ResponseWriter) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if _, pretty := w.(prettyResponseWriter); pretty || prettyJSONAlways {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrettyJSONMiddleware(t *testing.T) {
+	const compact = "{\"a\":1}\n"
+	const indented = "{\n  \"a\": 1\n}\n"
+	tests := []struct {
+		name   string
+		target string
+		always bool
+		want   string
+	}{
+		{"compact by default", "/api/status", false, compact},
+		{"pretty=1", "/api/status?pretty=1", false, indented},
+		{"pretty=true", "/api/status?pretty=true", false, indented},
+		{"pretty=0", "/api/status?pretty=0", false, compact},
+		{"PRETTY_JSON", "/api/status", true, indented},
+	}
+	prev := prettyJSONAlways
+	t.Cleanup(func() { SetPrettyJSON(prev) })
+	handler := PrettyJSONMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		newJSONEncoder(w).Encode(map[string]int{"a": 1})
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPrettyJSON(tt.always)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyResponseWriterFlushes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := http.NewResponseController(prettyResponseWriter{rec}).Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if !rec.Flushed {
+		t.Error("underlying writer was not flushed")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// screenshotRetentionInterval is how often expired screenshots are purged
+const screenshotRetentionInterval = time.Hour
+
+// RunScreenshotRetention purges expired screenshots until ctx is cancelled.
+// Successful jobs' screenshots are kept for successDays, failed jobs' for failureDays.
+func RunScreenshotRetention(ctx context.Context, successDays, failureDays int) {
+	if successDays <= 0 && failureDays <= 0 {
+		slog.Info("Screenshot retention disabled")
+		return
+	}
+	slog.Info("Screenshot retention enabled", "success_days", successDays, "failure_days", failureDays)
+
+	ticker := time.NewTicker(screenshotRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := PurgeExpiredScreenshots(successDays, failureDays); err != nil {
+			slog.Error("Screenshot retention failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Screenshot retention: purged screenshots", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpiredScreenshots deletes expired screenshot records and their files
+// (including cached thumbnails) and returns how many were purged
+func PurgeExpiredScreenshots(successDays, failureDays int) (int, error) {
+	expired, err := DeleteExpiredScreenshots(successDays, failureDays)
+	if err != nil {
+		return 0, err
+	}
+
+	jobDirs := make(map[string]bool)
+	for _, s := range expired {
+		jobDir := filepath.Join(screenshotsPath, fmt.Sprintf("%d", s.UserID), s.JobID)
+		jobDirs[jobDir] = true
+
+		path := filepath.Join(jobDir, filepath.Base(s.Filename))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			slog.Error("Failed to remove screenshot", "path", path, "error", err)
+		}
+
+		base := strings.TrimSuffix(filepath.Base(s.Filename), filepath.Ext(s.Filename))
+		thumbs, _ := filepath.Glob(filepath.Join(jobDir, base+".thumb*.png"))
+		for _, thumb := range thumbs {
+			os.Remove(thumb)
+		}
+	}
+
+	// Remove job directories that are now empty
+	for dir := range jobDirs {
+		os.Remove(dir) // fails harmlessly if not empty
+	}
+
+	return len(expired), nil
+}
+
+// jobRetentionInterval is how often old jobs are purged
+const jobRetentionInterval = time.Hour
+
+// RunJobRetention deletes finished jobs older than days, with their
+// screenshot files, until ctx is cancelled
+func RunJobRetention(ctx context.Context, days int) {
+	if days <= 0 {
+		slog.Info("Job retention disabled")
+		return
+	}
+	slog.Info("Job retention enabled", "days", days)
+
+	ticker := time.NewTicker(jobRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := PurgeOldJobs(time.Duration(days) * 24 * time.Hour); err != nil {
+			slog.Error("Job retention failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Job retention: purged jobs", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOldJobs deletes finished jobs older than age and their screenshot
+// directories, returning how many jobs were purged. Jobs with evidence
+// screenshots are never purged, so their directories stay.
+func PurgeOldJobs(age time.Duration) (int, error) {
+	deleted, err := DeleteJobsOlderThan(age)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range deleted {
+		dir := filepath.Join(screenshotsPath, fmt.Sprintf("%d", job.UserID), filepath.Base(job.ID))
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Error("Failed to remove screenshots of job", "job_id", job.ID, "user_id", job.UserID, "error", err)
+		}
+	}
+	return len(deleted), nil
+}
+
+// RunRefreshTokenCleanup deletes expired refresh tokens every interval until
+// ctx is cancelled
+func RunRefreshTokenCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := CleanExpiredRefreshTokens(); err != nil {
+			slog.Error("Refresh token cleanup failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Refresh token cleanup: deleted expired tokens", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// createAgedScreenshot records a screenshot file for job created daysOld days ago
+func createAgedScreenshot(t *testing.T, job *Job, filename string, daysOld int) string {
+	t.Helper()
+	dir := filepath.Join(screenshotsPath, fmt.Sprintf("%d", job.UserID), job.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, filename)
+	if err := os.WriteFile(path, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateScreenshot(job.ID, job.UserID, filename); err != nil {
+		t.Fatalf("CreateScreenshot: %v", err)
+	}
+	if _, err := db.Exec(
+		"UPDATE screenshots SET created_at = NOW() - make_interval(days => $1) WHERE job_id = $2 AND filename = $3",
+		daysOld, job.ID, filename,
+	); err != nil {
+		t.Fatalf("backdate screenshot: %v", err)
+	}
+	return path
+}
+
+func TestPurgeExpiredScreenshots(t *testing.T) {
+	user := createTestUser(t)
+	prevPath := screenshotsPath
+	SetScreenshotsPath(t.TempDir())
+	t.Cleanup(func() { SetScreenshotsPath(prevPath) })
+
+	succeeded := createTestJob(t, user.ID, "full")
+	failed := createTestJob(t, user.ID, "full")
+	if err := UpdateJobStatus(succeeded.ID, "completed", nil); err != nil {
+		t.Fatal(err)
+	}
+	errMsg := "login_failed"
+	if err := UpdateJobStatus(failed.ID, "failed", &errMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		job       *Job
+		filename  string
+		daysOld   int
+		wantPurge bool
+	}{
+		{"old success", succeeded, "01_login.png", 10, true},
+		{"recent success", succeeded, "02_form.png", 3, false},
+		{"failure past success retention", failed, "01_login.png", 10, false},
+		{"old failure", failed, "02_error_final.png", 100, true},
+		{"old evidence", succeeded, evidenceScreenshotPrefix + "submitted.png", 400, false},
+	}
+	paths := make([]string, len(tests))
+	for i, tt := range tests {
+		paths[i] = createAgedScreenshot(t, tt.job, tt.filename, tt.daysOld)
+	}
+	thumb := filepath.Join(filepath.Dir(paths[0]), thumbnailFilename("01_login.png", 320))
+	if err := os.WriteFile(thumb, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := PurgeExpiredScreenshots(7, 90)
+	if err != nil {
+		t.Fatalf("PurgeExpiredScreenshots: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("purged %d screenshots, want 2", n)
+	}
+	for i, tt := range tests {
+		_, statErr := os.Stat(paths[i])
+		if purged := os.IsNotExist(statErr); purged != tt.wantPurge {
+			t.Errorf("%s: file purged = %v, want %v", tt.name, purged, tt.wantPurge)
+		}
+	}
+	if _, err := os.Stat(thumb); !os.IsNotExist(err) {
+		t.Error("thumbnail of a purged screenshot was kept")
+	}
+}
+
+func TestPurgeExpiredScreenshotsZeroKeepsForever(t *testing.T) {
+	user := createTestUser(t)
+	prevPath := screenshotsPath
+	SetScreenshotsPath(t.TempDir())
+	t.Cleanup(func() { SetScreenshotsPath(prevPath) })
+
+	job := createTestJob(t, user.ID, "full")
+	if err := UpdateJobStatus(job.ID, "completed", nil); err != nil {
+		t.Fatal(err)
+	}
+	path := createAgedScreenshot(t, job, "01_login.png", 1000)
+
+	if _, err := PurgeExpiredScreenshots(0, 90); err != nil {
+		t.Fatalf("PurgeExpiredScreenshots: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("screenshot removed with success retention 0: %v", err)
+	}
+}
+
+func TestCleanExpiredRefreshTokens(t *testing.T) {
+	user := createTestUser(t)
+	if _, err := CleanExpiredRefreshTokens(); err != nil { // leftovers of earlier runs
+		t.Fatalf("CleanExpiredRefreshTokens: %v", err)
+	}
+
+	now := time.Now()
+	tokens := []struct {
+		hash      string
+		expiresAt time.Time
+		rotated   bool
+		wantKept  bool
+	}{
+		{uuid.NewString(), now.Add(-time.Hour), false, false},
+		{uuid.NewString(), now.Add(time.Hour), false, true},
+		{uuid.NewString(), now.Add(-time.Hour), true, false},
+		{uuid.NewString(), now.Add(-time.Minute), true, false},
+		{uuid.NewString(), now.Add(time.Hour), true, true},
+	}
+	for _, tok := range tokens {
+		var err error
+		if tok.rotated {
+			err = RecordRotatedRefreshToken(user.ID, tok.hash, tok.expiresAt)
+		} else {
+			err = SaveRefreshToken(user.ID, tok.hash, tok.expiresAt, "test", "127.0.0.1")
+		}
+		if err != nil {
+			t.Fatalf("store token: %v", err)
+		}
+	}
+
+	n, err := CleanExpiredRefreshTokens()
+	if err != nil {
+		t.Fatalf("CleanExpiredRefreshTokens: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("deleted %d tokens, want 3", n)
+	}
+	for _, tok := range tokens {
+		var kept bool
+		if tok.rotated {
+			_, kept, err = GetRotatedRefreshToken(tok.hash)
+		} else {
+			_, _, err = GetRefreshToken(tok.hash)
+			kept = err == nil
+			err = nil
+		}
+		if err != nil {
+			t.Fatalf("look up token: %v", err)
+		}
+		if kept != tok.wantKept {
+			t.Errorf("token expiring %v (rotated %v) kept = %v, want %v", tok.expiresAt, tok.rotated, kept, tok.wantKept)
+		}
+	}
+}
+
+func TestRunRefreshTokenCleanupRunsOnStart(t *testing.T) {
+	user := createTestUser(t)
+	hash := uuid.NewString()
+	if err := SaveRefreshToken(user.ID, hash, time.Now().Add(-time.Hour), "test", "127.0.0.1"); err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+
+	// A cancelled context still gets the first pass, then returns
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	RunRefreshTokenCleanup(ctx, time.Hour)
+
+	if _, _, err := GetRefreshToken(hash); err == nil {
+		t.Error("expired refresh token survived the cleanup")
+	}
+}
+
+func TestPurgeOldJobs(t *testing.T) {
+	user := createTestUser(t)
+	prevPath := screenshotsPath
+	SetScreenshotsPath(t.TempDir())
+	t.Cleanup(func() { SetScreenshotsPath(prevPath) })
+
+	tests := []struct {
+		name      string
+		status    string
+		daysOld   int
+		evidence  bool
+		wantPurge bool
+	}{
+		{"old finished job", "completed", 100, false, true},
+		{"recent finished job", "completed", 10, false, false},
+		{"old pending job", "pending", 100, false, false},
+		{"old running job", "running", 100, false, false},
+		{"old job with evidence", "completed", 100, true, false},
+	}
+	jobs := make([]*Job, len(tests))
+	for i, tt := range tests {
+		job := createTestJob(t, user.ID, "full")
+		if tt.status != "pending" {
+			if err := UpdateJobStatus(job.ID, tt.status, nil); err != nil {
+				t.Fatalf("UpdateJobStatus: %v", err)
+			}
+		}
+		filename := "01_login.png"
+		if tt.evidence {
+			filename = evidenceScreenshotPrefix + "submitted.png"
+		}
+		createAgedScreenshot(t, job, filename, tt.daysOld)
+		if _, err := db.Exec("UPDATE jobs SET created_at = NOW() - make_interval(days => $1) WHERE id = $2", tt.daysOld, job.ID); err != nil {
+			t.Fatalf("backdate job: %v", err)
+		}
+		jobs[i] = job
+	}
+
+	n, err := PurgeOldJobs(30 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeOldJobs: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("purged %d jobs, want 1", n)
+	}
+	for i, tt := range tests {
+		got, err := GetJob(context.Background(), jobs[i].ID)
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if purged := got == nil; purged != tt.wantPurge {
+			t.Errorf("%s: job purged = %v, want %v", tt.name, purged, tt.wantPurge)
+		}
+		_, statErr := os.Stat(filepath.Join(screenshotsPath, fmt.Sprintf("%d", user.ID), jobs[i].ID))
+		if removed := os.IsNotExist(statErr); removed != tt.wantPurge {
+			t.Errorf("%s: screenshot dir removed = %v, want %v", tt.name, removed, tt.wantPurge)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SecretProvider looks up secrets such as JWT_SECRET or DATABASE_URL by name.
+// A missing secret is reported as "" with a nil error so optional secrets keep
+// their defaults.
+type SecretProvider interface {
+	GetSecret(name string) (string, error)
+}
+
+// envSecretProvider reads secrets from environment variables (the default)
+type envSecretProvider struct{}
+
+func (envSecretProvider) GetSecret(name string) (string, error) {
+	return os.Getenv(name), nil
+}
+
+// fileSecretProvider reads each secret from a file named after it, as mounted
+// by Docker/Kubernetes secrets or a Vault agent sidecar
+type fileSecretProvider struct {
+	dir string
+}
+
+func (p fileSecretProvider) GetSecret(name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// secretProviders maps SECRET_PROVIDER values to constructors
+var secretProviders = map[string]func() (SecretProvider, error){
+	"env": func() (SecretProvider, error) {
+		return envSecretProvider{}, nil
+	},
+	"file": func() (SecretProvider, error) {
+		return fileSecretProvider{dir: getEnvOrDefault("SECRETS_DIR", "/run/secrets")}, nil
+	},
+}
+
+// RegisterSecretProvider makes a secret provider selectable via SECRET_PROVIDER,
+// e.g. a Vault or AWS Secrets Manager client
+func RegisterSecretProvider(name string, newProvider func() (SecretProvider, error)) {
+	secretProviders[name] = newProvider
+}
+
+// NewSecretProvider returns the provider selected by name ("" means env)
+func NewSecretProvider(name string) (SecretProvider, error) {
+	if name == "" {
+		name = "env"
+	}
+	newProvider, ok := secretProviders[name]
+	if !ok {
+		names := make([]string, 0, len(secretProviders))
+		for n := range secretProviders {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown secret provider %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return newProvider()
+}
+
+// loadSecrets fills each destination from the provider. Secrets the provider
+// doesn't have fall back to the environment.
+func loadSecrets(provider SecretProvider, secrets map[string]*string) error {
+	for name, dst := range secrets {
+		value, err := provider.GetSecret(name)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			value = os.Getenv(name)
+		}
+		*dst = value
+	}
+	return nil
+}
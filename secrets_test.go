@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSecretProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "JWT_SECRET"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "DATABASE_URL"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"JWT_SECRET", "from-file", false},
+		{"ENCRYPTION_KEY", "", false}, // missing secrets are not errors
+		{"DATABASE_URL", "", true},    // unreadable secrets are
+	}
+	p := fileSecretProvider{dir: dir}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.GetSecret(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetSecret(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetSecret(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSecretProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    SecretProvider
+		wantErr string
+	}{
+		{"", envSecretProvider{}, ""},
+		{"env", envSecretProvider{}, ""},
+		{"file", fileSecretProvider{dir: "/run/secrets"}, ""},
+		{"vault", nil, `unknown secret provider "vault" (available: env, file)`},
+	}
+	t.Setenv("SECRETS_DIR", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSecretProvider(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("NewSecretProvider(%q) error = %v, want %q", tt.name, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSecretProvider(%q): %v", tt.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("NewSecretProvider(%q) = %#v, want %#v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// mapSecretProvider serves secrets from memory, like a registered Vault client would
+type mapSecretProvider map[string]string
+
+func (p mapSecretProvider) GetSecret(name string) (string, error) {
+	if name == "BROKEN" {
+		return "", errors.New("vault sealed")
+	}
+	return p[name], nil
+}
+
+func TestRegisterSecretProvider(t *testing.T) {
+	t.Cleanup(func() { delete(secretProviders, "memory") })
+	RegisterSecretProvider("memory", func() (SecretProvider, error) {
+		return mapSecretProvider{"JWT_SECRET": "from-memory"}, nil
+	})
+	p, err := NewSecretProvider("memory")
+	if err != nil {
+		t.Fatalf("NewSecretProvider: %v", err)
+	}
+	if got, _ := p.GetSecret("JWT_SECRET"); got != "from-memory" {
+		t.Errorf("GetSecret = %q, want from-memory", got)
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://from-env")
+	t.Setenv("JWT_SECRET", "env-secret")
+
+	var jwtSecret, dbURL string
+	provider := mapSecretProvider{"JWT_SECRET": "provider-secret"}
+	if err := loadSecrets(provider, map[string]*string{"JWT_SECRET": &jwtSecret, "DATABASE_URL": &dbURL}); err != nil {
+		t.Fatalf("loadSecrets: %v", err)
+	}
+	if jwtSecret != "provider-secret" {
+		t.Errorf("JWT_SECRET = %q, want the provider's value", jwtSecret)
+	}
+	if dbURL != "postgres://from-env" {
+		t.Errorf("DATABASE_URL = %q, want the environment fallback", dbURL)
+	}
+
+	var broken string
+	err := loadSecrets(provider, map[string]*string{"BROKEN": &broken})
+	if err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("loadSecrets() error = %v, want the provider error", err)
+	}
+}
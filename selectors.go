@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Selectors holds the CSS selectors used to find elements on the Gasolina site.
+// Empty fields fall back to the defaults, so an override only needs the
+// selectors that changed.
+type Selectors struct {
+	// NavbarToggle opens the collapsed navigation (hamburger menu)
+	NavbarToggle string `json:"navbar_toggle,omitempty"`
+	// AccountDropdown opens the account list
+	AccountDropdown string `json:"account_dropdown,omitempty"`
+	// AccountItem matches each account in the opened list
+	AccountItem string `json:"account_item,omitempty"`
+	// ModalButton is the "Ввести" button that opens the reading form
+	ModalButton string `json:"modal_button,omitempty"`
+	// Modal is the reading form dialog
+	Modal string `json:"modal,omitempty"`
+	// ValueInput is the reading input inside the modal
+	ValueInput string `json:"value_input,omitempty"`
+	// YearFilter is the year <select> on the indicator page
+	YearFilter string `json:"year_filter,omitempty"`
+	// IndicatorTable is the table of submitted readings on the indicator page
+	IndicatorTable string `json:"indicator_table,omitempty"`
+}
+
+// builtinSelectors match the site layout as of this release
+var builtinSelectors = Selectors{
+	NavbarToggle:    `.navbar-toggler`,
+	AccountDropdown: `#dropdown01`,
+	AccountItem:     `.dropdown-menu a.dropdown-item`,
+	ModalButton:     `button[data-toggle="modal"][data-target="#counterModal"]`,
+	Modal:           `#counterModal`,
+	ValueInput:      `#value`,
+	YearFilter:      `[id="filter[year]"]`,
+	IndicatorTable:  `table.table`,
+}
+
+// defaultSelectors are the builtin selectors with the server-wide overrides applied
+var defaultSelectors = builtinSelectors
+
+// SetDefaultSelectors overrides the builtin selectors for every job; empty
+// fields keep the builtin value
+func SetDefaultSelectors(s Selectors) {
+	defaultSelectors = s.merge(builtinSelectors)
+}
+
+// LoadSelectorsFile reads selector overrides from a JSON file
+func LoadSelectorsFile(path string) (Selectors, error) {
+	var s Selectors
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("failed to read selectors file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid selectors file %s: %w", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return s, fmt.Errorf("invalid selectors file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// merge fills the empty fields of s from fallback
+func (s Selectors) merge(fallback Selectors) Selectors {
+	pick := func(v, d string) string {
+		if strings.TrimSpace(v) == "" {
+			return d
+		}
+		return v
+	}
+	return Selectors{
+		NavbarToggle:    pick(s.NavbarToggle, fallback.NavbarToggle),
+		AccountDropdown: pick(s.AccountDropdown, fallback.AccountDropdown),
+		AccountItem:     pick(s.AccountItem, fallback.AccountItem),
+		ModalButton:     pick(s.ModalButton, fallback.ModalButton),
+		Modal:           pick(s.Modal, fallback.Modal),
+		ValueInput:      pick(s.ValueInput, fallback.ValueInput),
+		YearFilter:      pick(s.YearFilter, fallback.YearFilter),
+		IndicatorTable:  pick(s.IndicatorTable, fallback.IndicatorTable),
+	}
+}
+
+// withDefaults returns s with empty fields taken from the default selectors
+func (s Selectors) withDefaults() Selectors {
+	return s.merge(defaultSelectors)
+}
+
+// IsZero reports whether no selector is overridden
+func (s Selectors) IsZero() bool {
+	return s == Selectors{}
+}
+
+// Validate rejects selectors that can't be valid CSS. The browser is the
+// real judge; this only catches obvious mistakes before they reach a job.
+func (s Selectors) Validate() error {
+	for name, v := range map[string]string{
+		"navbar_toggle":    s.NavbarToggle,
+		"account_dropdown": s.AccountDropdown,
+		"account_item":     s.AccountItem,
+		"modal_button":     s.ModalButton,
+		"modal":            s.Modal,
+		"value_input":      s.ValueInput,
+		"year_filter":      s.YearFilter,
+		"indicator_table":  s.IndicatorTable,
+	} {
+		if len(v) > 500 {
+			return fmt.Errorf("%s is too long", name)
+		}
+		if strings.ContainsAny(v, "\n\r") {
+			return fmt.Errorf("%s must be a single line", name)
+		}
+		if strings.Count(v, "[") != strings.Count(v, "]") || strings.Count(v, "(") != strings.Count(v, ")") {
+			return fmt.Errorf("%s has unbalanced brackets: %q", name, v)
+		}
+	}
+	return nil
+}
+
+// indicatorPageSelectors must exist on the indicator page whatever the
+// account's state; if any disappear the site layout has likely changed
+func (s Selectors) indicatorPageSelectors() []string {
+	return []string{s.YearFilter, s.IndicatorTable}
+}
+
+// selectors returns the config's selectors with defaults filled in
+func (c *Config) selectors() Selectors {
+	return c.Selectors.withDefaults()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setDefaultSelectorsForTest applies server-wide overrides and restores them afterwards
+func setDefaultSelectorsForTest(t *testing.T, s Selectors) {
+	t.Helper()
+	prev := defaultSelectors
+	SetDefaultSelectors(s)
+	t.Cleanup(func() { defaultSelectors = prev })
+}
+
+func TestConfigSelectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    Selectors
+		user      Selectors
+		wantModal string
+		wantInput string
+	}{
+		{"builtin", Selectors{}, Selectors{}, builtinSelectors.Modal, builtinSelectors.ValueInput},
+		{"server override", Selectors{Modal: "#readingModal"}, Selectors{}, "#readingModal", builtinSelectors.ValueInput},
+		{"user beats server", Selectors{Modal: "#readingModal"}, Selectors{Modal: "#userModal"}, "#userModal", builtinSelectors.ValueInput},
+		{"blank user value is ignored", Selectors{}, Selectors{ValueInput: "  "}, builtinSelectors.Modal, builtinSelectors.ValueInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDefaultSelectorsForTest(t, tt.server)
+			got := (&Config{Selectors: tt.user}).selectors()
+			if got.Modal != tt.wantModal || got.ValueInput != tt.wantInput {
+				t.Errorf("selectors() modal %q input %q, want %q %q", got.Modal, got.ValueInput, tt.wantModal, tt.wantInput)
+			}
+			if got.AccountDropdown != builtinSelectors.AccountDropdown || got.IndicatorTable != builtinSelectors.IndicatorTable {
+				t.Errorf("selectors() = %+v, untouched fields lost their builtin values", got)
+			}
+		})
+	}
+}
+
+func TestSelectorsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       Selectors
+		wantErr bool
+	}{
+		{"empty", Selectors{}, false},
+		{"builtin", builtinSelectors, false},
+		{"attribute selector", Selectors{YearFilter: `select[name="filter[year]"]`}, false},
+		{"pseudo-class", Selectors{AccountItem: `.dropdown-menu a:not(.disabled)`}, false},
+		{"unbalanced bracket", Selectors{Modal: `#counterModal[`}, true},
+		{"unbalanced paren", Selectors{AccountItem: `a:not(.disabled`}, true},
+		{"multi-line", Selectors{ValueInput: "#value\n#other"}, true},
+		{"too long", Selectors{IndicatorTable: strings.Repeat("a", 501)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.s.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSelectorsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Selectors
+		wantErr bool
+	}{
+		{"partial override", `{"modal": "#readingModal"}`, Selectors{Modal: "#readingModal"}, false},
+		{"invalid JSON", `{"modal": `, Selectors{}, true},
+		{"invalid selector", `{"modal": "#counterModal["}`, Selectors{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "selectors.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadSelectorsFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSelectorsFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("LoadSelectorsFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadSelectorsFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadSelectorsFile() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// midJobRelogin re-authenticates when the site drops the session between
+// navigations of a job
+var midJobRelogin = true
+
+// SetMidJobRelogin enables or disables re-login when the session drops mid-job
+func SetMidJobRelogin(enabled bool) {
+	midJobRelogin = enabled
+}
+
+// ErrSessionLost is returned when the site logged us out mid-job and re-login
+// is disabled or failed
+var ErrSessionLost = errors.New("session_lost")
+
+// isLoggedOut reports whether the current page shows the login form, which
+// the site serves in place of any page once the session is gone
+func isLoggedOut(ctx context.Context) (bool, error) {
+	emails, _ := json.Marshal(loginEmailSelectors)
+	passwords, _ := json.Marshal(loginPasswordSelectors)
+	containers, _ := json.Marshal(loginContainerSelectors)
+
+	var state struct {
+		Password string `json:"password"`
+	}
+	err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(loginPageStateJS, emails, passwords, containers), &state))
+	if err != nil {
+		return false, fmt.Errorf("failed to check login state: %w", err)
+	}
+	return state.Password != "", nil
+}
+
+// ensureSession checks that we're still logged in on the page at returnURL and,
+// if the session dropped, logs in again with the job's credentials and goes
+// back to returnURL
+func ensureSession(ctx context.Context, config *Config, returnURL string, logger Logger, saveScreenshot func(string)) error {
+	loggedOut, err := isLoggedOut(ctx)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Warning: %v", err))
+		return nil
+	}
+	if !loggedOut {
+		return nil
+	}
+
+	saveScreenshot("session_lost")
+	if !midJobRelogin {
+		return fmt.Errorf("%w: the site logged us out mid-job (GASOLINA_RELOGIN_MID_JOB=false)", ErrSessionLost)
+	}
+
+	logger.Log("Session dropped mid-job - logging in again")
+	if err := GasolinaLogin(ctx, config, logger, saveScreenshot); err != nil {
+		return fmt.Errorf("%w: re-login failed: %w", ErrSessionLost, err)
+	}
+	if err := chromedp.Run(ctx,
+		navigateAndSettle(returnURL, 2*time.Second),
+		chromedp.WaitReady("body"),
+	); err != nil {
+		return fmt.Errorf("failed to return to %s after re-login: %w", returnURL, err)
+	}
+
+	if loggedOut, err := isLoggedOut(ctx); err == nil && loggedOut {
+		return fmt.Errorf("%w: still logged out after re-login", ErrSessionLost)
+	}
+	logger.Log("Re-login succeeded, continuing the job")
+	return nil
+}
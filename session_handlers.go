@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSessionUserAgentLen caps the stored User-Agent; clients can send anything
+const maxSessionUserAgentLen = 512
+
+// sessionUserAgent returns the request's User-Agent, cut to a storable length
+func sessionUserAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) <= maxSessionUserAgentLen {
+		return ua
+	}
+	ua = ua[:maxSessionUserAgentLen]
+	for !utf8.ValidString(ua) {
+		ua = ua[:len(ua)-1]
+	}
+	return ua
+}
+
+// handleListSessions returns the user's active sessions (unexpired refresh tokens)
+func handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := ListUserSessions(userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get sessions", "user_id", userID, "error", err)
+		jsonError(w, "Failed to get sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]interface{}{"sessions": sessions})
+}
+
+// handleRevokeSession handles DELETE /api/me/sessions/{id}, signing that
+// session out once its access token expires
+func handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/me/sessions/"), 10, 64)
+	if err != nil || sessionID <= 0 {
+		jsonError(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	found, err := DeleteUserSession(userID, sessionID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to revoke session", "user_id", userID, "session_id", sessionID, "error", err)
+		jsonError(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		jsonError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(map[string]string{"message": "Session revoked"})
+}
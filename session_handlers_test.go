@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSessionUserAgent(t *testing.T) {
+	long := strings.Repeat("a", maxSessionUserAgentLen+10)
+	straddling := strings.Repeat("a", maxSessionUserAgentLen-1) + "é"
+	tests := []struct {
+		name string
+		ua   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"short", "Mozilla/5.0", "Mozilla/5.0"},
+		{"at the limit", long[:maxSessionUserAgentLen], long[:maxSessionUserAgentLen]},
+		{"too long", long, long[:maxSessionUserAgentLen]},
+		{"cut inside a rune", straddling, straddling[:maxSessionUserAgentLen-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+			r.Header.Set("User-Agent", tt.ua)
+			if got := sessionUserAgent(r); got != tt.want {
+				t.Errorf("sessionUserAgent() = %q (%d bytes), want %d bytes", got, len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSessionHandlersRejectRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		userID  int64
+		want    int
+	}{
+		{"list with POST", handleListSessions, http.MethodPost, "/api/me/sessions", 1, http.StatusMethodNotAllowed},
+		{"list without user", handleListSessions, http.MethodGet, "/api/me/sessions", 0, http.StatusUnauthorized},
+		{"revoke with GET", handleRevokeSession, http.MethodGet, "/api/me/sessions/1", 1, http.StatusMethodNotAllowed},
+		{"revoke without user", handleRevokeSession, http.MethodDelete, "/api/me/sessions/1", 0, http.StatusUnauthorized},
+		{"revoke without ID", handleRevokeSession, http.MethodDelete, "/api/me/sessions/", 1, http.StatusBadRequest},
+		{"revoke non-numeric ID", handleRevokeSession, http.MethodDelete, "/api/me/sessions/abc", 1, http.StatusBadRequest},
+		{"revoke zero ID", handleRevokeSession, http.MethodDelete, "/api/me/sessions/0", 1, http.StatusBadRequest},
+		{"revoke negative ID", handleRevokeSession, http.MethodDelete, "/api/me/sessions/-3", 1, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, newAuthedRequest(tt.method, tt.target, "", tt.userID))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+// listSessions returns the user's sessions through handleListSessions
+func listSessions(t *testing.T, userID int64) []*Session {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleListSessions(rec, newAuthedRequest(http.MethodGet, "/api/me/sessions", "", userID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list sessions status = %d: %s", rec.Code, errorMessage(t, rec))
+	}
+	var body struct {
+		Sessions []*Session `json:"sessions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return body.Sessions
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	user := createTestUser(t)
+	other := createTestUser(t)
+	for _, ua := range []string{"laptop", "phone"} {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		r.Header.Set("User-Agent", ua)
+		if _, err := generateRefreshToken(r, user.ID); err != nil {
+			t.Fatalf("generateRefreshToken: %v", err)
+		}
+	}
+
+	sessions := listSessions(t, user.ID)
+	if len(sessions) != 2 {
+		t.Fatalf("sessions = %d, want 2", len(sessions))
+	}
+	for _, s := range sessions {
+		if s.UserAgent != "laptop" && s.UserAgent != "phone" {
+			t.Errorf("session user agent = %q", s.UserAgent)
+		}
+		if s.IPAddress == "" || s.LastUsedAt == nil {
+			t.Errorf("session %d missing client metadata: %+v", s.ID, s)
+		}
+	}
+	if n := len(listSessions(t, other.ID)); n != 0 {
+		t.Errorf("other user's sessions = %d, want 0", n)
+	}
+
+	target := fmt.Sprintf("/api/me/sessions/%d", sessions[0].ID)
+	tests := []struct {
+		name   string
+		userID int64
+		want   int
+	}{
+		{"another user's session", other.ID, http.StatusNotFound},
+		{"own session", user.ID, http.StatusOK},
+		{"already revoked", user.ID, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleRevokeSession(rec, newAuthedRequest(http.MethodDelete, target, "", tt.userID))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	if remaining := listSessions(t, user.ID); len(remaining) != 1 || remaining[0].ID != sessions[1].ID {
+		t.Errorf("remaining sessions = %v, want only %d", remaining, sessions[1].ID)
+	}
+}
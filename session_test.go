@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEnsureSessionUnknownStateDoesNotFailTheJob(t *testing.T) {
+	prev := midJobRelogin
+	t.Cleanup(func() { SetMidJobRelogin(prev) })
+
+	for _, relogin := range []bool{true, false} {
+		SetMidJobRelogin(relogin)
+		logger := &testLogger{}
+		var screenshots []string
+		// Without a browser the login state can't be read; the job carries on
+		err := ensureSession(context.Background(), &Config{}, defaultBaseURL, logger,
+			func(name string) { screenshots = append(screenshots, name) })
+		if err != nil {
+			t.Errorf("relogin=%v: ensureSession() = %v, want nil", relogin, err)
+		}
+		if len(screenshots) != 0 {
+			t.Errorf("relogin=%v: screenshots = %v, want none", relogin, screenshots)
+		}
+		if !strings.Contains(strings.Join(logger.lines, "\n"), "failed to check login state") {
+			t.Errorf("relogin=%v: log %q has no warning", relogin, logger.lines)
+		}
+	}
+}
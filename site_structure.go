@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/chromedp/chromedp"
+)
+
+// structureCheckJS returns the selectors from the list that match nothing
+const structureCheckJS = `(%s).filter(s => !document.querySelector(s))`
+
+// verifyPageStructure reports which of the expected selectors are missing on the current page
+func verifyPageStructure(ctx context.Context, selectors []string) ([]string, error) {
+	list, err := json.Marshal(selectors)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(structureCheckJS, list), &missing)); err != nil {
+		return nil, fmt.Errorf("failed to verify page structure: %w", err)
+	}
+	return missing, nil
+}
+
+// structureReporter is implemented by loggers that track page structure checks
+type structureReporter interface {
+	Structure(missing []string)
+}
+
+// reportStructure hands a structure check result to the logger if it supports it
+func reportStructure(logger Logger, missing []string) {
+	if sr, ok := logger.(structureReporter); ok {
+		sr.Structure(missing)
+	}
+}
+
+// scrapingBrokenThreshold is how many consecutive jobs must fail the structure
+// check before a user's scraping is flagged as broken
+var scrapingBrokenThreshold = 2
+
+// SetScrapingBrokenThreshold sets the consecutive failures that flag scraping as broken
+func SetScrapingBrokenThreshold(n int) {
+	if n < 1 {
+		n = 1
+	}
+	scrapingBrokenThreshold = n
+}
+
+// recordStructureCheck stores a job's structure check and logs when the
+// user's scraping state flips
+func recordStructureCheck(userID int64, missing []string) {
+	health, wasBroken, err := RecordStructureCheck(userID, missing, scrapingBrokenThreshold)
+	if err != nil {
+		slog.Error("Failed to record structure check", "user_id", userID, "error", err)
+		return
+	}
+	switch {
+	case health.Broken && !wasBroken:
+		slog.Error("ALERT: site structure changed", "user_id", userID,
+			"missing", health.Missing, "consecutive_failures", health.ConsecutiveFailures)
+	case !health.Broken && wasBroken:
+		slog.Info("Site structure check recovered", "user_id", userID)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSetScrapingBrokenThreshold(t *testing.T) {
+	prev := scrapingBrokenThreshold
+	t.Cleanup(func() { scrapingBrokenThreshold = prev })
+
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{3, 3},
+		{1, 1},
+		{0, 1},
+		{-2, 1},
+	}
+	for _, tt := range tests {
+		SetScrapingBrokenThreshold(tt.n)
+		if scrapingBrokenThreshold != tt.want {
+			t.Errorf("SetScrapingBrokenThreshold(%d) = %d, want %d", tt.n, scrapingBrokenThreshold, tt.want)
+		}
+	}
+}
+
+func TestJobLoggerStructure(t *testing.T) {
+	tests := []struct {
+		name        string
+		report      bool
+		missing     []string
+		wantMissing []string
+		wantChecked bool
+	}{
+		{"no check ran", false, nil, nil, false},
+		{"passing check", true, nil, []string{}, true},
+		{"missing elements", true, []string{"#counter-table"}, []string{"#counter-table"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jl := NewJobLogger("job", 1)
+			if tt.report {
+				reportStructure(jl, tt.missing)
+			}
+			missing, checked := jl.structureCheck()
+			if checked != tt.wantChecked || !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("structureCheck() = %v, %v, want %v, %v", missing, checked, tt.wantMissing, tt.wantChecked)
+			}
+		})
+	}
+}
+
+func TestVerifyPageStructureWithoutBrowser(t *testing.T) {
+	// A context without a browser makes chromedp fail before evaluating anything
+	if _, err := verifyPageStructure(context.Background(), []string{"#counter-table"}); err == nil {
+		t.Error("verifyPageStructure() error = nil, want an error without a browser")
+	}
+}
+
+func TestRecordStructureCheck(t *testing.T) {
+	user := createTestUser(t)
+	missing := []string{"#counter-table", "#filter\\[year\\]"}
+
+	steps := []struct {
+		name          string
+		missing       []string
+		wantFailures  int
+		wantBroken    bool
+		wantWasBroken bool
+	}{
+		{"first failure", missing, 1, false, false},
+		{"threshold reached", missing, 2, true, false},
+		{"still broken", missing, 3, true, true},
+		{"recovered", nil, 0, false, true},
+		{"failing again", missing, 1, false, false},
+	}
+	for _, step := range steps {
+		health, wasBroken, err := RecordStructureCheck(user.ID, step.missing, 2)
+		if err != nil {
+			t.Fatalf("%s: RecordStructureCheck: %v", step.name, err)
+		}
+		if health.ConsecutiveFailures != step.wantFailures || health.Broken != step.wantBroken || wasBroken != step.wantWasBroken {
+			t.Errorf("%s: failures=%d broken=%v wasBroken=%v, want %d %v %v", step.name,
+				health.ConsecutiveFailures, health.Broken, wasBroken, step.wantFailures, step.wantBroken, step.wantWasBroken)
+		}
+	}
+
+	stored, err := GetScrapingHealth(user.ID)
+	if err != nil {
+		t.Fatalf("GetScrapingHealth: %v", err)
+	}
+	if !reflect.DeepEqual(stored.Missing, missing) || stored.Broken {
+		t.Errorf("stored health = %+v, want missing %v and not broken", stored, missing)
+	}
+}
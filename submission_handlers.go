@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// SubmissionListResponse is the response for listing submissions
+type SubmissionListResponse struct {
+	Submissions []*Submission      `json:"submissions"`
+	Total       int                `json:"total"`
+	Limit       int                `json:"limit"`
+	Offset      int                `json:"offset"`
+	Summary     *SubmissionSummary `json:"summary"`
+}
+
+// handleListSubmissions lists the user's submission history with optional filters
+func handleListSubmissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseSubmissionFilter(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	submissions, total, err := ListSubmissions(userID, filter, limit, offset)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get submissions", "user_id", userID, "error", err)
+		jsonError(w, "Failed to get submissions", http.StatusInternalServerError)
+		return
+	}
+
+	summary, err := GetSubmissionSummary(userID, filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to summarize submissions", "user_id", userID, "error", err)
+		jsonError(w, "Failed to summarize submissions", http.StatusInternalServerError)
+		return
+	}
+
+	if submissions == nil {
+		submissions = []*Submission{}
+	}
+
+	setPaginationHeaders(w, r, limit, offset, total)
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(SubmissionListResponse{
+		Submissions: submissions,
+		Total:       total,
+		Limit:       limit,
+		Offset:      offset,
+		Summary:     summary,
+	})
+}
+
+// parseSubmissionFilter reads and validates the year/month/counter/status query params
+func parseSubmissionFilter(r *http.Request) (SubmissionFilter, error) {
+	q := r.URL.Query()
+	var filter SubmissionFilter
+
+	if v := q.Get("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil || year < 2000 || year > 2100 {
+			return filter, fmt.Errorf("Invalid year")
+		}
+		filter.Year = year
+	}
+
+	if v := q.Get("month"); v != "" {
+		month, err := strconv.Atoi(v)
+		if err != nil || month < 1 || month > 12 {
+			return filter, fmt.Errorf("Invalid month. Must be 1-12")
+		}
+		filter.Month = month
+	}
+
+	filter.CounterSerial = q.Get("counter")
+
+	if v := q.Get("status"); v != "" {
+		switch v {
+		case SubmissionAttempted, SubmissionConfirmed, SubmissionAbandoned:
+			filter.Status = v
+		default:
+			return filter, fmt.Errorf("Invalid status")
+		}
+	}
+
+	return filter, nil
+}
+
+// ConsumptionSeries is one plottable line; values align with ConsumptionResponse.Labels
+// and are null for months without a confirmed submission
+type ConsumptionSeries struct {
+	Counter    string     `json:"counter,omitempty"`
+	Increments []*float64 `json:"increments"`
+	Values     []*float64 `json:"values,omitempty"`
+}
+
+// ConsumptionResponse is chart-ready consumption data
+type ConsumptionResponse struct {
+	Labels []string             `json:"labels"` // "YYYY-MM"
+	Series []*ConsumptionSeries `json:"series"`
+}
+
+// handleConsumptionAnalytics returns monthly consumption series built from confirmed submissions
+func handleConsumptionAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseSubmissionFilter(r)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupBy != "counter" {
+		jsonError(w, "Invalid group_by. Must be 'counter'", http.StatusBadRequest)
+		return
+	}
+
+	points, err := GetConsumptionPoints(userID, filter.Year)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get consumption data", "user_id", userID, "error", err)
+		jsonError(w, "Failed to get consumption data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	newJSONEncoder(w).Encode(buildConsumptionSeries(points, groupBy == "counter"))
+}
+
+// buildConsumptionSeries aligns points on a shared month axis. Without grouping,
+// increments of all counters are summed per month and values are omitted.
+func buildConsumptionSeries(points []ConsumptionPoint, byCounter bool) *ConsumptionResponse {
+	label := func(p ConsumptionPoint) string {
+		return fmt.Sprintf("%04d-%02d", p.Year, p.Month)
+	}
+
+	// Collect the sorted month axis
+	seen := make(map[string]bool)
+	resp := &ConsumptionResponse{Labels: []string{}, Series: []*ConsumptionSeries{}}
+	for _, p := range points {
+		if l := label(p); !seen[l] {
+			seen[l] = true
+			resp.Labels = append(resp.Labels, l)
+		}
+	}
+	sort.Strings(resp.Labels)
+	index := make(map[string]int, len(resp.Labels))
+	for i, l := range resp.Labels {
+		index[l] = i
+	}
+
+	series := make(map[string]*ConsumptionSeries)
+	for _, p := range points {
+		key := ""
+		if byCounter {
+			key = p.CounterSerial
+		}
+		s, ok := series[key]
+		if !ok {
+			s = &ConsumptionSeries{Counter: key, Increments: make([]*float64, len(resp.Labels))}
+			if byCounter {
+				s.Values = make([]*float64, len(resp.Labels))
+			}
+			series[key] = s
+			resp.Series = append(resp.Series, s)
+		}
+
+		i := index[label(p)]
+		if s.Increments[i] == nil {
+			s.Increments[i] = new(float64)
+		}
+		*s.Increments[i] = roundReading(*s.Increments[i] + p.Increment)
+		if byCounter {
+			v := p.SubmittedValue
+			s.Values[i] = &v
+		}
+	}
+
+	sort.Slice(resp.Series, func(i, j int) bool { return resp.Series[i].Counter < resp.Series[j].Counter })
+	return resp
+}
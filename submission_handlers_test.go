@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseSubmissionFilter(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    SubmissionFilter
+		wantErr string
+	}{
+		{"", SubmissionFilter{}, ""},
+		{"year=2026&month=3", SubmissionFilter{Year: 2026, Month: 3}, ""},
+		{"counter=ABC123&status=confirmed", SubmissionFilter{CounterSerial: "ABC123", Status: SubmissionConfirmed}, ""},
+		{"year=1999", SubmissionFilter{}, "Invalid year"},
+		{"year=twenty", SubmissionFilter{}, "Invalid year"},
+		{"month=0", SubmissionFilter{}, "Invalid month. Must be 1-12"},
+		{"month=13", SubmissionFilter{}, "Invalid month. Must be 1-12"},
+		{"status=done", SubmissionFilter{}, "Invalid status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseSubmissionFilter(httptest.NewRequest("GET", "/api/submissions?"+tt.query, nil))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("filter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmissionFilterWhere(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    SubmissionFilter
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{"user only", SubmissionFilter{}, "WHERE user_id = $1", []interface{}{int64(7)}},
+		{"period", SubmissionFilter{Year: 2026, Month: 3}, "WHERE user_id = $1 AND year = $2 AND month = $3",
+			[]interface{}{int64(7), 2026, 3}},
+		{"counter and status", SubmissionFilter{CounterSerial: "C1", Status: SubmissionAttempted},
+			"WHERE user_id = $1 AND counter_serial = $2 AND status = $3", []interface{}{int64(7), "C1", SubmissionAttempted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := tt.filter.where(7)
+			if where != tt.wantWhere || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("where = %q %v, want %q %v", where, args, tt.wantWhere, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBuildConsumptionSeries(t *testing.T) {
+	points := []ConsumptionPoint{
+		{CounterSerial: "B", Year: 2026, Month: 2, SubmittedValue: 220, Increment: 20},
+		{CounterSerial: "A", Year: 2026, Month: 1, SubmittedValue: 110, Increment: 10.5},
+		{CounterSerial: "B", Year: 2026, Month: 1, SubmittedValue: 200, Increment: 15},
+		{CounterSerial: "A", Year: 2025, Month: 12, SubmittedValue: 99.5, Increment: 9},
+	}
+	tests := []struct {
+		name      string
+		points    []ConsumptionPoint
+		byCounter bool
+		want      string
+	}{
+		{"no data", nil, false, `{"labels":[],"series":[]}`},
+		{"summed", points, false,
+			`{"labels":["2025-12","2026-01","2026-02"],"series":[{"increments":[9,25.5,20]}]}`},
+		{"by counter", points, true,
+			`{"labels":["2025-12","2026-01","2026-02"],"series":[` +
+				`{"counter":"A","increments":[9,10.5,null],"values":[99.5,110,null]},` +
+				`{"counter":"B","increments":[null,15,20],"values":[null,200,220]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(buildConsumptionSeries(tt.points, tt.byCounter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleConsumptionAnalyticsRejectsBadQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantError string
+	}{
+		{"group_by=month", "Invalid group_by. Must be 'counter'"},
+		{"year=abc", "Invalid year"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleConsumptionAnalytics(rec, newAuthedRequest(http.MethodGet, "/api/analytics/consumption?"+tt.query, "", 1))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", rec.Code)
+			}
+			if got := errorMessage(t, rec); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
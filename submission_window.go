@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // timezone-aware windows must work in slim images without system tzdata
+)
+
+// timeNow is the checker's clock; replaced to pin the time
+var timeNow = time.Now
+
+// ErrOutsideSubmissionHours is returned when a run falls outside the configured hours
+var ErrOutsideSubmissionHours = errors.New("outside_submission_hours")
+
+// validateSubmissionHours checks an optional [start, end) hour window and its timezone.
+// start == end is rejected; start > end wraps past midnight (e.g. 22-6).
+func validateSubmissionHours(start, end *int, timezone string) error {
+	if (start == nil) != (end == nil) {
+		return fmt.Errorf("submission hour start and end must be set together")
+	}
+	if start != nil {
+		if *start < 0 || *start > 23 {
+			return fmt.Errorf("submission hour start must be 0-23")
+		}
+		if *end < 1 || *end > 24 {
+			return fmt.Errorf("submission hour end must be 1-24")
+		}
+		if *start == *end {
+			return fmt.Errorf("submission hour start and end must differ")
+		}
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", timezone)
+		}
+	}
+	return nil
+}
+
+// submissionNow returns the current time in the configured submission timezone
+func (c *Config) submissionNow() time.Time {
+	now := timeNow()
+	if c.SubmissionTimezone != "" {
+		if loc, err := time.LoadLocation(c.SubmissionTimezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	return now
+}
+
+// checkSubmissionHours reports an error if now is outside the configured hour window
+func (c *Config) checkSubmissionHours(now time.Time) error {
+	if c.SubmissionHourStart == nil || c.SubmissionHourEnd == nil {
+		return nil
+	}
+	start, end, hour := *c.SubmissionHourStart, *c.SubmissionHourEnd, now.Hour()
+
+	inWindow := hour >= start && hour < end
+	if start > end {
+		inWindow = hour >= start || hour < end
+	}
+	if !inWindow {
+		return fmt.Errorf("%w: %s is outside %02d:00-%02d:00 %s",
+			ErrOutsideSubmissionHours, now.Format("15:04"), start, end, now.Location())
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int { return &n }
+
+// setTimeNowForTest pins timeNow to now for the rest of the test
+func setTimeNowForTest(t *testing.T, now time.Time) {
+	t.Helper()
+	prev := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = prev })
+}
+
+func TestValidateSubmissionHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end *int
+		timezone   string
+		wantErr    bool
+	}{
+		{"unset", nil, nil, "", false},
+		{"day window", intPtr(8), intPtr(20), "", false},
+		{"overnight window", intPtr(22), intPtr(6), "Europe/Kyiv", false},
+		{"up to midnight", intPtr(0), intPtr(24), "", false},
+		{"start only", intPtr(8), nil, "", true},
+		{"end only", nil, intPtr(20), "", true},
+		{"start out of range", intPtr(24), intPtr(6), "", true},
+		{"end zero", intPtr(8), intPtr(0), "", true},
+		{"empty window", intPtr(9), intPtr(9), "", true},
+		{"unknown timezone", nil, nil, "Mars/Olympus", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSubmissionHours(tt.start, tt.end, tt.timezone)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSubmissionHours() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckSubmissionHours(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC) }
+	tests := []struct {
+		name       string
+		start, end *int
+		now        time.Time
+		wantErr    bool
+	}{
+		{"no window", nil, nil, at(3, 0), false},
+		{"inside day window", intPtr(8), intPtr(20), at(12, 0), false},
+		{"start is inclusive", intPtr(8), intPtr(20), at(8, 0), false},
+		{"end is exclusive", intPtr(8), intPtr(20), at(20, 0), true},
+		{"last minute before end", intPtr(8), intPtr(20), at(19, 59), false},
+		{"before day window", intPtr(8), intPtr(20), at(7, 59), true},
+		{"overnight late", intPtr(22), intPtr(6), at(23, 30), false},
+		{"overnight early", intPtr(22), intPtr(6), at(5, 0), false},
+		{"overnight midday", intPtr(22), intPtr(6), at(12, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{SubmissionHourStart: tt.start, SubmissionHourEnd: tt.end}
+			err := c.checkSubmissionHours(tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSubmissionHours() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrOutsideSubmissionHours) {
+				t.Errorf("error %v does not wrap ErrOutsideSubmissionHours", err)
+			}
+		})
+	}
+}
+
+func TestSubmissionNow(t *testing.T) {
+	// 21:30 UTC is already the next day in Kyiv (UTC+3 in summer)
+	setTimeNowForTest(t, time.Date(2026, 7, 31, 21, 30, 0, 0, time.UTC))
+	tests := []struct {
+		timezone string
+		wantDay  int
+		wantHour int
+	}{
+		{"UTC", 31, 21},
+		{"Europe/Kyiv", 1, 0},
+		{"Mars/Olympus", 31, 21}, // unknown zones keep the clock's own location
+	}
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			now := (&Config{SubmissionTimezone: tt.timezone}).submissionNow()
+			if now.Day() != tt.wantDay || now.Hour() != tt.wantHour {
+				t.Errorf("submissionNow() = %s, want day %d hour %d", now, tt.wantDay, tt.wantHour)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultTelegramAPIURL is the Telegram Bot API base URL
+const defaultTelegramAPIURL = "https://api.telegram.org"
+
+var (
+	// telegramBotTokenPattern matches a BotFather token, e.g. 123456:ABC-DEF...
+	telegramBotTokenPattern = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]{30,}$`)
+	// telegramChatIDPattern matches a numeric chat ID or a public @channel name
+	telegramChatIDPattern = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z0-9_]{5,})$`)
+)
+
+// TelegramNotifier sends job results through the user's own Telegram bot
+type TelegramNotifier struct {
+	APIURL string
+	client *http.Client
+}
+
+// NewTelegramNotifier creates a Telegram notifier; an empty apiURL uses the public Bot API
+func NewTelegramNotifier(apiURL string) *TelegramNotifier {
+	if apiURL == "" {
+		apiURL = defaultTelegramAPIURL
+	}
+	return &TelegramNotifier{
+		APIURL: strings.TrimRight(apiURL, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NotifyJobComplete messages the job result if the user set a bot token and chat ID
+func (n *TelegramNotifier) NotifyJobComplete(job *Job, cfg *UserConfig) error {
+	if !IsEnabled(FlagTelegramNotifications) || cfg == nil || cfg.TelegramBotToken == "" || cfg.TelegramChatID == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"chat_id": cfg.TelegramChatID,
+		"text":    composeJobMessage(job),
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", n.APIURL, cfg.TelegramBotToken)
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL holds the bot token; don't let it end up in the job log
+		return fmt.Errorf("failed to reach Telegram: %w", redactError(err, cfg.TelegramBotToken))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Description string `json:"description"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+		return fmt.Errorf("telegram returned %s: %s", resp.Status, apiErr.Description)
+	}
+	return nil
+}
+
+// redactError replaces secret in the error text
+func redactError(err error, secret string) error {
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), secret, "***"))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testTelegramToken = "123456:ABCDEFGHIJKLMNOPQRSTUVWXYZabcdef"
+
+func TestTelegramNotifierSkips(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+	n := NewTelegramNotifier(srv.URL)
+
+	tests := []struct {
+		name    string
+		enabled bool
+		cfg     *UserConfig
+	}{
+		{"flag off", false, &UserConfig{TelegramBotToken: testTelegramToken, TelegramChatID: "42"}},
+		{"no config", true, nil},
+		{"no bot token", true, &UserConfig{TelegramChatID: "42"}},
+		{"no chat ID", true, &UserConfig{TelegramBotToken: testTelegramToken}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagTelegramNotifications: tt.enabled})
+			if err := n.NotifyJobComplete(&Job{ID: "job-1", Status: "completed"}, tt.cfg); err != nil {
+				t.Errorf("NotifyJobComplete() error = %v", err)
+			}
+		})
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("Telegram called %d times, want 0", n)
+	}
+}
+
+func TestTelegramNotifierSendsMessage(t *testing.T) {
+	setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagTelegramNotifications: true})
+	job := &Job{ID: "job-1", Type: "full", Status: "completed"}
+
+	var gotPath string
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	// A trailing slash on the API URL must not double up in the path
+	n := NewTelegramNotifier(srv.URL + "/")
+	if err := n.NotifyJobComplete(job, &UserConfig{TelegramBotToken: testTelegramToken, TelegramChatID: "@my_channel"}); err != nil {
+		t.Fatalf("NotifyJobComplete: %v", err)
+	}
+	if want := "/bot" + testTelegramToken + "/sendMessage"; gotPath != want {
+		t.Errorf("path = %q, want %q", gotPath, want)
+	}
+	if got["chat_id"] != "@my_channel" || got["text"] != composeJobMessage(job) {
+		t.Errorf("body = %v, want chat_id @my_channel and the job message", got)
+	}
+}
+
+func TestTelegramNotifierAPIError(t *testing.T) {
+	setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagTelegramNotifications: true})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	}))
+	defer srv.Close()
+
+	err := NewTelegramNotifier(srv.URL).NotifyJobComplete(&Job{ID: "job-1"}, &UserConfig{TelegramBotToken: testTelegramToken, TelegramChatID: "42"})
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("error = %v, want the API description", err)
+	}
+}
+
+func TestTelegramNotifierRedactsTokenFromTransportErrors(t *testing.T) {
+	setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagTelegramNotifications: true})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.Close() // nothing listens: the request URL, token included, ends up in the error
+
+	err := NewTelegramNotifier(srv.URL).NotifyJobComplete(&Job{ID: "job-1"}, &UserConfig{TelegramBotToken: testTelegramToken, TelegramChatID: "42"})
+	if err == nil {
+		t.Fatal("NotifyJobComplete() error = nil, want a transport error")
+	}
+	if strings.Contains(err.Error(), testTelegramToken) || !strings.Contains(err.Error(), "/bot***/") {
+		t.Errorf("error = %q, want the bot token redacted", err)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	tests := []struct {
+		err    string
+		secret string
+		want   string
+	}{
+		{"GET /botSECRET/x: refused", "SECRET", "GET /bot***/x: refused"},
+		{"SECRET and SECRET", "SECRET", "*** and ***"},
+		{"nothing to hide", "SECRET", "nothing to hide"},
+	}
+	for _, tt := range tests {
+		if got := redactError(errors.New(tt.err), tt.secret).Error(); got != tt.want {
+			t.Errorf("redactError(%q) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
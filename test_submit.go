@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// TestSubmitSandbox is the site a test-submit job runs the live flow against
+type TestSubmitSandbox struct {
+	// URL is the sandbox's main page; the indicator page is URL + "indicator"
+	URL string
+	// Email, Password and AccountNumber log in to the sandbox. The user's real
+	// Gasolina credentials are never sent to it.
+	Email         string
+	Password      string
+	AccountNumber string
+	// AllowRealSite lets URL point at the real Gasolina site
+	AllowRealSite bool
+}
+
+var testSubmitSandbox TestSubmitSandbox
+
+// SetTestSubmitSandbox configures the sandbox for test-submit jobs; an empty URL disables them
+func SetTestSubmitSandbox(s TestSubmitSandbox) {
+	testSubmitSandbox = s
+}
+
+// testSubmitEnabled reports whether a sandbox is configured for test-submit jobs
+func testSubmitEnabled() bool {
+	return testSubmitSandbox.URL != ""
+}
+
+// validateTestSubmitSandbox checks the sandbox URL and refuses the real site
+// unless explicitly allowed
+func validateTestSubmitSandbox(s TestSubmitSandbox) error {
+	if s.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("sandbox URL must be an absolute http(s) URL, got %q", s.URL)
+	}
+	if isRealSiteHost(u.Hostname()) && !s.AllowRealSite {
+		return fmt.Errorf("sandbox URL %s is the real Gasolina site; set TEST_SUBMIT_ALLOW_REAL_SITE=true to allow it", s.URL)
+	}
+	return nil
+}
+
+// isRealSiteHost reports whether host is the production Gasolina site
+func isRealSiteHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host == allowedSiteHost || strings.HasSuffix(host, "."+allowedSiteHost)
+}
+
+// sandboxConfig returns the job config pointed at the sandbox: its URLs and
+// credentials, live submission and no submission window
+func (s TestSubmitSandbox) sandboxConfig(cfg *UserConfig) (*Config, error) {
+	if err := validateTestSubmitSandbox(s); err != nil {
+		return nil, err
+	}
+	base := s.URL
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	config := cfg.ToConfig()
+	config.Email = s.Email
+	config.Password = s.Password
+	config.AccountNumber = s.AccountNumber
+	config.BaseURL = base
+	config.LoginURL = base
+	config.CheckURL = base + "indicator"
+	config.DryRun = false
+	config.IgnoreSubmissionWindow = true
+	config.SubmissionHourStart, config.SubmissionHourEnd = nil, nil
+	config.LandingURLPattern = ""
+	// Sandbox submissions must not confirm or abandon the user's real ones
+	config.Submissions = nil
+	return config, nil
+}
+
+// runTestSubmitJob runs the whole flow, including the submit click, against
+// the sandbox. The global dry-run kill switch still applies to the real site.
+func (e *chromedpExecutor) runTestSubmitJob(ctx context.Context, job *Job, cfg *UserConfig, logger *JobLogger, saveScreenshot func(string)) error {
+	if !testSubmitEnabled() {
+		return fmt.Errorf("test-submit is not available: TEST_SUBMIT_SANDBOX_URL is not set")
+	}
+	config, err := testSubmitSandbox.sandboxConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if globalForceDryRun {
+		if u, err := url.Parse(config.BaseURL); err == nil && isRealSiteHost(u.Hostname()) {
+			logger.Log("GLOBAL_FORCE_DRY_RUN is set on this server: the form will be filled but not submitted")
+			config.DryRun = true
+		}
+	}
+	logger.Log(fmt.Sprintf("Starting submit test against sandbox %s", config.BaseURL))
+
+	if err := GasolinaLogin(ctx, config, logger, saveScreenshot); err != nil {
+		return fmt.Errorf("sandbox login failed: %w", err)
+	}
+
+	applyIncrementsSource(ctx, config, logger)
+	if err := CheckAndUpdateIfNeededWithLogger(ctx, config, logger, saveScreenshot); err != nil {
+		return fmt.Errorf("sandbox submit failed: %w", err)
+	}
+
+	saveScreenshot("test_submit_success")
+	logger.Log("Submit test passed")
+	return nil
+}
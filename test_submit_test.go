@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestIsRealSiteHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{allowedSiteHost, true},
+		{"www." + allowedSiteHost, true},
+		{allowedSiteHost + ".", true},
+		{"GASOLINA." + allowedSiteHost, true},
+		{"sandbox.example.com", false},
+		{"not" + allowedSiteHost, false},
+		{allowedSiteHost + ".example.com", false},
+	}
+	for _, tt := range tests {
+		if got := isRealSiteHost(tt.host); got != tt.want {
+			t.Errorf("isRealSiteHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTestSubmitSandbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		sandbox TestSubmitSandbox
+		wantErr bool
+	}{
+		{"disabled", TestSubmitSandbox{}, false},
+		{"sandbox", TestSubmitSandbox{URL: "http://localhost:8081"}, false},
+		{"relative URL", TestSubmitSandbox{URL: "sandbox/"}, true},
+		{"other scheme", TestSubmitSandbox{URL: "ftp://sandbox.example.com"}, true},
+		{"real site", TestSubmitSandbox{URL: "https://" + allowedSiteHost + "/"}, true},
+		{"real site allowed", TestSubmitSandbox{URL: "https://" + allowedSiteHost + "/", AllowRealSite: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTestSubmitSandbox(tt.sandbox); (err != nil) != tt.wantErr {
+				t.Errorf("validateTestSubmitSandbox() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSandboxConfig(t *testing.T) {
+	sandbox := TestSubmitSandbox{URL: "http://localhost:8081", Email: "sandbox@example.com", Password: "sandbox", AccountNumber: "000001"}
+	user := &UserConfig{AccountNumber: "123456", DryRun: true, SubmissionHourStart: intPtr(9), SubmissionHourEnd: intPtr(17)}
+
+	config, err := sandbox.sandboxConfig(user)
+	if err != nil {
+		t.Fatalf("sandboxConfig: %v", err)
+	}
+	if config.Email != sandbox.Email || config.Password != sandbox.Password || config.AccountNumber != sandbox.AccountNumber {
+		t.Errorf("credentials = %q/%q/%q, want the sandbox's", config.Email, config.Password, config.AccountNumber)
+	}
+	if config.BaseURL != "http://localhost:8081/" || config.CheckURL != "http://localhost:8081/indicator" {
+		t.Errorf("URLs = %q, %q", config.BaseURL, config.CheckURL)
+	}
+	if config.DryRun || !config.IgnoreSubmissionWindow || config.SubmissionHourStart != nil || config.Submissions != nil {
+		t.Errorf("config = %+v, want a live run without window, hours or submission tracking", config)
+	}
+
+	if _, err := (TestSubmitSandbox{URL: "https://" + allowedSiteHost}).sandboxConfig(user); err == nil {
+		t.Error("sandboxConfig() pointed at the real site without AllowRealSite")
+	}
+}
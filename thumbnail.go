@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var thumbnailMaxDimension = 320
+
+// SetThumbnailMaxDimension sets the maximum width/height of screenshot thumbnails
+func SetThumbnailMaxDimension(size int) {
+	if size > 0 {
+		thumbnailMaxDimension = size
+	}
+}
+
+// handleGetScreenshotThumbnail serves a downscaled PNG of a screenshot, generating
+// and caching it next to the original on first request
+func handleGetScreenshotThumbnail(w http.ResponseWriter, r *http.Request, jobID, filename string) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := GetUserIDFromContext(r.Context())
+	if !ok {
+		jsonError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := requireOwnedJob(r.Context(), userID, jobID); err != nil {
+		writeJobLookupError(w, r, err)
+		return
+	}
+
+	filename, ok = sanitizeFilename(filename)
+	if !ok {
+		jsonError(w, "Invalid filename", http.StatusBadRequest)
+		return
+	}
+
+	dir := filepath.Join(screenshotsPath, fmt.Sprintf("%d", userID), jobID)
+	srcPath := filepath.Join(dir, filename)
+	info, err := os.Stat(srcPath)
+	if err != nil || info.IsDir() {
+		jsonError(w, "Screenshot not found", http.StatusNotFound)
+		return
+	}
+
+	thumbPath := filepath.Join(dir, thumbnailFilename(filename, thumbnailMaxDimension))
+	thumbInfo, err := os.Stat(thumbPath)
+	if err != nil || thumbInfo.ModTime().Before(info.ModTime()) {
+		if err := generateThumbnail(srcPath, thumbPath, thumbnailMaxDimension); err != nil {
+			jsonError(w, "Failed to generate thumbnail", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	file, err := os.Open(thumbPath)
+	if err != nil {
+		jsonError(w, "Failed to open thumbnail", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		jsonError(w, "Failed to open thumbnail", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, filepath.Base(thumbPath), stat.ModTime(), file)
+}
+
+// thumbnailFilename returns the cache filename for a thumbnail of the given size
+func thumbnailFilename(filename string, maxDim int) string {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return fmt.Sprintf("%s.thumb%d.png", base, maxDim)
+}
+
+// generateThumbnail decodes an image, downscales it to fit maxDim and writes it as PNG
+func generateThumbnail(srcPath, dstPath string, maxDim int) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	thumb := resizeToFit(img, maxDim)
+
+	// Write to a temp file first so concurrent requests never serve a partial thumbnail
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), ".thumb-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := png.Encode(tmp, thumb); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dstPath)
+}
+
+// resizeToFit downscales img so neither side exceeds maxDim, averaging the
+// source pixels covered by each destination pixel. Smaller images are returned as-is.
+func resizeToFit(img image.Image, maxDim int) image.Image {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW <= maxDim && srcH <= maxDim {
+		return img
+	}
+
+	dstW, dstH := maxDim, maxDim
+	if srcW > srcH {
+		dstH = max(1, srcH*maxDim/srcW)
+	} else {
+		dstW = max(1, srcW*maxDim/srcH)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := b.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, b.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := b.Min.X + x*srcW/dstW
+			x1 := max(x0+1, b.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThumbnailFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		maxDim   int
+		want     string
+	}{
+		{"success.png", 320, "success.thumb320.png"},
+		{"step.1.jpg", 160, "step.1.thumb160.png"},
+		{"noext", 320, "noext.thumb320.png"},
+	}
+	for _, tt := range tests {
+		if got := thumbnailFilename(tt.filename, tt.maxDim); got != tt.want {
+			t.Errorf("thumbnailFilename(%q, %d) = %q, want %q", tt.filename, tt.maxDim, got, tt.want)
+		}
+	}
+}
+
+func TestResizeToFit(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h, maxDim int
+		wantW, wantH int
+	}{
+		{"smaller is untouched", 100, 50, 320, 100, 50},
+		{"exact fit is untouched", 320, 320, 320, 320, 320},
+		{"landscape", 1280, 720, 320, 320, 180},
+		{"portrait", 720, 1280, 320, 180, 320},
+		{"thin strip keeps a pixel", 4000, 2, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.w, tt.h))
+			b := resizeToFit(img, tt.maxDim).Bounds()
+			if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("resized to %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestResizeToFitAveragesPixels(t *testing.T) {
+	// Alternating black and white columns average to mid grey
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		c := color.RGBA{A: 255}
+		if x%2 == 1 {
+			c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		}
+		img.Set(x, 0, c)
+		img.Set(x, 1, c)
+	}
+	r, _, _, a := resizeToFit(img, 2).At(0, 0).RGBA()
+	if r>>8 != 127 || a>>8 != 255 {
+		t.Errorf("averaged pixel red %d alpha %d, want 127 and 255", r>>8, a>>8)
+	}
+}
+
+func TestGenerateThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shot.png")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 640, 480))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dst := filepath.Join(dir, thumbnailFilename("shot.png", 64))
+	if err := generateThumbnail(src, dst, 64); err != nil {
+		t.Fatalf("generateThumbnail: %v", err)
+	}
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	cfg, err := png.DecodeConfig(out)
+	if err != nil {
+		t.Fatalf("thumbnail is not a PNG: %v", err)
+	}
+	if cfg.Width != 64 || cfg.Height != 48 {
+		t.Errorf("thumbnail is %dx%d, want 64x48", cfg.Width, cfg.Height)
+	}
+
+	if err := generateThumbnail(filepath.Join(dir, "missing.png"), dst, 64); err == nil {
+		t.Error("generateThumbnail of a missing file succeeded")
+	}
+}
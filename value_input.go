@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// How the reading is put into the #value input
+const (
+	// ValueInputModeType types the value like a user would (default)
+	ValueInputModeType = "type"
+	// ValueInputModeJS sets the value from JavaScript and fires input/change
+	// events, for masked inputs that mangle typed keys
+	ValueInputModeJS = "js"
+)
+
+var valueInputMode = ValueInputModeType
+
+// SetValueInputMode selects how readings are entered into the form
+func SetValueInputMode(mode string) error {
+	switch mode {
+	case "":
+		valueInputMode = ValueInputModeType
+	case ValueInputModeType, ValueInputModeJS:
+		valueInputMode = mode
+	default:
+		return fmt.Errorf("value input mode must be %q or %q, got %q", ValueInputModeType, ValueInputModeJS, mode)
+	}
+	return nil
+}
+
+// ErrInputNotCleared is returned when a pre-filled input keeps its content
+var ErrInputNotCleared = errors.New("input_not_cleared")
+
+// ErrValueMismatch is returned when the input doesn't hold the value we entered
+var ErrValueMismatch = errors.New("value_mismatch")
+
+// setInputValueJS sets an input's value through the native setter, so
+// framework-managed inputs notice, and fires the events a user would
+const setInputValueJS = `
+	(function(selector, value) {
+		const el = document.querySelector(selector);
+		if (!el) return false;
+		el.focus();
+		const setter = Object.getOwnPropertyDescriptor(HTMLInputElement.prototype, 'value').set;
+		setter.call(el, value);
+		el.dispatchEvent(new Event('input', {bubbles: true}));
+		el.dispatchEvent(new Event('change', {bubbles: true}));
+		return true;
+	})(%q, %q)
+`
+
+// clearInput empties an input that may be pre-filled or masked. It clears via
+// JavaScript first and falls back to deleting key by key, then verifies.
+func clearInput(ctx context.Context, selector string, logger Logger) error {
+	var found bool
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(setInputValueJS, selector, ""), &found)); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", selector, err)
+	}
+	if !found {
+		return fmt.Errorf("input %s not found", selector)
+	}
+
+	var residue string
+	if err := chromedp.Run(ctx, chromedp.Value(selector, &residue, chromedp.ByQuery)); err != nil {
+		return fmt.Errorf("failed to read %s: %w", selector, err)
+	}
+	if residue == "" {
+		return nil
+	}
+
+	// Some masks restore their content; delete it the way a user would
+	logVerbose(logger, fmt.Sprintf("Input %s still holds %q after clearing, deleting by key", selector, residue))
+	keys := kb.End + strings.Repeat(kb.Backspace, len([]rune(residue)))
+	if err := chromedp.Run(ctx,
+		chromedp.SendKeys(selector, keys, chromedp.ByQuery),
+		chromedp.Value(selector, &residue, chromedp.ByQuery),
+	); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", selector, err)
+	}
+	if residue != "" {
+		return fmt.Errorf("%w: %s still contains %q", ErrInputNotCleared, selector, residue)
+	}
+	return nil
+}
+
+// fillValueInput clears the input and enters value using valueInputMode
+func fillValueInput(ctx context.Context, selector, value string, logger Logger) error {
+	if err := clearInput(ctx, selector, logger); err != nil {
+		return err
+	}
+
+	if valueInputMode == ValueInputModeJS {
+		return chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(setInputValueJS, selector, value), nil))
+	}
+	return chromedp.Run(ctx, chromedp.SendKeys(selector, value, chromedp.ByQuery))
+}
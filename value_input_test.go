@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetValueInputMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{"", ValueInputModeType, false},
+		{"type", ValueInputModeType, false},
+		{"js", ValueInputModeJS, false},
+		{"paste", ValueInputModeJS, true}, // a bad mode keeps the previous one
+	}
+	prev := valueInputMode
+	t.Cleanup(func() { valueInputMode = prev })
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			err := SetValueInputMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetValueInputMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if valueInputMode != tt.want {
+				t.Errorf("valueInputMode = %q, want %q", valueInputMode, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetInputValueJSQuotesArguments(t *testing.T) {
+	// A value can't break out of its string literal into the page
+	script := strings.TrimSpace(fmt.Sprintf(setInputValueJS, `input[name="value"]`, `1'); alert(1); ('`))
+	want := `})("input[name=\"value\"]", "1'); alert(1); ('")`
+	if !strings.HasSuffix(script, want) {
+		t.Errorf("script ends with %q, want %q", script[strings.LastIndex(script, "})"):], want)
+	}
+}
+
+func TestFillValueInputWithoutBrowser(t *testing.T) {
+	logger := &testLogger{}
+	err := fillValueInput(context.Background(), "#value", "1234", logger)
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to clear #value") {
+		t.Errorf("fillValueInput() error = %v, want a clear failure", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// defaultElementWaitTimeout bounds waits for elements the page should show
+const defaultElementWaitTimeout = 10 * time.Second
+
+// elementWaitTimeout is how long to wait for an awaited element or page state
+// before failing instead of hanging the job
+var elementWaitTimeout = defaultElementWaitTimeout
+
+// SetElementWaitTimeout sets how long to wait for awaited elements
+func SetElementWaitTimeout(d time.Duration) {
+	elementWaitTimeout = d
+}
+
+// ParseElementWaitTimeout parses GASOLINA_ELEMENT_WAIT_TIMEOUT; empty means the default
+func ParseElementWaitTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultElementWaitTimeout, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+// waitPollInterval is how often waitUntil re-evaluates its predicate
+const waitPollInterval = 100 * time.Millisecond
+
+// waitVisible waits for selector to become visible, up to elementWaitTimeout
+func waitVisible(ctx context.Context, selector string) error {
+	wctx, cancel := context.WithTimeout(ctx, elementWaitTimeout)
+	defer cancel()
+	if err := chromedp.Run(wctx, chromedp.WaitVisible(selector, chromedp.ByQuery)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("%s did not become visible within %v", selector, elementWaitTimeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// waitUntil polls a JavaScript predicate until it is truthy, up to
+// elementWaitTimeout. Unlike chromedp.Poll it survives navigations: evaluation
+// errors while a new page loads are retried.
+func waitUntil(ctx context.Context, what, predicate string) error {
+	script := fmt.Sprintf("!!(%s)", predicate)
+	deadline := time.Now().Add(elementWaitTimeout)
+	for {
+		var ok bool
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &ok)); err == nil && ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for %s", elementWaitTimeout, what)
+		}
+		if err := sleepCtx(ctx, waitPollInterval); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseElementWaitTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", defaultElementWaitTimeout, false},
+		{"30s", 30 * time.Second, false},
+		{"1500ms", 1500 * time.Millisecond, false},
+		{"0s", 0, true},
+		{"-5s", 0, true},
+		{"10", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseElementWaitTimeout(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseElementWaitTimeout(%q) = %v, %v, want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	prev := elementWaitTimeout
+	t.Cleanup(func() { SetElementWaitTimeout(prev) })
+	SetElementWaitTimeout(150 * time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Without a browser the predicate never evaluates, like a page that keeps loading
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr func(error) bool
+		maxWait time.Duration
+	}{
+		{"gives up at the timeout", context.Background(),
+			func(err error) bool { return err != nil && strings.Contains(err.Error(), "the reading form") }, time.Second},
+		{"stops when cancelled", cancelled,
+			func(err error) bool { return errors.Is(err, context.Canceled) }, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := waitUntil(tt.ctx, "the reading form", "document.querySelector('#value')")
+			if !tt.wantErr(err) {
+				t.Errorf("waitUntil() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxWait {
+				t.Errorf("waitUntil() took %v, want at most %v", elapsed, tt.maxWait)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ProgressEvent is posted to the progress webhook on each phase transition
+type ProgressEvent struct {
+	JobID    string    `json:"job_id"`
+	Phase    string    `json:"phase"`
+	Progress int       `json:"progress"`
+	At       time.Time `json:"at"`
+}
+
+// progressWebhookQueue bounds events waiting to be posted; beyond it they are dropped
+const progressWebhookQueue = 100
+
+// ProgressWebhook posts phase events to a URL, one at a time and in order,
+// so a slow endpoint never holds up the browser automation
+type ProgressWebhook struct {
+	url    string
+	client *http.Client
+	events chan ProgressEvent
+}
+
+// progressWebhook is nil unless PROGRESS_WEBHOOK_URL is set
+var progressWebhook *ProgressWebhook
+
+// SetProgressWebhook sets the global progress webhook (nil disables it)
+func SetProgressWebhook(w *ProgressWebhook) {
+	progressWebhook = w
+}
+
+// NewProgressWebhook validates the URL and starts the sender
+func NewProgressWebhook(rawURL string) (*ProgressWebhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("must be an http(s) URL")
+	}
+	w := &ProgressWebhook{
+		url:    rawURL,
+		client: &http.Client{Timeout: 5 * time.Second},
+		events: make(chan ProgressEvent, progressWebhookQueue),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Send queues an event without blocking
+func (w *ProgressWebhook) Send(ev ProgressEvent) {
+	select {
+	case w.events <- ev:
+	default:
+		slog.Warn("Progress webhook queue full, dropping event", "phase", ev.Phase, "job_id", ev.JobID)
+	}
+}
+
+// run posts queued events until the process exits
+func (w *ProgressWebhook) run() {
+	for ev := range w.events {
+		if err := w.post(ev); err != nil {
+			slog.Error("Progress webhook failed", "job_id", ev.JobID, "phase", ev.Phase, "error", err)
+		}
+	}
+}
+
+// post delivers one event; dashboards only care about the latest state, so
+// failures are logged and not retried
+func (w *ProgressWebhook) post(ev ProgressEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyProgress posts a phase event when the webhooks flag is on
+func notifyProgress(jobID, phase string, progress int) {
+	if progressWebhook == nil || !IsEnabled(FlagWebhooks) {
+		return
+	}
+	progressWebhook.Send(ProgressEvent{
+		JobID:    jobID,
+		Phase:    phase,
+		Progress: progress,
+		At:       time.Now().UTC(),
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewProgressWebhookValidatesURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://hooks.example.com/progress", false},
+		{"http://localhost:9000/progress", false},
+		{"ftp://hooks.example.com/progress", true},
+		{"hooks.example.com/progress", true},
+		{"https://", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := NewProgressWebhook(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewProgressWebhook(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProgressWebhookPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusNoContent, false},
+		{"server error", http.StatusInternalServerError, true},
+		{"redirect", http.StatusFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ProgressEvent
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decode event: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			w := &ProgressWebhook{url: srv.URL, client: srv.Client()}
+			ev := ProgressEvent{JobID: "job-1", Phase: PhaseLoginDone, Progress: 25, At: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)}
+			if err := w.post(ev); (err != nil) != tt.wantErr {
+				t.Errorf("post() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.At.Equal(ev.At) || got.JobID != ev.JobID || got.Phase != ev.Phase || got.Progress != ev.Progress {
+				t.Errorf("posted %+v, want %+v", got, ev)
+			}
+		})
+	}
+}
+
+func TestProgressWebhookSendDropsWhenFull(t *testing.T) {
+	// No sender is running, so the queue only fills up
+	w := &ProgressWebhook{events: make(chan ProgressEvent, 2)}
+	for i := 0; i < 5; i++ {
+		w.Send(ProgressEvent{JobID: "job-1", Progress: i})
+	}
+	if len(w.events) != 2 {
+		t.Fatalf("queued %d events, want 2", len(w.events))
+	}
+	if first := <-w.events; first.Progress != 0 {
+		t.Errorf("first queued event has progress %d, want the oldest (0)", first.Progress)
+	}
+}
+
+func TestNotifyProgress(t *testing.T) {
+	prev := progressWebhook
+	t.Cleanup(func() { SetProgressWebhook(prev) })
+
+	tests := []struct {
+		name       string
+		hook       bool
+		flagOn     bool
+		wantQueued int
+	}{
+		{"enabled", true, true, 1},
+		{"flag off", true, false, 0},
+		{"no webhook", false, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFeatureFlagsForTest(t, map[FeatureFlag]bool{FlagWebhooks: tt.flagOn})
+			w := &ProgressWebhook{events: make(chan ProgressEvent, 1)}
+			SetProgressWebhook(nil)
+			if tt.hook {
+				SetProgressWebhook(w)
+			}
+			notifyProgress("job-1", PhaseValueRead, 50)
+			if len(w.events) != tt.wantQueued {
+				t.Errorf("queued %d events, want %d", len(w.events), tt.wantQueued)
+			}
+		})
+	}
+}